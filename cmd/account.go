@@ -6,7 +6,9 @@ import (
 	"os"
 	"strings"
 	"text/tabwriter"
+	"time"
 
+	"github.com/guzus/birdy/internal/output"
 	"github.com/guzus/birdy/internal/store"
 	"github.com/spf13/cobra"
 )
@@ -72,7 +74,28 @@ var accountListCmd = &cobra.Command{
 			return err
 		}
 
+		format, fields, err := parseOutputFlags(formatFlag, fieldsFlag)
+		if err != nil {
+			return err
+		}
+
 		accounts := st.List()
+		if format != output.Raw {
+			rows := output.Rows{Fields: []string{"name", "uses", "last_used", "added"}}
+			for _, a := range accounts {
+				lastUsed := ""
+				if !a.LastUsed.IsZero() {
+					lastUsed = a.LastUsed.Format(time.RFC3339)
+				}
+				rows.Items = append(rows.Items, map[string]any{
+					"name":      a.Name,
+					"uses":      a.UseCount,
+					"last_used": lastUsed,
+					"added":     a.AddedAt.Format(time.RFC3339),
+				})
+			}
+			return output.Render(os.Stdout, format, rows, fields)
+		}
 		if len(accounts) == 0 {
 			fmt.Println("No accounts configured. Run: birdy account add <name>")
 			return nil
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/mcp"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/spf13/cobra"
+)
+
+var agentExecCmd = &cobra.Command{
+	Use:   "agent-exec",
+	Short: "Serve an agent run's bird tools over MCP stdio",
+	Long: `The entrypoint the claude CLI uses to run bird commands for birdy's agent.
+It speaks MCP on stdin/stdout like birdy mcp, but each tool call is checked
+against the agent policy before it runs:
+
+  - the input must match the command's parameters in the bird command
+    registry; args are built from it, never parsed from a shell string
+  - write commands are left out in read-only mode
+  - the agent profile (BIRDY_AGENT_PROFILE) limits commands and accounts
+  - the run's budget (BIRDY_AGENT_RUN_ID) is charged for each call
+  - writes wait for the user's approval when the run has an approval socket
+  - tweet texts, user names and bios in the output are marked as untrusted content
+
+Agent runs start it themselves; there is no need to run it by hand.`,
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prof, err := profile.FromEnv()
+		if err != nil {
+			return fmt.Errorf("loading agent profile: %w", err)
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		srv := &mcp.Server{
+			Name:    "birdy",
+			Version: version,
+			Tools:   claude.MCPTools(strategyFlag, audit.Caller(), audit.RunID(), os.Getenv(approval.SocketEnv), prof),
+		}
+		return srv.ServeStdio(ctx, cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(agentExecCmd)
+}
@@ -4,17 +4,21 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/guzus/birdy/internal/answercache"
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/budget"
 	"github.com/guzus/birdy/internal/claude"
-	"github.com/guzus/birdy/internal/rotation"
-	"github.com/guzus/birdy/internal/runner"
-	"github.com/guzus/birdy/internal/state"
-	"github.com/guzus/birdy/internal/store"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/recipe"
 )
 
 type apiError struct {
@@ -39,35 +43,26 @@ type apiCommandResponse struct {
 }
 
 type apiChatRequest struct {
-	Prompt string `json:"prompt"`
-	Model  string `json:"model,omitempty"`
+	Prompt  string            `json:"prompt"`
+	Model   string            `json:"model,omitempty"`
+	Profile string            `json:"profile,omitempty"` // agent profile; see internal/profile
+	Recipe  string            `json:"recipe,omitempty"`  // run a recipe instead of a prompt
+	Params  map[string]string `json:"params,omitempty"`  // recipe parameters
+	Refresh bool              `json:"refresh,omitempty"` // answer a cached recipe again
+
+	run      *recipe.Run      // set by normalize for a recipe
+	resolved *profile.Profile // set by normalize
+	accounts []string         // the API key's account pool, set by limitTo
+	readOnly bool             // the API key lacks the write scope
 }
 
-var apiAllowedBirdCommands = map[string]struct{}{
-	"about":         {},
-	"bookmarks":     {},
-	"check":         {},
-	"follow":        {},
-	"followers":     {},
-	"following":     {},
-	"home":          {},
-	"likes":         {},
-	"list-timeline": {},
-	"lists":         {},
-	"mentions":      {},
-	"news":          {},
-	"query-ids":     {},
-	"read":          {},
-	"replies":       {},
-	"reply":         {},
-	"search":        {},
-	"thread":        {},
-	"tweet":         {},
-	"unbookmark":    {},
-	"unfollow":      {},
-	"user-tweets":   {},
-	"whoami":        {},
-}
+var apiAllowedBirdCommands = func() map[string]struct{} {
+	m := map[string]struct{}{}
+	for _, c := range birdcmd.Commands {
+		m[c.Name] = struct{}{}
+	}
+	return m
+}()
 
 func hostRequestInviteCode(r *http.Request) string {
 	if v := strings.TrimSpace(r.Header.Get("X-Invite-Code")); v != "" {
@@ -92,6 +87,77 @@ func apiAuthorized(r *http.Request, inviteCode string) bool {
 	return subtle.ConstantTimeCompare([]byte(inviteCode), []byte(got)) == 1
 }
 
+// defaultInviteScopes keeps the invite code as capable as it was before API
+// keys existed, so the web client continues to work.
+var defaultInviteScopes = []apikey.Scope{apikey.ScopeRead, apikey.ScopeWrite, apikey.ScopeChat}
+
+// hostInviteScopes is what the host invite code may do on the API; set from
+// --invite-scopes when the host starts. Empty disables API access with the
+// invite code.
+var hostInviteScopes = defaultInviteScopes
+
+// apiAuthenticate resolves the caller of an API request: the host invite
+// code, or a key created with `birdy apikey create`.
+func apiAuthenticate(r *http.Request, inviteCode string) (*apikey.Key, error) {
+	token := hostRequestInviteCode(r)
+	if token == "" {
+		authFailures.Inc("api", "missing")
+		return nil, apikey.ErrInvalid
+	}
+	if apiAuthorized(r, inviteCode) {
+		if len(hostInviteScopes) == 0 {
+			authFailures.Inc("api", "invalid")
+			return nil, apikey.ErrInvalid
+		}
+		return &apikey.Key{Name: "invite", Scopes: hostInviteScopes}, nil
+	}
+
+	keys, err := apikey.Open()
+	if err != nil {
+		return nil, err
+	}
+	key, err := keys.Authenticate(token, time.Now())
+	if err != nil {
+		reason := "invalid"
+		if errors.Is(err, apikey.ErrExpired) {
+			reason = "expired"
+		}
+		authFailures.Inc("api", reason)
+		return nil, err
+	}
+	// last_used is informational; a failed write should not reject the call.
+	_ = keys.Touch(key.ID, time.Now())
+	return key, nil
+}
+
+// apiAuthFailure maps an apiAuthenticate error to a status and message.
+func apiAuthFailure(err error) (int, string) {
+	switch {
+	case errors.Is(err, apikey.ErrExpired):
+		return http.StatusUnauthorized, err.Error()
+	case errors.Is(err, apikey.ErrInvalid):
+		return http.StatusUnauthorized, "unauthorized"
+	default:
+		return http.StatusInternalServerError, err.Error()
+	}
+}
+
+// apiCommandScope returns the scope needed to run a bird command.
+func apiCommandScope(args []string) apikey.Scope {
+	if _, ok := readOnlyBlockedBirdCommands[firstBirdCommand(args)]; ok {
+		return apikey.ScopeWrite
+	}
+	return apikey.ScopeRead
+}
+
+// apiCheckAccount rejects an explicitly requested account outside the key's pool.
+func apiCheckAccount(key *apikey.Key, account string) error {
+	if account = strings.TrimSpace(account); account != "" && !key.AllowsAccount(account) {
+		return fmt.Errorf("api key %q may not use account %q", key.Name, account)
+	}
+	return nil
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(status)
@@ -100,8 +166,18 @@ func writeJSON(w http.ResponseWriter, status int, v any) {
 
 func handleAPICommand(inviteCode string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !apiAuthorized(r, inviteCode) {
-			writeJSON(w, http.StatusUnauthorized, apiError{OK: false, Error: "unauthorized"})
+		if f := apiThrottleIP(w, r); f != nil {
+			writeJSON(w, f.Status, apiError{OK: false, Error: f.Message})
+			return
+		}
+		key, err := apiAuthenticate(r, inviteCode)
+		if err != nil {
+			status, msg := apiAuthFailure(err)
+			writeJSON(w, status, apiError{OK: false, Error: msg})
+			return
+		}
+		if f := apiThrottle(w, r, key); f != nil {
+			writeJSON(w, f.Status, apiError{OK: false, Error: f.Message})
 			return
 		}
 		if r.Method != http.MethodPost {
@@ -118,107 +194,147 @@ func handleAPICommand(inviteCode string) http.HandlerFunc {
 			return
 		}
 
-		cmdName := strings.TrimSpace(req.Command)
-		args := make([]string, 0, 1+len(req.Args))
-		if cmdName != "" {
-			args = append(args, cmdName)
-			args = append(args, req.Args...)
-		} else if len(req.Args) > 0 {
-			args = append(args, req.Args...)
-		}
-		if len(args) == 0 {
-			writeJSON(w, http.StatusBadRequest, apiError{OK: false, Error: "missing command"})
+		args, f := apiCommandArgs(key, req)
+		if f != nil {
+			writeJSON(w, f.Status, apiError{OK: false, Error: f.Message})
 			return
 		}
 
-		// This API is intentionally limited to the bird commands that birdy forwards
-		// (see cmd/bird_commands.go) so it can't be used to run arbitrary subcommands.
-		first := firstBirdCommand(args)
-		if first == "" {
-			writeJSON(w, http.StatusBadRequest, apiError{OK: false, Error: "missing command"})
-			return
-		}
-		if _, ok := apiAllowedBirdCommands[first]; !ok {
-			writeJSON(w, http.StatusBadRequest, apiError{OK: false, Error: "unsupported command"})
+		res, err := birdcmd.Run(r.Context(), birdcmd.Request{
+			Args:     args,
+			Account:  req.Account,
+			Strategy: apiStrategy(req.Strategy),
+			Pool:     key.Accounts,
+			Caller:   apiCaller(key),
+		})
+		if err != nil {
+			status, _ := apiRunErrorStatus(err)
+			if errors.Is(err, birdcmd.ErrNoAccounts) {
+				// Kept as 400 for existing /api/command clients.
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, apiError{OK: false, Error: err.Error()})
 			return
 		}
 
-		if blocked, name := isReadOnlyBirdCommand(args); blocked {
-			writeJSON(w, http.StatusForbidden, apiError{OK: false, Error: fmt.Sprintf("%q is disabled in read-only mode", name)})
-			return
-		}
+		writeJSON(w, http.StatusOK, apiCommandResponse{
+			OK:        true,
+			Account:   res.Account,
+			ExitCode:  res.ExitCode,
+			Stdout:    res.Stdout,
+			Stderr:    res.Stderr,
+			DurationM: res.Duration.Milliseconds(),
+		})
+	}
+}
 
-		start := time.Now()
+// apiCommandArgs builds and vets the bird argv for a command request: the
+// command must be one birdy forwards, allowed in read-only mode, covered by
+// the key's scopes and run on an account in its pool.
+func apiCommandArgs(key *apikey.Key, req apiCommandRequest) ([]string, *apiV1Failure) {
+	cmdName := strings.TrimSpace(req.Command)
+	args := make([]string, 0, 1+len(req.Args))
+	if cmdName != "" {
+		args = append(args, cmdName)
+		args = append(args, req.Args...)
+	} else if len(req.Args) > 0 {
+		args = append(args, req.Args...)
+	}
+	if len(args) == 0 {
+		return nil, apiV1BadRequest("missing command")
+	}
 
-		st, err := store.Open()
-		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, apiError{OK: false, Error: "opening account store"})
-			return
-		}
-		if st.Len() == 0 {
-			writeJSON(w, http.StatusBadRequest, apiError{OK: false, Error: "no accounts configured"})
-			return
-		}
+	// This API is intentionally limited to the bird commands that birdy forwards
+	// (see cmd/bird_commands.go) so it can't be used to run arbitrary subcommands.
+	first := firstBirdCommand(args)
+	if first == "" {
+		return nil, apiV1BadRequest("missing command")
+	}
+	if _, ok := apiAllowedBirdCommands[first]; !ok {
+		return nil, apiV1BadRequest("unsupported command")
+	}
 
-		var account *store.Account
-		accountName := strings.TrimSpace(req.Account)
-		if accountName != "" {
-			account, err = st.Get(accountName)
-			if err != nil {
-				writeJSON(w, http.StatusBadRequest, apiError{OK: false, Error: err.Error()})
-				return
-			}
-		} else {
-			strat := strategyFlag
-			if strings.TrimSpace(req.Strategy) != "" {
-				strat = strings.TrimSpace(req.Strategy)
-			}
-			parsed, err := rotation.ParseStrategy(strat)
-			if err != nil {
-				writeJSON(w, http.StatusBadRequest, apiError{OK: false, Error: "invalid strategy"})
-				return
-			}
+	if blocked, name := isReadOnlyBirdCommand(args); blocked {
+		return nil, apiForbidden(fmt.Sprintf("%q is disabled in read-only mode", name))
+	}
+	if scope := apiCommandScope(args); !key.Has(scope) {
+		return nil, apiForbidden(fmt.Sprintf("api key lacks the %q scope", scope))
+	}
+	if err := apiCheckAccount(key, req.Account); err != nil {
+		return nil, apiForbidden(err.Error())
+	}
+	return args, nil
+}
 
-			rs, err := state.Load()
-			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, apiError{OK: false, Error: "loading rotation state"})
-				return
-			}
-			account, err = rotation.Pick(st.List(), parsed, rs.LastUsedName)
-			if err != nil {
-				writeJSON(w, http.StatusInternalServerError, apiError{OK: false, Error: err.Error()})
-				return
-			}
+// apiChatAllowed checks that the key may start a chat. What the agent may
+// then do is limited by limitTo.
+func apiChatAllowed(key *apikey.Key) *apiV1Failure {
+	if !key.Has(apikey.ScopeChat) {
+		return apiForbidden(`api key lacks the "chat" scope`)
+	}
+	return nil
+}
 
-			rs.LastUsedName = account.Name
-			_ = rs.Save()
-			accountName = account.Name
-		}
+// limitTo holds the agent run of req to what key may do itself: its account
+// pool, and no write commands without the write scope.
+func (req *apiChatRequest) limitTo(key *apikey.Key) {
+	req.accounts = key.Accounts
+	req.readOnly = !key.Has(apikey.ScopeWrite)
+}
 
-		_ = st.RecordUsage(account.Name)
-		_ = st.Save()
+// apiChatBudget refuses new chats once a daily agent budget is exhausted.
+func apiChatBudget() *apiV1Failure {
+	err := claude.CheckBudget()
+	if errors.Is(err, budget.ErrExhausted) {
+		return &apiV1Failure{Status: http.StatusTooManyRequests, Code: "budget_exhausted", Message: err.Error()}
+	}
+	return nil
+}
 
-		exitCode, stdout, stderr, runErr := runner.RunCapture(account, args)
-		if runErr != nil {
-			writeJSON(w, http.StatusInternalServerError, apiError{OK: false, Error: runErr.Error()})
-			return
-		}
+func apiForbidden(msg string) *apiV1Failure {
+	return &apiV1Failure{Status: http.StatusForbidden, Code: "forbidden", Message: msg}
+}
 
-		writeJSON(w, http.StatusOK, apiCommandResponse{
-			OK:        true,
-			Account:   account.Name,
-			ExitCode:  exitCode,
-			Stdout:    stdout,
-			Stderr:    stderr,
-			DurationM: time.Since(start).Milliseconds(),
-		})
+// apiStrategy falls back to the host's --strategy when a request names none.
+func apiStrategy(requested string) string {
+	if s := strings.TrimSpace(requested); s != "" {
+		return s
+	}
+	return strategyFlag
+}
+
+// apiRunErrorStatus maps a birdcmd.Run failure to an HTTP status and a
+// stable error code.
+func apiRunErrorStatus(err error) (int, string) {
+	var inputErr *birdcmd.InputError
+	switch {
+	case errors.Is(err, birdcmd.ErrNoAccounts):
+		return http.StatusServiceUnavailable, "no_accounts"
+	case errors.As(err, &inputErr):
+		return http.StatusBadRequest, "bad_request"
+	default:
+		return http.StatusInternalServerError, "internal"
 	}
 }
 
 func handleAPIChat(inviteCode string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if !apiAuthorized(r, inviteCode) {
-			writeJSON(w, http.StatusUnauthorized, apiError{OK: false, Error: "unauthorized"})
+		if f := apiThrottleIP(w, r); f != nil {
+			writeJSON(w, f.Status, apiError{OK: false, Error: f.Message})
+			return
+		}
+		key, err := apiAuthenticate(r, inviteCode)
+		if err != nil {
+			status, msg := apiAuthFailure(err)
+			writeJSON(w, status, apiError{OK: false, Error: msg})
+			return
+		}
+		if f := apiThrottle(w, r, key); f != nil {
+			writeJSON(w, f.Status, apiError{OK: false, Error: f.Message})
+			return
+		}
+		if f := apiChatAllowed(key); f != nil {
+			writeJSON(w, f.Status, apiError{OK: false, Error: f.Message})
 			return
 		}
 		if r.Method != http.MethodPost {
@@ -234,46 +350,203 @@ func handleAPIChat(inviteCode string) http.HandlerFunc {
 			writeJSON(w, http.StatusBadRequest, apiError{OK: false, Error: "invalid json"})
 			return
 		}
-		prompt := strings.TrimSpace(req.Prompt)
-		if prompt == "" {
-			writeJSON(w, http.StatusBadRequest, apiError{OK: false, Error: "missing prompt"})
+		req.limitTo(key)
+		if f := req.normalize(); f != nil {
+			writeJSON(w, f.Status, apiError{OK: false, Error: f.Message})
 			return
 		}
-		model := strings.TrimSpace(req.Model)
-		if model == "" {
-			model = "sonnet"
+		if f := apiChatBudget(); f != nil {
+			writeJSON(w, f.Status, apiError{OK: false, Error: f.Message})
+			return
 		}
 
-		ctx, cancel := context.WithTimeout(r.Context(), 6*time.Minute)
-		defer cancel()
+		emit, ok := apiChatEventStream(w)
+		if !ok {
+			writeJSON(w, http.StatusInternalServerError, apiError{OK: false, Error: "streaming unsupported"})
+			return
+		}
+		runAPIChat(r.Context(), "stream", apiCaller(key), req, emit)
+	}
+}
+
+// apiChatEventStream sets the SSE headers and returns an emitter that writes
+// each agent event as it arrives.
+func apiChatEventStream(w http.ResponseWriter) (func(claude.Event), bool) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
 
-		exePath, err := os.Executable()
-		if err != nil || strings.TrimSpace(exePath) == "" {
-			exePath = "birdy"
+	enc := json.NewEncoder(w)
+	return func(ev claude.Event) {
+		// SSE: event + json payload
+		_, _ = fmt.Fprintf(w, "event: %s\n", ev.Type)
+		_, _ = fmt.Fprint(w, "data: ")
+		_ = enc.Encode(ev)
+		_, _ = fmt.Fprint(w, "\n")
+		flusher.Flush()
+	}, true
+}
+
+// normalize trims the prompt, or renders the recipe in its place, checks
+// the agent profile and fills in the default model: the recipe's, the
+// profile's, then the backend's.
+func (req *apiChatRequest) normalize() *apiV1Failure {
+	req.Prompt = strings.TrimSpace(req.Prompt)
+	if req.Recipe = strings.TrimSpace(req.Recipe); req.Recipe != "" {
+		if req.Prompt != "" {
+			return apiV1BadRequest("send either a prompt or a recipe, not both")
+		}
+		r, err := recipe.Load(req.Recipe)
+		if errors.Is(err, recipe.ErrNotFound) {
+			return apiV1BadRequest("unknown recipe %q", req.Recipe)
+		}
+		if err != nil {
+			return &apiV1Failure{Status: http.StatusInternalServerError, Code: "internal", Message: err.Error()}
+		}
+		run, err := r.Start(req.Params, time.Now())
+		if err != nil {
+			return apiV1BadRequest("%s", err.Error())
 		}
+		req.run, req.Prompt = run, run.Prompt
+		if strings.TrimSpace(req.Model) == "" {
+			req.Model = r.Model
+		}
+		if strings.TrimSpace(req.Profile) == "" {
+			req.Profile = r.Profile
+		}
+	}
+	if req.Prompt == "" {
+		return apiV1BadRequest("missing prompt")
+	}
+	req.Profile = strings.TrimSpace(req.Profile)
+	p, err := profile.Resolve(req.Profile, strings.Join(req.commands(), ","))
+	if errors.Is(err, profile.ErrNotFound) {
+		return apiV1BadRequest("unknown profile %q", req.Profile)
+	}
+	if err != nil {
+		return &apiV1Failure{Status: http.StatusInternalServerError, Code: "internal", Message: err.Error()}
+	}
+	if p, err = p.LimitAccounts(strings.Join(req.accounts, ",")); err != nil {
+		return apiForbidden(err.Error())
+	}
+	req.resolved = p
+	req.Model = strings.TrimSpace(req.Model)
+	if req.Model == "" && p != nil {
+		req.Model = p.Model
+	}
+	if req.Model == "" {
+		req.Model = claude.DefaultModel()
+	}
+	return nil
+}
 
-		w.Header().Set("Content-Type", "text/event-stream")
-		w.Header().Set("Cache-Control", "no-cache")
-		w.Header().Set("Connection", "keep-alive")
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("X-Accel-Buffering", "no")
+// commands is the command limit of the request's recipe, if any.
+func (req *apiChatRequest) commands() []string {
+	if req.run == nil {
+		return nil
+	}
+	return req.run.Recipe.Commands
+}
 
-		flusher, ok := w.(http.Flusher)
-		if !ok {
-			writeJSON(w, http.StatusInternalServerError, apiError{OK: false, Error: "streaming unsupported"})
-			return
+// agentEnv is the environment naming the request's profile, command limit
+// and account pool for the agent run, read-only when limitTo says so.
+func (req *apiChatRequest) agentEnv() []string {
+	env := []string{
+		profile.Env + "=" + req.Profile,
+		profile.CommandsEnv + "=" + strings.Join(req.commands(), ","),
+		profile.AccountsEnv + "=" + strings.Join(req.accounts, ","),
+	}
+	if req.readOnly {
+		env = append(env, birdcmd.ReadOnlyEnv+"=1")
+	}
+	return env
+}
+
+// streamAgent runs the agent for a normalized request. The answer of a
+// recipe with cache set is kept in the answer cache, and later runs with the
+// same prompt, account pool, model, profile and read-only mode replay it as
+// a snapshot with cached_at set, unless the request asks for a refresh. It
+// reports whether the answer came from the cache.
+func streamAgent(ctx context.Context, req apiChatRequest, exePath string, env []string, emit func(claude.Event)) bool {
+	if req.run == nil || req.run.Recipe.Cache <= 0 {
+		claude.Stream(ctx, req.Prompt, req.Model, exePath, env, emit)
+		return false
+	}
+	cache, err := answercache.Open()
+	if err != nil {
+		claude.Stream(ctx, req.Prompt, req.Model, exePath, env, emit)
+		return false
+	}
+	key := answercache.Key{Prompt: req.Prompt, Model: req.Model, ReadOnly: req.readOnly || birdcmd.ReadOnly()}
+	var pool []string
+	if req.resolved != nil {
+		pool = req.resolved.Accounts
+		key.Profile = req.resolved.Name
+		key.ReadOnly = key.ReadOnly || req.resolved.ReadOnly
+	}
+	key.Accounts = answercache.Pool(pool)
+	if !req.Refresh {
+		if e, ok, _ := cache.Get(key, time.Now()); ok {
+			emit(claude.Event{Type: claude.EventSnapshot, Text: e.Answer, CachedAt: e.CachedAt})
+			emit(claude.Event{Type: claude.EventDone})
+			return true
 		}
+	}
 
-		enc := json.NewEncoder(w)
-		emit := func(ev claude.Event) {
-			// SSE: event + json payload
-			_, _ = fmt.Fprintf(w, "event: %s\n", ev.Type)
-			_, _ = fmt.Fprint(w, "data: ")
-			_ = enc.Encode(ev)
-			_, _ = fmt.Fprint(w, "\n")
-			flusher.Flush()
+	var reply sessionReply
+	failed := false
+	claude.Stream(ctx, req.Prompt, req.Model, exePath, env, func(ev claude.Event) {
+		if ev.Type == claude.EventError {
+			failed = true
 		}
+		reply.add(ev)
+		emit(ev)
+	})
+	if !failed && ctx.Err() == nil {
+		_, _ = cache.Put(key, reply.answer(), req.run.Recipe.Cache, time.Now())
+	}
+	return false
+}
 
-		claude.Stream(ctx, prompt, model, exePath, emit)
+// runAPIChat runs the agent for a normalized chat request, capped at six
+// minutes. mode labels the chat metrics; caller is recorded in the audit log
+// for the commands the agent runs. A recipe's reply is saved to its output.
+func runAPIChat(ctx context.Context, mode, caller string, req apiChatRequest, emit func(claude.Event)) {
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, 6*time.Minute)
+	defer cancel()
+
+	exePath, err := os.Executable()
+	if err != nil || strings.TrimSpace(exePath) == "" {
+		exePath = "birdy"
+	}
+
+	chatStreamsActive.Inc()
+	start := time.Now()
+	result := "ok"
+	env := append([]string{audit.CallerEnv + "=" + caller}, req.agentEnv()...)
+	var reply sessionReply
+	cached := streamAgent(ctx, req, exePath, env, func(ev claude.Event) {
+		if ev.Type == claude.EventError {
+			result = "error"
+		}
+		reply.add(ev)
+		emit(ev)
+	})
+	if req.run != nil && !cached {
+		_ = req.run.Save(reply.messages(ctx.Err() != nil))
+	}
+	if parent.Err() != nil {
+		result = "canceled"
 	}
+	chatStreamsActive.Dec()
+	chatStreamsTotal.Inc(mode, result)
+	chatDuration.Observe(time.Since(start).Seconds(), mode)
 }
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/jobs"
+)
+
+// apiJobRequest is either a command (command/args) or a chat (prompt).
+type apiJobRequest struct {
+	apiCommandRequest
+	apiChatRequest
+}
+
+type apiJobResponse struct {
+	OK  bool      `json:"ok"`
+	Job *jobs.Job `json:"job"`
+}
+
+// apiChatResult is the stored result of a chat job.
+type apiChatResult struct {
+	Text     string        `json:"text"`
+	Commands []string      `json:"commands,omitempty"`
+	Usage    *claude.Usage `json:"usage,omitempty"`
+}
+
+type apiJobHandlerFunc func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error)
+
+// registerAPIJobs mounts the background job endpoints on mux.
+func registerAPIJobs(mux *http.ServeMux, inviteCode string, m *jobs.Manager) {
+	mux.HandleFunc("POST /api/jobs", apiJobsHandle(inviteCode, func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		return apiCreateJob(w, r, key, m)
+	}))
+	mux.HandleFunc("GET /api/jobs/{id}", apiJobsHandle(inviteCode, func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		job, err := apiOwnedJob(m, r.PathValue("id"), key)
+		if err != nil {
+			return nil, err
+		}
+		return apiJobResponse{OK: true, Job: job}, nil
+	}))
+	mux.HandleFunc("DELETE /api/jobs/{id}", apiJobsHandle(inviteCode, func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		job, err := apiOwnedJob(m, r.PathValue("id"), key)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.Cancel(job.ID); err != nil {
+			if errors.Is(err, jobs.ErrFinished) {
+				return nil, &apiV1Failure{Status: http.StatusConflict, Code: "conflict", Message: "job already finished"}
+			}
+			return nil, err
+		}
+		job, _ = m.Get(job.ID)
+		return apiJobResponse{OK: true, Job: job}, nil
+	}))
+	mux.HandleFunc("GET /api/jobs/{id}/events", apiJobsHandle(inviteCode, func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		return nil, apiStreamJobEvents(w, r, key, m)
+	}))
+}
+
+// apiJobsHandle authenticates and throttles a jobs request. A handler that
+// writes its own response returns (nil, nil).
+func apiJobsHandle(inviteCode string, h apiJobHandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if f := apiThrottleIP(w, r); f != nil {
+			writeAPIV1Error(w, f)
+			return
+		}
+		key, err := apiAuthenticate(r, inviteCode)
+		if err != nil {
+			status, msg := apiAuthFailure(err)
+			writeAPIV1Error(w, &apiV1Failure{Status: status, Code: "unauthorized", Message: msg})
+			return
+		}
+		if f := apiThrottle(w, r, key); f != nil {
+			writeAPIV1Error(w, f)
+			return
+		}
+		resp, err := h(w, r, key)
+		if err != nil {
+			writeAPIV1Error(w, err)
+			return
+		}
+		if resp != nil {
+			status := http.StatusOK
+			if r.Method == http.MethodPost || r.Method == http.MethodDelete {
+				status = http.StatusAccepted
+			}
+			writeJSON(w, status, resp)
+		}
+	}
+}
+
+// apiOwnedJob loads a job the key may see: its own, or any job for admin.
+// Other callers' jobs are reported as missing.
+func apiOwnedJob(m *jobs.Manager, id string, key *apikey.Key) (*jobs.Job, error) {
+	job, err := m.Get(id)
+	if errors.Is(err, jobs.ErrNotFound) || (err == nil && job.Owner != apiKeyID(key) && !key.Has(apikey.ScopeAdmin)) {
+		return nil, &apiV1Failure{Status: http.StatusNotFound, Code: "not_found", Message: "job not found"}
+	}
+	return job, err
+}
+
+func apiCreateJob(w http.ResponseWriter, r *http.Request, key *apikey.Key, m *jobs.Manager) (any, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, 256*1024)
+	defer r.Body.Close()
+
+	var req apiJobRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, apiV1BadRequest("invalid json")
+	}
+	isChat := strings.TrimSpace(req.Prompt) != "" || strings.TrimSpace(req.Recipe) != ""
+	isCommand := strings.TrimSpace(req.Command) != "" || len(req.Args) > 0
+	switch {
+	case isChat && isCommand:
+		return nil, apiV1BadRequest("send either a command or a prompt, not both")
+	case isChat:
+		if f := apiChatAllowed(key); f != nil {
+			return nil, f
+		}
+		chat := req.apiChatRequest
+		chat.limitTo(key)
+		if f := chat.normalize(); f != nil {
+			return nil, f
+		}
+		if f := apiChatBudget(); f != nil {
+			return nil, f
+		}
+		job, err := m.Start("chat", apiKeyID(key), chat, func(ctx context.Context, emit func(string, any)) (any, error) {
+			return runChatJob(ctx, apiCaller(key), chat, emit)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return apiJobResponse{OK: true, Job: job}, nil
+	default:
+		cmdReq := req.apiCommandRequest
+		args, f := apiCommandArgs(key, cmdReq)
+		if f != nil {
+			return nil, f
+		}
+		pool := key.Accounts
+		strategy := apiStrategy(cmdReq.Strategy)
+		job, err := m.Start("command", apiKeyID(key), cmdReq, func(ctx context.Context, emit func(string, any)) (any, error) {
+			res, err := birdcmd.Run(ctx, birdcmd.Request{
+				Args:     args,
+				Account:  cmdReq.Account,
+				Strategy: strategy,
+				Pool:     pool,
+				Caller:   apiCaller(key),
+			})
+			if err != nil {
+				return nil, err
+			}
+			out := apiCommandResponse{
+				OK:        res.ExitCode == 0,
+				Account:   res.Account,
+				ExitCode:  res.ExitCode,
+				Stdout:    res.Stdout,
+				Stderr:    res.Stderr,
+				DurationM: res.Duration.Milliseconds(),
+			}
+			emit("result", out)
+			if res.ExitCode != 0 {
+				return out, fmt.Errorf("bird exited with code %d", res.ExitCode)
+			}
+			return out, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return apiJobResponse{OK: true, Job: job}, nil
+	}
+}
+
+// runChatJob streams a chat into the job's event log and returns the final
+// answer.
+func runChatJob(ctx context.Context, caller string, req apiChatRequest, emit func(string, any)) (any, error) {
+	var result apiChatResult
+	var tokens strings.Builder
+	var lastErr string
+	runAPIChat(ctx, "job", caller, req, func(ev claude.Event) {
+		switch ev.Type {
+		case claude.EventSnapshot:
+			result.Text = ev.Text
+		case claude.EventToken:
+			tokens.WriteString(ev.Text)
+		case claude.EventToolUse:
+			result.Commands = append(result.Commands, ev.Command)
+		case claude.EventUsage:
+			result.Usage = ev.Usage
+		case claude.EventError:
+			lastErr = ev.Error
+		}
+		emit(string(ev.Type), ev)
+	})
+	if result.Text == "" {
+		result.Text = tokens.String()
+	}
+	if lastErr != "" {
+		return result, errors.New(lastErr)
+	}
+	return result, ctx.Err()
+}
+
+// apiStreamJobEvents replays a job's events from ?offset= (or after the
+// Last-Event-ID header) as SSE and follows the job until it finishes. The
+// last message is an "end" event carrying the job record.
+func apiStreamJobEvents(w http.ResponseWriter, r *http.Request, key *apikey.Key, m *jobs.Manager) error {
+	job, err := apiOwnedJob(m, r.PathValue("id"), key)
+	if err != nil {
+		return err
+	}
+	offset := 0
+	if v := strings.TrimSpace(r.URL.Query().Get("offset")); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return apiV1BadRequest("offset must be a non-negative integer")
+		}
+	} else if v := strings.TrimSpace(r.Header.Get("Last-Event-ID")); v != "" {
+		if last, err := strconv.Atoi(v); err == nil && last >= 0 {
+			offset = last + 1
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return fmt.Errorf("streaming unsupported")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for {
+		events, done, wait, err := m.Events(job.ID, offset)
+		if err != nil {
+			return nil
+		}
+		for _, ev := range events {
+			data := ev.Data
+			if len(data) == 0 {
+				data = json.RawMessage("{}")
+			}
+			_, _ = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, data)
+			offset = ev.Seq + 1
+		}
+		if done {
+			if final, err := m.Get(job.ID); err == nil {
+				data, _ := json.Marshal(final)
+				_, _ = fmt.Fprintf(w, "event: end\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+			return nil
+		}
+		flusher.Flush()
+		select {
+		case <-wait:
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/jobs"
+)
+
+func TestAPIJobs(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	m, err := jobs.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	registerAPIJobs(h.(*http.ServeMux), "secret", m)
+
+	keys, _ := apikey.Open()
+	other, _, err := keys.Create(apikey.CreateOptions{Name: "other", Scopes: []apikey.Scope{apikey.ScopeRead}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(method, path, token, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := call("POST", "/api/jobs", "secret", `{"command":"read","args":["123","--json"]}`)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	var created apiJobResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	id := created.Job.ID
+
+	// The events stream replays from the start and ends with the job record.
+	w = call("GET", "/api/jobs/"+id+"/events", "secret", "")
+	body := w.Body.String()
+	if w.Code != 200 || !strings.Contains(body, "id: 0\nevent: result\n") || !strings.Contains(body, "event: end\n") {
+		t.Fatalf("events: %d %q", w.Code, body)
+	}
+	if w := call("GET", "/api/jobs/"+id+"/events?offset=1", "secret", ""); strings.Contains(w.Body.String(), "event: result") {
+		t.Fatalf("offset should skip replayed events: %q", w.Body.String())
+	}
+
+	w = call("GET", "/api/jobs/"+id, "secret", "")
+	var got apiJobResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &got)
+	var result apiCommandResponse
+	_ = json.Unmarshal(got.Job.Result, &result)
+	if got.Job.Status != jobs.StatusSucceeded || result.Account != "main" || !strings.Contains(result.Stdout, `"id":"123"`) {
+		t.Fatalf("job: %s", w.Body)
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"other keys can't see the job", "GET", "/api/jobs/" + id, other, "", 404},
+		{"finished jobs can't be canceled", "DELETE", "/api/jobs/" + id, "secret", "", 409},
+		{"chat needs the chat scope", "POST", "/api/jobs", other, `{"prompt":"hi"}`, 403},
+		{"command and prompt together", "POST", "/api/jobs", "secret", `{"command":"home","prompt":"hi"}`, 400},
+		{"unsupported command", "POST", "/api/jobs", "secret", `{"command":"rm"}`, 400},
+		{"unknown job", "GET", "/api/jobs/0123456789abcdef", "secret", "", 404},
+	}
+	for _, c := range cases {
+		if w := call(c.method, c.path, c.token, c.body); w.Code != c.want {
+			t.Errorf("%s: got %d %s, want %d", c.name, w.Code, w.Body, c.want)
+		}
+	}
+}
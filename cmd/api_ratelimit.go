@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/ratelimit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hostAPIRateFlag    int
+	hostAPIQuotaFlag   int
+	hostAPIIPRateFlag  int
+	hostAPIIPQuotaFlag int
+	hostAPITrustProxy  bool
+)
+
+// apiLimiter throttles /api requests; nil disables throttling. The host
+// sets it up at startup.
+var apiLimiter *ratelimit.Limiter
+
+// apiTrustProxy makes apiClientIP take the client address from
+// X-Forwarded-For. Only set it when a proxy in front of the host sets the
+// header; otherwise any client could pick its own IP limit bucket.
+var apiTrustProxy bool
+
+// apiKeyLimit and apiIPLimit are the host defaults. A key's own RateLimit
+// and DailyQuota override apiKeyLimit.
+var (
+	apiKeyLimit ratelimit.Limit
+	apiIPLimit  ratelimit.Limit
+)
+
+// setupAPIRateLimits reads the host's rate limit flags, falling back to
+// their BIRDY_API_* env vars when a flag isn't given.
+func setupAPIRateLimits(cmd *cobra.Command) error {
+	values := []struct {
+		flag string
+		env  string
+		dst  *int
+		src  int
+	}{
+		{"api-rate", "BIRDY_API_RATE", &apiKeyLimit.PerMinute, hostAPIRateFlag},
+		{"api-quota", "BIRDY_API_QUOTA", &apiKeyLimit.PerDay, hostAPIQuotaFlag},
+		{"api-ip-rate", "BIRDY_API_IP_RATE", &apiIPLimit.PerMinute, hostAPIIPRateFlag},
+		{"api-ip-quota", "BIRDY_API_IP_QUOTA", &apiIPLimit.PerDay, hostAPIIPQuotaFlag},
+	}
+	for _, v := range values {
+		*v.dst = v.src
+		if cmd.Flags().Changed(v.flag) {
+			continue
+		}
+		if raw, ok := os.LookupEnv(v.env); ok && strings.TrimSpace(raw) != "" {
+			n, err := strconv.Atoi(strings.TrimSpace(raw))
+			if err != nil || n < 0 {
+				return fmt.Errorf("%s must be a non-negative integer", v.env)
+			}
+			*v.dst = n
+		}
+	}
+
+	apiTrustProxy = hostAPITrustProxy
+	if !cmd.Flags().Changed("trust-proxy") {
+		switch strings.ToLower(strings.TrimSpace(os.Getenv("BIRDY_API_TRUST_PROXY"))) {
+		case "1", "true", "yes":
+			apiTrustProxy = true
+		}
+	}
+
+	path, err := ratelimit.DefaultPath()
+	if err != nil {
+		return err
+	}
+	apiLimiter, err = ratelimit.Open(path)
+	return err
+}
+
+// apiThrottleIP charges a request to the client IP. It runs before
+// authentication, so failed logins count against the IP too.
+func apiThrottleIP(w http.ResponseWriter, r *http.Request) *apiV1Failure {
+	return apiCharge(w, ratelimit.Subject{ID: "ip:" + apiClientIP(r), Limit: apiIPLimit})
+}
+
+// apiThrottle charges an authenticated request to the caller's key.
+func apiThrottle(w http.ResponseWriter, r *http.Request, key *apikey.Key) *apiV1Failure {
+	keyLimit := apiKeyLimit
+	if key.RateLimit > 0 {
+		keyLimit.PerMinute = key.RateLimit
+	}
+	if key.DailyQuota > 0 {
+		keyLimit.PerDay = key.DailyQuota
+	}
+	return apiCharge(w, ratelimit.Subject{ID: apiKeyID(key), Limit: keyLimit})
+}
+
+// apiCharge charges one request to s and sets the X-RateLimit-* headers
+// unless an earlier charge left a tighter limit in them. It returns a
+// rate_limited failure when s is over its limit.
+func apiCharge(w http.ResponseWriter, s ratelimit.Subject) *apiV1Failure {
+	if apiLimiter == nil {
+		return nil
+	}
+	d, err := apiLimiter.Allow(time.Now(), s)
+	if err != nil {
+		// Losing a quota write should not take the API down.
+		fmt.Fprintf(os.Stderr, "[birdy] %v\n", err)
+	}
+	h := w.Header()
+	if d.Limit > 0 {
+		prev, err := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+		if err != nil || !d.Allowed || d.Remaining < prev {
+			h.Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(max(d.Remaining, 0)))
+			h.Set("X-RateLimit-Reset", strconv.FormatInt(d.Reset.Unix(), 10))
+		}
+	}
+	if d.Allowed {
+		return nil
+	}
+
+	h.Set("Retry-After", strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds()))))
+	what, limit := "rate limit", "minute"
+	if d.Daily {
+		what, limit = "daily quota", "day"
+	}
+	by, subject := "api key", "key"
+	if strings.HasPrefix(d.Subject, "ip:") {
+		by, subject = "client ip", "ip"
+	}
+	rateLimited.Inc(subject, limit)
+	return &apiV1Failure{
+		Status:  http.StatusTooManyRequests,
+		Code:    "rate_limited",
+		Message: fmt.Sprintf("%s %s exceeded; retry in %s", by, what, d.RetryAfter.Round(time.Second)),
+	}
+}
+
+// apiKeyID identifies the caller for quotas and job ownership. The invite
+// code has no stored key, so it goes by name.
+func apiKeyID(key *apikey.Key) string {
+	if key.ID == "" {
+		return "key:" + key.Name
+	}
+	return "key:" + key.ID
+}
+
+// apiCaller names the key in the audit log.
+func apiCaller(key *apikey.Key) string {
+	return "api:" + key.Name
+}
+
+// apiClientIP returns the request's client address. With apiTrustProxy it
+// uses the last X-Forwarded-For hop, which the nearest proxy appended.
+func apiClientIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); apiTrustProxy && xff != "" {
+		parts := strings.Split(xff, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
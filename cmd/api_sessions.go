@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/chatsession"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/transcript"
+)
+
+type apiSessionResponse struct {
+	OK       bool                 `json:"ok"`
+	Session  *chatsession.Session `json:"session"`
+	Messages []transcript.Message `json:"messages,omitempty"`
+}
+
+type apiSessionsResponse struct {
+	OK       bool                  `json:"ok"`
+	Sessions []chatsession.Session `json:"sessions"`
+}
+
+// openChatSessions opens the session store next to the TUI's transcripts.
+func openChatSessions() (*chatsession.Manager, error) {
+	dir, err := chatsession.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	chatDir, err := transcript.Dir()
+	if err != nil {
+		return nil, err
+	}
+	return chatsession.Open(dir, chatDir)
+}
+
+// registerAPISessions mounts the multi-turn chat session endpoints on mux.
+func registerAPISessions(mux *http.ServeMux, inviteCode string, m *chatsession.Manager) {
+	handle := func(h apiJobHandlerFunc) http.HandlerFunc {
+		return apiJobsHandle(inviteCode, func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+			if f := apiChatAllowed(key); f != nil {
+				return nil, f
+			}
+			return h(w, r, key)
+		})
+	}
+
+	mux.HandleFunc("POST /api/chat/sessions", handle(func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		r.Body = http.MaxBytesReader(w, r.Body, 64*1024)
+		defer r.Body.Close()
+		var req struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			return nil, apiV1BadRequest("invalid json")
+		}
+		model := strings.TrimSpace(req.Model)
+		if model == "" {
+			model = claude.DefaultModel()
+		}
+		s, err := m.Create(apiKeyID(key), model)
+		if err != nil {
+			return nil, err
+		}
+		writeJSON(w, http.StatusCreated, apiSessionResponse{OK: true, Session: s})
+		return nil, nil
+	}))
+	mux.HandleFunc("GET /api/chat/sessions", handle(func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		owner := apiKeyID(key)
+		if key.Has(apikey.ScopeAdmin) {
+			owner = ""
+		}
+		sessions, err := m.List(owner)
+		if err != nil {
+			return nil, err
+		}
+		return apiSessionsResponse{OK: true, Sessions: sessions}, nil
+	}))
+	mux.HandleFunc("GET /api/chat/sessions/{id}", handle(func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		s, err := apiOwnedSession(m, r.PathValue("id"), key)
+		if err != nil {
+			return nil, err
+		}
+		messages, err := m.Messages(s.ID)
+		if err != nil {
+			return nil, err
+		}
+		return apiSessionResponse{OK: true, Session: s, Messages: messages}, nil
+	}))
+	mux.HandleFunc("DELETE /api/chat/sessions/{id}", handle(func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		s, err := apiOwnedSession(m, r.PathValue("id"), key)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.Delete(s.ID); err != nil {
+			return nil, err
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+		return nil, nil
+	}))
+	mux.HandleFunc("DELETE /api/chat/sessions/{id}/turn", handle(func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		s, err := apiOwnedSession(m, r.PathValue("id"), key)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.Cancel(s.ID); err != nil {
+			if errors.Is(err, chatsession.ErrIdle) {
+				return nil, &apiV1Failure{Status: http.StatusConflict, Code: "conflict", Message: "no turn in progress"}
+			}
+			return nil, err
+		}
+		return map[string]bool{"ok": true}, nil
+	}))
+	mux.HandleFunc("POST /api/chat/sessions/{id}/messages", handle(func(w http.ResponseWriter, r *http.Request, key *apikey.Key) (any, error) {
+		return nil, apiSessionTurn(w, r, key, m)
+	}))
+}
+
+// apiOwnedSession loads a session the key may use: its own, or any for
+// admin. Other callers' sessions are reported as missing.
+func apiOwnedSession(m *chatsession.Manager, id string, key *apikey.Key) (*chatsession.Session, error) {
+	s, err := m.Get(id)
+	if errors.Is(err, chatsession.ErrNotFound) || (err == nil && s.Owner != apiKeyID(key) && !key.Has(apikey.ScopeAdmin)) {
+		return nil, &apiV1Failure{Status: http.StatusNotFound, Code: "not_found", Message: "session not found"}
+	}
+	return s, err
+}
+
+// apiSessionTurn adds the user's message to the session and streams the
+// agent's reply as SSE, in the same events as /api/chat. The reply is
+// appended to the transcript when the turn ends, including when it is
+// canceled.
+func apiSessionTurn(w http.ResponseWriter, r *http.Request, key *apikey.Key, m *chatsession.Manager) error {
+	s, err := apiOwnedSession(m, r.PathValue("id"), key)
+	if err != nil {
+		return err
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, 256*1024)
+	defer r.Body.Close()
+
+	var req apiChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return apiV1BadRequest("invalid json")
+	}
+	if strings.TrimSpace(req.Model) == "" {
+		req.Model = s.Model
+	}
+	req.limitTo(key)
+	if f := req.normalize(); f != nil {
+		return f
+	}
+	if f := apiChatBudget(); f != nil {
+		return f
+	}
+	if _, ok := w.(http.Flusher); !ok {
+		return errors.New("streaming unsupported")
+	}
+
+	turn, err := m.Begin(r.Context(), s.ID, req.Prompt)
+	if errors.Is(err, chatsession.ErrBusy) {
+		return &apiV1Failure{Status: http.StatusConflict, Code: "conflict", Message: "a turn is already in progress"}
+	}
+	if err != nil {
+		return err
+	}
+
+	var reply sessionReply
+	defer func() { _ = turn.End(reply.messages(turn.Context().Err() != nil)) }()
+
+	emit, _ := apiChatEventStream(w)
+	req.Prompt = transcript.TurnPrompt(turn.History)
+	runAPIChat(turn.Context(), "session", apiCaller(key), req, func(ev claude.Event) {
+		reply.add(ev)
+		emit(ev)
+	})
+	return nil
+}
+
+// sessionReply collects an agent run's events into the transcript messages
+// of a session turn: the commands, errors and the final answer.
+type sessionReply struct {
+	msgs         []transcript.Message
+	text, tokens strings.Builder
+}
+
+func (r *sessionReply) add(ev claude.Event) {
+	switch ev.Type {
+	case claude.EventSnapshot:
+		r.text.Reset()
+		r.text.WriteString(ev.Text)
+	case claude.EventToken:
+		r.tokens.WriteString(ev.Text)
+	case claude.EventToolUse:
+		r.msgs = append(r.msgs, transcript.Message{Role: transcript.RoleTool, Content: ev.Command})
+	case claude.EventError:
+		r.msgs = append(r.msgs, transcript.Message{Role: transcript.RoleError, Content: ev.Error})
+	}
+}
+
+// messages returns the reply to append to the transcript; canceled marks a
+// turn cut short.
+func (r *sessionReply) messages(canceled bool) []transcript.Message {
+	out := r.msgs
+	if answer := r.answer(); answer != "" {
+		out = append(out, transcript.Message{Role: transcript.RoleAssistant, Content: answer})
+	}
+	if canceled {
+		out = append(out, transcript.Message{Role: transcript.RoleError, Content: "cancelled"})
+	}
+	return out
+}
+
+// answer returns the agent's final answer so far.
+func (r *sessionReply) answer() string {
+	if answer := r.text.String(); answer != "" {
+		return answer
+	}
+	return r.tokens.String()
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeClaudeScript records the prompt it was given and answers with one
+// tool call and a final result, in claude's stream-json format.
+const fakeClaudeScript = `#!/bin/sh
+while [ $# -gt 0 ]; do
+  if [ "$1" = "-p" ]; then printf '%s' "$2" > "$(dirname "$0")/prompt"; fi
+  shift
+done
+echo '{"type":"assistant","message":{"content":[{"type":"tool_use","id":"t1","name":"mcp__birdy__home","input":{}}]}}'
+echo '{"type":"result","result":"Here is your timeline."}'
+`
+
+func TestAPIChatSessions(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(fakeClaudeScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	m, err := openChatSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := h.(*http.ServeMux)
+	registerAPISessions(mux, "secret", m)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("POST", "/api/chat/sessions", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body)
+	}
+	var created apiSessionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	id := created.Session.ID
+
+	w = do("POST", "/api/chat/sessions/"+id+"/messages", `{"prompt":"what's new?"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Here is your timeline.") {
+		t.Fatalf("first turn: %d %s", w.Code, w.Body)
+	}
+	w = do("POST", "/api/chat/sessions/"+id+"/messages", `{"prompt":"and mentions?"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("second turn: %d %s", w.Code, w.Body)
+	}
+	prompt, err := os.ReadFile(filepath.Join(bin, "prompt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"User: what's new?", "Tool: birdy home", "Assistant: Here is your timeline.", "User: and mentions?"} {
+		if !strings.Contains(string(prompt), want) {
+			t.Errorf("second prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	w = do("GET", "/api/chat/sessions/"+id, "")
+	var got apiSessionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Session.Turns != 2 || len(got.Messages) != 6 {
+		t.Fatalf("session: %+v, %d messages", got.Session, len(got.Messages))
+	}
+	transcript, err := os.ReadFile(got.Session.Transcript)
+	if err != nil || !strings.Contains(string(transcript), "## You\n\nand mentions?") {
+		t.Fatalf("transcript: %v\n%s", err, transcript)
+	}
+
+	if w := do("DELETE", "/api/chat/sessions/"+id+"/turn", ""); w.Code != http.StatusConflict {
+		t.Fatalf("cancel idle turn: %d", w.Code)
+	}
+	if w := do("GET", "/api/chat/sessions", ""); !strings.Contains(w.Body.String(), id) {
+		t.Fatalf("list: %s", w.Body)
+	}
+	if w := do("DELETE", "/api/chat/sessions/"+id, ""); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d", w.Code)
+	}
+	if w := do("GET", "/api/chat/sessions/"+id, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("get deleted: %d", w.Code)
+	}
+}
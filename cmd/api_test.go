@@ -2,8 +2,24 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/budget"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/ratelimit"
 )
 
 func TestAPIAuthHeader(t *testing.T) {
@@ -19,3 +35,338 @@ func TestAPIAuthHeader(t *testing.T) {
 		t.Fatalf("expected x-invite-code parsed, got %q", got)
 	}
 }
+
+func TestAPIKeyScopes(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	h.(*http.ServeMux).HandleFunc("/api/command", handleAPICommand("secret"))
+	h.(*http.ServeMux).HandleFunc("/api/chat", handleAPIChat("secret"))
+
+	keys, err := apikey.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, _, err := keys.Create(apikey.CreateOptions{Name: "reader", Scopes: []apikey.Scope{apikey.ScopeRead}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pooled, _, err := keys.Create(apikey.CreateOptions{Name: "pooled", Scopes: []apikey.Scope{apikey.ScopeAdmin}, Accounts: []string{"other"}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(method, path, token, body string) int {
+		t.Helper()
+		r := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{"read key reads", "GET", "/api/v1/tweets/1", reader, "", 200},
+		{"read key runs read command", "POST", "/api/command", reader, `{"command":"read","args":["1","--json"]}`, 200},
+		{"read key cannot write", "POST", "/api/command", reader, `{"command":"tweet","args":["hi"]}`, 403},
+		{"read key cannot chat", "POST", "/api/chat", reader, `{"prompt":"hi"}`, 403},
+		{"pool rejects explicit account", "GET", "/api/v1/tweets/1?account=main", pooled, "", 403},
+		{"pool limits rotation", "GET", "/api/v1/tweets/1", pooled, "", 400},
+		{"unknown key", "GET", "/api/v1/tweets/1", "birdy_nope", "", 401},
+		{"invite code still works", "POST", "/api/command", "secret", `{"command":"read","args":["1"]}`, 200},
+	}
+	for _, c := range cases {
+		if got := call(c.method, c.path, c.token, c.body); got != c.want {
+			t.Errorf("%s: got %d, want %d", c.name, got, c.want)
+		}
+	}
+
+	keys, _ = apikey.Open()
+	if k := keys.List()[0]; k.LastUsed.IsZero() {
+		t.Error("expected last_used to be recorded")
+	}
+	if err := keys.Revoke("reader"); err != nil {
+		t.Fatal(err)
+	}
+	if got := call("GET", "/api/v1/tweets/1", reader, ""); got != 401 {
+		t.Errorf("revoked key: got %d, want 401", got)
+	}
+
+	old := hostInviteScopes
+	hostInviteScopes = nil
+	defer func() { hostInviteScopes = old }()
+	if got := call("GET", "/api/v1/tweets/1", "secret", ""); got != 401 {
+		t.Errorf("disabled invite code: got %d, want 401", got)
+	}
+}
+
+func TestAPIRateLimitHeaders(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	limiter, err := ratelimit.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldLimiter, oldKey, oldIP := apiLimiter, apiKeyLimit, apiIPLimit
+	apiLimiter, apiKeyLimit, apiIPLimit = limiter, ratelimit.Limit{PerMinute: 2}, ratelimit.Limit{}
+	defer func() { apiLimiter, apiKeyLimit, apiIPLimit = oldLimiter, oldKey, oldIP }()
+
+	keys, _ := apikey.Open()
+	quota, _, err := keys.Create(apikey.CreateOptions{Name: "quota", Scopes: []apikey.Scope{apikey.ScopeRead}, DailyQuota: 1}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/v1/tweets/1", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := get("secret")
+		if w.Code != 200 || w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != wantRemaining {
+			t.Fatalf("request %d: %d %v", i, w.Code, w.Header())
+		}
+	}
+	w := get("secret")
+	if w.Code != 429 || w.Header().Get("Retry-After") == "" || !bytes.Contains(w.Body.Bytes(), []byte(`"rate_limited"`)) {
+		t.Fatalf("expected 429 with Retry-After, got %d %v %s", w.Code, w.Header(), w.Body)
+	}
+
+	// Keys are limited independently, and a key's own quota applies.
+	if w := get(quota); w.Code != 200 {
+		t.Fatalf("quota key first request: %d", w.Code)
+	}
+	if w := get(quota); w.Code != 429 || !bytes.Contains(w.Body.Bytes(), []byte("daily quota")) {
+		t.Fatalf("expected daily quota denial, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestAPIRateLimitFailedAuth(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	limiter, err := ratelimit.Open("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldLimiter, oldKey, oldIP := apiLimiter, apiKeyLimit, apiIPLimit
+	apiLimiter, apiKeyLimit, apiIPLimit = limiter, ratelimit.Limit{PerMinute: 5}, ratelimit.Limit{PerMinute: 2}
+	defer func() { apiLimiter, apiKeyLimit, apiIPLimit = oldLimiter, oldKey, oldIP }()
+
+	get := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/v1/tweets/1", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// The IP limit is the tighter one, so its headers win.
+	if w := get("secret"); w.Code != 200 || w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("first request: %d %v", w.Code, w.Header())
+	}
+	// Guessing tokens is charged to the IP before authentication.
+	if w := get("wrong"); w.Code != 401 {
+		t.Fatalf("bad token: got %d, want 401", w.Code)
+	}
+	if w := get("wrong"); w.Code != 429 {
+		t.Fatalf("bad token over the ip limit: got %d, want 429", w.Code)
+	}
+	if w := get("secret"); w.Code != 429 || !bytes.Contains(w.Body.Bytes(), []byte("client ip")) {
+		t.Fatalf("valid token over the ip limit: got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestAPIClientIP(t *testing.T) {
+	r := httptest.NewRequest("GET", "/api/v1/search", nil)
+	r.RemoteAddr = "10.0.0.1:5555"
+	if got := apiClientIP(r); got != "10.0.0.1" {
+		t.Fatalf("got %q", got)
+	}
+	r.Header.Set("X-Forwarded-For", "1.1.1.1, 203.0.113.7")
+	if got := apiClientIP(r); got != "10.0.0.1" {
+		t.Fatalf("X-Forwarded-For trusted without a proxy: got %q", got)
+	}
+	apiTrustProxy = true
+	t.Cleanup(func() { apiTrustProxy = false })
+	if got := apiClientIP(r); got != "203.0.113.7" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestAPIChatBudgetExhausted(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	h.(*http.ServeMux).HandleFunc("/api/chat", handleAPIChat("secret"))
+	t.Setenv(budget.DayTurnsEnv, "1")
+
+	tracker, err := budget.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.Turn("earlier-run"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("POST", "/api/chat", bytes.NewBufferString(`{"prompt":"hi"}`))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != 429 || !bytes.Contains(w.Body.Bytes(), []byte(budget.DayTurnsEnv)) {
+		t.Fatalf("expected budget refusal, got %d %s", w.Code, w.Body)
+	}
+
+	var out bytes.Buffer
+	if err := printBudget(&out, tracker); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte("agent budget exhausted: 1 of 1 agent turns used today")) {
+		t.Fatalf("budget output:\n%s", out.String())
+	}
+}
+
+func TestAPIChatProfile(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	h.(*http.ServeMux).HandleFunc("/api/chat", handleAPIChat("secret"))
+	home, _ := os.UserHomeDir()
+	profiles := `{"researcher": {"read_only": true, "model": "opus"}}`
+	if err := os.WriteFile(filepath.Join(home, ".config", "birdy", "profiles.json"), []byte(profiles), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	req := apiChatRequest{Prompt: "hi", Profile: " researcher "}
+	if f := req.normalize(); f != nil || req.Profile != "researcher" || req.Model != "opus" {
+		t.Fatalf("normalize = %+v, %+v", req, f)
+	}
+	req = apiChatRequest{Prompt: "hi", Profile: "researcher", Model: "haiku"}
+	if f := req.normalize(); f != nil || req.Model != "haiku" {
+		t.Fatalf("explicit model = %+v, %+v", req, f)
+	}
+
+	r := httptest.NewRequest("POST", "/api/chat", bytes.NewBufferString(`{"prompt":"hi","profile":"nope"}`))
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest || !bytes.Contains(w.Body.Bytes(), []byte(`unknown profile \"nope\"`)) {
+		t.Fatalf("expected unknown profile refusal, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestAPIChatRecipe(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	h.(*http.ServeMux).HandleFunc("/api/chat", handleAPIChat("secret"))
+	writeTestRecipe(t, "digest", "---\nmodel: opus\ncommands: user-tweets\nparams: handle, days=7\n---\nDigest @{handle} over {days} days.")
+
+	req := apiChatRequest{Recipe: "digest", Params: map[string]string{"handle": "acme"}}
+	if f := req.normalize(); f != nil || req.Prompt != "Digest @acme over 7 days." || req.Model != "opus" || req.run == nil {
+		t.Fatalf("normalize = %+v, %+v", req, f)
+	}
+	if env := strings.Join(req.agentEnv(), " "); !strings.Contains(env, profile.CommandsEnv+"=user-tweets") {
+		t.Fatalf("agentEnv = %q", env)
+	}
+
+	for body, want := range map[string]string{
+		`{"recipe":"nope"}`:                           `unknown recipe \"nope\"`,
+		`{"recipe":"digest"}`:                         `needs parameter \"handle\"`,
+		`{"recipe":"digest","prompt":"hi"}`:           "either a prompt or a recipe",
+		`{"recipe":"digest","params":{"colour":"x"}}`: `no parameter \"colour\"`,
+	} {
+		r := httptest.NewRequest("POST", "/api/chat", bytes.NewBufferString(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), want) {
+			t.Errorf("%s: got %d %s", body, w.Code, w.Body)
+		}
+	}
+}
+
+func TestAPIChatKeyLimits(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	h.(*http.ServeMux).HandleFunc("/api/chat", handleAPIChat("secret"))
+
+	var (
+		mu    sync.Mutex
+		tools [][]string
+	)
+	messages := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Tools []struct{ Name string }
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var names []string
+		for _, tool := range req.Tools {
+			names = append(names, tool.Name)
+		}
+		mu.Lock()
+		tools = append(tools, names)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, ev := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","role":"assistant","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Done."}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", ev)
+		}
+	}))
+	defer messages.Close()
+	t.Setenv(claude.BackendEnv, claude.BackendAPI)
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("ANTHROPIC_BASE_URL", messages.URL)
+
+	keys, err := apikey.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chatOnly, _, err := keys.Create(apikey.CreateOptions{Name: "chat-only", Scopes: []apikey.Scope{apikey.ScopeRead, apikey.ScopeChat}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pooled, _, err := keys.Create(apikey.CreateOptions{Name: "pooled", Scopes: []apikey.Scope{apikey.ScopeAdmin}, Accounts: []string{"main"}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{chatOnly, pooled} {
+		r := httptest.NewRequest("POST", "/api/chat", bytes.NewBufferString(`{"prompt":"hi"}`))
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Done.") {
+			t.Fatalf("chat: %d %s", w.Code, w.Body.String())
+		}
+	}
+	if len(tools) != 2 {
+		t.Fatalf("model requests = %d", len(tools))
+	}
+	for _, write := range []string{"tweet", "reply", "follow", "unfollow", "unbookmark"} {
+		if slices.Contains(tools[0], write) {
+			t.Errorf("a key without the write scope got the %s tool: %v", write, tools[0])
+		}
+		if !slices.Contains(tools[1], write) {
+			t.Errorf("a write key lacks the %s tool: %v", write, tools[1])
+		}
+	}
+
+	req := apiChatRequest{Prompt: "hi"}
+	req.limitTo(&apikey.Key{Scopes: []apikey.Scope{apikey.ScopeChat}, Accounts: []string{"main"}})
+	if f := req.normalize(); f != nil {
+		t.Fatal(f.Message)
+	}
+	env := strings.Join(req.agentEnv(), " ")
+	if !strings.Contains(env, profile.AccountsEnv+"=main") || !strings.Contains(env, birdcmd.ReadOnlyEnv+"=1") {
+		t.Fatalf("agent env = %q", env)
+	}
+	if req.resolved == nil || !slices.Equal(req.resolved.Accounts, []string{"main"}) {
+		t.Fatalf("resolved = %+v", req.resolved)
+	}
+}
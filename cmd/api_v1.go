@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/bird"
+	"github.com/guzus/birdy/internal/birdcmd"
+)
+
+//go:embed openapi.json
+var apiV1OpenAPISpec []byte
+
+const (
+	apiV1DefaultCount = 20
+	apiV1MaxCount     = 100
+	apiV1MaxCursorLen = 1024
+)
+
+var (
+	apiV1TweetIDPattern = regexp.MustCompile(`^[0-9]{1,25}$`)
+	apiV1HandlePattern  = regexp.MustCompile(`^[A-Za-z0-9_]{1,15}$`)
+)
+
+// apiV1Error is the error body for every /api/v1 endpoint. Code is one of:
+// unauthorized, forbidden, bad_request, not_found, rate_limited, upstream_error,
+// no_accounts, internal.
+type apiV1Error struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+type apiV1TweetResponse struct {
+	OK      bool       `json:"ok"`
+	Account string     `json:"account"`
+	Tweet   bird.Tweet `json:"tweet"`
+}
+
+type apiV1TweetsResponse struct {
+	OK         bool         `json:"ok"`
+	Account    string       `json:"account"`
+	Tweets     []bird.Tweet `json:"tweets"`
+	NextCursor *string      `json:"next_cursor"`
+}
+
+// apiV1AboutResponse carries bird's about data for a user: where the
+// account is based and how that was determined, not a profile.
+type apiV1AboutResponse struct {
+	OK       bool       `json:"ok"`
+	Account  string     `json:"account"`
+	Username string     `json:"username"`
+	About    bird.About `json:"about"`
+}
+
+// apiV1Failure is an error with an HTTP status and a stable error code.
+type apiV1Failure struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (f *apiV1Failure) Error() string { return f.Message }
+
+func apiV1BadRequest(format string, args ...any) *apiV1Failure {
+	return &apiV1Failure{Status: http.StatusBadRequest, Code: "bad_request", Message: fmt.Sprintf(format, args...)}
+}
+
+type apiV1HandlerFunc func(r *http.Request) (any, error)
+
+// registerAPIV1 mounts the versioned resource endpoints on mux.
+func registerAPIV1(mux *http.ServeMux, inviteCode string) {
+	mux.HandleFunc("GET /api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write(apiV1OpenAPISpec)
+	})
+	mux.HandleFunc("GET /api/v1/tweets/{id}", apiV1Handle(inviteCode, apiV1GetTweet))
+	mux.HandleFunc("GET /api/v1/threads/{id}", apiV1Handle(inviteCode, apiV1GetThread))
+	mux.HandleFunc("GET /api/v1/search", apiV1Handle(inviteCode, apiV1Search))
+	mux.HandleFunc("GET /api/v1/users/{handle}/about", apiV1Handle(inviteCode, apiV1GetUserAbout))
+	mux.HandleFunc("GET /api/v1/users/{handle}/tweets", apiV1Handle(inviteCode, apiV1GetUserTweets))
+	mux.HandleFunc("GET /api/v1/me/mentions", apiV1Handle(inviteCode, apiV1GetMentions))
+	mux.HandleFunc("/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIV1Error(w, &apiV1Failure{Status: http.StatusNotFound, Code: "not_found", Message: "no such endpoint"})
+	})
+}
+
+type apiV1KeyContext struct{}
+
+func apiV1Handle(inviteCode string, h apiV1HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if f := apiThrottleIP(w, r); f != nil {
+			writeAPIV1Error(w, f)
+			return
+		}
+		key, err := apiAuthenticate(r, inviteCode)
+		if err != nil {
+			status, msg := apiAuthFailure(err)
+			code := "unauthorized"
+			if status == http.StatusInternalServerError {
+				code = "internal"
+			}
+			writeAPIV1Error(w, &apiV1Failure{Status: status, Code: code, Message: msg})
+			return
+		}
+		if f := apiThrottle(w, r, key); f != nil {
+			writeAPIV1Error(w, f)
+			return
+		}
+		if !key.Has(apikey.ScopeRead) {
+			writeAPIV1Error(w, &apiV1Failure{Status: http.StatusForbidden, Code: "forbidden", Message: `api key lacks the "read" scope`})
+			return
+		}
+		if err := apiCheckAccount(key, r.URL.Query().Get("account")); err != nil {
+			writeAPIV1Error(w, &apiV1Failure{Status: http.StatusForbidden, Code: "forbidden", Message: err.Error()})
+			return
+		}
+		resp, err := h(r.WithContext(context.WithValue(r.Context(), apiV1KeyContext{}, key)))
+		if err != nil {
+			writeAPIV1Error(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func writeAPIV1Error(w http.ResponseWriter, err error) {
+	var f *apiV1Failure
+	if !errors.As(err, &f) {
+		status, code := apiRunErrorStatus(err)
+		f = &apiV1Failure{Status: status, Code: code, Message: err.Error()}
+	}
+	writeJSON(w, f.Status, apiV1Error{OK: false, Error: f.Message, Code: f.Code})
+}
+
+// runAPIV1Bird runs bird through the same rotation path as /api/command and
+// turns a failed invocation into an apiV1Failure.
+func runAPIV1Bird(r *http.Request, args []string) (*birdcmd.Result, error) {
+	q := r.URL.Query()
+	var pool []string
+	var caller string
+	if key, ok := r.Context().Value(apiV1KeyContext{}).(*apikey.Key); ok {
+		pool, caller = key.Accounts, apiCaller(key)
+	}
+	res, err := birdcmd.Run(r.Context(), birdcmd.Request{
+		Args:     args,
+		Account:  q.Get("account"),
+		Strategy: apiStrategy(q.Get("strategy")),
+		Pool:     pool,
+		Caller:   caller,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if res.ExitCode != 0 {
+		return nil, classifyBirdFailure(res)
+	}
+	return res, nil
+}
+
+// classifyBirdFailure maps bird's stderr to an API error code.
+func classifyBirdFailure(res *birdcmd.Result) *apiV1Failure {
+	msg := strings.TrimSpace(firstLine(strings.TrimSpace(res.Stderr)))
+	if msg == "" {
+		msg = fmt.Sprintf("bird exited with code %d", res.ExitCode)
+	}
+	lower := strings.ToLower(res.Stderr)
+	switch {
+	case strings.Contains(lower, "not found"), strings.Contains(lower, "could not find"),
+		strings.Contains(lower, "does not exist"), strings.Contains(lower, "no status found"):
+		return &apiV1Failure{Status: http.StatusNotFound, Code: "not_found", Message: msg}
+	case strings.Contains(lower, "rate limit"), strings.Contains(lower, "429"), strings.Contains(lower, "too many requests"):
+		return &apiV1Failure{Status: http.StatusTooManyRequests, Code: "rate_limited", Message: msg}
+	default:
+		return &apiV1Failure{Status: http.StatusBadGateway, Code: "upstream_error", Message: msg}
+	}
+}
+
+// apiV1Page reads the count and cursor query parameters.
+func apiV1Page(r *http.Request) (count int, cursor string, err error) {
+	q := r.URL.Query()
+	count = apiV1DefaultCount
+	if v := strings.TrimSpace(q.Get("count")); v != "" {
+		n, convErr := strconv.Atoi(v)
+		if convErr != nil || n < 1 || n > apiV1MaxCount {
+			return 0, "", apiV1BadRequest("count must be between 1 and %d", apiV1MaxCount)
+		}
+		count = n
+	}
+	cursor = strings.TrimSpace(q.Get("cursor"))
+	if len(cursor) > apiV1MaxCursorLen {
+		return 0, "", apiV1BadRequest("cursor is too long")
+	}
+	return count, cursor, nil
+}
+
+func apiV1TweetID(r *http.Request) (string, error) {
+	id := r.PathValue("id")
+	if !apiV1TweetIDPattern.MatchString(id) {
+		return "", apiV1BadRequest("invalid tweet id %q", id)
+	}
+	return id, nil
+}
+
+func apiV1Username(r *http.Request) (string, error) {
+	h := strings.TrimPrefix(r.PathValue("handle"), "@")
+	if !apiV1HandlePattern.MatchString(h) {
+		return "", apiV1BadRequest("invalid handle %q", r.PathValue("handle"))
+	}
+	return h, nil
+}
+
+// apiV1TweetsPage runs a paged bird command and wraps its output.
+func apiV1TweetsPage(r *http.Request, args []string) (any, error) {
+	res, err := runAPIV1Bird(r, args)
+	if err != nil {
+		return nil, err
+	}
+	tweets, next, err := bird.ParseTweets([]byte(res.Stdout))
+	if err != nil {
+		return nil, &apiV1Failure{Status: http.StatusBadGateway, Code: "upstream_error", Message: err.Error()}
+	}
+	if tweets == nil {
+		tweets = []bird.Tweet{}
+	}
+	resp := apiV1TweetsResponse{OK: true, Account: res.Account, Tweets: tweets}
+	if next != "" {
+		resp.NextCursor = &next
+	}
+	return resp, nil
+}
+
+func apiV1GetTweet(r *http.Request) (any, error) {
+	id, err := apiV1TweetID(r)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runAPIV1Bird(r, []string{"read", id, "--json"})
+	if err != nil {
+		return nil, err
+	}
+	tweets, _, err := bird.ParseTweets([]byte(res.Stdout))
+	if err != nil {
+		return nil, &apiV1Failure{Status: http.StatusBadGateway, Code: "upstream_error", Message: err.Error()}
+	}
+	if len(tweets) == 0 {
+		return nil, &apiV1Failure{Status: http.StatusNotFound, Code: "not_found", Message: "tweet not found"}
+	}
+	return apiV1TweetResponse{OK: true, Account: res.Account, Tweet: tweets[0]}, nil
+}
+
+func apiV1GetThread(r *http.Request) (any, error) {
+	id, err := apiV1TweetID(r)
+	if err != nil {
+		return nil, err
+	}
+	_, cursor, err := apiV1Page(r)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"thread", id, "--max-pages", "1", "--json"}
+	if cursor != "" {
+		args = append(args, "--cursor", cursor)
+	}
+	return apiV1TweetsPage(r, args)
+}
+
+func apiV1Search(r *http.Request) (any, error) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		return nil, apiV1BadRequest("missing q")
+	}
+	count, cursor, err := apiV1Page(r)
+	if err != nil {
+		return nil, err
+	}
+	// bird only reports a next cursor in paged mode.
+	flags := []string{"-n", strconv.Itoa(count), "--max-pages", "1", "--json"}
+	if cursor != "" {
+		flags = append(flags, "--cursor", cursor)
+	} else {
+		flags = append(flags, "--all")
+	}
+	// A query starting with a dash (e.g. "-filter:replies") would parse as
+	// a flag, so like birdcmd.Command.Args it goes after "--".
+	args := append([]string{"search", query}, flags...)
+	if strings.HasPrefix(query, "-") {
+		args = append(append([]string{"search"}, flags...), "--", query)
+	}
+	return apiV1TweetsPage(r, args)
+}
+
+func apiV1GetUserAbout(r *http.Request) (any, error) {
+	handle, err := apiV1Username(r)
+	if err != nil {
+		return nil, err
+	}
+	res, err := runAPIV1Bird(r, []string{"about", "@" + handle, "--json"})
+	if err != nil {
+		return nil, err
+	}
+	var about bird.About
+	if err := json.Unmarshal([]byte(strings.TrimSpace(res.Stdout)), &about); err != nil {
+		return nil, &apiV1Failure{Status: http.StatusBadGateway, Code: "upstream_error", Message: "parsing about: " + err.Error()}
+	}
+	return apiV1AboutResponse{OK: true, Account: res.Account, Username: handle, About: about}, nil
+}
+
+func apiV1GetUserTweets(r *http.Request) (any, error) {
+	handle, err := apiV1Username(r)
+	if err != nil {
+		return nil, err
+	}
+	count, cursor, err := apiV1Page(r)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"user-tweets", "@" + handle, "-n", strconv.Itoa(count), "--max-pages", "1", "--json"}
+	if cursor != "" {
+		args = append(args, "--cursor", cursor)
+	}
+	return apiV1TweetsPage(r, args)
+}
+
+func apiV1GetMentions(r *http.Request) (any, error) {
+	count, cursor, err := apiV1Page(r)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != "" {
+		return nil, apiV1BadRequest("mentions do not support cursors")
+	}
+	return apiV1TweetsPage(r, []string{"mentions", "-n", strconv.Itoa(count), "--json"})
+}
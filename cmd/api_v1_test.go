@@ -0,0 +1,178 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/store"
+)
+
+// fakeBirdScript answers the handful of commands the v1 endpoints run and
+// records its argv so tests can check what was passed through.
+const fakeBirdScript = `#!/bin/sh
+echo "$@" > "$(dirname "$0")/argv"
+case "$1" in
+read)
+  if [ "$2" = "404" ]; then echo "Tweet not found" >&2; exit 1; fi
+  echo '{"id":"'"$2"'","text":"hello","author":{"username":"alice","name":"Alice"}}' ;;
+thread|search|user-tweets)
+  echo '{"tweets":[{"id":"1","text":"one","author":{"username":"alice","name":"Alice"}}],"nextCursor":"c2"}' ;;
+mentions)
+  echo '[]' ;;
+about)
+  echo '{"accountBasedIn":"Norway","locationAccurate":true}' ;;
+*)
+  echo "Rate limit exceeded (429)" >&2; exit 1 ;;
+esac
+`
+
+func setupAPIV1(t *testing.T) (http.Handler, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake bird is a shell script")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	st, err := store.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Add("main", "token", "ct0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	bin := filepath.Join(t.TempDir(), "bird")
+	if err := os.WriteFile(bin, []byte(fakeBirdScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BIRDY_BIRD_PATH", bin)
+
+	mux := http.NewServeMux()
+	registerAPIV1(mux, "secret")
+	return mux, filepath.Join(filepath.Dir(bin), "argv")
+}
+
+func getAPIV1(t *testing.T, h http.Handler, path string, auth bool) (int, map[string]any) {
+	t.Helper()
+	r := httptest.NewRequest("GET", path, nil)
+	if auth {
+		r.Header.Set("Authorization", "Bearer secret")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: invalid JSON %q: %v", path, w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+func TestAPIV1Endpoints(t *testing.T) {
+	h, argvPath := setupAPIV1(t)
+	argv := func() string {
+		data, _ := os.ReadFile(argvPath)
+		return strings.TrimSpace(string(data))
+	}
+
+	code, body := getAPIV1(t, h, "/api/v1/tweets/123", true)
+	tweet, _ := body["tweet"].(map[string]any)
+	if code != 200 || body["account"] != "main" || tweet["id"] != "123" {
+		t.Fatalf("tweets: %d %v", code, body)
+	}
+
+	code, body = getAPIV1(t, h, "/api/v1/search?q=golang&count=5", true)
+	if code != 200 || body["next_cursor"] != "c2" || len(body["tweets"].([]any)) != 1 {
+		t.Fatalf("search: %d %v", code, body)
+	}
+	if got := argv(); got != "search golang -n 5 --max-pages 1 --json --all" {
+		t.Fatalf("search argv: %q", got)
+	}
+	getAPIV1(t, h, "/api/v1/search?q=-filter:replies+golang&count=5", true)
+	if got := argv(); got != "search -n 5 --max-pages 1 --json --all -- -filter:replies golang" {
+		t.Fatalf("dashed search argv: %q", got)
+	}
+	getAPIV1(t, h, "/api/v1/users/@alice/tweets?cursor=c2", true)
+	if got := argv(); got != "user-tweets @alice -n 20 --max-pages 1 --json --cursor c2" {
+		t.Fatalf("user-tweets argv: %q", got)
+	}
+
+	code, body = getAPIV1(t, h, "/api/v1/me/mentions", true)
+	if code != 200 || body["next_cursor"] != nil || body["tweets"] == nil {
+		t.Fatalf("mentions: %d %v", code, body)
+	}
+
+	code, body = getAPIV1(t, h, "/api/v1/users/alice/about", true)
+	about, _ := body["about"].(map[string]any)
+	if code != 200 || body["username"] != "alice" || about["accountBasedIn"] != "Norway" {
+		t.Fatalf("about: %d %v", code, body)
+	}
+
+	// The about data is not a profile, so the bare user path is not served.
+	if code, body = getAPIV1(t, h, "/api/v1/users/alice", true); code != 404 {
+		t.Fatalf("users: %d %v", code, body)
+	}
+}
+
+func TestAPIV1Errors(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	cases := []struct {
+		path string
+		auth bool
+		code int
+		want string
+	}{
+		{"/api/v1/tweets/123", false, 401, "unauthorized"},
+		{"/api/v1/tweets/abc", true, 400, "bad_request"},
+		{"/api/v1/search", true, 400, "bad_request"},
+		{"/api/v1/search?q=x&count=500", true, 400, "bad_request"},
+		{"/api/v1/users/not-a-handle/about", true, 400, "bad_request"},
+		{"/api/v1/tweets/404", true, 404, "not_found"},
+		{"/api/v1/tweets/1?account=nobody", true, 400, "bad_request"},
+		{"/api/v1/nope", true, 404, "not_found"},
+	}
+	for _, c := range cases {
+		code, body := getAPIV1(t, h, c.path, c.auth)
+		if code != c.code || body["code"] != c.want || body["ok"] != false {
+			t.Errorf("%s: got %d %v, want %d %s", c.path, code, body, c.code, c.want)
+		}
+	}
+}
+
+func TestAPIV1OpenAPISpec(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	code, spec := getAPIV1(t, h, "/api/v1/openapi.json", false)
+	if code != 200 || spec["openapi"] == nil {
+		t.Fatalf("spec: %d", code)
+	}
+	paths, _ := spec["paths"].(map[string]any)
+	for _, p := range []string{"/tweets/{id}", "/threads/{id}", "/search", "/users/{handle}/about", "/users/{handle}/tweets", "/me/mentions"} {
+		if _, ok := paths[p]; !ok {
+			t.Errorf("spec is missing %s", p)
+		}
+	}
+}
+
+func TestClassifyBirdFailure(t *testing.T) {
+	cases := map[string]string{
+		"Rate limit exceeded (429)\n": "rate_limited",
+		"Error: User not found\n":     "not_found",
+		"Error: fetch failed\n":       "upstream_error",
+		"":                            "upstream_error",
+	}
+	for stderr, want := range cases {
+		f := classifyBirdFailure(&birdcmd.Result{ExitCode: 1, Stderr: stderr})
+		if f.Code != want || f.Message == "" {
+			t.Errorf("%q: got %+v, want %s", stderr, f, want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/output"
+	"github.com/spf13/cobra"
+)
+
+var (
+	apikeyScopesFlag   string
+	apikeyAccountsFlag string
+	apikeyExpiresFlag  string
+	apikeyRateFlag     int
+	apikeyQuotaFlag    int
+)
+
+var apikeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage API keys for birdy host",
+	Long: `Create, list and revoke scoped API keys for the hosted HTTP API.
+
+Keys are stored hashed in ~/.config/birdy/apikeys.json. Scopes:
+  read   read-only bird commands and /api/v1
+  write  commands that post, follow or bookmark
+  chat   the /api/chat agent
+  admin  everything`,
+	GroupID: "birdy",
+}
+
+var apikeyCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create an API key and print its token once",
+	Long: `Create an API key. The token is printed once and cannot be shown again.
+
+Examples:
+  birdy apikey create dashboard --scopes read
+  birdy apikey create bot --scopes read,write --accounts bot1,bot2 --expires 720h
+  birdy apikey create partner --scopes read --rate 10 --quota 1000
+  birdy apikey create ops --scopes admin --expires 2026-12-31`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scopes, err := apikey.ParseScopes(apikeyScopesFlag)
+		if err != nil {
+			return err
+		}
+		if apikeyRateFlag < 0 || apikeyQuotaFlag < 0 {
+			return fmt.Errorf("--rate and --quota must not be negative")
+		}
+		now := time.Now()
+		expires, err := parseExpiry(apikeyExpiresFlag, now)
+		if err != nil {
+			return err
+		}
+		var accounts []string
+		for _, a := range strings.Split(apikeyAccountsFlag, ",") {
+			if a = strings.TrimSpace(a); a != "" {
+				accounts = append(accounts, a)
+			}
+		}
+
+		keys, err := apikey.Open()
+		if err != nil {
+			return err
+		}
+		token, key, err := keys.Create(apikey.CreateOptions{
+			Name:       args[0],
+			Scopes:     scopes,
+			Accounts:   accounts,
+			RateLimit:  apikeyRateFlag,
+			DailyQuota: apikeyQuotaFlag,
+			ExpiresAt:  expires,
+		}, now)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		fmt.Fprintf(out, "API key %q created (id %s). Store this token now; it will not be shown again:\n\n%s\n", key.Name, key.ID, token)
+		return nil
+	},
+}
+
+var apikeyListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List API keys",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := apikey.Open()
+		if err != nil {
+			return err
+		}
+		format, fields, err := parseOutputFlags(formatFlag, fieldsFlag)
+		if err != nil {
+			return err
+		}
+
+		list := keys.List()
+		now := time.Now()
+		if format != output.Raw {
+			rows := output.Rows{Fields: []string{"id", "name", "scopes", "accounts", "limits", "expires", "last_used"}}
+			for _, k := range list {
+				rows.Items = append(rows.Items, map[string]any{
+					"id":          k.ID,
+					"name":        k.Name,
+					"hint":        k.Hint,
+					"scopes":      joinScopes(k.Scopes),
+					"accounts":    strings.Join(k.Accounts, ","),
+					"limits":      formatKeyLimits(k),
+					"rate_limit":  k.RateLimit,
+					"daily_quota": k.DailyQuota,
+					"created":     k.CreatedAt.Format(time.RFC3339),
+					"expires":     formatOptionalTime(k.ExpiresAt, time.RFC3339),
+					"last_used":   formatOptionalTime(k.LastUsed, time.RFC3339),
+					"expired":     k.Expired(now),
+				})
+			}
+			return output.Render(cmd.OutOrStdout(), format, rows, fields)
+		}
+		if len(list) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No API keys. Run: birdy apikey create <name> --scopes read")
+			return nil
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tTOKEN\tSCOPES\tACCOUNTS\tLIMITS\tEXPIRES\tLAST USED")
+		for _, k := range list {
+			accounts := strings.Join(k.Accounts, ",")
+			if accounts == "" {
+				accounts = "all"
+			}
+			expires := formatOptionalTime(k.ExpiresAt, "2006-01-02 15:04")
+			if k.Expired(now) {
+				expires += " (expired)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s…\t%s\t%s\t%s\t%s\t%s\n",
+				k.ID, k.Name, k.Hint, joinScopes(k.Scopes), accounts, orDash(formatKeyLimits(k)),
+				orDash(expires), orDash(formatOptionalTime(k.LastUsed, "2006-01-02 15:04")))
+		}
+		return w.Flush()
+	},
+}
+
+var apikeyRevokeCmd = &cobra.Command{
+	Use:     "revoke <name|id>",
+	Aliases: []string{"rm"},
+	Short:   "Revoke an API key",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		keys, err := apikey.Open()
+		if err != nil {
+			return err
+		}
+		if err := keys.Revoke(args[0]); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "API key %q revoked.\n", args[0])
+		return nil
+	},
+}
+
+// parseExpiry accepts a duration from now ("720h"), a date or an RFC3339
+// timestamp. Empty means no expiry.
+func parseExpiry(v string, now time.Time) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("--expires must be in the future")
+		}
+		return now.Add(d), nil
+	}
+	t, err := parseDateFlag("--expires", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("--expires must be in the future")
+	}
+	return t, nil
+}
+
+// formatKeyLimits describes a key's own rate limit and quota; host defaults
+// apply to anything left blank.
+func formatKeyLimits(k apikey.Key) string {
+	var parts []string
+	if k.RateLimit > 0 {
+		parts = append(parts, fmt.Sprintf("%d/min", k.RateLimit))
+	}
+	if k.DailyQuota > 0 {
+		parts = append(parts, fmt.Sprintf("%d/day", k.DailyQuota))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func joinScopes(scopes []apikey.Scope) string {
+	parts := make([]string, len(scopes))
+	for i, s := range scopes {
+		parts[i] = string(s)
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatOptionalTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(layout)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func init() {
+	apikeyCreateCmd.Flags().StringVar(&apikeyScopesFlag, "scopes", "read", "comma-separated scopes: read, write, chat, admin")
+	apikeyCreateCmd.Flags().StringVar(&apikeyAccountsFlag, "accounts", "", "comma-separated accounts the key may use (default: all)")
+	apikeyCreateCmd.Flags().IntVar(&apikeyRateFlag, "rate", 0, "requests per minute (default: the host's --api-rate)")
+	apikeyCreateCmd.Flags().IntVar(&apikeyQuotaFlag, "quota", 0, "requests per UTC day (default: the host's --api-quota)")
+	apikeyCreateCmd.Flags().StringVar(&apikeyExpiresFlag, "expires", "", "expiry as a duration from now (720h), date or RFC3339 time")
+
+	apikeyCmd.AddCommand(apikeyCreateCmd, apikeyListCmd, apikeyRevokeCmd)
+	rootCmd.AddCommand(apikeyCmd)
+}
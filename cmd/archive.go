@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/guzus/birdy/internal/archive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveMaxPagesFlag int
+	archiveCountFlag    int
+	archiveDelayFlag    time.Duration
+
+	archiveSQLFlag      string
+	archiveQueryLimit   int
+	archiveExportLimit  int
+	archiveJSONFlag     bool
+	archiveExportFormat string
+	archiveOutputFlag   string
+
+	archiveFilter struct {
+		author   string
+		source   string
+		contains string
+		since    string
+		until    string
+	}
+)
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Keep a local SQLite archive of tweets",
+	Long: `Sync bookmarks, likes, mentions and user timelines into a local SQLite
+database at ~/.config/birdy/archive.db, then query or export it offline.
+
+Syncs resume from saved cursors and rotate accounts between pages.`,
+	GroupID: "birdy",
+}
+
+var archiveSyncCmd = &cobra.Command{
+	Use:   "sync [bookmarks|likes|mentions|user-tweets <handle>]",
+	Short: "Fetch new tweets into the archive",
+	Long: `Fetch new tweets into the archive. With no source, bookmarks, likes and
+mentions are synced.
+
+Each sync first reads the newest page(s) until it reaches tweets that are
+already archived, then continues the backfill from the saved cursor until
+--max-pages is spent.
+
+Examples:
+  birdy archive sync
+  birdy archive sync bookmarks --max-pages 20
+  birdy archive sync user-tweets @steipete`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sources := archive.DefaultSources
+		if len(args) > 0 {
+			src, err := archive.ParseSource(args)
+			if err != nil {
+				return err
+			}
+			sources = []archive.Source{src}
+		}
+
+		db, err := archive.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		opts := archive.SyncOptions{
+			MaxPages: archiveMaxPagesFlag,
+			Count:    archiveCountFlag,
+			Delay:    archiveDelayFlag,
+			Progress: func(source string, page int, account string, added int) {
+				if verboseFlag {
+					fmt.Fprintf(os.Stderr, "[birdy] %s page %d via %s: %d new\n", source, page, account, added)
+				}
+			},
+		}
+
+		out := cmd.OutOrStdout()
+		var failed bool
+		for _, src := range sources {
+			res, err := db.Sync(ctx, src, fetchBird, opts)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				failed = true
+				fmt.Fprintf(os.Stderr, "%s: %v\n", src.Key(), err)
+				continue
+			}
+			state := "more to backfill"
+			if res.Complete {
+				state = "fully synced"
+			}
+			fmt.Fprintf(out, "%s: %d new from %d page(s), %s\n", res.Source, res.Added, res.Pages, state)
+		}
+		if failed {
+			return fmt.Errorf("some sources failed to sync")
+		}
+		return nil
+	},
+}
+
+var archiveQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Query archived tweets with filters or SQL",
+	Long: `Query archived tweets with simple filters, or run a read-only SQL
+statement against the tables: tweets, users, tweet_sources, cursors.
+
+Examples:
+  birdy archive query --author steipete --since 2026-01-01
+  birdy archive query --source bookmarks --contains golang --json
+  birdy archive query --sql "SELECT author, COUNT(*) FROM tweets GROUP BY author ORDER BY 2 DESC LIMIT 10"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := archive.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		out := cmd.OutOrStdout()
+		if strings.TrimSpace(archiveSQLFlag) != "" {
+			cols, rows, err := db.Query(archiveSQLFlag)
+			if err != nil {
+				return err
+			}
+			return writeArchiveRows(out, cols, rows)
+		}
+
+		filter, err := archiveFilterFromFlags(archiveQueryLimit)
+		if err != nil {
+			return err
+		}
+		records, err := db.Tweets(filter)
+		if err != nil {
+			return err
+		}
+		if archiveJSONFlag {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(records)
+		}
+		if len(records) == 0 {
+			fmt.Fprintln(out, "No archived tweets match.")
+			return nil
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tAUTHOR\tCREATED\tTEXT")
+		for _, r := range records {
+			created := "-"
+			if !r.CreatedAt.IsZero() {
+				created = r.CreatedAt.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(w, "%s\t@%s\t%s\t%s\n", r.ID, r.Author, created, oneLine(r.Text, 80))
+		}
+		return w.Flush()
+	},
+}
+
+var archiveExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export archived tweets as jsonl, csv or md",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The global --format and --fields render bird output; export has
+		// its own formats.
+		if cmd.Flags().Changed("format") || cmd.Flags().Changed("fields") {
+			return fmt.Errorf("archive export does not take --format or --fields; use --export-format jsonl, csv or md")
+		}
+		filter, err := archiveFilterFromFlags(archiveExportLimit)
+		if err != nil {
+			return err
+		}
+
+		db, err := archive.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		records, err := db.Tweets(filter)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if archiveOutputFlag != "" && archiveOutputFlag != "-" {
+			f, err := os.OpenFile(archiveOutputFlag, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := archive.Export(out, archiveExportFormat, records); err != nil {
+			return err
+		}
+		if out != cmd.OutOrStdout() {
+			fmt.Fprintf(os.Stderr, "Exported %d tweets to %s\n", len(records), archiveOutputFlag)
+		}
+		return nil
+	},
+}
+
+// archiveFilterFromFlags builds the filter of query and export. Their
+// --limit defaults differ, so each has its own variable.
+func archiveFilterFromFlags(limit int) (archive.Filter, error) {
+	f := archive.Filter{
+		Author:   archiveFilter.author,
+		Source:   archiveFilter.source,
+		Contains: archiveFilter.contains,
+		Limit:    limit,
+	}
+	var err error
+	if f.Since, err = parseDateFlag("--since", archiveFilter.since); err != nil {
+		return f, err
+	}
+	if f.Until, err = parseDateFlag("--until", archiveFilter.until); err != nil {
+		return f, err
+	}
+	return f, nil
+}
+
+// parseDateFlag accepts YYYY-MM-DD, RFC 3339, or a relative duration such
+// as 72h (meaning "that long ago").
+func parseDateFlag(name, v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", v, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid %s %q (use YYYY-MM-DD, RFC 3339 or a duration like 72h)", name, v)
+}
+
+func writeArchiveRows(out io.Writer, cols []string, rows [][]string) error {
+	if archiveJSONFlag {
+		objs := make([]map[string]string, 0, len(rows))
+		for _, row := range rows {
+			obj := make(map[string]string, len(cols))
+			for i, c := range cols {
+				obj[c] = row[i]
+			}
+			objs = append(objs, obj)
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(objs)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.ToUpper(strings.Join(cols, "\t")))
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for i, c := range row {
+			cells[i] = oneLine(c, 80)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	return w.Flush()
+}
+
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if max > 3 && len([]rune(s)) > max {
+		return string([]rune(s)[:max-3]) + "..."
+	}
+	return s
+}
+
+func init() {
+	archiveSyncCmd.Flags().IntVar(&archiveMaxPagesFlag, "max-pages", 5, "maximum pages to fetch per source")
+	archiveSyncCmd.Flags().IntVarP(&archiveCountFlag, "count", "n", 20, "tweets to request per page")
+	archiveSyncCmd.Flags().DurationVar(&archiveDelayFlag, "delay", time.Second, "pause between pages")
+
+	for _, c := range []*cobra.Command{archiveQueryCmd, archiveExportCmd} {
+		c.Flags().StringVar(&archiveFilter.author, "author", "", "only tweets by this handle")
+		c.Flags().StringVar(&archiveFilter.source, "source", "", "only tweets synced from this source (bookmarks, likes, mentions, user-tweets)")
+		c.Flags().StringVar(&archiveFilter.contains, "contains", "", "only tweets whose text contains this string")
+		c.Flags().StringVar(&archiveFilter.since, "since", "", "only tweets created at or after this date")
+		c.Flags().StringVar(&archiveFilter.until, "until", "", "only tweets created before this date")
+	}
+	archiveQueryCmd.Flags().IntVar(&archiveQueryLimit, "limit", 50, "maximum tweets to show (0 for all)")
+	archiveQueryCmd.Flags().StringVar(&archiveSQLFlag, "sql", "", "run a read-only SQL statement instead of filters")
+	archiveQueryCmd.Flags().BoolVar(&archiveJSONFlag, "json", false, "output as JSON")
+
+	archiveExportCmd.Flags().IntVar(&archiveExportLimit, "limit", 0, "maximum tweets to export (0 for all)")
+	archiveExportCmd.Flags().StringVar(&archiveExportFormat, "export-format", "jsonl", "export format: jsonl, csv, md")
+	archiveExportCmd.Flags().StringVarP(&archiveOutputFlag, "output", "o", "", "write to a file instead of stdout")
+
+	archiveCmd.AddCommand(archiveSyncCmd, archiveQueryCmd, archiveExportCmd)
+	rootCmd.AddCommand(archiveCmd)
+}
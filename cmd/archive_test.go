@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArchiveLimitDefaults(t *testing.T) {
+	// query and export register --limit with different defaults; each must
+	// keep its own.
+	if archiveQueryLimit != 50 || archiveExportLimit != 0 {
+		t.Fatalf("limits = %d (query), %d (export); want 50, 0", archiveQueryLimit, archiveExportLimit)
+	}
+	for _, c := range []struct {
+		name string
+		want string
+	}{{"query", "50"}, {"export", "0"}} {
+		cmd, _, err := archiveCmd.Find([]string{c.name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := cmd.Flags().Lookup("limit").DefValue; got != c.want {
+			t.Errorf("archive %s --limit default = %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestArchiveExportRejectsGlobalFormat(t *testing.T) {
+	if archiveExportCmd.Flags().Lookup("export-format") == nil {
+		t.Fatal("expected archive export to have --export-format")
+	}
+	if err := archiveExportCmd.ParseFlags([]string{"--format", "csv"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		archiveExportCmd.Flags().Lookup("format").Changed = false
+		formatFlag = ""
+	})
+	err := archiveExportCmd.RunE(archiveExportCmd, nil)
+	if err == nil || !strings.Contains(err.Error(), "--export-format") {
+		t.Fatalf("expected a pointer to --export-format, got %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/budget"
+	"github.com/guzus/birdy/internal/chatsession"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/transcript"
+	"github.com/spf13/cobra"
+)
+
+// birdy ask exit codes, besides 0 for an answered prompt.
+const (
+	askExitError       = 1   // the agent failed
+	askExitUsage       = 2   // bad prompt, profile or session
+	askExitBudget      = 3   // an agent budget is exhausted
+	askExitInterrupted = 130 // interrupted with ctrl+c
+)
+
+var (
+	askJSON    bool
+	askModel   string
+	askProfile string
+	askSession string
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask <prompt>",
+	Short: "Run the agent once without the TUI",
+	Long: `Run the agent on a prompt and print its answer, for scripts, cron and CI.
+The answer streams to stdout and the commands the agent runs to stderr
+(with their output too under -v). A prompt of "-" is read from stdin.
+
+--json prints every agent event as a JSON line instead, the same events
+/api/chat streams. --session continues a chat session: "new" starts one
+and prints its id to stderr, and later asks pass that id. Sessions are
+saved with the TUI's chat history.
+
+Exit codes: 0 answered, 1 agent error, 2 bad prompt, profile or session,
+3 agent budget exhausted, 130 interrupted.
+
+Examples:
+  birdy ask "summarize my mentions from today"
+  birdy ask --profile researcher --json "what is @golang posting about?" > out.jsonl
+  birdy ask --session new "draft a reply to 1234567890"
+  birdy ask --session 4b1e0c2f9a7d3e15 "make it shorter"`,
+	GroupID: "birdy",
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    runAsk,
+}
+
+func init() {
+	askCmd.Flags().BoolVar(&askJSON, "json", false, "print agent events as JSON lines")
+	askCmd.Flags().StringVar(&askModel, "model", "", "model to use (default: the profile's, then the backend's)")
+	askCmd.Flags().StringVar(&askProfile, "profile", "", "agent profile from ~/.config/birdy/profiles.json")
+	askCmd.Flags().StringVar(&askSession, "session", "", `chat session to continue, or "new" to start one`)
+	rootCmd.AddCommand(askCmd)
+}
+
+// exitError is an error with the process exit code it should produce.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+func (e *exitError) ExitCode() int { return e.code }
+
+func runAsk(cmd *cobra.Command, args []string) error {
+	prompt := strings.Join(args, " ")
+	if prompt == "-" {
+		data, err := io.ReadAll(cmd.InOrStdin())
+		if err != nil {
+			return fmt.Errorf("reading prompt: %w", err)
+		}
+		prompt = string(data)
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	req := apiChatRequest{Prompt: prompt, Model: askModel, Profile: askProfile}
+	return runAgent(ctx, req, askSession, askJSON, cmd.OutOrStdout(), cmd.ErrOrStderr())
+}
+
+// runAgent runs the agent for req, continuing session when it is set,
+// and writes the answer, or the events as JSON lines, to stdout and
+// progress to stderr. A recipe's reply is saved to its output.
+func runAgent(ctx context.Context, req apiChatRequest, session string, jsonOut bool, stdout, stderr io.Writer) error {
+	var sessions *chatsession.Manager
+	var sess *chatsession.Session
+	if session != "" {
+		var err error
+		if sessions, err = openChatSessions(); err != nil {
+			return err
+		}
+		if session != "new" {
+			sess, err = sessions.Get(session)
+			if errors.Is(err, chatsession.ErrNotFound) {
+				return &exitError{askExitUsage, fmt.Errorf("unknown session %q", session)}
+			}
+			if err != nil {
+				return err
+			}
+			if strings.TrimSpace(req.Model) == "" {
+				req.Model = sess.Model
+			}
+		}
+	}
+	if f := req.normalize(); f != nil {
+		return &exitError{askExitUsage, f}
+	}
+
+	var turn *chatsession.Turn
+	var reply sessionReply
+	if sessions != nil {
+		var err error
+		if sess == nil {
+			if sess, err = sessions.Create(audit.Caller(), req.Model); err != nil {
+				return err
+			}
+			fmt.Fprintf(stderr, "session: %s\n", sess.ID)
+		}
+		if turn, err = sessions.Begin(ctx, sess.ID, req.Prompt); err != nil {
+			return err
+		}
+		ctx = turn.Context()
+		req.Prompt = transcript.TurnPrompt(turn.History)
+	}
+
+	exePath, err := os.Executable()
+	if err != nil || strings.TrimSpace(exePath) == "" {
+		exePath = "birdy"
+	}
+	out := askPrinter{stdout: stdout, stderr: stderr, json: jsonOut, verbose: verboseFlag}
+	var agentErr string
+	var cachedAt time.Time
+	cached := streamAgent(ctx, req, exePath, req.agentEnv(), func(ev claude.Event) {
+		if ev.Type == claude.EventError && agentErr == "" {
+			agentErr = ev.Error
+		}
+		if !ev.CachedAt.IsZero() {
+			cachedAt = ev.CachedAt
+		}
+		reply.add(ev)
+		out.print(ev)
+	})
+	out.finish()
+	canceled := ctx.Err() != nil
+	if turn != nil {
+		if err := turn.End(reply.messages(canceled)); err != nil {
+			return fmt.Errorf("saving session: %w", err)
+		}
+	}
+	switch {
+	case cached:
+		fmt.Fprintf(stderr, "cached answer from %s (--refresh to run again)\n", cachedAt.Local().Format("2006-01-02 15:04"))
+	case req.run != nil:
+		if err := req.run.Save(reply.messages(canceled)); err != nil {
+			return err
+		}
+		fmt.Fprintf(stderr, "saved: %s\n", req.run.Output)
+	}
+
+	switch {
+	case canceled:
+		return &exitError{askExitInterrupted, errors.New("interrupted")}
+	case strings.HasPrefix(agentErr, budget.ErrExhausted.Error()):
+		return &exitError{askExitBudget, errors.New(agentErr)}
+	case agentErr != "":
+		return &exitError{askExitError, errors.New(agentErr)}
+	}
+	return nil
+}
+
+// askPrinter writes agent events for birdy ask. In text mode the answer
+// goes to stdout as it streams, each message of a multi-step run in its own
+// paragraph, and commands (and, verbose, their output) go to stderr.
+type askPrinter struct {
+	stdout, stderr io.Writer
+	json           bool
+	verbose        bool
+	cur            string // text of the current message printed so far
+	wrote          bool
+}
+
+func (p *askPrinter) print(ev claude.Event) {
+	if p.json {
+		_ = json.NewEncoder(p.stdout).Encode(ev)
+		return
+	}
+	switch ev.Type {
+	case claude.EventToken:
+		p.text(p.cur + ev.Text)
+	case claude.EventSnapshot:
+		p.text(ev.Text)
+	case claude.EventToolUse:
+		p.cur = ""
+		fmt.Fprintf(p.stderr, "> %s\n", ev.Command)
+	case claude.EventToolResult:
+		if p.verbose && ev.Output != "" {
+			fmt.Fprintln(p.stderr, strings.TrimRight(ev.Output, "\n"))
+		}
+	case claude.EventUsage:
+		if p.verbose && ev.Usage != nil {
+			fmt.Fprintf(p.stderr, "[birdy] %d tokens, $%.4f, %d turns\n", ev.Usage.Tokens(), ev.Usage.CostUSD, ev.Usage.Turns)
+		}
+	}
+}
+
+// text prints what is new in text, the current message so far. Text that
+// does not continue the printed message starts a new paragraph.
+func (p *askPrinter) text(text string) {
+	if text == "" || strings.HasPrefix(p.cur, text) {
+		return
+	}
+	if rest, ok := strings.CutPrefix(text, p.cur); ok && p.cur != "" {
+		io.WriteString(p.stdout, rest)
+	} else {
+		if p.wrote {
+			io.WriteString(p.stdout, "\n\n")
+		}
+		io.WriteString(p.stdout, text)
+	}
+	p.cur = text
+	p.wrote = true
+}
+
+// finish ends the answer with a newline.
+func (p *askPrinter) finish() {
+	if p.wrote && !p.json {
+		io.WriteString(p.stdout, "\n")
+	}
+}
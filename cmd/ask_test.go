@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/guzus/birdy/internal/claude"
+)
+
+// setupFakeClaude puts a claude CLI on PATH that answers in two messages
+// around a tool call, or fails when the prompt says "fail". It records the
+// last prompt in the returned file, and the agent's command limit next to
+// it with a .commands suffix.
+func setupFakeClaude(t *testing.T) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake claude is a shell script")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(claude.BackendEnv, "")
+	bin := t.TempDir()
+	promptFile := filepath.Join(bin, "prompt")
+	script := `#!/bin/sh
+while [ $# -gt 0 ]; do
+  if [ "$1" = "-p" ]; then printf '%s' "$2" > "` + promptFile + `"; fi
+  shift
+done
+printf '%s' "$BIRDY_AGENT_COMMANDS" > "` + promptFile + `.commands"
+case "$(cat "` + promptFile + `")" in
+*fail*)
+  echo '{"type":"result","subtype":"error","is_error":true,"result":"model overloaded"}'
+  exit 0 ;;
+esac
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Looking."},{"type":"tool_use","id":"t1","name":"mcp__birdy__search","input":{"query":"golang"}}]}}'
+echo '{"type":"assistant","message":{"content":[{"type":"text","text":"Found it."}]}}'
+echo '{"type":"result","subtype":"success","result":"Found it.","num_turns":2}'
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return promptFile
+}
+
+func TestAskPrintsAnswerAndCommands(t *testing.T) {
+	setupFakeClaude(t)
+
+	var stdout, stderr bytes.Buffer
+	if err := runAgent(context.Background(), apiChatRequest{Prompt: "find golang tweets"}, "", false, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if stdout.String() != "Looking.\n\nFound it.\n" {
+		t.Fatalf("stdout = %q", stdout.String())
+	}
+	if !strings.Contains(stderr.String(), "> birdy search golang --json\n") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+}
+
+func TestAskJSONAndExitCodes(t *testing.T) {
+	setupFakeClaude(t)
+
+	var stdout bytes.Buffer
+	err := runAgent(context.Background(), apiChatRequest{Prompt: "please fail"}, "", true, &stdout, &bytes.Buffer{})
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.ExitCode() != askExitError || err.Error() != "model overloaded" {
+		t.Fatalf("err = %v", err)
+	}
+	var types []claude.EventType
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var ev claude.Event
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			t.Fatalf("line %q: %v", line, err)
+		}
+		types = append(types, ev.Type)
+	}
+	if len(types) < 2 || types[0] != claude.EventError || types[len(types)-1] != claude.EventDone {
+		t.Fatalf("events = %q", types)
+	}
+
+	err = runAgent(context.Background(), apiChatRequest{Prompt: "hi", Profile: "missing"}, "", false, &bytes.Buffer{}, &bytes.Buffer{})
+	if !errors.As(err, &exit) || exit.ExitCode() != askExitUsage {
+		t.Fatalf("unknown profile: %v", err)
+	}
+	err = runAgent(context.Background(), apiChatRequest{Prompt: "hi"}, "0000000000000000", false, &bytes.Buffer{}, &bytes.Buffer{})
+	if !errors.As(err, &exit) || exit.ExitCode() != askExitUsage {
+		t.Fatalf("unknown session: %v", err)
+	}
+}
+
+func TestAskContinuesSession(t *testing.T) {
+	promptFile := setupFakeClaude(t)
+
+	var stderr bytes.Buffer
+	if err := runAgent(context.Background(), apiChatRequest{Prompt: "find golang tweets"}, "new", false, &bytes.Buffer{}, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	id, ok := strings.CutPrefix(strings.SplitN(stderr.String(), "\n", 2)[0], "session: ")
+	if !ok {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+
+	if err := runAgent(context.Background(), apiChatRequest{Prompt: "summarize them"}, id, false, &bytes.Buffer{}, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	prompt, err := os.ReadFile(promptFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"find golang tweets", "Found it.", "summarize them"} {
+		if !strings.Contains(string(prompt), want) {
+			t.Fatalf("second prompt lacks %q:\n%s", want, prompt)
+		}
+	}
+
+	m, err := openChatSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := m.Messages(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 6 || msgs[5].Content != "Found it." {
+		t.Fatalf("transcript = %+v", msgs)
+	}
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditJSONFlag  bool
+	auditTailLines int
+	auditFilter    struct {
+		account string
+		command string
+		class   string
+		caller  string
+		runID   string
+		since   string
+		until   string
+		limit   int // query's --limit; tail has its own -n default
+	}
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the log of executed bird commands",
+	Long: `Every bird command birdy runs (from the CLI, the host API, web sessions and
+agent chats) is appended to ~/.config/birdy/audit.jsonl with the caller, the
+account, redacted args, the exit code and the duration.
+
+Each entry includes the hash of the previous one; "birdy audit verify" reports
+entries that were edited, removed or reordered.`,
+	GroupID: "birdy",
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Show the most recent audit entries",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runAuditQuery(cmd.OutOrStdout(), audit.Filter{Limit: auditTailLines})
+	},
+}
+
+var auditQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Filter audit entries by account, command, caller or time",
+	Long: `Filter audit entries by account, command, class, caller or time range.
+
+Examples:
+  birdy audit query --class write --since 24h
+  birdy audit query --filter-account main --command search --json
+  birdy audit query --caller api: --since 2026-01-01 --until 2026-02-01`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The global --account picks the account bird runs as.
+		if cmd.Flags().Changed("account") {
+			return fmt.Errorf("audit query filters by account with --filter-account, not --account")
+		}
+		f := audit.Filter{
+			Account: strings.TrimPrefix(strings.TrimSpace(auditFilter.account), "@"),
+			Command: strings.ToLower(strings.TrimSpace(auditFilter.command)),
+			Class:   strings.ToLower(strings.TrimSpace(auditFilter.class)),
+			Caller:  strings.TrimSpace(auditFilter.caller),
+			RunID:   strings.TrimSpace(auditFilter.runID),
+			Limit:   auditFilter.limit,
+		}
+		if f.Class != "" && f.Class != audit.ClassRead && f.Class != audit.ClassWrite {
+			return fmt.Errorf("invalid --class %q (use read or write)", auditFilter.class)
+		}
+		var err error
+		if f.Since, err = parseDateFlag("--since", auditFilter.since); err != nil {
+			return err
+		}
+		if f.Until, err = parseDateFlag("--until", auditFilter.until); err != nil {
+			return err
+		}
+		return runAuditQuery(cmd.OutOrStdout(), f)
+	},
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check that the audit log has not been modified",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := audit.DefaultPath()
+		if err != nil {
+			return err
+		}
+		n, err := audit.Verify(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Audit log OK: %d entries\n", n)
+		return nil
+	},
+}
+
+func runAuditQuery(out io.Writer, f audit.Filter) error {
+	path, err := audit.DefaultPath()
+	if err != nil {
+		return err
+	}
+	entries, err := audit.Query(path, f)
+	if err != nil {
+		return err
+	}
+	if auditJSONFlag {
+		enc := json.NewEncoder(out)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No audit entries match.")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tCALLER\tACCOUNT\tCOMMAND\tEXIT\tDURATION\tARGS")
+	for _, e := range entries {
+		command := e.Command
+		if e.Class == audit.ClassWrite {
+			command += " (write)"
+		}
+		caller := e.Caller
+		if e.RunID != "" {
+			caller += " run:" + e.RunID
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%dms\t%s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), caller, e.Account, command,
+			e.ExitCode, e.DurationMS, oneLine(strings.Join(e.Args, " "), 60))
+	}
+	return w.Flush()
+}
+
+func init() {
+	auditTailCmd.Flags().IntVarP(&auditTailLines, "lines", "n", 20, "number of entries to show")
+	auditQueryCmd.Flags().IntVar(&auditFilter.limit, "limit", 100, "maximum entries to show, newest kept (0 for all)")
+	auditQueryCmd.Flags().StringVar(&auditFilter.account, "filter-account", "", "only commands run as this account")
+	auditQueryCmd.Flags().StringVar(&auditFilter.command, "command", "", "only this bird command (e.g. tweet, search)")
+	auditQueryCmd.Flags().StringVar(&auditFilter.class, "class", "", "only read or write commands")
+	auditQueryCmd.Flags().StringVar(&auditFilter.caller, "caller", "", "only callers starting with this (e.g. api:, web:, cli:alice)")
+	auditQueryCmd.Flags().StringVar(&auditFilter.runID, "run", "", "only commands from this agent run id")
+	auditQueryCmd.Flags().StringVar(&auditFilter.since, "since", "", "only entries at or after this date or duration ago")
+	auditQueryCmd.Flags().StringVar(&auditFilter.until, "until", "", "only entries before this date")
+	for _, c := range []*cobra.Command{auditTailCmd, auditQueryCmd} {
+		c.Flags().BoolVar(&auditJSONFlag, "json", false, "output as JSON lines")
+	}
+
+	auditCmd.AddCommand(auditTailCmd, auditQueryCmd, auditVerifyCmd)
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/guzus/birdy/internal/audit"
+)
+
+func TestAPICommandsAreAudited(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	if code, _ := getAPIV1(t, h, "/api/v1/tweets/123", true); code != http.StatusOK {
+		t.Fatalf("tweet status %d", code)
+	}
+	if code, _ := getAPIV1(t, h, "/api/v1/tweets/404", true); code != http.StatusNotFound {
+		t.Fatalf("missing tweet status %d", code)
+	}
+
+	path, err := audit.DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := audit.Query(path, audit.Filter{Caller: "api:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	e := entries[1]
+	if e.Caller != "api:invite" || e.Account != "main" || e.Command != "read" ||
+		e.Class != audit.ClassRead || e.ExitCode != 1 || e.Strategy != "round-robin" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if n, err := audit.Verify(path); err != nil || n != 2 {
+		t.Fatalf("Verify = %d, %v", n, err)
+	}
+}
+
+func TestAuditLimitDefaults(t *testing.T) {
+	// tail -n and query --limit have different defaults; each must keep its own.
+	if auditTailLines != 20 || auditFilter.limit != 100 {
+		t.Fatalf("limits = %d (tail), %d (query); want 20, 100", auditTailLines, auditFilter.limit)
+	}
+}
@@ -1,6 +1,9 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/spf13/cobra"
+)
 
 // makeBirdCmd creates a lightweight cobra command that forwards to bird
 // via the existing passthrough logic. DisableFlagParsing ensures all
@@ -26,33 +29,7 @@ func init() {
 		&cobra.Group{ID: "birdy", Title: "Birdy Commands:"},
 	)
 
-	birdCmds := []struct{ use, short string }{
-		{"about", "Get account information for a user"},
-		{"bookmarks", "Get your bookmarked tweets"},
-		{"check", "Check credential availability"},
-		{"follow", "Follow a user"},
-		{"followers", "Get followers for a user"},
-		{"following", "Get following for a user"},
-		{"home", "Get your home timeline"},
-		{"likes", "Get likes for a user"},
-		{"list-timeline", "Get tweets from a list"},
-		{"lists", "Get lists for a user"},
-		{"mentions", "Get your mentions"},
-		{"news", "Get trending news"},
-		{"query-ids", "Query tweets by IDs"},
-		{"read", "Read a tweet by ID or URL"},
-		{"replies", "Get replies to a tweet"},
-		{"reply", "Reply to a tweet"},
-		{"search", "Search for tweets"},
-		{"thread", "Read a tweet thread"},
-		{"tweet", "Post a new tweet"},
-		{"unbookmark", "Remove a tweet from bookmarks"},
-		{"unfollow", "Unfollow a user"},
-		{"user-tweets", "Get tweets for a user"},
-		{"whoami", "Show current authenticated user"},
-	}
-
-	for _, c := range birdCmds {
-		rootCmd.AddCommand(makeBirdCmd(c.use, c.short))
+	for _, c := range birdcmd.Commands {
+		rootCmd.AddCommand(makeBirdCmd(c.Name, c.Description))
 	}
 }
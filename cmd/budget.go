@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/guzus/birdy/internal/budget"
+	"github.com/spf13/cobra"
+)
+
+var budgetCmd = &cobra.Command{
+	Use:   "budget",
+	Short: "Show today's agent spending against its budgets",
+	Long: `Agent runs (TUI chat, /api/chat, chat sessions and jobs) are limited per run
+and per day. Each run's birdy calls carry a run id, so birdy can count model
+turns, bird invocations and dollars in ~/.config/birdy/budget.json. Once a
+budget is exhausted, further agent commands are refused.
+
+Limits are read from the environment; 0 or unset means unlimited:
+  BIRDY_AGENT_RUN_TURNS   model turns per run (default 25)
+  BIRDY_AGENT_RUN_CALLS   bird invocations per run
+  BIRDY_AGENT_RUN_COST    dollars per run
+  BIRDY_AGENT_DAY_TURNS   model turns per day
+  BIRDY_AGENT_DAY_CALLS   bird invocations per day
+  BIRDY_AGENT_DAY_COST    dollars per day`,
+	GroupID: "birdy",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := budget.Open()
+		if err != nil {
+			return err
+		}
+		return printBudget(cmd.OutOrStdout(), t)
+	},
+}
+
+var budgetResetCmd = &cobra.Command{
+	Use:   "reset",
+	Short: "Clear today's agent spending counters",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		t, err := budget.Open()
+		if err != nil {
+			return err
+		}
+		if err := t.Reset(); err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), "Agent budget counters reset for today.")
+		return nil
+	},
+}
+
+func printBudget(out io.Writer, t *budget.Tracker) error {
+	day, _, err := t.Status("")
+	if err != nil {
+		return err
+	}
+	l := t.Limits()
+	limit := func(n float64, money bool) string {
+		switch {
+		case n <= 0:
+			return "unlimited"
+		case money:
+			return fmt.Sprintf("$%.2f", n)
+		default:
+			return fmt.Sprintf("%d", int(n))
+		}
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tTODAY\tDAY LIMIT\tRUN LIMIT")
+	fmt.Fprintf(w, "turns\t%d\t%s\t%s\n", day.Turns, limit(float64(l.DayTurns), false), limit(float64(l.RunTurns), false))
+	fmt.Fprintf(w, "bird calls\t%d\t%s\t%s\n", day.Calls, limit(float64(l.DayCalls), false), limit(float64(l.RunCalls), false))
+	fmt.Fprintf(w, "cost\t$%.2f\t%s\t%s\n", day.CostUSD, limit(l.DayCostUSD, true), limit(l.RunCostUSD, true))
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := t.Check(); err != nil {
+		fmt.Fprintf(out, "\n%v\n", err)
+	}
+	return nil
+}
+
+func init() {
+	budgetCmd.AddCommand(budgetResetCmd)
+	rootCmd.AddCommand(budgetCmd)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guzus/birdy/internal/archive"
+	"github.com/spf13/cobra"
+)
+
+var (
+	findAuthorFlag string
+	findSinceFlag  string
+	findUntilFlag  string
+	findInFlag     string
+	findLimitFlag  int
+	findJSONFlag   bool
+)
+
+var findCmd = &cobra.Command{
+	Use:   "find <terms...>",
+	Short: "Search archived tweets and saved chats offline",
+	Long: `Ranked full-text search over the local tweet archive and the TUI chat
+transcripts in ~/.config/birdy/chats. Works without network access.
+
+Every term must match. Wrap phrases in quotes and end a term with * for a
+prefix match.
+
+Examples:
+  birdy find sqlite
+  birdy find '"rate limit"' --author steipete --since 2026-01-01
+  birdy find deploy* --in chats`,
+	GroupID: "birdy",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := archive.SearchOptions{
+			Query:  strings.Join(args, " "),
+			Author: findAuthorFlag,
+			Limit:  findLimitFlag,
+		}
+		switch strings.ToLower(strings.TrimSpace(findInFlag)) {
+		case "", "all":
+		case "tweets":
+			opts.Tweets = true
+		case "chats":
+			opts.Chats = true
+		default:
+			return fmt.Errorf("invalid --in %q (valid: all, tweets, chats)", findInFlag)
+		}
+		var err error
+		if opts.Since, err = parseDateFlag("--since", findSinceFlag); err != nil {
+			return err
+		}
+		if opts.Until, err = parseDateFlag("--until", findUntilFlag); err != nil {
+			return err
+		}
+
+		db, err := archive.Open()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		if !opts.Tweets {
+			dir, err := archive.DefaultChatDir()
+			if err != nil {
+				return err
+			}
+			if err := db.IndexChats(dir); err != nil {
+				return err
+			}
+		}
+
+		hits, err := db.Search(opts)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if findJSONFlag {
+			for i := range hits {
+				hits[i].Snippet = hits[i].Highlight("", "")
+			}
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(hits)
+		}
+		if len(hits) == 0 {
+			fmt.Fprintln(out, "No matches.")
+			return nil
+		}
+
+		meta := lipgloss.NewStyle().Faint(true)
+		mark := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("11"))
+		home, _ := os.UserHomeDir()
+		for i, h := range hits {
+			if i > 0 {
+				fmt.Fprintln(out)
+			}
+			when := "unknown date"
+			if !h.CreatedAt.IsZero() {
+				when = h.CreatedAt.Local().Format("2006-01-02 15:04")
+			}
+			var header string
+			if h.Kind == "tweet" {
+				header = fmt.Sprintf("@%s · %s · %s", h.Author, when, h.URL())
+			} else {
+				p := h.Path
+				if home != "" {
+					p = strings.Replace(p, home, "~", 1)
+				}
+				header = fmt.Sprintf("chat · %s · %s", when, p)
+			}
+			fmt.Fprintln(out, meta.Render(header))
+			fmt.Fprintln(out, "  "+highlightSnippet(h, mark))
+		}
+		return nil
+	},
+}
+
+func highlightSnippet(h archive.Hit, mark lipgloss.Style) string {
+	const open, close = "\x00[", "]\x00"
+	s := oneLine(h.Highlight(open, close), 0)
+	var b strings.Builder
+	for {
+		i := strings.Index(s, open)
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		j := strings.Index(s[i:], close)
+		if j < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		b.WriteString(mark.Render(s[i+len(open) : i+j]))
+		s = s[i+j+len(close):]
+	}
+	return b.String()
+}
+
+func init() {
+	findCmd.Flags().StringVar(&findAuthorFlag, "author", "", "only tweets by this handle (skips chats)")
+	findCmd.Flags().StringVar(&findSinceFlag, "since", "", "only results created at or after this date")
+	findCmd.Flags().StringVar(&findUntilFlag, "until", "", "only results created before this date")
+	findCmd.Flags().StringVar(&findInFlag, "in", "all", "where to search: all, tweets, chats")
+	findCmd.Flags().IntVarP(&findLimitFlag, "limit", "n", 20, "maximum results")
+	findCmd.Flags().BoolVar(&findJSONFlag, "json", false, "output as JSON")
+	rootCmd.AddCommand(findCmd)
+}
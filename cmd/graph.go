@@ -0,0 +1,233 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+	"github.com/guzus/birdy/internal/graph"
+	"github.com/spf13/cobra"
+)
+
+var (
+	graphUserIDFlag   string
+	graphCountFlag    int
+	graphMaxPagesFlag int
+	graphDelayFlag    time.Duration
+	graphRestartFlag  bool
+	graphSinceFlag    string
+	graphJSONFlag     bool
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Snapshot and diff followers/following over time",
+	Long: `Store dated follower/following snapshots under ~/.config/birdy/graph/
+and report who followed, unfollowed or was followed between them.`,
+	GroupID: "birdy",
+}
+
+var graphSnapshotCmd = &cobra.Command{
+	Use:   "snapshot <handle>",
+	Short: "Page followers and following into a dated snapshot",
+	Long: `Page the followers and following lists of an account through the
+rotation pool and store them as a dated snapshot.
+
+Progress is saved after every page. If a run stops (rate limit, Ctrl-C or
+--max-pages), running the command again resumes from the saved cursor.
+
+Examples:
+  birdy graph snapshot @steipete
+  birdy graph snapshot steipete --max-pages 20
+  birdy graph snapshot steipete --user-id 25401953`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := graph.Open(args[0])
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		out := cmd.OutOrStdout()
+		snap, err := store.Pending()
+		if err != nil {
+			return err
+		}
+		if snap != nil && graphRestartFlag {
+			if err := store.Discard(); err != nil {
+				return err
+			}
+			snap = nil
+		}
+		if snap != nil {
+			fmt.Fprintf(out, "Resuming snapshot of @%s started %s (%d followers, %d following so far)\n",
+				store.Handle, snap.StartedAt.Local().Format("2006-01-02 15:04"), len(snap.Followers), len(snap.Following))
+		} else {
+			userID := graphUserIDFlag
+			if userID == "" {
+				if userID, err = graph.ResolveUserID(ctx, store.Handle, fetchBird); err != nil {
+					return err
+				}
+			}
+			if snap, err = store.Begin(userID, time.Now()); err != nil {
+				return err
+			}
+		}
+
+		pages, err := graph.Page(ctx, snap, fetchBird, graph.PageOptions{
+			Count:    graphCountFlag,
+			MaxPages: graphMaxPagesFlag,
+			Delay:    graphDelayFlag,
+			Progress: func(list string, page int, account string, total int) {
+				if verboseFlag {
+					fmt.Fprintf(os.Stderr, "[birdy] %s page %d via %s: %d users\n", list, page, account, total)
+				}
+			},
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Stopped after %d page(s); progress saved. Run again to resume.\n", pages)
+			return err
+		}
+		if !snap.Complete() {
+			fmt.Fprintf(out, "Paused after %d page(s) (%d followers, %d following so far). Run again to continue.\n",
+				pages, len(snap.Followers), len(snap.Following))
+			return nil
+		}
+
+		path, err := store.Finish(snap, time.Now())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Saved snapshot of @%s: %d followers, %d following\n%s\n",
+			store.Handle, len(snap.Followers), len(snap.Following), path)
+		return nil
+	},
+}
+
+var graphDiffCmd = &cobra.Command{
+	Use:   "diff <handle>",
+	Short: "Show new and lost followers and follows between snapshots",
+	Long: `Compare the latest snapshot with an earlier one: by default the
+previous snapshot, or with --since the newest snapshot taken at or before
+that date.
+
+Examples:
+  birdy graph diff steipete
+  birdy graph diff steipete --since 168h
+  birdy graph diff steipete --since 2026-03-01 --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		since, err := parseDateFlag("--since", graphSinceFlag)
+		if err != nil {
+			return err
+		}
+		store, err := graph.Open(args[0])
+		if err != nil {
+			return err
+		}
+		latest, err := store.Latest()
+		if err != nil {
+			return err
+		}
+		if latest == nil {
+			return fmt.Errorf("no snapshots of @%s yet; run: birdy graph snapshot %s", store.Handle, store.Handle)
+		}
+		base, err := store.Baseline(since)
+		if err != nil {
+			return err
+		}
+		d := graph.Compare(base, latest)
+
+		out := cmd.OutOrStdout()
+		if graphJSONFlag {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(d)
+		}
+
+		fmt.Fprintf(out, "@%s: %s → %s\n", d.Handle,
+			d.From.Local().Format("2006-01-02 15:04"), d.To.Local().Format("2006-01-02 15:04"))
+		fmt.Fprintf(out, "Followers: %d (+%d, -%d)\n", d.Followers, len(d.NewFollowers), len(d.LostFollowers))
+		fmt.Fprintf(out, "Following: %d (+%d, -%d)\n", d.Following, len(d.NewFollows), len(d.Unfollowed))
+		writeUserSection(out, "New followers", d.NewFollowers)
+		writeUserSection(out, "Lost followers", d.LostFollowers)
+		writeUserSection(out, "New follows", d.NewFollows)
+		writeUserSection(out, "Unfollowed", d.Unfollowed)
+		return nil
+	},
+}
+
+var graphListCmd = &cobra.Command{
+	Use:   "list <handle>",
+	Short: "List stored snapshots for an account",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store, err := graph.Open(args[0])
+		if err != nil {
+			return err
+		}
+		infos, err := store.List()
+		if err != nil {
+			return err
+		}
+		pending, err := store.Pending()
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if len(infos) == 0 && pending == nil {
+			fmt.Fprintf(out, "No snapshots of @%s yet.\n", store.Handle)
+			return nil
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TAKEN\tFOLLOWERS\tFOLLOWING\tFILE")
+		for _, info := range infos {
+			snap, err := info.Load()
+			if err != nil {
+				fmt.Fprintf(w, "%s\t-\t-\t%s (%v)\n", info.TakenAt.Local().Format("2006-01-02 15:04"), info.Path, err)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", info.TakenAt.Local().Format("2006-01-02 15:04"),
+				len(snap.Followers), len(snap.Following), info.Path)
+		}
+		if pending != nil {
+			fmt.Fprintf(w, "pending\t%d\t%d\tstarted %s\n", len(pending.Followers), len(pending.Following),
+				pending.StartedAt.Local().Format("2006-01-02 15:04"))
+		}
+		return w.Flush()
+	},
+}
+
+func writeUserSection(out io.Writer, title string, users []bird.User) {
+	if len(users) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "\n%s (%d):\n", title, len(users))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, u := range users {
+		fmt.Fprintf(w, "  @%s\t%s\t%d followers\n", u.Username, oneLine(u.Name, 40), u.FollowersCount)
+	}
+	w.Flush()
+}
+
+func init() {
+	graphSnapshotCmd.Flags().StringVar(&graphUserIDFlag, "user-id", "", "numeric user id (skips the lookup from recent tweets)")
+	graphSnapshotCmd.Flags().IntVarP(&graphCountFlag, "count", "n", 100, "users to request per page")
+	graphSnapshotCmd.Flags().IntVar(&graphMaxPagesFlag, "max-pages", 0, "stop after this many pages in one run (0 for no limit)")
+	graphSnapshotCmd.Flags().DurationVar(&graphDelayFlag, "delay", time.Second, "pause between pages")
+	graphSnapshotCmd.Flags().BoolVar(&graphRestartFlag, "restart", false, "discard an unfinished snapshot and start over")
+
+	graphDiffCmd.Flags().StringVar(&graphSinceFlag, "since", "", "diff against the newest snapshot at or before this date")
+	graphDiffCmd.Flags().BoolVar(&graphJSONFlag, "json", false, "output as JSON")
+
+	graphCmd.AddCommand(graphSnapshotCmd, graphDiffCmd, graphListCmd)
+	rootCmd.AddCommand(graphCmd)
+}
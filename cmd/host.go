@@ -17,6 +17,9 @@ import (
 
 	"github.com/creack/pty"
 	"github.com/gorilla/websocket"
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/jobs"
 	"github.com/spf13/cobra"
 )
 
@@ -27,8 +30,9 @@ const (
 )
 
 var (
-	hostAddrFlag       string
-	hostInviteCodeFlag string
+	hostAddrFlag         string
+	hostInviteCodeFlag   string
+	hostInviteScopesFlag string
 )
 
 var hostCmd = &cobra.Command{
@@ -41,6 +45,30 @@ var hostCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		scopes := hostInviteScopesFlag
+		if !cmd.Flags().Changed("invite-scopes") {
+			if v, ok := os.LookupEnv("BIRDY_HOST_INVITE_SCOPES"); ok {
+				scopes = v
+			}
+		}
+		if hostInviteScopes, err = apikey.ParseScopes(scopes); err != nil {
+			return fmt.Errorf("--invite-scopes: %w", err)
+		}
+		if err := setupAPIRateLimits(cmd); err != nil {
+			return err
+		}
+		jobsDir, err := jobs.DefaultDir()
+		if err != nil {
+			return err
+		}
+		jobManager, err := jobs.Open(jobsDir)
+		if err != nil {
+			return err
+		}
+		sessionManager, err := openChatSessions()
+		if err != nil {
+			return err
+		}
 
 		allowedOrigins := parseAllowedOrigins(os.Getenv("BIRDY_HOST_ALLOWED_ORIGINS"))
 		webDir, _ := resolveHostWebDir()
@@ -66,6 +94,11 @@ var hostCmd = &cobra.Command{
 		})
 		mux.HandleFunc("/api/command", handleAPICommand(inviteCode))
 		mux.HandleFunc("/api/chat", handleAPIChat(inviteCode))
+		registerAPIV1(mux, inviteCode)
+		registerAPIJobs(mux, inviteCode, jobManager)
+		registerAPISessions(mux, inviteCode, sessionManager)
+		mux.HandleFunc("/mcp", handleMCP(inviteCode))
+		mux.HandleFunc("/metrics", handleMetrics(inviteCode))
 
 		mux.Handle("/", makeHostedWebHandler(webDir))
 
@@ -136,6 +169,9 @@ func serveHostedTTY(w http.ResponseWriter, r *http.Request, inviteCode string) {
 	if ok := authenticateHostedWS(conn, inviteCode); !ok {
 		return
 	}
+	wsSessionsActive.Inc()
+	wsSessionsTotal.Inc()
+	defer wsSessionsActive.Dec()
 
 	// Wait for the browser to send its terminal size before starting the TUI.
 	initSize := pty.Winsize{Cols: 120, Rows: 36}
@@ -176,7 +212,7 @@ func serveHostedTTY(w http.ResponseWriter, r *http.Request, inviteCode string) {
 	if strings.TrimSpace(os.Getenv("BIRDY_TUI_HIDE_HISTORY")) == "" {
 		childEnv = append(childEnv, "BIRDY_TUI_HIDE_HISTORY=1")
 	}
-	child.Env = append(childEnv, "BIRDY_TUI_MOUSE=1")
+	child.Env = append(childEnv, "BIRDY_TUI_MOUSE=1", audit.CallerEnv+"=web:"+audit.NewRunID())
 
 	ptmx, err := pty.StartWithSize(child, &initSize)
 	if err != nil {
@@ -348,6 +384,7 @@ func authenticateHostedWS(conn *websocket.Conn, inviteCode string) bool {
 
 	var msg hostedWSMessage
 	if err := json.Unmarshal(payload, &msg); err != nil || msg.Type != "auth" {
+		authFailures.Inc("ws", "missing")
 		_ = conn.WriteJSON(hostedWSAuthMessage{Type: "auth", OK: false, Error: "missing auth message"})
 		_ = conn.WriteControl(websocket.CloseMessage,
 			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "missing auth"),
@@ -356,6 +393,7 @@ func authenticateHostedWS(conn *websocket.Conn, inviteCode string) bool {
 	}
 
 	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(msg.Code)), []byte(inviteCode)) != 1 {
+		authFailures.Inc("ws", "invalid")
 		_ = conn.WriteJSON(hostedWSAuthMessage{Type: "auth", OK: false, Error: "invalid invite code"})
 		_ = conn.WriteControl(websocket.CloseMessage,
 			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "invalid invite code"),
@@ -556,5 +594,13 @@ func init() {
 	hostCmd.Flags().StringVar(&hostInviteCodeFlag, "invite-code", "", "invite code for web host (or set BIRDY_HOST_INVITE_CODE)")
 	hostCmd.Flags().StringVar(&hostInviteCodeFlag, "token", "", "deprecated alias for --invite-code")
 	_ = hostCmd.Flags().MarkHidden("token")
+	hostCmd.Flags().StringVar(&hostInviteScopesFlag, "invite-scopes", "read,write,chat",
+		"API scopes granted to the invite code; empty disables it on /api (or set BIRDY_HOST_INVITE_SCOPES)")
+	hostCmd.Flags().IntVar(&hostAPIRateFlag, "api-rate", 60, "API requests per minute per key, 0 for no limit (or set BIRDY_API_RATE)")
+	hostCmd.Flags().IntVar(&hostAPIQuotaFlag, "api-quota", 0, "API requests per UTC day per key, 0 for no limit (or set BIRDY_API_QUOTA)")
+	hostCmd.Flags().IntVar(&hostAPIIPRateFlag, "api-ip-rate", 120, "API requests per minute per client IP, 0 for no limit (or set BIRDY_API_IP_RATE)")
+	hostCmd.Flags().IntVar(&hostAPIIPQuotaFlag, "api-ip-quota", 0, "API requests per UTC day per client IP, 0 for no limit (or set BIRDY_API_IP_QUOTA)")
+	hostCmd.Flags().BoolVar(&hostAPITrustProxy, "trust-proxy", false,
+		"take the client IP from X-Forwarded-For; only behind a proxy that sets it (or set BIRDY_API_TRUST_PROXY=1)")
 	rootCmd.AddCommand(hostCmd)
 }
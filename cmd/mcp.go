@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/mcp"
+	"github.com/spf13/cobra"
+)
+
+var mcpReadOnlyFlag bool
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Serve bird commands as MCP tools over stdio",
+	Long: `Speak the Model Context Protocol on stdin/stdout so MCP-capable agents can
+use birdy's account pool directly. Every bird command is a typed tool
+(search, read, thread, about, home, mentions, ...); accounts rotate with
+--strategy as they do for the CLI, and --account pins one.
+
+Write tools (tweet, reply, follow, unfollow, unbookmark) are left out with
+--read-only or BIRDY_READ_ONLY=1.
+
+Example (Claude Code):
+  claude mcp add birdy -- birdy mcp --read-only`,
+	GroupID: "birdy",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		srv := newMCPServer(mcpToolOptions{
+			Writes:   !mcpReadOnlyFlag && !readOnlyModeEnabled(),
+			Account:  accountFlag,
+			Strategy: strategyFlag,
+		})
+		return srv.ServeStdio(ctx, cmd.InOrStdin(), cmd.OutOrStdout())
+	},
+}
+
+type mcpToolOptions struct {
+	Writes   bool // expose tools that change account state
+	Account  string
+	Strategy string
+	Pool     []string
+	Caller   string
+}
+
+// newMCPServer exposes each bird command in birdcmd.Commands as a tool.
+func newMCPServer(opts mcpToolOptions) *mcp.Server {
+	srv := &mcp.Server{Name: "birdy", Version: version}
+	for _, c := range birdcmd.Commands {
+		if c.Write && !opts.Writes {
+			continue
+		}
+		srv.Tools = append(srv.Tools, mcp.Tool{
+			Name:        c.ToolName(),
+			Description: c.Description,
+			InputSchema: c.Schema(),
+			Annotations: map[string]any{
+				"readOnlyHint":  !c.Write,
+				"openWorldHint": true,
+			},
+			Call: func(ctx context.Context, input map[string]any) (string, error) {
+				args, err := c.Args(input)
+				if err != nil {
+					return "", err
+				}
+				res, err := birdcmd.Run(ctx, birdcmd.Request{
+					Args:     args,
+					Account:  opts.Account,
+					Strategy: opts.Strategy,
+					Pool:     opts.Pool,
+					Caller:   opts.Caller,
+				})
+				if err != nil {
+					return "", err
+				}
+				if res.ExitCode != 0 {
+					msg := strings.TrimSpace(res.Stderr)
+					if msg == "" {
+						msg = fmt.Sprintf("bird exited with code %d", res.ExitCode)
+					}
+					return strings.TrimSpace(res.Stdout), fmt.Errorf("%s", msg)
+				}
+				return res.Stdout, nil
+			},
+		})
+	}
+	return srv
+}
+
+// handleMCP serves MCP over HTTP for API keys. Tools are limited to what the
+// key may do: write tools need the write scope, and accounts rotate within
+// the key's pool.
+func handleMCP(inviteCode string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if f := apiThrottleIP(w, r); f != nil {
+			writeAPIV1Error(w, f)
+			return
+		}
+		key, err := apiAuthenticate(r, inviteCode)
+		if err != nil {
+			status, msg := apiAuthFailure(err)
+			writeAPIV1Error(w, &apiV1Failure{Status: status, Code: "unauthorized", Message: msg})
+			return
+		}
+		if f := apiThrottle(w, r, key); f != nil {
+			writeAPIV1Error(w, f)
+			return
+		}
+		if !key.Has(apikey.ScopeRead) {
+			writeAPIV1Error(w, apiForbidden(`api key lacks the "read" scope`))
+			return
+		}
+		newMCPServer(mcpToolOptions{
+			Writes:   key.Has(apikey.ScopeWrite) && !readOnlyModeEnabled(),
+			Strategy: apiStrategy(""),
+			Pool:     key.Accounts,
+			Caller:   apiCaller(key),
+		}).ServeHTTP(w, r)
+	}
+}
+
+func init() {
+	mcpCmd.Flags().BoolVar(&mcpReadOnlyFlag, "read-only", false, "leave out tools that change account state")
+	rootCmd.AddCommand(mcpCmd)
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMCPOverHTTP(t *testing.T) {
+	h, argv := setupAPIV1(t)
+	mux := h.(*http.ServeMux)
+	mux.HandleFunc("/mcp", handleMCP("secret"))
+
+	call := func(body string) map[string]any {
+		t.Helper()
+		r := httptest.NewRequest("POST", "/mcp", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", w.Code, w.Body)
+		}
+		var resp map[string]any
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		result, _ := resp["result"].(map[string]any)
+		return result
+	}
+	toolNames := func() []string {
+		var names []string
+		for _, tool := range call(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)["tools"].([]any) {
+			names = append(names, tool.(map[string]any)["name"].(string))
+		}
+		return names
+	}
+
+	names := strings.Join(toolNames(), ",")
+	if !strings.Contains(names, "user_tweets") || !strings.Contains(names, "tweet") {
+		t.Fatalf("tools: %s", names)
+	}
+	t.Setenv("BIRDY_READ_ONLY", "1")
+	for _, name := range toolNames() {
+		if name == "tweet" || name == "follow" {
+			t.Fatalf("read-only mode exposes %q", name)
+		}
+	}
+
+	result := call(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"about","arguments":{"username":"alice"}}}`)
+	text := result["content"].([]any)[0].(map[string]any)["text"].(string)
+	if result["isError"] != nil || !strings.Contains(text, "Norway") {
+		t.Fatalf("about: %v", result)
+	}
+	if data, _ := os.ReadFile(argv); strings.TrimSpace(string(data)) != "about @alice --json" {
+		t.Fatalf("bird argv %q", data)
+	}
+
+	result = call(`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"read","arguments":{"tweet":"404"}}}`)
+	if result["isError"] != true {
+		t.Fatalf("failed read should be a tool error: %v", result)
+	}
+}
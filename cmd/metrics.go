@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/guzus/birdy/internal/apikey"
+	"github.com/guzus/birdy/internal/metrics"
+)
+
+var (
+	authFailures = metrics.NewCounter("birdy_auth_failures_total",
+		"Rejected credentials by surface (api, ws) and reason (missing, invalid, expired).", "surface", "reason")
+	rateLimited = metrics.NewCounter("birdy_api_rate_limited_total",
+		"API requests rejected by the rate limiter, by subject (key, ip) and limit (minute, day).", "subject", "limit")
+	wsSessionsActive = metrics.NewGauge("birdy_ws_sessions_active",
+		"Authenticated web TUI sessions currently open.")
+	wsSessionsTotal = metrics.NewCounter("birdy_ws_sessions_total",
+		"Authenticated web TUI sessions started.")
+	chatStreamsActive = metrics.NewGauge("birdy_chat_streams_active",
+		"Agent chats currently running.")
+	chatStreamsTotal = metrics.NewCounter("birdy_chat_streams_total",
+		"Agent chats by mode (stream for /api/chat, session for chat sessions, job for /api/jobs) and result (ok, error, canceled).", "mode", "result")
+	chatDuration = metrics.NewHistogram("birdy_chat_duration_seconds",
+		"Agent chat duration by mode.", nil, "mode")
+)
+
+// handleMetrics serves the Prometheus metrics. Scrapers authenticate with
+// BIRDY_METRICS_TOKEN when it is set, or with an admin API key.
+func handleMetrics(inviteCode string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !metricsAuthorized(r, inviteCode) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = metrics.Default.WriteText(w)
+	}
+}
+
+func metricsAuthorized(r *http.Request, inviteCode string) bool {
+	if want := strings.TrimSpace(os.Getenv("BIRDY_METRICS_TOKEN")); want != "" {
+		if subtle.ConstantTimeCompare([]byte(hostRequestInviteCode(r)), []byte(want)) == 1 {
+			return true
+		}
+	}
+	key, err := apiAuthenticate(r, inviteCode)
+	return err == nil && key.Has(apikey.ScopeAdmin)
+}
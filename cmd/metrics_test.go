@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	h, _ := setupAPIV1(t)
+	mux := h.(*http.ServeMux)
+	mux.HandleFunc("/metrics", handleMetrics("secret"))
+	t.Setenv("BIRDY_METRICS_TOKEN", "scrape")
+
+	before := authFailures.Value("api", "invalid")
+	if code, _ := getAPIV1(t, h, "/api/v1/tweets/123", true); code != http.StatusOK {
+		t.Fatalf("tweet status %d", code)
+	}
+	r := httptest.NewRequest("GET", "/api/v1/tweets/123", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got := authFailures.Value("api", "invalid"); got != before+1 {
+		t.Fatalf("auth failures = %v, want %v", got, before+1)
+	}
+
+	scrape := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/metrics", nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+	// The invite code has no admin scope, so it cannot scrape.
+	for _, token := range []string{"", "secret", "wrong"} {
+		if w := scrape(token); w.Code != http.StatusUnauthorized {
+			t.Fatalf("token %q: status %d", token, w.Code)
+		}
+	}
+	w := scrape("scrape")
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape status %d", w.Code)
+	}
+	body := w.Body.String()
+	for _, want := range []string{
+		`birdy_bird_executions_total{command="read",account="main",result="ok"}`,
+		`birdy_bird_duration_seconds_count{command="read"}`,
+		`birdy_rotation_picks_total{account="main",mode="rotation"}`,
+		`birdy_auth_failures_total{surface="api",reason="invalid"}`,
+		"birdy_ws_sessions_active 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
@@ -3,82 +3,98 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+	"time"
 
-	"github.com/guzus/birdy/internal/rotation"
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/output"
+	"github.com/guzus/birdy/internal/profile"
 	"github.com/guzus/birdy/internal/runner"
-	"github.com/guzus/birdy/internal/state"
 	"github.com/guzus/birdy/internal/store"
+	"github.com/guzus/birdy/internal/untrusted"
 	"github.com/spf13/cobra"
 )
 
-var readOnlyBlockedBirdCommands = map[string]struct{}{
-	"tweet":      {},
-	"reply":      {},
-	"follow":     {},
-	"unfollow":   {},
-	"unbookmark": {},
-}
+var readOnlyBlockedBirdCommands = birdcmd.WriteCommands
 
 func runPassthrough(cmd *cobra.Command, args []string) error {
 	if len(args) == 0 {
 		return cmd.Help()
 	}
 
+	args, format, fields, err := splitFormatArgs(args)
+	if err != nil {
+		return err
+	}
+
 	if blocked, name := isReadOnlyBirdCommand(args); blocked {
 		return fmt.Errorf("%q is disabled in read-only mode (BIRDY_READ_ONLY)", name)
 	}
 
-	st, err := store.Open()
+	prof, err := profile.FromEnv()
 	if err != nil {
-		return fmt.Errorf("opening account store: %w", err)
+		return fmt.Errorf("loading agent profile: %w", err)
 	}
-
-	if st.Len() == 0 {
-		return fmt.Errorf("no accounts configured\nRun: birdy account add <name>")
+	if err := birdcmd.CheckProfile(prof, args); err != nil {
+		return err
 	}
 
-	var account *store.Account
+	if err := birdcmd.ChargeAgentCall(""); err != nil {
+		return err
+	}
 
-	if accountFlag != "" {
-		account, err = st.Get(accountFlag)
-		if err != nil {
-			return err
-		}
-	} else {
-		strat, err := rotation.ParseStrategy(strategyFlag)
-		if err != nil {
+	args, edited, err := birdcmd.ApproveAgentWrite(cmd.Context(), "", "", args)
+	if err != nil {
+		return err
+	}
+	if edited {
+		if err := birdcmd.CheckProfile(prof, args); err != nil {
 			return err
 		}
+		fmt.Fprintf(os.Stderr, "[birdy] the user edited the command to: birdy %s\n", approval.Quote(args))
+	}
 
-		rs, err := state.Load()
-		if err != nil {
-			return fmt.Errorf("loading rotation state: %w", err)
-		}
+	st, err := store.Open()
+	if err != nil {
+		return fmt.Errorf("opening account store: %w", err)
+	}
 
-		account, err = rotation.Pick(st.List(), strat, rs.LastUsedName)
-		if err != nil {
-			return err
-		}
+	if st.Len() == 0 {
+		return fmt.Errorf("no accounts configured\nRun: birdy account add <name>")
+	}
 
-		rs.LastUsedName = account.Name
-		if err := rs.Save(); err != nil {
-			return fmt.Errorf("saving rotation state: %w", err)
-		}
+	var pool []string
+	if prof != nil {
+		pool = prof.Accounts
+	}
+	account, err := birdcmd.PickAccount(st, accountFlag, strategyFlag, pool)
+	if err != nil {
+		return err
 	}
 
 	if verboseFlag {
 		fmt.Fprintf(os.Stderr, "[birdy] using account: %s\n", account.Name)
 	}
 
-	if err := st.RecordUsage(account.Name); err != nil {
-		return err
+	entry := audit.Entry{Account: account.Name}
+	if accountFlag == "" {
+		entry.Strategy = strategyFlag
+	}
+
+	if format != output.Raw {
+		return runFormatted(account, args, format, fields, entry)
 	}
-	if err := st.Save(); err != nil {
-		return fmt.Errorf("saving account store: %w", err)
+	if untrusted.Enabled() {
+		return runForAgent(account, args, entry)
 	}
 
+	start := time.Now()
 	exitCode, err := runner.Run(account, args)
+	entry.ExitCode = exitCode
+	birdcmd.Audit(entry, args, time.Since(start), err)
 	if err != nil {
 		return err
 	}
@@ -88,6 +104,130 @@ func runPassthrough(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runFormatted asks bird for JSON and re-renders it in the requested format.
+func runFormatted(account *store.Account, args []string, format output.Format, fields []string, entry audit.Entry) error {
+	args = withJSON(args)
+	start := time.Now()
+	exitCode, stdout, stderr, err := runner.RunCapture(account, args)
+	entry.ExitCode = exitCode
+	birdcmd.Audit(entry, args, time.Since(start), err)
+	if err != nil {
+		return err
+	}
+	os.Stderr.WriteString(stderr)
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	data := []byte(stdout)
+	if untrusted.Enabled() {
+		data = untrusted.Mark(data, untrusted.NewNonce())
+	}
+	rows, err := output.Decode(data)
+	if err != nil {
+		return fmt.Errorf("--format needs a bird command with JSON output: %w", err)
+	}
+	return output.Render(os.Stdout, format, rows, fields)
+}
+
+// runForAgent runs a bird command for an agent: as JSON when the command
+// supports it, with tweet texts, user names and bios marked as untrusted
+// content.
+func runForAgent(account *store.Account, args []string, entry audit.Entry) error {
+	if c, ok := birdcmd.Lookup(firstBirdCommand(args)); ok && c.JSON {
+		args = withJSON(args)
+	}
+	start := time.Now()
+	exitCode, stdout, stderr, err := runner.RunCapture(account, args)
+	entry.ExitCode = exitCode
+	birdcmd.Audit(entry, args, time.Since(start), err)
+	if err != nil {
+		return err
+	}
+	os.Stderr.WriteString(untrusted.Sanitize(stderr))
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+	_, err = os.Stdout.Write(untrusted.Mark([]byte(stdout), untrusted.NewNonce()))
+	return err
+}
+
+// splitFormatArgs removes birdy's --format/--fields flags from bird args,
+// since bird commands skip cobra flag parsing. Values already parsed by
+// cobra (birdy --format json ...) are used when the args have none.
+func splitFormatArgs(args []string) ([]string, output.Format, []string, error) {
+	formatValue, fieldsValue := formatFlag, fieldsFlag
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(a, "=")
+		if name != "--format" && name != "--fields" {
+			rest = append(rest, a)
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, output.Raw, nil, fmt.Errorf("%s requires a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "--format" {
+			formatValue = value
+		} else {
+			fieldsValue = value
+		}
+	}
+
+	format, fields, err := parseOutputFlags(formatValue, fieldsValue)
+	if err != nil {
+		return nil, output.Raw, nil, err
+	}
+	return rest, format, fields, nil
+}
+
+// parseOutputFlags validates --format/--fields. Selecting fields without a
+// format implies a table.
+func parseOutputFlags(formatValue, fieldsValue string) (output.Format, []string, error) {
+	format, err := output.Parse(formatValue)
+	if err != nil {
+		return output.Raw, nil, err
+	}
+	fields := output.ParseFields(fieldsValue)
+	if format == output.Raw && len(fields) > 0 {
+		format = output.Table
+	}
+	return format, fields, nil
+}
+
+// withJSON adds --json to bird args that ask for no JSON output yet,
+// before any "--".
+func withJSON(args []string) []string {
+	if hasArg(args, "--json") || hasArg(args, "--json-full") {
+		return args
+	}
+	i := slices.Index(args, "--")
+	if i < 0 {
+		return append(args, "--json")
+	}
+	return slices.Insert(slices.Clone(args), i, "--json")
+}
+
+func hasArg(args []string, want string) bool {
+	for _, a := range args {
+		if a == "--" {
+			return false
+		}
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
+
 func isReadOnlyBirdCommand(args []string) (bool, string) {
 	if !readOnlyModeEnabled() {
 		return false, ""
@@ -102,12 +242,7 @@ func isReadOnlyBirdCommand(args []string) (bool, string) {
 }
 
 func readOnlyModeEnabled() bool {
-	switch strings.ToLower(strings.TrimSpace(os.Getenv("BIRDY_READ_ONLY"))) {
-	case "1", "true", "yes", "on":
-		return true
-	default:
-		return false
-	}
+	return birdcmd.ReadOnly()
 }
 
 func firstBirdCommand(args []string) string {
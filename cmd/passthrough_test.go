@@ -1,6 +1,11 @@
 package cmd
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/guzus/birdy/internal/output"
+)
 
 func TestFirstBirdCommandSkipsFlags(t *testing.T) {
 	if got := firstBirdCommand([]string{"--foo", "-v", "tweet"}); got != "tweet" {
@@ -24,3 +29,39 @@ func TestIsReadOnlyBirdCommand(t *testing.T) {
 		t.Fatalf("expected home allowed, got blocked=%v name=%q", blocked, name)
 	}
 }
+
+func TestSplitFormatArgs(t *testing.T) {
+	args, format, fields, err := splitFormatArgs([]string{"search", "golang", "--format", "csv", "-n", "5", "--fields=id,text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(args, " ") != "search golang -n 5" || format != output.CSV || strings.Join(fields, ",") != "id,text" {
+		t.Fatalf("got args=%v format=%q fields=%v", args, format, fields)
+	}
+
+	// Fields alone imply a table; anything after -- belongs to bird.
+	args, format, _, err = splitFormatArgs([]string{"search", "--fields", "id", "--", "--format", "x"})
+	if err != nil || format != output.Table || strings.Join(args, " ") != "search -- --format x" {
+		t.Fatalf("got args=%v format=%q err=%v", args, format, err)
+	}
+
+	if _, _, _, err := splitFormatArgs([]string{"home", "--format"}); err == nil {
+		t.Fatal("expected error for missing value")
+	}
+	if _, _, _, err := splitFormatArgs([]string{"home", "--format", "yaml"}); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestWithJSON(t *testing.T) {
+	for in, want := range map[string]string{
+		"home -n 5":          "home -n 5 --json",
+		"search -- -golang":  "search --json -- -golang",
+		"home --json-full":   "home --json-full",
+		"search --json -- x": "search --json -- x",
+	} {
+		if got := strings.Join(withJSON(strings.Fields(in)), " "); got != want {
+			t.Errorf("withJSON(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
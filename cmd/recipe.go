@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/guzus/birdy/internal/recipe"
+	"github.com/spf13/cobra"
+)
+
+var (
+	recipeParams  []string
+	recipeJSON    bool
+	recipeModel   string
+	recipeRefresh bool
+)
+
+var recipeCmd = &cobra.Command{
+	Use:   "recipe",
+	Short: "List and run saved agent workflows",
+	Long: `Recipes are saved agent prompts in ~/.config/birdy/recipes/<name>.md: a
+markdown prompt template with a short front matter header.
+
+  ---
+  description: What a competitor posted lately
+  model: opus
+  profile: researcher
+  commands: user-tweets, thread, replies, about
+  params: handle, days=7
+  output: competitor-{handle}-{date}.md
+  ---
+  Write a digest of what @{handle} posted in the last {days} days.
+
+{name} placeholders are filled from params; those without a default are
+required, and {date} is today's date. commands limits the bird commands the
+agent may run, on top of the profile. Each run's reply is saved as markdown
+in ~/.config/birdy/chats, under output or a timestamped name.
+
+A recipe with a cache duration (cache: 6h) reuses its answer to the same
+filled-in prompt, accounts and model for that long, from the answer cache
+in ~/.config/birdy/answer_cache.json. --refresh runs it again.
+
+Recipes also run from the TUI (ctrl+r) and from /api/chat with
+{"recipe": "<name>", "params": {...}}.`,
+	GroupID: "birdy",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var recipeListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved recipes",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		recipes, err := recipe.List()
+		printRecipes(cmd.OutOrStdout(), recipes)
+		return err
+	},
+}
+
+var recipeRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "Run a recipe and save its reply",
+	Long: `Run a recipe like birdy ask runs a prompt: the answer streams to stdout,
+the commands the agent runs to stderr, and the path of the saved reply is
+printed last, or the time of a cached answer. Exit codes are those of birdy
+ask.
+
+Examples:
+  birdy recipe run competitor-digest --param handle=acme
+  birdy recipe run mentions-triage --json > triage.jsonl`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		params, err := recipe.ParseParams(recipeParams)
+		if err != nil {
+			return &exitError{askExitUsage, err}
+		}
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		req := apiChatRequest{Recipe: args[0], Params: params, Model: recipeModel, Refresh: recipeRefresh}
+		return runAgent(ctx, req, "", recipeJSON, cmd.OutOrStdout(), cmd.ErrOrStderr())
+	},
+}
+
+func printRecipes(out io.Writer, recipes []recipe.Recipe) {
+	if len(recipes) == 0 {
+		fmt.Fprintln(out, "No recipes in ~/.config/birdy/recipes.")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPARAMS\tDESCRIPTION")
+	for _, r := range recipes {
+		var params []string
+		for _, p := range r.Params {
+			if p.HasDefault {
+				params = append(params, p.Name+"="+p.Default)
+			} else {
+				params = append(params, p.Name)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, strings.Join(params, " "), r.Description)
+	}
+	_ = w.Flush()
+}
+
+func init() {
+	recipeRunCmd.Flags().StringArrayVar(&recipeParams, "param", nil, "recipe parameter as key=value (repeatable)")
+	recipeRunCmd.Flags().BoolVar(&recipeJSON, "json", false, "print agent events as JSON lines")
+	recipeRunCmd.Flags().StringVar(&recipeModel, "model", "", "model to use (default: the recipe's)")
+	recipeRunCmd.Flags().BoolVar(&recipeRefresh, "refresh", false, "run again even if the answer is cached")
+	recipeCmd.AddCommand(recipeListCmd, recipeRunCmd)
+	rootCmd.AddCommand(recipeCmd)
+}
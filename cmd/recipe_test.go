@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/guzus/birdy/internal/recipe"
+	"github.com/guzus/birdy/internal/transcript"
+)
+
+func writeTestRecipe(t *testing.T, name, src string) {
+	t.Helper()
+	dir, err := recipe.DefaultDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".md"), []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecipeRunSavesReply(t *testing.T) {
+	promptFile := setupFakeClaude(t)
+	writeTestRecipe(t, "digest", "---\ncommands: search, user-tweets\nparams: handle\noutput: digest-{handle}\n---\nDigest golang posts by @{handle}.")
+
+	var stdout, stderr bytes.Buffer
+	req := apiChatRequest{Recipe: "digest", Params: map[string]string{"handle": "golang"}}
+	if err := runAgent(context.Background(), req, "", false, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(promptFile); string(got) != "Digest golang posts by @golang." {
+		t.Fatalf("prompt = %q", got)
+	}
+	if got, _ := os.ReadFile(promptFile + ".commands"); string(got) != "search,user-tweets" {
+		t.Fatalf("commands = %q", got)
+	}
+
+	dir, _ := transcript.Dir()
+	path := filepath.Join(dir, "digest-golang.md")
+	if !strings.Contains(stderr.String(), "saved: "+path+"\n") {
+		t.Fatalf("stderr = %q", stderr.String())
+	}
+	msgs, _, err := transcript.Load(path)
+	if err != nil || len(msgs) != 3 || msgs[0].Content != "Digest golang posts by @golang." || msgs[2].Content != "Found it." {
+		t.Fatalf("saved = %+v, %v", msgs, err)
+	}
+
+	err = runAgent(context.Background(), apiChatRequest{Recipe: "digest"}, "", false, &bytes.Buffer{}, &bytes.Buffer{})
+	var exit *exitError
+	if !errors.As(err, &exit) || exit.ExitCode() != askExitUsage || !strings.Contains(err.Error(), `needs parameter "handle"`) {
+		t.Fatalf("missing param = %v", err)
+	}
+}
+
+func TestPrintRecipes(t *testing.T) {
+	var out bytes.Buffer
+	printRecipes(&out, nil)
+	if !strings.Contains(out.String(), "No recipes") {
+		t.Fatalf("empty = %q", out.String())
+	}
+	out.Reset()
+	printRecipes(&out, []recipe.Recipe{{Name: "digest", Description: "Weekly digest", Params: []recipe.Param{{Name: "handle"}, {Name: "days", Default: "7", HasDefault: true}}}})
+	if !strings.Contains(out.String(), "digest  handle days=7  Weekly digest") {
+		t.Fatalf("list = %q", out.String())
+	}
+}
+
+func TestRecipeRunReusesCachedAnswer(t *testing.T) {
+	promptFile := setupFakeClaude(t)
+	writeTestRecipe(t, "triage", "---\ncache: 1h\n---\nTriage my mentions.")
+
+	run := func(refresh bool) (string, string) {
+		t.Helper()
+		os.Remove(promptFile)
+		var stdout, stderr bytes.Buffer
+		req := apiChatRequest{Recipe: "triage", Refresh: refresh}
+		if err := runAgent(context.Background(), req, "", false, &stdout, &stderr); err != nil {
+			t.Fatal(err)
+		}
+		return stdout.String(), stderr.String()
+	}
+
+	if _, stderr := run(false); !strings.Contains(stderr, "saved: ") {
+		t.Fatalf("first run stderr = %q", stderr)
+	}
+	stdout, stderr := run(false)
+	if _, err := os.Stat(promptFile); !os.IsNotExist(err) {
+		t.Fatal("a cached recipe should not run the agent")
+	}
+	if stdout != "Found it.\n" || !strings.Contains(stderr, "cached answer from ") {
+		t.Fatalf("cached run = %q, %q", stdout, stderr)
+	}
+	if _, stderr := run(true); !strings.Contains(stderr, "saved: ") {
+		t.Fatalf("refresh stderr = %q", stderr)
+	}
+	if _, err := os.Stat(promptFile); err != nil {
+		t.Fatal("--refresh should run the agent")
+	}
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -11,6 +12,8 @@ var (
 	strategyFlag string
 	accountFlag  string
 	verboseFlag  bool
+	formatFlag   string
+	fieldsFlag   string
 )
 
 var rootCmd = &cobra.Command{
@@ -27,7 +30,8 @@ Examples:
   birdy search "golang"           # search, auto-rotating accounts
   birdy --account main home       # use a specific account
   birdy account add main          # add a new account
-  birdy account list              # list all accounts`,
+  birdy account list              # list all accounts
+  birdy search "golang" --format csv --fields id,author.username,text`,
 	// If no subcommand matches, treat everything as bird args.
 	RunE:          runPassthrough,
 	SilenceUsage:  true,
@@ -45,12 +49,22 @@ func init() {
 		"use a specific account by name (skip rotation)")
 	rootCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false,
 		"show which account is being used")
+	rootCmd.PersistentFlags().StringVar(&formatFlag, "format", "",
+		"render structured output as table, json, csv or markdown")
+	rootCmd.PersistentFlags().StringVar(&fieldsFlag, "fields", "",
+		"comma-separated fields to include with --format (e.g. id,author.username,text)")
 }
 
-// Execute runs the root command.
+// Execute runs the root command. Errors exit with status 1 unless they
+// carry their own exit code.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		code := 1
+		var coded interface{ ExitCode() int }
+		if errors.As(err, &coded) {
+			code = coded.ExitCode()
+		}
+		os.Exit(code)
 	}
 }
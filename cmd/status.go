@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 
+	"github.com/guzus/birdy/internal/output"
 	"github.com/guzus/birdy/internal/state"
 	"github.com/guzus/birdy/internal/store"
 	"github.com/spf13/cobra"
@@ -23,7 +25,30 @@ var statusCmd = &cobra.Command{
 			return err
 		}
 
+		format, fields, err := parseOutputFlags(formatFlag, fieldsFlag)
+		if err != nil {
+			return err
+		}
+
 		accounts := st.List()
+		var totalUses int64
+		for _, a := range accounts {
+			totalUses += a.UseCount
+		}
+
+		if format != output.Raw {
+			rows := output.Rows{
+				Fields: []string{"accounts", "strategy", "last_used", "total_uses"},
+				Items: []map[string]any{{
+					"accounts":   len(accounts),
+					"strategy":   strategyFlag,
+					"last_used":  rs.LastUsedName,
+					"total_uses": totalUses,
+				}},
+			}
+			return output.Render(os.Stdout, format, rows, fields)
+		}
+
 		fmt.Printf("Accounts:   %d\n", len(accounts))
 		fmt.Printf("Strategy:   %s\n", strategyFlag)
 
@@ -32,11 +57,6 @@ var statusCmd = &cobra.Command{
 		} else {
 			fmt.Printf("Last used:  (none)\n")
 		}
-
-		var totalUses int64
-		for _, a := range accounts {
-			totalUses += a.UseCount
-		}
 		fmt.Printf("Total uses: %d\n", totalUses)
 		return nil
 	},
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchIntervalFlag    time.Duration
+	watchCountFlag       int
+	watchSinkFlags       []string
+	watchNameFlag        string
+	watchOnceFlag        bool
+	watchEmitInitialFlag bool
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Poll a bird feed and emit only new tweets",
+	Long: `Poll search, mentions, list or user timelines at an interval and emit
+only tweets that have not been seen before.
+
+Each poll picks the next account in the rotation. Progress is tracked per
+watch in ~/.config/birdy/watch/ using the highest tweet id seen, so a
+restarted watch resumes where it stopped.
+
+New tweets are written as JSONL to stdout by default. Use --sink to deliver
+them elsewhere (repeatable):
+  stdout               JSONL on stdout
+  file:<path>          append JSONL to a file
+  exec:<cmd> [args]    run a command with the batch as JSONL on stdin
+  webhook:<url>        POST {"watch","count","tweets"} as JSON
+
+Delivery is at-least-once and tracked per sink: a sink that fails keeps its
+batch and is sent it again on the next poll, together with newer tweets,
+while the other sinks carry on. A sink may see a batch twice if birdy stops
+between delivering and saving progress.
+
+The first poll of a new watch only records a baseline; pass --emit-initial
+to emit the tweets it finds.
+
+Examples:
+  birdy watch search "golang" --interval 2m
+  birdy watch mentions --sink webhook:https://hooks.example.com/birdy
+  birdy watch user-tweets @steipete --sink file:steipete.jsonl`,
+	GroupID: "birdy",
+}
+
+func makeWatchSubcommand(use, short string, nargs int) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.ExactArgs(nargs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			birdArgs := append([]string{cmd.Name()}, args...)
+			return runWatch(cmd, birdArgs)
+		},
+	}
+}
+
+func runWatch(cmd *cobra.Command, birdArgs []string) error {
+	if watchIntervalFlag < 5*time.Second {
+		return fmt.Errorf("--interval must be at least 5s")
+	}
+	if watchCountFlag <= 0 {
+		return fmt.Errorf("--count must be positive")
+	}
+
+	specs := watchSinkFlags
+	if len(specs) == 0 {
+		specs = []string{"stdout"}
+	}
+	targets := make([]watch.Target, 0, len(specs))
+	for _, spec := range specs {
+		sink, err := watch.ParseSink(spec, cmd.OutOrStdout())
+		if err != nil {
+			return err
+		}
+		targets = append(targets, watch.Target{Spec: strings.TrimSpace(spec), Sink: sink})
+	}
+
+	key := watch.Key(watchNameFlag, birdArgs)
+	st, err := watch.Load(key)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pollArgs := append(append([]string(nil), birdArgs...), "-n", strconv.Itoa(watchCountFlag), "--json")
+	for {
+		if err := pollWatch(ctx, key, st, pollArgs, targets); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if watchOnceFlag {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "[birdy] watch %s: %v\n", key, err)
+		}
+		if watchOnceFlag {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(watchIntervalFlag):
+		}
+	}
+}
+
+func pollWatch(ctx context.Context, key string, st *watch.State, args []string, targets []watch.Target) error {
+	res, err := birdcmd.Run(ctx, birdcmd.Request{
+		Args:     args,
+		Account:  accountFlag,
+		Strategy: strategyFlag,
+	})
+	if err != nil {
+		return err
+	}
+	if verboseFlag {
+		fmt.Fprintf(os.Stderr, "[birdy] watch %s: polled with account %s\n", key, res.Account)
+	}
+	if res.ExitCode != 0 {
+		return fmt.Errorf("bird exited with code %d (account %s): %s", res.ExitCode, res.Account, firstLine(res.Stderr))
+	}
+
+	tweets, _, err := bird.ParseTweets([]byte(res.Stdout))
+	if err != nil {
+		return err
+	}
+
+	initial := st.Fresh()
+	fresh := st.Filter(tweets, time.Now())
+	if initial && !watchEmitInitialFlag {
+		fresh = nil
+	}
+
+	// A sink that fails keeps its batch in the state and gets it again on
+	// the next poll; the sinks that succeeded are not sent it twice.
+	deliverErr := st.Deliver(ctx, key, targets, fresh)
+	if err := st.Save(); err != nil {
+		return err
+	}
+	return deliverErr
+}
+
+// fetchBird runs bird once on the next account in the rotation and treats
+// a non-zero exit as an error. It is the fetcher for paged commands.
+func fetchBird(ctx context.Context, args []string) (stdout, account string, err error) {
+	res, err := birdcmd.Run(ctx, birdcmd.Request{
+		Args:     args,
+		Account:  accountFlag,
+		Strategy: strategyFlag,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if res.ExitCode != 0 {
+		return "", res.Account, fmt.Errorf("bird exited with code %d (account %s): %s", res.ExitCode, res.Account, firstLine(res.Stderr))
+	}
+	return res.Stdout, res.Account, nil
+}
+
+func firstLine(s string) string {
+	for i, r := range s {
+		if r == '\n' {
+			return s[:i]
+		}
+	}
+	return s
+}
+
+func init() {
+	watchCmd.PersistentFlags().DurationVar(&watchIntervalFlag, "interval", time.Minute, "time between polls")
+	watchCmd.PersistentFlags().IntVarP(&watchCountFlag, "count", "n", 20, "tweets to fetch per poll")
+	watchCmd.PersistentFlags().StringArrayVar(&watchSinkFlags, "sink", nil, "where to deliver new tweets (stdout, file:, exec:, webhook:)")
+	watchCmd.PersistentFlags().StringVar(&watchNameFlag, "name", "", "state name for this watch (default derived from the query)")
+	watchCmd.PersistentFlags().BoolVar(&watchOnceFlag, "once", false, "poll once and exit")
+	watchCmd.PersistentFlags().BoolVar(&watchEmitInitialFlag, "emit-initial", false, "emit tweets found by the first poll of a new watch")
+
+	watchCmd.AddCommand(
+		makeWatchSubcommand("search <query>", "Watch search results", 1),
+		makeWatchSubcommand("mentions", "Watch your mentions", 0),
+		makeWatchSubcommand("list-timeline <list-id>", "Watch a list timeline", 1),
+		makeWatchSubcommand("user-tweets <handle>", "Watch tweets from a user", 1),
+	)
+	rootCmd.AddCommand(watchCmd)
+}
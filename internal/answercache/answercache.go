@@ -0,0 +1,203 @@
+// Package answercache keeps agent answers in ~/.config/birdy/answer_cache.json
+// so repeated questions, such as the TUI's startup home summary or a recipe,
+// are answered without a new agent run. Answers are keyed by prompt, account
+// pool, model, agent profile and read-only mode, and each expires after the
+// TTL it was stored with.
+package answercache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/filelock"
+	"github.com/guzus/birdy/internal/store"
+)
+
+// TTLEnv overrides DefaultTTL, as a Go duration such as 30m; 0 turns the
+// cache off.
+const TTLEnv = "BIRDY_ANSWER_CACHE_TTL"
+
+// DefaultTTL is how long answers are kept when TTLEnv is unset.
+const DefaultTTL = time.Hour
+
+// TTL returns the configured default TTL.
+func TTL() time.Duration {
+	if v := strings.TrimSpace(os.Getenv(TTLEnv)); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return DefaultTTL
+}
+
+// Key identifies a cached answer.
+type Key struct {
+	Prompt   string // the prompt as sent, parameters filled in
+	Accounts string // the account pool, from Pool
+	Model    string
+	Profile  string // agent profile name; empty for none
+	ReadOnly bool   // the run had no write commands
+}
+
+func (k Key) id() string {
+	sum := sha256.Sum256([]byte(k.Prompt + "\x00" + k.Accounts + "\x00" + k.Model + "\x00" + k.Profile + "\x00" + strconv.FormatBool(k.ReadOnly)))
+	return hex.EncodeToString(sum[:12])
+}
+
+// Pool returns the Key.Accounts of runs that rotate through only, or
+// through every configured account when only is empty.
+func Pool(only []string) string {
+	names := slices.Clone(only)
+	if len(names) == 0 {
+		if st, err := store.Open(); err == nil {
+			for _, a := range st.List() {
+				names = append(names, a.Name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return strings.Join(slices.Compact(names), ",")
+}
+
+// Entry is a cached answer.
+type Entry struct {
+	Prompt    string    `json:"prompt"`
+	Accounts  string    `json:"accounts,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	Profile   string    `json:"profile,omitempty"`
+	ReadOnly  bool      `json:"read_only,omitempty"`
+	Answer    string    `json:"answer"`
+	CachedAt  time.Time `json:"cached_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Age returns how old the answer is at now.
+func (e *Entry) Age(now time.Time) time.Duration {
+	return max(now.Sub(e.CachedAt), 0)
+}
+
+// TTL returns how long the answer was stored for.
+func (e *Entry) TTL() time.Duration {
+	return e.ExpiresAt.Sub(e.CachedAt)
+}
+
+// RefreshAt returns when the answer should be refreshed in the background,
+// three quarters into its TTL, so it is replaced before it expires.
+func (e *Entry) RefreshAt() time.Time {
+	return e.CachedAt.Add(e.TTL() * 3 / 4)
+}
+
+// Cache is the answer cache file.
+type Cache struct {
+	path string
+}
+
+// DefaultPath returns ~/.config/birdy/answer_cache.json.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "answer_cache.json"), nil
+}
+
+// Open returns the cache at the default path.
+func Open() (*Cache, error) {
+	p, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenPath(p), nil
+}
+
+// OpenPath returns the cache stored at path.
+func OpenPath(path string) *Cache {
+	return &Cache{path: path}
+}
+
+// Get returns the answer for key if it has not expired at now.
+func (c *Cache) Get(key Key, now time.Time) (*Entry, bool, error) {
+	entries, err := c.load()
+	if err != nil {
+		return nil, false, err
+	}
+	e, ok := entries[key.id()]
+	if !ok || !now.Before(e.ExpiresAt) || strings.TrimSpace(e.Answer) == "" {
+		return nil, false, nil
+	}
+	return e, true, nil
+}
+
+// Put stores answer for key for ttl and drops expired answers. A
+// non-positive ttl or an empty answer stores nothing.
+func (c *Cache) Put(key Key, answer string, ttl time.Duration, now time.Time) (*Entry, error) {
+	answer = strings.TrimSpace(answer)
+	if ttl <= 0 || answer == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return nil, fmt.Errorf("creating cache dir: %w", err)
+	}
+	unlock, err := filelock.Acquire(c.path + ".lock")
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := c.load()
+	if err != nil {
+		return nil, err
+	}
+	for id, e := range entries {
+		if !now.Before(e.ExpiresAt) {
+			delete(entries, id)
+		}
+	}
+	e := &Entry{
+		Prompt:    key.Prompt,
+		Accounts:  key.Accounts,
+		Model:     key.Model,
+		Profile:   key.Profile,
+		ReadOnly:  key.ReadOnly,
+		Answer:    answer,
+		CachedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	entries[key.id()] = e
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling cache: %w", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return nil, fmt.Errorf("writing cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return nil, fmt.Errorf("writing cache: %w", err)
+	}
+	return e, nil
+}
+
+func (c *Cache) load() (map[string]*Entry, error) {
+	entries := map[string]*Entry{}
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing cache: %w", err)
+	}
+	return entries, nil
+}
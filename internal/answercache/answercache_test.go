@@ -0,0 +1,85 @@
+package answercache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPutAndGet(t *testing.T) {
+	c := OpenPath(filepath.Join(t.TempDir(), "answer_cache.json"))
+	now := time.Date(2026, 2, 12, 12, 0, 0, 0, time.UTC)
+	key := Key{Prompt: "summarize my home timeline", Accounts: "alice,bob", Model: "sonnet"}
+
+	if _, ok, err := c.Get(key, now); ok || err != nil {
+		t.Fatalf("empty cache = %v, %v", ok, err)
+	}
+	if _, err := c.Put(key, "  quiet day  ", time.Hour, now); err != nil {
+		t.Fatal(err)
+	}
+	e, ok, err := c.Get(key, now.Add(30*time.Minute))
+	if err != nil || !ok || e.Answer != "quiet day" || e.TTL() != time.Hour {
+		t.Fatalf("Get = %+v, %v, %v", e, ok, err)
+	}
+	if e.Age(now.Add(30*time.Minute)) != 30*time.Minute || !e.RefreshAt().Equal(now.Add(45*time.Minute)) {
+		t.Fatalf("age/refresh of %+v", e)
+	}
+
+	for _, other := range []Key{
+		{Prompt: key.Prompt, Accounts: "alice", Model: key.Model},
+		{Prompt: key.Prompt, Accounts: key.Accounts, Model: "opus"},
+		{Prompt: "summarize my mentions", Accounts: key.Accounts, Model: key.Model},
+		{Prompt: key.Prompt, Accounts: key.Accounts, Model: key.Model, Profile: "drafter"},
+		{Prompt: key.Prompt, Accounts: key.Accounts, Model: key.Model, ReadOnly: true},
+	} {
+		if _, ok, _ := c.Get(other, now); ok {
+			t.Errorf("hit for %+v", other)
+		}
+	}
+	if _, ok, _ := c.Get(key, now.Add(time.Hour)); ok {
+		t.Fatal("expected a miss after the TTL")
+	}
+
+	// Expired answers are dropped on the next write.
+	other := Key{Prompt: "other"}
+	if _, err := c.Put(other, "x", time.Minute, now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := c.load()
+	if len(entries) != 1 {
+		t.Fatalf("entries = %+v", entries)
+	}
+	if e, err := c.Put(other, "y", 0, now); e != nil || err != nil {
+		t.Fatalf("zero TTL stored %+v, %v", e, err)
+	}
+}
+
+func TestTTLFromEnv(t *testing.T) {
+	t.Setenv(TTLEnv, "")
+	if TTL() != DefaultTTL {
+		t.Fatalf("default TTL = %v", TTL())
+	}
+	t.Setenv(TTLEnv, "15m")
+	if TTL() != 15*time.Minute {
+		t.Fatalf("TTL = %v", TTL())
+	}
+	t.Setenv(TTLEnv, "0")
+	if TTL() != 0 {
+		t.Fatalf("disabled TTL = %v", TTL())
+	}
+	t.Setenv(TTLEnv, "soon")
+	if TTL() != DefaultTTL {
+		t.Fatalf("invalid TTL = %v", TTL())
+	}
+}
+
+func TestPool(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("BIRDY_ACCOUNTS", `[{"name":"zed","auth_token":"a","ct0":"b"},{"name":"amy","auth_token":"a","ct0":"b"}]`)
+	if got := Pool(nil); got != "amy,zed" {
+		t.Fatalf("Pool(nil) = %q", got)
+	}
+	if got := Pool([]string{"zed", "brand", "zed"}); got != "brand,zed" {
+		t.Fatalf("Pool(only) = %q", got)
+	}
+}
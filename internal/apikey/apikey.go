@@ -0,0 +1,326 @@
+// Package apikey manages scoped API keys for the hosted HTTP API. Keys are
+// stored hashed in ~/.config/birdy/apikeys.json; the plaintext token is only
+// shown once, when the key is created.
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guzus/birdy/internal/filelock"
+)
+
+// Scope grants access to a class of API operations.
+type Scope string
+
+const (
+	ScopeRead  Scope = "read"  // read-only bird commands and /api/v1
+	ScopeWrite Scope = "write" // bird commands that post, follow or bookmark
+	ScopeChat  Scope = "chat"  // the /api/chat agent
+	ScopeAdmin Scope = "admin" // everything
+)
+
+// Scopes lists every valid scope.
+var Scopes = []Scope{ScopeRead, ScopeWrite, ScopeChat, ScopeAdmin}
+
+// TokenPrefix starts every generated token so keys are easy to spot.
+const TokenPrefix = "birdy_"
+
+var (
+	ErrInvalid = errors.New("invalid api key")
+	ErrExpired = errors.New("api key expired")
+)
+
+// Key is a stored API key. Only the SHA-256 of the token is kept.
+type Key struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Hash       string    `json:"hash"`
+	Hint       string    `json:"hint"` // first characters of the token, for display
+	Scopes     []Scope   `json:"scopes"`
+	Accounts   []string  `json:"accounts,omitempty"`    // allowed account pool; empty means all
+	RateLimit  int       `json:"rate_limit,omitempty"`  // requests per minute; 0 uses the host default
+	DailyQuota int       `json:"daily_quota,omitempty"` // requests per UTC day; 0 uses the host default
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+	LastUsed   time.Time `json:"last_used,omitempty"`
+}
+
+// Has reports whether the key grants scope. admin grants every scope.
+func (k *Key) Has(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope) || slices.Contains(k.Scopes, ScopeAdmin)
+}
+
+// AllowsAccount reports whether the key may use the named account.
+func (k *Key) AllowsAccount(name string) bool {
+	return len(k.Accounts) == 0 || slices.Contains(k.Accounts, name)
+}
+
+// Expired reports whether the key has expired at now.
+func (k *Key) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// ParseScopes parses a comma-separated scope list such as "read,chat".
+func ParseScopes(s string) ([]Scope, error) {
+	var out []Scope
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		scope := Scope(part)
+		if !slices.Contains(Scopes, scope) {
+			return nil, fmt.Errorf("unknown scope %q (valid: read, write, chat, admin)", part)
+		}
+		if !slices.Contains(out, scope) {
+			out = append(out, scope)
+		}
+	}
+	return out, nil
+}
+
+// Store manages API keys persisted to disk.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	Keys []Key `json:"keys"`
+}
+
+// DefaultPath returns ~/.config/birdy/apikeys.json.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "apikeys.json"), nil
+}
+
+// Open loads the key store at the default location.
+func Open() (*Store, error) {
+	p, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenPath(p)
+}
+
+// OpenPath loads the key store at path. A missing file is an empty store.
+func OpenPath(path string) (*Store, error) {
+	keys, err := readKeys(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{path: path, Keys: keys}, nil
+}
+
+func readKeys(path string) ([]Key, error) {
+	keys := []Key{}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return keys, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading api keys: %w", err)
+	}
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("parsing api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Touch records that key id was used at now. The file is reread under its
+// lock and only the key's last_used changes, so keys created or revoked by
+// other processes since Open are kept as they are.
+func (s *Store) Touch(id string, now time.Time) error {
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	keys, err := readKeys(s.path)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(keys, func(k Key) bool { return k.ID == id })
+	if i < 0 {
+		return nil
+	}
+	keys[i].LastUsed = now.UTC()
+	return writeKeys(s.path, keys)
+}
+
+func (s *Store) lock() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return nil, fmt.Errorf("creating config dir: %w", err)
+	}
+	return filelock.Acquire(s.path + ".lock")
+}
+
+// writeKeys replaces the file at path with keys; the caller holds its lock.
+func writeKeys(path string, keys []Key) error {
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling api keys: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing api keys: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing api keys: %w", err)
+	}
+	return nil
+}
+
+// Len returns the number of stored keys.
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.Keys)
+}
+
+// List returns a copy of all keys.
+func (s *Store) List() []Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.Keys)
+}
+
+// CreateOptions describes a new key.
+type CreateOptions struct {
+	Name       string
+	Scopes     []Scope
+	Accounts   []string
+	RateLimit  int
+	DailyQuota int
+	ExpiresAt  time.Time
+}
+
+// Create generates a key, writes it to the store file and returns its
+// plaintext token, which is not stored and cannot be recovered later. The
+// file is reread under its lock, so keys created or revoked by other
+// processes since Open are kept.
+func (s *Store) Create(opts CreateOptions, now time.Time) (string, *Key, error) {
+	name := strings.TrimSpace(opts.Name)
+	if name == "" {
+		return "", nil, fmt.Errorf("key name is required")
+	}
+	if len(opts.Scopes) == 0 {
+		return "", nil, fmt.Errorf("at least one scope is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return "", nil, err
+	}
+	defer unlock()
+	keys, err := readKeys(s.path)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, k := range keys {
+		if k.Name == name {
+			return "", nil, fmt.Errorf("api key %q already exists", name)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("generating api key: %w", err)
+	}
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, fmt.Errorf("generating api key: %w", err)
+	}
+	token := TokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	k := Key{
+		ID:         hex.EncodeToString(id),
+		Name:       name,
+		Hash:       hashToken(token),
+		Hint:       token[:len(TokenPrefix)+4],
+		Scopes:     slices.Clone(opts.Scopes),
+		Accounts:   slices.Clone(opts.Accounts),
+		RateLimit:  opts.RateLimit,
+		DailyQuota: opts.DailyQuota,
+		CreatedAt:  now.UTC(),
+		ExpiresAt:  opts.ExpiresAt.UTC(),
+	}
+	keys = append(keys, k)
+	if err := writeKeys(s.path, keys); err != nil {
+		return "", nil, err
+	}
+	s.Keys = keys
+	return token, &k, nil
+}
+
+// Revoke deletes the key with the given name or id from the store file,
+// rereading it under its lock like Create.
+func (s *Store) Revoke(nameOrID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	keys, err := readKeys(s.path)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(keys, func(k Key) bool { return k.Name == nameOrID || k.ID == nameOrID })
+	if i < 0 {
+		return fmt.Errorf("api key %q not found", nameOrID)
+	}
+	keys = slices.Delete(keys, i, i+1)
+	if err := writeKeys(s.path, keys); err != nil {
+		return err
+	}
+	s.Keys = keys
+	return nil
+}
+
+// Authenticate finds the key matching token. The caller records its use
+// with Touch.
+func (s *Store) Authenticate(token string, now time.Time) (*Key, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return nil, ErrInvalid
+	}
+	want := hashToken(token)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.Keys {
+		k := &s.Keys[i]
+		if subtle.ConstantTimeCompare([]byte(k.Hash), []byte(want)) != 1 {
+			continue
+		}
+		if k.Expired(now) {
+			return nil, ErrExpired
+		}
+		found := *k
+		return &found, nil
+	}
+	return nil, ErrInvalid
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
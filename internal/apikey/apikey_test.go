@@ -0,0 +1,148 @@
+package apikey
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAuthenticateRevoke(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	st, err := OpenPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	token, key, err := st.Create(CreateOptions{
+		Name:      "dashboard",
+		Scopes:    []Scope{ScopeRead},
+		Accounts:  []string{"alice"},
+		ExpiresAt: now.Add(24 * time.Hour),
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, TokenPrefix) || !strings.HasPrefix(token, key.Hint) {
+		t.Fatalf("unexpected token %q / hint %q", token, key.Hint)
+	}
+	if _, _, err := st.Create(CreateOptions{Name: "dashboard", Scopes: []Scope{ScopeRead}}, now); err == nil {
+		t.Fatal("expected duplicate name to fail")
+	}
+
+	// Only the hash is written to disk.
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), token) {
+		t.Fatal("plaintext token was persisted")
+	}
+
+	st, _ = OpenPath(path)
+	got, err := st.Authenticate(token, now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "dashboard" {
+		t.Fatalf("unexpected key %+v", got)
+	}
+	if !got.Has(ScopeRead) || got.Has(ScopeWrite) || !got.AllowsAccount("alice") || got.AllowsAccount("bob") {
+		t.Fatalf("unexpected permissions %+v", got)
+	}
+	if _, err := st.Authenticate(token+"x", now); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+	if _, err := st.Authenticate(token, now.Add(48*time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Fatalf("expected ErrExpired, got %v", err)
+	}
+
+	if err := st.Revoke(key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Authenticate(token, now); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected revoked key to be invalid, got %v", err)
+	}
+}
+
+func TestTouchKeepsConcurrentChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	st, _ := OpenPath(path)
+	_, a, _ := st.Create(CreateOptions{Name: "a", Scopes: []Scope{ScopeRead}}, now)
+	_, b, _ := st.Create(CreateOptions{Name: "b", Scopes: []Scope{ScopeRead}}, now)
+
+	// A server loaded the store, then another process revoked b and
+	// created c before the server recorded the uses.
+	server, _ := OpenPath(path)
+	admin, _ := OpenPath(path)
+	if err := admin.Revoke(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := admin.Create(CreateOptions{Name: "c", Scopes: []Scope{ScopeRead}}, now); err != nil {
+		t.Fatal(err)
+	}
+	used := now.Add(time.Hour)
+	if err := server.Touch(a.ID, used); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Touch(b.ID, used); err != nil {
+		t.Fatal(err)
+	}
+
+	st, _ = OpenPath(path)
+	var names []string
+	for _, k := range st.List() {
+		names = append(names, k.Name)
+		if k.Name == "a" && !k.LastUsed.Equal(used) {
+			t.Fatalf("last_used not recorded: %+v", k)
+		}
+	}
+	if strings.Join(names, ",") != "a,c" {
+		t.Fatalf("got keys %v, want a,c", names)
+	}
+}
+
+func TestCreateRevokeKeepConcurrentChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apikeys.json")
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	st, _ := OpenPath(path)
+	if _, _, err := st.Create(CreateOptions{Name: "a", Scopes: []Scope{ScopeRead}}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two CLI runs opened the same snapshot; neither may undo the other.
+	first, _ := OpenPath(path)
+	second, _ := OpenPath(path)
+	if _, _, err := first.Create(CreateOptions{Name: "b", Scopes: []Scope{ScopeRead}}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Revoke("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := first.Create(CreateOptions{Name: "c", Scopes: []Scope{ScopeRead}}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	st, _ = OpenPath(path)
+	var names []string
+	for _, k := range st.List() {
+		names = append(names, k.Name)
+	}
+	if strings.Join(names, ",") != "b,c" {
+		t.Fatalf("got keys %v, want b,c", names)
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("read, Chat,read")
+	if err != nil || len(scopes) != 2 || scopes[0] != ScopeRead || scopes[1] != ScopeChat {
+		t.Fatalf("got %v %v", scopes, err)
+	}
+	if _, err := ParseScopes("read,root"); err == nil {
+		t.Fatal("expected unknown scope to fail")
+	}
+	admin := Key{Scopes: []Scope{ScopeAdmin}}
+	if !admin.Has(ScopeWrite) || !admin.Has(ScopeChat) {
+		t.Fatal("admin should grant every scope")
+	}
+}
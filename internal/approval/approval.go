@@ -0,0 +1,225 @@
+// Package approval lets the user approve, edit or deny the write commands an
+// agent issues. The TUI listens on a unix socket and hands its path to the
+// agent run (SocketEnv); birdy asks over it before running a write command
+// for that run and waits for the user's decision.
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SocketEnv holds the path of the approval socket of the agent run.
+const SocketEnv = "BIRDY_APPROVAL_SOCKET"
+
+// Decision actions.
+const (
+	Approve = "approve"
+	Edit    = "edit"
+	Deny    = "deny"
+)
+
+// Request is a write command an agent run wants to execute.
+type Request struct {
+	RunID string   `json:"run_id"`
+	Args  []string `json:"args"`
+}
+
+// Decision is the user's answer to a request. Args is the command to run
+// instead when the action is Edit.
+type Decision struct {
+	Action string   `json:"action"`
+	Args   []string `json:"args,omitempty"`
+}
+
+// Pending is a request waiting for the user.
+type Pending struct {
+	Request
+	reply chan Decision
+	once  sync.Once
+}
+
+// Decide answers the request; later calls are ignored.
+func (p *Pending) Decide(d Decision) {
+	p.once.Do(func() { p.reply <- d })
+}
+
+// Server receives requests on a unix socket. Requests still open when the
+// server closes are denied.
+type Server struct {
+	ln        net.Listener
+	dir       string
+	requests  chan *Pending
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Listen starts a server on a socket in a new temporary directory.
+func Listen() (*Server, error) {
+	dir, err := os.MkdirTemp("", "birdy-approval-")
+	if err != nil {
+		return nil, fmt.Errorf("creating approval socket dir: %w", err)
+	}
+	ln, err := net.Listen("unix", filepath.Join(dir, "sock"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("listening for approvals: %w", err)
+	}
+	s := &Server{ln: ln, dir: dir, requests: make(chan *Pending), done: make(chan struct{})}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Path returns the socket path to pass in SocketEnv.
+func (s *Server) Path() string { return s.ln.Addr().String() }
+
+// Requests delivers incoming requests. Each must be answered with Decide.
+func (s *Server) Requests() <-chan *Pending { return s.requests }
+
+// Close stops the server, denies open requests and removes the socket.
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.ln.Close()
+		s.wg.Wait()
+		os.RemoveAll(s.dir)
+	})
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	var req Request
+	if err := json.NewDecoder(conn).Decode(&req); err != nil {
+		return
+	}
+	p := &Pending{Request: req, reply: make(chan Decision, 1)}
+	d := Decision{Action: Deny}
+	select {
+	case s.requests <- p:
+		select {
+		case d = <-p.reply:
+		case <-s.done:
+		}
+	case <-s.done:
+	}
+	_ = json.NewEncoder(conn).Encode(d)
+}
+
+// Ask sends req to the server at path and waits for the decision.
+func Ask(ctx context.Context, path string, req Request) (Decision, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", path)
+	if err != nil {
+		return Decision{}, fmt.Errorf("connecting to approval socket: %w", err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return Decision{}, fmt.Errorf("sending approval request: %w", err)
+	}
+	var d Decision
+	if err := json.NewDecoder(conn).Decode(&d); err != nil {
+		return Decision{}, fmt.Errorf("reading approval decision: %w", err)
+	}
+	switch d.Action {
+	case Approve, Deny:
+	case Edit:
+		if len(d.Args) == 0 {
+			return Decision{}, fmt.Errorf("edited command is empty")
+		}
+	default:
+		return Decision{}, fmt.Errorf("unknown approval action %q", d.Action)
+	}
+	return d, nil
+}
+
+// Quote renders args as a shell command line that Split reads back.
+func Quote(args []string) string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && !strings.ContainsAny(a, " \t\n'\"\\$`|&;<>()*?[]#~{}!") {
+			out[i] = a
+			continue
+		}
+		out[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+	return strings.Join(out, " ")
+}
+
+// Split parses a shell-style command line into words, honoring single
+// quotes, double quotes and backslash escapes.
+func Split(line string) ([]string, error) {
+	var (
+		words   []string
+		cur     strings.Builder
+		inWord  bool
+		quote   rune
+		escaped bool
+	)
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
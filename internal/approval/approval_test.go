@@ -0,0 +1,100 @@
+package approval
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T) *Server {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets")
+	}
+	s, err := Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestAskDecisions(t *testing.T) {
+	s := listen(t)
+	go func() {
+		for p := range s.Requests() {
+			switch p.Args[0] {
+			case "follow":
+				p.Decide(Decision{Action: Approve})
+			case "tweet":
+				p.Decide(Decision{Action: Edit, Args: []string{"tweet", "edited"}})
+			default:
+				p.Decide(Decision{Action: Deny})
+			}
+		}
+	}()
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		args []string
+		want Decision
+	}{
+		{[]string{"follow", "@a"}, Decision{Action: Approve}},
+		{[]string{"tweet", "hi"}, Decision{Action: Edit, Args: []string{"tweet", "edited"}}},
+		{[]string{"unfollow", "@a"}, Decision{Action: Deny}},
+	} {
+		got, err := Ask(ctx, s.Path(), Request{RunID: "run1", Args: tc.args})
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%v: got %+v, %v", tc.args, got, err)
+		}
+	}
+}
+
+func TestCloseDeniesOpenRequests(t *testing.T) {
+	s := listen(t)
+	got := make(chan *Pending, 1)
+	go func() { got <- <-s.Requests() }()
+
+	res := make(chan Decision, 1)
+	go func() {
+		d, _ := Ask(context.Background(), s.Path(), Request{RunID: "run1", Args: []string{"tweet", "hi"}})
+		res <- d
+	}()
+	p := <-got
+	if p.RunID != "run1" {
+		t.Fatalf("request = %+v", p.Request)
+	}
+	s.Close()
+	select {
+	case d := <-res:
+		if d.Action != Deny {
+			t.Fatalf("decision = %+v", d)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not answered on close")
+	}
+}
+
+func TestQuoteSplitRoundTrip(t *testing.T) {
+	for _, args := range [][]string{
+		{"tweet", "hello world"},
+		{"reply", "123", `it's "quoted" \ and $HOME`},
+		{"follow", "@someone"},
+		{"tweet", ""},
+	} {
+		got, err := Split(Quote(args))
+		if err != nil || !reflect.DeepEqual(got, args) {
+			t.Errorf("%q -> %q -> %q (%v)", args, Quote(args), got, err)
+		}
+	}
+
+	got, err := Split(`tweet "double \"quoted\"" plain\ word`)
+	if err != nil || !reflect.DeepEqual(got, []string{"tweet", `double "quoted"`, "plain word"}) {
+		t.Errorf("Split = %q, %v", got, err)
+	}
+	if _, err := Split(`tweet 'open`); err == nil {
+		t.Error("expected an error for an unterminated quote")
+	}
+}
@@ -0,0 +1,289 @@
+package archive
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS users (
+	username        TEXT PRIMARY KEY COLLATE NOCASE,
+	id              TEXT NOT NULL DEFAULT '',
+	name            TEXT NOT NULL DEFAULT '',
+	description     TEXT NOT NULL DEFAULT '',
+	followers_count INTEGER NOT NULL DEFAULT 0,
+	following_count INTEGER NOT NULL DEFAULT 0,
+	updated_at      TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tweets (
+	id              TEXT PRIMARY KEY,
+	author          TEXT NOT NULL DEFAULT '' COLLATE NOCASE,
+	author_id       TEXT NOT NULL DEFAULT '',
+	text            TEXT NOT NULL DEFAULT '',
+	created_at      TEXT NOT NULL DEFAULT '',
+	created_unix    INTEGER NOT NULL DEFAULT 0,
+	reply_count     INTEGER NOT NULL DEFAULT 0,
+	retweet_count   INTEGER NOT NULL DEFAULT 0,
+	like_count      INTEGER NOT NULL DEFAULT 0,
+	conversation_id TEXT NOT NULL DEFAULT '',
+	in_reply_to     TEXT NOT NULL DEFAULT '',
+	quoted_id       TEXT NOT NULL DEFAULT '',
+	raw             TEXT NOT NULL DEFAULT '',
+	first_seen      TEXT NOT NULL,
+	last_seen       TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS tweets_author ON tweets(author);
+CREATE INDEX IF NOT EXISTS tweets_created ON tweets(created_unix);
+
+CREATE TABLE IF NOT EXISTS tweet_sources (
+	tweet_id TEXT NOT NULL,
+	source   TEXT NOT NULL,
+	PRIMARY KEY (tweet_id, source)
+);
+CREATE INDEX IF NOT EXISTS tweet_sources_source ON tweet_sources(source);
+
+CREATE TABLE IF NOT EXISTS cursors (
+	source     TEXT PRIMARY KEY,
+	cursor     TEXT NOT NULL DEFAULT '',
+	complete   INTEGER NOT NULL DEFAULT 0,
+	pages      INTEGER NOT NULL DEFAULT 0,
+	updated_at TEXT NOT NULL
+);
+`
+
+// createdAtLayouts are the timestamp formats bird is known to emit.
+var createdAtLayouts = []string{
+	time.RubyDate,
+	time.RFC3339,
+	time.RFC3339Nano,
+}
+
+// DB is the local tweet archive.
+type DB struct {
+	db   *sql.DB
+	path string
+}
+
+// DefaultPath returns ~/.config/birdy/archive.db.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "archive.db"), nil
+}
+
+// Open opens (or creates) the archive at the default location.
+func Open() (*DB, error) {
+	p, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenPath(p)
+}
+
+// OpenPath opens (or creates) the archive at a custom path.
+func OpenPath(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("creating config dir: %w", err)
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, fmt.Errorf("opening archive: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing archive: %w", err)
+	}
+	if err := initSearch(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("initializing search index: %w", err)
+	}
+	_ = os.Chmod(path, 0600)
+	return &DB{db: db, path: path}, nil
+}
+
+// Close releases the database handle.
+func (d *DB) Close() error {
+	return d.db.Close()
+}
+
+// Path returns the database file path.
+func (d *DB) Path() string {
+	return d.path
+}
+
+// ParseCreatedAt converts a bird timestamp to a time, or zero if unknown.
+func ParseCreatedAt(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range createdAtLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// SaveTweets upserts tweets and their authors, tagging each with source.
+// It returns how many tweets had not been archived from source before.
+func (d *DB) SaveTweets(source string, tweets []bird.Tweet, now time.Time) (added int, err error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	ts := now.UTC().Format(time.RFC3339)
+	for _, t := range tweets {
+		n, err := saveTweet(tx, source, t, ts)
+		if err != nil {
+			return 0, err
+		}
+		added += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return added, nil
+}
+
+func saveTweet(tx *sql.Tx, source string, t bird.Tweet, ts string) (int, error) {
+	if t.ID == "" {
+		return 0, nil
+	}
+	if t.QuotedTweet != nil {
+		if _, err := saveTweet(tx, "", *t.QuotedTweet, ts); err != nil {
+			return 0, err
+		}
+	}
+
+	if t.Author.Username != "" {
+		if _, err := tx.Exec(`
+			INSERT INTO users (username, id, name, updated_at) VALUES (?, ?, ?, ?)
+			ON CONFLICT(username) DO UPDATE SET
+				id = CASE WHEN excluded.id != '' THEN excluded.id ELSE users.id END,
+				name = CASE WHEN excluded.name != '' THEN excluded.name ELSE users.name END,
+				updated_at = excluded.updated_at`,
+			t.Author.Username, t.AuthorID, t.Author.Name, ts); err != nil {
+			return 0, fmt.Errorf("saving user %s: %w", t.Author.Username, err)
+		}
+	}
+
+	raw, err := json.Marshal(t)
+	if err != nil {
+		return 0, err
+	}
+	var createdUnix int64
+	if created := ParseCreatedAt(t.CreatedAt); !created.IsZero() {
+		createdUnix = created.Unix()
+	}
+	quotedID := ""
+	if t.QuotedTweet != nil {
+		quotedID = t.QuotedTweet.ID
+	}
+
+	if _, err := tx.Exec(`
+		INSERT INTO tweets (id, author, author_id, text, created_at, created_unix, reply_count,
+			retweet_count, like_count, conversation_id, in_reply_to, quoted_id, raw, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			text = excluded.text,
+			reply_count = excluded.reply_count,
+			retweet_count = excluded.retweet_count,
+			like_count = excluded.like_count,
+			raw = excluded.raw,
+			last_seen = excluded.last_seen`,
+		t.ID, t.Author.Username, t.AuthorID, t.Text, t.CreatedAt, createdUnix, t.ReplyCount,
+		t.RetweetCount, t.LikeCount, t.ConversationID, t.InReplyToStatusID, quotedID, string(raw), ts, ts); err != nil {
+		return 0, fmt.Errorf("saving tweet %s: %w", t.ID, err)
+	}
+
+	if source == "" {
+		return 0, nil
+	}
+	res, err := tx.Exec(`INSERT OR IGNORE INTO tweet_sources (tweet_id, source) VALUES (?, ?)`, t.ID, source)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// CursorState is the saved pagination position for one source.
+type CursorState struct {
+	Source    string
+	Cursor    string
+	Complete  bool
+	Pages     int
+	UpdatedAt time.Time
+}
+
+// Cursor returns the saved pagination state for source.
+func (d *DB) Cursor(source string) (CursorState, error) {
+	cs := CursorState{Source: source}
+	var complete int
+	var updated string
+	err := d.db.QueryRow(`SELECT cursor, complete, pages, updated_at FROM cursors WHERE source = ?`, source).
+		Scan(&cs.Cursor, &complete, &cs.Pages, &updated)
+	if err == sql.ErrNoRows {
+		return cs, nil
+	}
+	if err != nil {
+		return cs, err
+	}
+	cs.Complete = complete != 0
+	cs.UpdatedAt, _ = time.Parse(time.RFC3339, updated)
+	return cs, nil
+}
+
+// SaveCursor persists the pagination state for a source.
+func (d *DB) SaveCursor(cs CursorState) error {
+	complete := 0
+	if cs.Complete {
+		complete = 1
+	}
+	_, err := d.db.Exec(`
+		INSERT INTO cursors (source, cursor, complete, pages, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(source) DO UPDATE SET
+			cursor = excluded.cursor,
+			complete = excluded.complete,
+			pages = excluded.pages,
+			updated_at = excluded.updated_at`,
+		cs.Source, cs.Cursor, complete, cs.Pages, cs.UpdatedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// Cursors lists all saved pagination states.
+func (d *DB) Cursors() ([]CursorState, error) {
+	rows, err := d.db.Query(`SELECT source, cursor, complete, pages, updated_at FROM cursors ORDER BY source`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CursorState
+	for rows.Next() {
+		var cs CursorState
+		var complete int
+		var updated string
+		if err := rows.Scan(&cs.Source, &cs.Cursor, &complete, &cs.Pages, &updated); err != nil {
+			return nil, err
+		}
+		cs.Complete = complete != 0
+		cs.UpdatedAt, _ = time.Parse(time.RFC3339, updated)
+		out = append(out, cs)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,260 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+func openTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := OpenPath(filepath.Join(t.TempDir(), "archive.db"))
+	if err != nil {
+		t.Fatalf("OpenPath: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func tweet(id, author, text, created string) bird.Tweet {
+	return bird.Tweet{ID: id, Text: text, Author: bird.Author{Username: author}, CreatedAt: created}
+}
+
+func TestSaveTweetsCountsNewPerSource(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+
+	added, err := db.SaveTweets("bookmarks", []bird.Tweet{tweet("1", "alice", "hi", ""), tweet("2", "bob", "yo", "")}, now)
+	if err != nil || added != 2 {
+		t.Fatalf("first save: added=%d err=%v", added, err)
+	}
+	added, err = db.SaveTweets("bookmarks", []bird.Tweet{tweet("2", "bob", "yo", ""), tweet("3", "bob", "new", "")}, now)
+	if err != nil || added != 1 {
+		t.Fatalf("second save: added=%d err=%v", added, err)
+	}
+	// The same tweet from another source is new to that source.
+	added, err = db.SaveTweets("likes", []bird.Tweet{tweet("1", "alice", "hi", "")}, now)
+	if err != nil || added != 1 {
+		t.Fatalf("likes save: added=%d err=%v", added, err)
+	}
+
+	stats, err := db.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Tweets != 3 || stats.Users != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestTweetsFilters(t *testing.T) {
+	db := openTestDB(t)
+	now := time.Now()
+	if _, err := db.SaveTweets("bookmarks", []bird.Tweet{
+		tweet("10", "alice", "learning golang today", "Mon Jan 05 10:00:00 +0000 2026"),
+		tweet("11", "bob", "coffee 100% please", "Tue Feb 03 10:00:00 +0000 2026"),
+	}, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SaveTweets("user-tweets:@alice", []bird.Tweet{
+		tweet("12", "Alice", "more golang", "2026-03-01T00:00:00Z"),
+	}, now); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := db.Tweets(Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].ID != "12" || all[2].ID != "10" {
+		t.Fatalf("expected newest first, got %#v", all)
+	}
+
+	cases := []struct {
+		name string
+		f    Filter
+		want []string
+	}{
+		{"author case-insensitive", Filter{Author: "@ALICE"}, []string{"12", "10"}},
+		{"source prefix", Filter{Source: "user-tweets"}, []string{"12"}},
+		{"contains", Filter{Contains: "golang"}, []string{"12", "10"}},
+		{"contains literal percent", Filter{Contains: "100%"}, []string{"11"}},
+		{"since", Filter{Since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"12", "11"}},
+		{"until", Filter{Until: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)}, []string{"10"}},
+		{"limit", Filter{Limit: 1}, []string{"12"}},
+	}
+	for _, tc := range cases {
+		got, err := db.Tweets(tc.f)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		var ids []string
+		for _, r := range got {
+			ids = append(ids, r.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: got %v, want %v", tc.name, ids, tc.want)
+		}
+	}
+}
+
+func TestQueryIsReadOnly(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.SaveTweets("likes", []bird.Tweet{tweet("1", "alice", "hi", "")}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	cols, rows, err := db.Query("SELECT author, COUNT(*) AS n FROM tweets GROUP BY author")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if strings.Join(cols, ",") != "author,n" || len(rows) != 1 || rows[0][1] != "1" {
+		t.Fatalf("unexpected result: %v %v", cols, rows)
+	}
+
+	if _, _, err := db.Query("DELETE FROM tweets"); err == nil {
+		t.Fatal("expected write to be rejected")
+	}
+	// The connection must be usable for writes again afterwards.
+	if _, err := db.SaveTweets("likes", []bird.Tweet{tweet("2", "bob", "yo", "")}, time.Now()); err != nil {
+		t.Fatalf("write after Query: %v", err)
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	records := []Record{{
+		ID:        "42",
+		Author:    "alice",
+		Text:      "hello, \"world\"\nsecond line",
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC),
+		LikeCount: 7,
+		Sources:   []string{"bookmarks", "likes"},
+	}}
+
+	var buf bytes.Buffer
+	if err := Export(&buf, "jsonl", records); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Record
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || decoded.ID != "42" {
+		t.Fatalf("jsonl round trip: %v %#v", err, decoded)
+	}
+
+	buf.Reset()
+	if err := Export(&buf, "csv", records); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"hello, ""world""`) || !strings.Contains(buf.String(), "bookmarks;likes") {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := Export(&buf, "md", records); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "> second line") || !strings.Contains(buf.String(), "https://x.com/alice/status/42") {
+		t.Fatalf("unexpected markdown:\n%s", buf.String())
+	}
+
+	if err := Export(&buf, "xml", records); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	src, err := ParseSource([]string{"user-tweets", "@Steipete"})
+	if err != nil || src.Key() != "user-tweets:@steipete" {
+		t.Fatalf("got %#v %v", src, err)
+	}
+	if src, err := ParseSource([]string{"user-tweets:jack"}); err != nil || src.Handle != "@jack" {
+		t.Fatalf("got %#v %v", src, err)
+	}
+	if _, err := ParseSource([]string{"home"}); err == nil {
+		t.Fatal("expected error for unsupported source")
+	}
+	if _, err := ParseSource([]string{"user-tweets"}); err == nil {
+		t.Fatal("expected error for missing handle")
+	}
+}
+
+// fakeFeed serves a newest-first feed of ids in pages, keyed by cursor.
+type fakeFeed struct {
+	ids   []int
+	page  int
+	calls [][]string
+}
+
+func (f *fakeFeed) fetch(_ context.Context, args []string) (string, string, error) {
+	f.calls = append(f.calls, args)
+	start := 0
+	for i, a := range args {
+		if a == "--cursor" {
+			fmt.Sscanf(args[i+1], "c%d", &start)
+		}
+	}
+	end := start + f.page
+	if end > len(f.ids) {
+		end = len(f.ids)
+	}
+	var tweets []bird.Tweet
+	for _, id := range f.ids[start:end] {
+		tweets = append(tweets, tweet(fmt.Sprint(id), "alice", "t", ""))
+	}
+	next := ""
+	if end < len(f.ids) {
+		next = fmt.Sprintf("c%d", end)
+	}
+	out, _ := json.Marshal(map[string]any{"tweets": tweets, "nextCursor": next})
+	return string(out), "acct", nil
+}
+
+func TestSyncResumesBackfillAndCatchesUp(t *testing.T) {
+	db := openTestDB(t)
+	src := Source{Command: "bookmarks"}
+	feed := &fakeFeed{ids: []int{10, 9, 8, 7, 6, 5}, page: 2}
+	ctx := context.Background()
+
+	res, err := db.Sync(ctx, src, feed.fetch, SyncOptions{MaxPages: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Added != 4 || res.Complete {
+		t.Fatalf("first sync: %+v", res)
+	}
+	if cs, _ := db.Cursor("bookmarks"); cs.Cursor != "c4" {
+		t.Fatalf("expected saved cursor c4, got %+v", cs)
+	}
+
+	// New tweets arrive at the top; the head pass stops at known tweets and
+	// the backfill resumes from the saved cursor.
+	feed.ids = append([]int{11}, feed.ids...)
+	feed.calls = nil
+	res, err = db.Sync(ctx, src, feed.fetch, SyncOptions{MaxPages: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Complete {
+		t.Fatalf("expected complete after second sync: %+v", res)
+	}
+	if strings.Join(feed.calls[0], " ") != "bookmarks -n 20 --json --all --max-pages 1" {
+		t.Fatalf("unexpected head args: %v", feed.calls[0])
+	}
+	if strings.Join(feed.calls[1], " ") != "bookmarks -n 20 --json --cursor c4 --max-pages 1" {
+		t.Fatalf("unexpected backfill args: %v", feed.calls[1])
+	}
+
+	all, err := db.Tweets(Filter{Source: "bookmarks"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 7 {
+		t.Fatalf("expected 7 archived tweets, got %d", len(all))
+	}
+}
@@ -0,0 +1,90 @@
+package archive
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormats lists the supported export formats.
+var ExportFormats = []string{"jsonl", "csv", "md"}
+
+// Export writes records to w in the given format.
+func Export(w io.Writer, format string, records []Record) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "jsonl", "":
+		return exportJSONL(w, records)
+	case "csv":
+		return exportCSV(w, records)
+	case "md", "markdown":
+		return exportMarkdown(w, records)
+	default:
+		return fmt.Errorf("unknown export format %q (valid: %s)", format, strings.Join(ExportFormats, ", "))
+	}
+}
+
+func exportJSONL(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"id", "author", "created_at", "text", "reply_count", "retweet_count", "like_count", "sources", "url"}); err != nil {
+		return err
+	}
+	for _, r := range records {
+		created := ""
+		if !r.CreatedAt.IsZero() {
+			created = r.CreatedAt.Format(time.RFC3339)
+		}
+		if err := cw.Write([]string{
+			r.ID,
+			r.Author,
+			created,
+			r.Text,
+			strconv.Itoa(r.ReplyCount),
+			strconv.Itoa(r.RetweetCount),
+			strconv.Itoa(r.LikeCount),
+			strings.Join(r.Sources, ";"),
+			r.URL(),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func exportMarkdown(w io.Writer, records []Record) error {
+	if _, err := fmt.Fprintf(w, "# birdy archive export\n\n%d tweets\n\n", len(records)); err != nil {
+		return err
+	}
+	for _, r := range records {
+		created := "unknown date"
+		if !r.CreatedAt.IsZero() {
+			created = r.CreatedAt.Format("2006-01-02 15:04")
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "## @%s — %s\n\n", r.Author, created)
+		for _, line := range strings.Split(strings.TrimSpace(r.Text), "\n") {
+			b.WriteString("> ")
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "\n%d replies · %d reposts · %d likes · [link](%s)\n\n", r.ReplyCount, r.RetweetCount, r.LikeCount, r.URL())
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Record is an archived tweet as stored locally.
+type Record struct {
+	ID             string    `json:"id"`
+	Author         string    `json:"author"`
+	Text           string    `json:"text"`
+	CreatedAt      time.Time `json:"created_at,omitempty"`
+	ReplyCount     int       `json:"reply_count"`
+	RetweetCount   int       `json:"retweet_count"`
+	LikeCount      int       `json:"like_count"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	InReplyTo      string    `json:"in_reply_to,omitempty"`
+	Sources        []string  `json:"sources,omitempty"`
+}
+
+// URL returns the x.com link for the tweet.
+func (r Record) URL() string {
+	handle := r.Author
+	if handle == "" {
+		handle = "i"
+	}
+	return "https://x.com/" + handle + "/status/" + r.ID
+}
+
+// Filter selects archived tweets.
+type Filter struct {
+	Author   string
+	Source   string
+	Contains string
+	Since    time.Time
+	Until    time.Time
+	Limit    int
+}
+
+// Tweets returns archived tweets matching f, newest first.
+func (d *DB) Tweets(f Filter) ([]Record, error) {
+	var (
+		where []string
+		args  []any
+	)
+	if a := strings.TrimPrefix(strings.TrimSpace(f.Author), "@"); a != "" {
+		where = append(where, "t.author = ?")
+		args = append(args, a)
+	}
+	if s := strings.TrimSpace(f.Source); s != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM tweet_sources s WHERE s.tweet_id = t.id AND (s.source = ? OR s.source LIKE ?))")
+		args = append(args, s, s+":%")
+	}
+	if c := strings.TrimSpace(f.Contains); c != "" {
+		where = append(where, "t.text LIKE ? ESCAPE '\\'")
+		args = append(args, "%"+escapeLike(c)+"%")
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "t.created_unix >= ?")
+		args = append(args, f.Since.Unix())
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "t.created_unix < ?")
+		args = append(args, f.Until.Unix())
+	}
+
+	q := `SELECT t.id, t.author, t.text, t.created_unix, t.reply_count, t.retweet_count, t.like_count,
+		t.conversation_id, t.in_reply_to,
+		COALESCE((SELECT GROUP_CONCAT(source, ',') FROM tweet_sources s WHERE s.tweet_id = t.id), '')
+		FROM tweets t`
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY t.created_unix DESC, LENGTH(t.id) DESC, t.id DESC"
+	if f.Limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := d.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Record
+	for rows.Next() {
+		var (
+			r       Record
+			created int64
+			sources string
+		)
+		if err := rows.Scan(&r.ID, &r.Author, &r.Text, &created, &r.ReplyCount, &r.RetweetCount,
+			&r.LikeCount, &r.ConversationID, &r.InReplyTo, &sources); err != nil {
+			return nil, err
+		}
+		if created > 0 {
+			r.CreatedAt = time.Unix(created, 0).UTC()
+		}
+		if sources != "" {
+			r.Sources = strings.Split(sources, ",")
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// Query runs a read-only SQL statement and returns column names and rows
+// rendered as strings.
+func (d *DB) Query(query string, args ...any) ([]string, [][]string, error) {
+	conn, err := d.db.Conn(context.Background())
+	if err != nil {
+		return nil, nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(context.Background(), "PRAGMA query_only = ON"); err != nil {
+		return nil, nil, err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA query_only = OFF")
+
+	rows, err := conn.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var out [][]string
+	for rows.Next() {
+		vals := make([]sql.NullString, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		row := make([]string, len(cols))
+		for i, v := range vals {
+			if v.Valid {
+				row[i] = v.String
+			}
+		}
+		out = append(out, row)
+	}
+	return cols, out, rows.Err()
+}
+
+// Stats summarizes archive contents.
+type Stats struct {
+	Tweets int
+	Users  int
+}
+
+// Stats counts archived tweets and users.
+func (d *DB) Stats() (Stats, error) {
+	var s Stats
+	if err := d.db.QueryRow(`SELECT COUNT(1) FROM tweets`).Scan(&s.Tweets); err != nil {
+		return s, err
+	}
+	if err := d.db.QueryRow(`SELECT COUNT(1) FROM users`).Scan(&s.Users); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
@@ -0,0 +1,368 @@
+package archive
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tweet ids are numeric, so the FTS rowid doubles as the tweet id and stays
+// stable across VACUUM. Chats are keyed by their transcript path.
+const searchSchema = `
+CREATE VIRTUAL TABLE IF NOT EXISTS tweets_fts USING fts5(
+	author, text, tokenize = 'unicode61 remove_diacritics 2'
+);
+
+CREATE TRIGGER IF NOT EXISTS tweets_fts_insert AFTER INSERT ON tweets
+WHEN new.id GLOB '[0-9]*' BEGIN
+	INSERT INTO tweets_fts (rowid, author, text) VALUES (CAST(new.id AS INTEGER), new.author, new.text);
+END;
+
+CREATE TRIGGER IF NOT EXISTS tweets_fts_update AFTER UPDATE OF author, text ON tweets
+WHEN new.id GLOB '[0-9]*' AND (old.author != new.author OR old.text != new.text) BEGIN
+	DELETE FROM tweets_fts WHERE rowid = CAST(old.id AS INTEGER);
+	INSERT INTO tweets_fts (rowid, author, text) VALUES (CAST(new.id AS INTEGER), new.author, new.text);
+END;
+
+CREATE TRIGGER IF NOT EXISTS tweets_fts_delete AFTER DELETE ON tweets
+WHEN old.id GLOB '[0-9]*' BEGIN
+	DELETE FROM tweets_fts WHERE rowid = CAST(old.id AS INTEGER);
+END;
+
+CREATE TABLE IF NOT EXISTS chats (
+	path         TEXT PRIMARY KEY,
+	title        TEXT NOT NULL DEFAULT '',
+	created_unix INTEGER NOT NULL DEFAULT 0,
+	mtime        INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS chats_fts USING fts5(
+	path UNINDEXED, body, tokenize = 'unicode61 remove_diacritics 2'
+);
+`
+
+// Snippet highlight markers. Use Hit.Highlight to swap them for real markup.
+const (
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+)
+
+// initSearch creates the full-text tables, indexing tweets archived before
+// search existed.
+func initSearch(db *sql.DB) error {
+	var existed int
+	if err := db.QueryRow(`SELECT COUNT(1) FROM sqlite_master WHERE name = 'tweets_fts'`).Scan(&existed); err != nil {
+		return err
+	}
+	if _, err := db.Exec(searchSchema); err != nil {
+		return err
+	}
+	if existed > 0 {
+		return nil
+	}
+	_, err := db.Exec(`INSERT INTO tweets_fts (rowid, author, text)
+		SELECT CAST(id AS INTEGER), author, text FROM tweets WHERE id GLOB '[0-9]*'`)
+	return err
+}
+
+// DefaultChatDir returns ~/.config/birdy/chats, where the TUI saves transcripts.
+func DefaultChatDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "chats"), nil
+}
+
+// IndexChats brings the chat index in line with the markdown transcripts in
+// dir: new and modified files are (re)indexed, deleted ones are dropped.
+func (d *DB) IndexChats(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading chats: %w", err)
+	}
+
+	indexed := make(map[string]int64)
+	rows, err := d.db.Query(`SELECT path, mtime FROM chats`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var p string
+		var mtime int64
+		if err := rows.Scan(&p, &mtime); err != nil {
+			rows.Close()
+			return err
+		}
+		indexed[p] = mtime
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		mtime := info.ModTime().UnixNano()
+		prev, ok := indexed[p]
+		delete(indexed, p)
+		if ok && prev == mtime {
+			continue
+		}
+		raw, err := os.ReadFile(p)
+		if err != nil {
+			continue
+		}
+		title, body := splitChatTitle(string(raw))
+		created := chatCreatedAt(e.Name(), info.ModTime())
+		if _, err := tx.Exec(`DELETE FROM chats_fts WHERE path = ?`, p); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT INTO chats_fts (path, body) VALUES (?, ?)`, p, body); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO chats (path, title, created_unix, mtime) VALUES (?, ?, ?, ?)
+			ON CONFLICT(path) DO UPDATE SET
+				title = excluded.title,
+				created_unix = excluded.created_unix,
+				mtime = excluded.mtime`,
+			p, title, created.Unix(), mtime); err != nil {
+			return err
+		}
+	}
+
+	for p := range indexed {
+		if _, err := tx.Exec(`DELETE FROM chats_fts WHERE path = ?`, p); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM chats WHERE path = ?`, p); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func splitChatTitle(raw string) (title, body string) {
+	raw = strings.ReplaceAll(raw, "\r\n", "\n")
+	first, rest, _ := strings.Cut(raw, "\n")
+	if strings.HasPrefix(first, "# ") {
+		return strings.TrimSpace(strings.TrimPrefix(first, "# ")), rest
+	}
+	return "", raw
+}
+
+func chatCreatedAt(name string, mtime time.Time) time.Time {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if ts, err := time.ParseInLocation("2006-01-02_150405", base, time.Local); err == nil {
+		return ts
+	}
+	return mtime
+}
+
+// SearchOptions selects what Search looks through.
+type SearchOptions struct {
+	Query  string
+	Author string // tweets only; setting it skips chats
+	Since  time.Time
+	Until  time.Time
+	Tweets bool
+	Chats  bool
+	Limit  int
+	Prefix bool // treat the last term as a prefix, for search-as-you-type
+}
+
+// Hit is one search result.
+type Hit struct {
+	Kind      string    `json:"kind"` // "tweet" or "chat"
+	ID        string    `json:"id,omitempty"`
+	Author    string    `json:"author,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	Snippet   string    `json:"snippet"`
+	Score     float64   `json:"score"`
+}
+
+// URL returns the x.com link for tweet hits.
+func (h Hit) URL() string {
+	if h.Kind != "tweet" {
+		return ""
+	}
+	return Record{ID: h.ID, Author: h.Author}.URL()
+}
+
+// Highlight returns the snippet with matched terms wrapped in open/close.
+func (h Hit) Highlight(open, close string) string {
+	return strings.NewReplacer(highlightStart, open, highlightEnd, close).Replace(h.Snippet)
+}
+
+// Search runs a ranked full-text query over archived tweets and indexed
+// chats. Results are ordered best match first.
+func (d *DB) Search(opts SearchOptions) ([]Hit, error) {
+	match := MatchExpr(opts.Query, opts.Prefix)
+	if match == "" {
+		return nil, fmt.Errorf("empty search query")
+	}
+	if !opts.Tweets && !opts.Chats {
+		opts.Tweets, opts.Chats = true, true
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+
+	var hits []Hit
+	if opts.Tweets {
+		h, err := d.searchTweets(match, opts)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, h...)
+	}
+	if opts.Chats && strings.TrimSpace(opts.Author) == "" {
+		h, err := d.searchChats(match, opts)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, h...)
+	}
+
+	// bm25 scores are lower-is-better.
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score < hits[j].Score })
+	if len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+	return hits, nil
+}
+
+func (d *DB) searchTweets(match string, opts SearchOptions) ([]Hit, error) {
+	where := []string{"tweets_fts MATCH ?"}
+	args := []any{match}
+	if a := strings.TrimPrefix(strings.TrimSpace(opts.Author), "@"); a != "" {
+		where = append(where, "t.author = ?")
+		args = append(args, a)
+	}
+	if !opts.Since.IsZero() {
+		where = append(where, "t.created_unix >= ?")
+		args = append(args, opts.Since.Unix())
+	}
+	if !opts.Until.IsZero() {
+		where = append(where, "t.created_unix < ?")
+		args = append(args, opts.Until.Unix())
+	}
+	args = append(args, opts.Limit)
+
+	rows, err := d.db.Query(`
+		SELECT t.id, t.author, t.created_unix,
+			snippet(tweets_fts, 1, '`+highlightStart+`', '`+highlightEnd+`', '…', 24),
+			bm25(tweets_fts, 0.5, 1.0)
+		FROM tweets_fts JOIN tweets t ON t.id = CAST(tweets_fts.rowid AS TEXT)
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY 5 LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching tweets: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Hit
+	for rows.Next() {
+		h := Hit{Kind: "tweet"}
+		var created int64
+		if err := rows.Scan(&h.ID, &h.Author, &created, &h.Snippet, &h.Score); err != nil {
+			return nil, err
+		}
+		if created > 0 {
+			h.CreatedAt = time.Unix(created, 0).UTC()
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+func (d *DB) searchChats(match string, opts SearchOptions) ([]Hit, error) {
+	where := []string{"chats_fts MATCH ?"}
+	args := []any{match}
+	if !opts.Since.IsZero() {
+		where = append(where, "c.created_unix >= ?")
+		args = append(args, opts.Since.Unix())
+	}
+	if !opts.Until.IsZero() {
+		where = append(where, "c.created_unix < ?")
+		args = append(args, opts.Until.Unix())
+	}
+	args = append(args, opts.Limit)
+
+	rows, err := d.db.Query(`
+		SELECT c.path, c.title, c.created_unix,
+			snippet(chats_fts, 1, '`+highlightStart+`', '`+highlightEnd+`', '…', 24),
+			bm25(chats_fts)
+		FROM chats_fts JOIN chats c ON c.path = chats_fts.path
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY 5 LIMIT ?`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("searching chats: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Hit
+	for rows.Next() {
+		h := Hit{Kind: "chat"}
+		var created int64
+		if err := rows.Scan(&h.Path, &h.Title, &created, &h.Snippet, &h.Score); err != nil {
+			return nil, err
+		}
+		if created > 0 {
+			h.CreatedAt = time.Unix(created, 0)
+		}
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// MatchExpr turns free-form search terms into an FTS5 query that matches
+// documents containing every term. Quotes and FTS operators in the input are
+// treated as plain text; "quoted phrases" are kept together.
+func MatchExpr(query string, prefix bool) string {
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		if i%2 == 1 {
+			if p := strings.TrimSpace(part); p != "" {
+				terms = append(terms, p)
+			}
+			continue
+		}
+		terms = append(terms, strings.Fields(part)...)
+	}
+
+	quoted := make([]string, 0, len(terms))
+	for i, t := range terms {
+		star := strings.HasSuffix(t, "*") || (prefix && i == len(terms)-1 && !strings.HasSuffix(query, " "))
+		t = strings.TrimRight(t, "*")
+		if t == "" {
+			continue
+		}
+		q := `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+		if star {
+			q += "*"
+		}
+		quoted = append(quoted, q)
+	}
+	return strings.Join(quoted, " ")
+}
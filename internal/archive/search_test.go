@@ -0,0 +1,154 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+func TestMatchExpr(t *testing.T) {
+	cases := []struct {
+		in     string
+		prefix bool
+		want   string
+	}{
+		{"golang tips", false, `"golang" "tips"`},
+		{`"rate limit" NOT x`, false, `"rate limit" "NOT" "x"`},
+		{"go*", false, `"go"*`},
+		{"learn go", true, `"learn" "go"*`},
+		{"learn go ", true, `"learn" "go"`},
+		{`a"b`, false, `"a" "b"`},
+		{"   ", false, ""},
+	}
+	for _, tc := range cases {
+		if got := MatchExpr(tc.in, tc.prefix); got != tc.want {
+			t.Errorf("MatchExpr(%q, %v) = %q, want %q", tc.in, tc.prefix, got, tc.want)
+		}
+	}
+}
+
+func TestSearchTweetsRankedWithFilters(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.SaveTweets("likes", []bird.Tweet{
+		tweet("1", "alice", "Rust and Go are both nice", "2026-01-10T00:00:00Z"),
+		tweet("2", "bob", "Go go go: a Go concurrency thread", "2026-02-10T00:00:00Z"),
+		tweet("3", "carol", "café opening hours", "2026-03-10T00:00:00Z"),
+	}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	hits, err := db.Search(SearchOptions{Query: "go", Tweets: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].ID != "2" {
+		t.Fatalf("expected tweet 2 ranked first, got %#v", hits)
+	}
+	if got := hits[0].Highlight("[", "]"); !strings.Contains(got, "[Go]") {
+		t.Fatalf("expected highlighted snippet, got %q", got)
+	}
+
+	hits, err = db.Search(SearchOptions{Query: "go", Author: "@alice"})
+	if err != nil || len(hits) != 1 || hits[0].ID != "1" {
+		t.Fatalf("author filter: %#v %v", hits, err)
+	}
+	hits, err = db.Search(SearchOptions{Query: "go", Since: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil || len(hits) != 1 || hits[0].ID != "2" {
+		t.Fatalf("since filter: %#v %v", hits, err)
+	}
+	hits, err = db.Search(SearchOptions{Query: "cafe"})
+	if err != nil || len(hits) != 1 || hits[0].ID != "3" {
+		t.Fatalf("diacritics folding: %#v %v", hits, err)
+	}
+
+	// Edits to a tweet's text are reflected in the index.
+	if _, err := db.SaveTweets("likes", []bird.Tweet{tweet("3", "carol", "tea shop", "2026-03-10T00:00:00Z")}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := db.Search(SearchOptions{Query: "cafe"}); len(hits) != 0 {
+		t.Fatalf("expected stale text to be unindexed, got %#v", hits)
+	}
+}
+
+func TestIndexChatsTracksChanges(t *testing.T) {
+	db := openTestDB(t)
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	first := write("2026-02-11_123000.md", "# birdy chat — 2026-02-11 12:30:00\n\n## You\n\nsummarize bookmarks about sqlite\n\n## birdy\n\nHere you go.\n")
+	write("2026-02-12_090000.md", "# birdy chat\n\n## You\n\nwhat is trending\n")
+	write("notes.txt", "sqlite")
+
+	if err := db.IndexChats(dir); err != nil {
+		t.Fatal(err)
+	}
+	hits, err := db.Search(SearchOptions{Query: "sqlite", Chats: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || hits[0].Path != first || hits[0].Kind != "chat" {
+		t.Fatalf("unexpected hits: %#v", hits)
+	}
+	if hits[0].CreatedAt.Format("2006-01-02 15:04") != "2026-02-11 12:30" {
+		t.Fatalf("expected created time from filename, got %v", hits[0].CreatedAt)
+	}
+	if strings.Contains(hits[0].Snippet, "birdy chat —") {
+		t.Fatalf("title should not be part of the indexed body: %q", hits[0].Snippet)
+	}
+
+	// Rewrite and delete: the index follows the directory.
+	future := time.Now().Add(time.Minute)
+	write("2026-02-11_123000.md", "# birdy chat\n\n## You\n\npostgres only now\n")
+	if err := os.Chtimes(first, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, "2026-02-12_090000.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.IndexChats(dir); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ := db.Search(SearchOptions{Query: "sqlite", Chats: true}); len(hits) != 0 {
+		t.Fatalf("expected rewritten chat to drop old terms, got %#v", hits)
+	}
+	if hits, _ := db.Search(SearchOptions{Query: "trending", Chats: true}); len(hits) != 0 {
+		t.Fatalf("expected deleted chat to be unindexed, got %#v", hits)
+	}
+	if hits, _ := db.Search(SearchOptions{Query: "postgres"}); len(hits) != 1 {
+		t.Fatalf("expected rewritten chat to be searchable, got %#v", hits)
+	}
+}
+
+func TestSearchIndexesExistingTweetsOnUpgrade(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.db")
+	db, err := OpenPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SaveTweets("likes", []bird.Tweet{tweet("7", "alice", "archived before search", "")}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate an archive created before the search index existed.
+	if _, err := db.db.Exec(`DROP TABLE tweets_fts`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = OpenPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if hits, err := db.Search(SearchOptions{Query: "archived"}); err != nil || len(hits) != 1 {
+		t.Fatalf("expected backfilled index, got %#v %v", hits, err)
+	}
+}
@@ -0,0 +1,199 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+// Source is a bird feed that can be synced into the archive.
+type Source struct {
+	Command string // bird command: bookmarks, likes, mentions, user-tweets
+	Handle  string // user-tweets only
+}
+
+// DefaultSources are synced when no source is named.
+var DefaultSources = []Source{
+	{Command: "bookmarks"},
+	{Command: "likes"},
+	{Command: "mentions"},
+}
+
+// ParseSource parses "bookmarks", "likes", "mentions" or
+// "user-tweets <handle>" (also "user-tweets:<handle>").
+func ParseSource(args []string) (Source, error) {
+	if len(args) == 0 {
+		return Source{}, fmt.Errorf("missing source")
+	}
+	name := strings.ToLower(strings.TrimSpace(args[0]))
+	if cmd, handle, ok := strings.Cut(name, ":"); ok {
+		name = cmd
+		args = []string{cmd, handle}
+	}
+	switch name {
+	case "bookmarks", "likes", "mentions":
+		if len(args) > 1 {
+			return Source{}, fmt.Errorf("%s takes no arguments", name)
+		}
+		return Source{Command: name}, nil
+	case "user-tweets":
+		if len(args) != 2 || strings.TrimPrefix(strings.TrimSpace(args[1]), "@") == "" {
+			return Source{}, fmt.Errorf("user-tweets requires a handle")
+		}
+		return Source{Command: name, Handle: "@" + strings.TrimPrefix(strings.TrimSpace(args[1]), "@")}, nil
+	default:
+		return Source{}, fmt.Errorf("unsupported source %q (valid: bookmarks, likes, mentions, user-tweets <handle>)", args[0])
+	}
+}
+
+// Key is the identifier stored with tweets and cursors.
+func (s Source) Key() string {
+	if s.Handle != "" {
+		return s.Command + ":" + strings.ToLower(s.Handle)
+	}
+	return s.Command
+}
+
+// Paged reports whether bird supports cursors for this source.
+func (s Source) Paged() bool {
+	return s.Command != "mentions"
+}
+
+// Args builds the bird argv for one page starting at cursor.
+func (s Source) Args(cursor string, count int) []string {
+	args := []string{s.Command}
+	if s.Handle != "" {
+		args = append(args, s.Handle)
+	}
+	args = append(args, "-n", strconv.Itoa(count), "--json")
+	if !s.Paged() {
+		return args
+	}
+	if cursor != "" {
+		return append(args, "--cursor", cursor, "--max-pages", "1")
+	}
+	if s.Command == "user-tweets" {
+		return append(args, "--max-pages", "1")
+	}
+	return append(args, "--all", "--max-pages", "1")
+}
+
+// Fetcher runs bird once (on the next account in the rotation) and returns
+// its stdout and the account that served it.
+type Fetcher func(ctx context.Context, args []string) (stdout, account string, err error)
+
+// SyncOptions tunes a sync run.
+type SyncOptions struct {
+	MaxPages int           // page budget per source, across both passes
+	Count    int           // tweets requested per page
+	Delay    time.Duration // pause between pages
+	Progress func(source string, page int, account string, added int)
+}
+
+// SyncResult summarizes a sync run for one source.
+type SyncResult struct {
+	Source   string
+	Pages    int
+	Added    int
+	Complete bool
+}
+
+// Sync fetches new tweets for a source. It first reads from the top of the
+// feed until it reaches tweets that are already archived, then continues
+// the backfill from the saved cursor until the page budget is spent.
+func (d *DB) Sync(ctx context.Context, src Source, fetch Fetcher, opts SyncOptions) (SyncResult, error) {
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = 5
+	}
+	if opts.Count <= 0 {
+		opts.Count = 20
+	}
+	res := SyncResult{Source: src.Key()}
+
+	cs, err := d.Cursor(src.Key())
+	if err != nil {
+		return res, err
+	}
+
+	fetchPage := func(cursor string) ([]bird.Tweet, string, int, error) {
+		if res.Pages > 0 && opts.Delay > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, "", 0, ctx.Err()
+			case <-time.After(opts.Delay):
+			}
+		}
+		stdout, account, err := fetch(ctx, src.Args(cursor, opts.Count))
+		if err != nil {
+			return nil, "", 0, err
+		}
+		tweets, next, err := bird.ParseTweets([]byte(stdout))
+		if err != nil {
+			return nil, "", 0, err
+		}
+		added, err := d.SaveTweets(src.Key(), tweets, time.Now())
+		if err != nil {
+			return nil, "", 0, err
+		}
+		res.Pages++
+		res.Added += added
+		if opts.Progress != nil {
+			opts.Progress(src.Key(), res.Pages, account, added)
+		}
+		return tweets, next, added, nil
+	}
+
+	// Head pass: catch up with anything newer than what we have. On the
+	// first sync ever it doubles as the backfill.
+	headIsBackfill := src.Paged() && cs.Cursor == "" && !cs.Complete
+	cursor := ""
+	for res.Pages < opts.MaxPages {
+		tweets, next, added, err := fetchPage(cursor)
+		if err != nil {
+			return res, err
+		}
+		if headIsBackfill {
+			cs.Cursor = next
+			cs.Complete = next == ""
+			cs.Pages++
+			cs.UpdatedAt = time.Now()
+			if err := d.SaveCursor(cs); err != nil {
+				return res, err
+			}
+		}
+		if !src.Paged() || next == "" || len(tweets) == 0 || added < len(tweets) {
+			break
+		}
+		cursor = next
+	}
+
+	// Backfill pass: resume walking older pages from the saved cursor.
+	for src.Paged() && !cs.Complete && cs.Cursor != "" && res.Pages < opts.MaxPages {
+		tweets, next, _, err := fetchPage(cs.Cursor)
+		if err != nil {
+			return res, err
+		}
+		cs.Cursor = next
+		cs.Complete = next == "" || len(tweets) == 0
+		cs.Pages++
+		cs.UpdatedAt = time.Now()
+		if err := d.SaveCursor(cs); err != nil {
+			return res, err
+		}
+	}
+
+	if !src.Paged() {
+		cs.Complete = true
+		cs.Pages++
+		cs.UpdatedAt = time.Now()
+		if err := d.SaveCursor(cs); err != nil {
+			return res, err
+		}
+	}
+	res.Complete = cs.Complete
+	return res, nil
+}
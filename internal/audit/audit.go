@@ -0,0 +1,342 @@
+// Package audit keeps an append-only JSONL log of executed bird commands.
+// Each entry carries the hash of the previous one, so editing or deleting a
+// line breaks the chain and is caught by Verify.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guzus/birdy/internal/filelock"
+)
+
+const (
+	// CallerEnv overrides the caller recorded for CLI runs. The host sets it
+	// for web sessions and API chats so agent-initiated commands are
+	// attributed to whoever started the agent.
+	CallerEnv = "BIRDY_AUDIT_CALLER"
+	// RunIDEnv identifies the agent run a command was issued from.
+	RunIDEnv = "BIRDY_AGENT_RUN_ID"
+)
+
+// Command classes.
+const (
+	ClassRead  = "read"
+	ClassWrite = "write"
+)
+
+// Entry is one executed bird command.
+type Entry struct {
+	Seq        int64     `json:"seq"`
+	Time       time.Time `json:"time"`
+	Caller     string    `json:"caller"`
+	RunID      string    `json:"run_id,omitempty"`
+	Command    string    `json:"command"`
+	Class      string    `json:"class"`
+	Args       []string  `json:"args"`
+	Account    string    `json:"account"`
+	Strategy   string    `json:"strategy,omitempty"`
+	ExitCode   int       `json:"exit_code"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	Prev       string    `json:"prev"`
+	Hash       string    `json:"hash"`
+}
+
+// DefaultPath returns ~/.config/birdy/audit.jsonl.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "audit.jsonl"), nil
+}
+
+// Caller describes who is running this process: the value of CallerEnv when
+// set, otherwise "cli:<os user>".
+func Caller() string {
+	if c := strings.TrimSpace(os.Getenv(CallerEnv)); c != "" {
+		return c
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "cli:" + u.Username
+	}
+	return "cli"
+}
+
+// RunID returns the agent run id from the environment, if any.
+func RunID() string {
+	return strings.TrimSpace(os.Getenv(RunIDEnv))
+}
+
+// NewRunID returns a random id for an agent run.
+func NewRunID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+var (
+	secretFlags = map[string]bool{
+		"--auth-token": true, "--auth_token": true, "--ct0": true,
+		"--cookie": true, "--cookies": true, "--token": true, "--password": true,
+	}
+	secretValue = regexp.MustCompile(`^(?i:[0-9a-f]{40,}|auth_token=.*|ct0=.*)$`)
+)
+
+const maxArgLen = 512
+
+// Redact masks credentials in bird args and truncates very long values.
+// Tweet text is kept so write actions stay traceable.
+func Redact(args []string) []string {
+	out := make([]string, 0, len(args))
+	redactNext := false
+	for _, a := range args {
+		switch {
+		case redactNext:
+			a = "[redacted]"
+			redactNext = false
+		case strings.HasPrefix(a, "--") && strings.Contains(a, "="):
+			if name, _, _ := strings.Cut(a, "="); secretFlags[strings.ToLower(name)] {
+				a = name + "=[redacted]"
+			}
+		case secretFlags[strings.ToLower(a)]:
+			redactNext = true
+		case secretValue.MatchString(a):
+			a = "[redacted]"
+		}
+		if r := []rune(a); len(r) > maxArgLen {
+			a = string(r[:maxArgLen]) + "…"
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// hash is the SHA-256 of the entry's JSON encoding with Hash unset.
+func hash(e Entry) (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("marshaling audit entry: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log appends to an audit file. Appends are serialized across processes
+// with a lock file next to it.
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// Open returns the log at path. The file is created on first append.
+func Open(path string) *Log {
+	return &Log{path: path}
+}
+
+// Append fills in the entry's sequence number and hash chain and writes it.
+func (l *Log) Append(e Entry) (Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return e, fmt.Errorf("creating config dir: %w", err)
+	}
+	unlock, err := filelock.Acquire(l.path + ".lock")
+	if err != nil {
+		return e, err
+	}
+	defer unlock()
+
+	last, err := lastEntry(l.path)
+	if err != nil {
+		return e, err
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	e.Seq = last.Seq + 1
+	e.Prev = last.Hash
+	if e.Hash, err = hash(e); err != nil {
+		return e, err
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return e, fmt.Errorf("marshaling audit entry: %w", err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return e, fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return e, fmt.Errorf("writing audit log: %w", err)
+	}
+	return e, nil
+}
+
+// lastEntry reads the final line of the log, or a zero entry when it is
+// empty or missing.
+func lastEntry(path string) (Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return Entry{}, nil
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return Entry{}, fmt.Errorf("reading audit log: %w", err)
+	}
+	size := info.Size()
+	for chunk := int64(64 * 1024); ; chunk *= 4 {
+		offset := max(size-chunk, 0)
+		buf := make([]byte, size-offset)
+		if _, err := f.ReadAt(buf, offset); err != nil && !errors.Is(err, io.EOF) {
+			return Entry{}, fmt.Errorf("reading audit log: %w", err)
+		}
+		buf = bytes.TrimRight(buf, "\n")
+		if len(buf) == 0 {
+			return Entry{}, nil
+		}
+		i := bytes.LastIndexByte(buf, '\n')
+		if i < 0 && offset > 0 {
+			continue // the last line is longer than the chunk
+		}
+		var e Entry
+		if err := json.Unmarshal(buf[i+1:], &e); err != nil {
+			return Entry{}, fmt.Errorf("parsing last audit entry: %w", err)
+		}
+		return e, nil
+	}
+}
+
+// Filter selects entries. Zero fields match everything.
+type Filter struct {
+	Account string
+	Command string
+	Class   string
+	Caller  string // prefix match, so "api:" selects every API key
+	RunID   string
+	Since   time.Time
+	Until   time.Time
+	Limit   int // keep only the newest Limit entries
+}
+
+func (f Filter) match(e Entry) bool {
+	switch {
+	case f.Account != "" && !strings.EqualFold(e.Account, f.Account):
+		return false
+	case f.Command != "" && e.Command != f.Command:
+		return false
+	case f.Class != "" && e.Class != f.Class:
+		return false
+	case f.Caller != "" && !strings.HasPrefix(e.Caller, f.Caller):
+		return false
+	case f.RunID != "" && e.RunID != f.RunID:
+		return false
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	}
+	return true
+}
+
+// Query returns the matching entries in the log at path, oldest first.
+func Query(path string, f Filter) ([]Entry, error) {
+	var out []Entry
+	err := scan(path, func(e Entry) error {
+		if f.match(e) {
+			out = append(out, e)
+			if f.Limit > 0 && len(out) > 2*f.Limit {
+				out = append(out[:0], out[len(out)-f.Limit:]...)
+			}
+		}
+		return nil
+	})
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[len(out)-f.Limit:]
+	}
+	return out, err
+}
+
+// ErrTampered is returned by Verify when the hash chain is broken.
+var ErrTampered = errors.New("audit log has been modified")
+
+// Verify checks the hash chain of the log at path and returns the number of
+// entries. A broken chain is reported as ErrTampered with the first bad line.
+func Verify(path string) (int, error) {
+	n := 0
+	var prev Entry
+	err := scan(path, func(e Entry) error {
+		n++
+		want, err := hash(e)
+		if err != nil {
+			return err
+		}
+		switch {
+		case e.Seq != prev.Seq+1:
+			return fmt.Errorf("%w: line %d has seq %d, want %d", ErrTampered, n, e.Seq, prev.Seq+1)
+		case e.Prev != prev.Hash:
+			return fmt.Errorf("%w: line %d (seq %d) does not follow the previous entry", ErrTampered, n, e.Seq)
+		case e.Hash != want:
+			return fmt.Errorf("%w: line %d (seq %d) does not match its hash", ErrTampered, n, e.Seq)
+		}
+		prev = e
+		return nil
+	})
+	return n, err
+}
+
+func scan(path string, fn func(Entry) error) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("opening audit log: %w", err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for line := 1; ; line++ {
+		data, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(data)) > 0 {
+			var e Entry
+			if jerr := json.Unmarshal(data, &e); jerr != nil {
+				return fmt.Errorf("%w: line %d is not a valid entry", ErrTampered, line)
+			}
+			if ferr := fn(e); ferr != nil {
+				return ferr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading audit log: %w", err)
+		}
+	}
+}
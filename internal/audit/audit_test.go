@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAppendAndVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l := Open(path)
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, cmd := range []string{"search", "tweet", "home"} {
+		class := ClassRead
+		if cmd == "tweet" {
+			class = ClassWrite
+		}
+		e, err := l.Append(Entry{
+			Time:    base.Add(time.Duration(i) * time.Hour),
+			Caller:  "api:ci",
+			Command: cmd,
+			Class:   class,
+			Args:    []string{cmd, "x"},
+			Account: []string{"main", "alt", "main"}[i],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Seq != int64(i+1) || e.Hash == "" {
+			t.Fatalf("entry %d: seq %d hash %q", i, e.Seq, e.Hash)
+		}
+	}
+
+	if n, err := Verify(path); err != nil || n != 3 {
+		t.Fatalf("Verify = %d, %v", n, err)
+	}
+
+	got, err := Query(path, Filter{Account: "main"})
+	if err != nil || len(got) != 2 {
+		t.Fatalf("account filter: %d entries, %v", len(got), err)
+	}
+	got, _ = Query(path, Filter{Class: ClassWrite})
+	if len(got) != 1 || got[0].Command != "tweet" {
+		t.Fatalf("class filter: %+v", got)
+	}
+	got, _ = Query(path, Filter{Since: base.Add(30 * time.Minute), Until: base.Add(2 * time.Hour)})
+	if len(got) != 1 || got[0].Seq != 2 {
+		t.Fatalf("time filter: %+v", got)
+	}
+	got, _ = Query(path, Filter{Limit: 2})
+	if len(got) != 2 || got[0].Seq != 2 || got[1].Seq != 3 {
+		t.Fatalf("limit keeps newest: %+v", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := strings.Replace(string(data), `"account":"alt"`, `"account":"main"`, 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(path); !errors.Is(err, ErrTampered) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("edited entry: %v", err)
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if err := os.WriteFile(path, []byte(lines[0]+lines[2]), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(path); !errors.Is(err, ErrTampered) {
+		t.Fatalf("deleted entry: %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	got := Redact([]string{
+		"tweet", "hello world",
+		"--auth-token", "secret",
+		"--ct0=abc",
+		"0123456789abcdef0123456789abcdef01234567",
+	})
+	want := []string{"tweet", "hello world", "--auth-token", "[redacted]", "--ct0=[redacted]", "[redacted]"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("Redact = %q, want %q", got, want)
+	}
+}
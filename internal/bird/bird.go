@@ -0,0 +1,146 @@
+package bird
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Author is the compact author object embedded in bird tweet JSON.
+type Author struct {
+	Username string `json:"username"`
+	Name     string `json:"name"`
+}
+
+// Tweet mirrors the tweet objects bird prints with --json.
+type Tweet struct {
+	ID                string `json:"id"`
+	Text              string `json:"text"`
+	Author            Author `json:"author"`
+	AuthorID          string `json:"authorId,omitempty"`
+	CreatedAt         string `json:"createdAt,omitempty"`
+	ReplyCount        int    `json:"replyCount,omitempty"`
+	RetweetCount      int    `json:"retweetCount,omitempty"`
+	LikeCount         int    `json:"likeCount,omitempty"`
+	ConversationID    string `json:"conversationId,omitempty"`
+	InReplyToStatusID string `json:"inReplyToStatusId,omitempty"`
+	QuotedTweet       *Tweet `json:"quotedTweet,omitempty"`
+}
+
+// User mirrors the user objects bird prints for following/followers.
+type User struct {
+	ID              string `json:"id"`
+	Username        string `json:"username"`
+	Name            string `json:"name"`
+	Description     string `json:"description,omitempty"`
+	FollowersCount  int    `json:"followersCount,omitempty"`
+	FollowingCount  int    `json:"followingCount,omitempty"`
+	IsBlueVerified  bool   `json:"isBlueVerified,omitempty"`
+	ProfileImageURL string `json:"profileImageUrl,omitempty"`
+	CreatedAt       string `json:"createdAt,omitempty"`
+}
+
+// About mirrors the account origin object bird prints for about --json.
+type About struct {
+	AccountBasedIn         string `json:"accountBasedIn,omitempty"`
+	Source                 string `json:"source,omitempty"`
+	CreatedCountryAccurate *bool  `json:"createdCountryAccurate,omitempty"`
+	LocationAccurate       *bool  `json:"locationAccurate,omitempty"`
+	LearnMoreURL           string `json:"learnMoreUrl,omitempty"`
+}
+
+// ParseTweets decodes bird --json output. It accepts a bare array, the
+// paginated {tweets, nextCursor} envelope, and the single object printed
+// by read.
+func ParseTweets(data []byte) (tweets []Tweet, nextCursor string, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, "", nil
+	}
+
+	switch data[0] {
+	case '[':
+		if err := json.Unmarshal(data, &tweets); err != nil {
+			return nil, "", fmt.Errorf("parsing tweets: %w", err)
+		}
+		return tweets, "", nil
+	case '{':
+		var page struct {
+			Tweets     *[]Tweet `json:"tweets"`
+			NextCursor *string  `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, "", fmt.Errorf("parsing tweets: %w", err)
+		}
+		if page.Tweets != nil {
+			if page.NextCursor != nil {
+				nextCursor = *page.NextCursor
+			}
+			return *page.Tweets, nextCursor, nil
+		}
+		var t Tweet
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, "", fmt.Errorf("parsing tweet: %w", err)
+		}
+		if t.ID == "" {
+			return nil, "", fmt.Errorf("parsing tweets: unrecognized output")
+		}
+		return []Tweet{t}, "", nil
+	default:
+		return nil, "", fmt.Errorf("parsing tweets: output is not JSON")
+	}
+}
+
+// ParseUsers decodes bird --json output for following/followers.
+func ParseUsers(data []byte) (users []User, nextCursor string, err error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, "", nil
+	}
+
+	switch data[0] {
+	case '[':
+		if err := json.Unmarshal(data, &users); err != nil {
+			return nil, "", fmt.Errorf("parsing users: %w", err)
+		}
+		return users, "", nil
+	case '{':
+		var page struct {
+			Users      []User  `json:"users"`
+			NextCursor *string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, "", fmt.Errorf("parsing users: %w", err)
+		}
+		if page.NextCursor != nil {
+			nextCursor = *page.NextCursor
+		}
+		return page.Users, nextCursor, nil
+	default:
+		return nil, "", fmt.Errorf("parsing users: output is not JSON")
+	}
+}
+
+// CompareIDs orders numeric snowflake IDs without overflowing int64.
+// It returns -1, 0 or 1 like strings.Compare.
+func CompareIDs(a, b string) int {
+	a = strings.TrimLeft(strings.TrimSpace(a), "0")
+	b = strings.TrimLeft(strings.TrimSpace(b), "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// URL returns the canonical x.com link for a tweet.
+func (t Tweet) URL() string {
+	handle := t.Author.Username
+	if handle == "" {
+		handle = "i"
+	}
+	return "https://x.com/" + handle + "/status/" + t.ID
+}
@@ -0,0 +1,66 @@
+package bird
+
+import "testing"
+
+func TestParseTweetsFormats(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantIDs    []string
+		wantCursor string
+	}{
+		{name: "array", input: `[{"id":"1","text":"a"},{"id":"2","text":"b"}]`, wantIDs: []string{"1", "2"}},
+		{name: "page", input: `{"tweets":[{"id":"3"}],"nextCursor":"abc"}`, wantIDs: []string{"3"}, wantCursor: "abc"},
+		{name: "page without cursor", input: `{"tweets":[],"nextCursor":null}`, wantIDs: nil},
+		{name: "single", input: `{"id":"4","text":"read","author":{"username":"x","name":"X"}}`, wantIDs: []string{"4"}},
+		{name: "empty", input: "  ", wantIDs: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tweets, cursor, err := ParseTweets([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ParseTweets: %v", err)
+			}
+			if len(tweets) != len(tt.wantIDs) {
+				t.Fatalf("expected %d tweets, got %d", len(tt.wantIDs), len(tweets))
+			}
+			for i, id := range tt.wantIDs {
+				if tweets[i].ID != id {
+					t.Fatalf("tweet %d: expected id %q, got %q", i, id, tweets[i].ID)
+				}
+			}
+			if cursor != tt.wantCursor {
+				t.Fatalf("expected cursor %q, got %q", tt.wantCursor, cursor)
+			}
+		})
+	}
+}
+
+func TestParseTweetsRejectsText(t *testing.T) {
+	if _, _, err := ParseTweets([]byte("@someone: hello")); err == nil {
+		t.Fatal("expected plain text output to be rejected")
+	}
+}
+
+func TestParseUsersPage(t *testing.T) {
+	users, cursor, err := ParseUsers([]byte(`{"users":[{"id":"9","username":"a"}],"nextCursor":"n1"}`))
+	if err != nil {
+		t.Fatalf("ParseUsers: %v", err)
+	}
+	if len(users) != 1 || users[0].Username != "a" || cursor != "n1" {
+		t.Fatalf("unexpected result: %#v cursor=%q", users, cursor)
+	}
+}
+
+func TestCompareIDs(t *testing.T) {
+	if CompareIDs("999", "1000") != -1 {
+		t.Fatal("expected shorter id to sort first")
+	}
+	if CompareIDs("1890000000000000001", "1890000000000000000") != 1 {
+		t.Fatal("expected larger snowflake to compare greater")
+	}
+	if CompareIDs("42", "42") != 0 {
+		t.Fatal("expected equal ids")
+	}
+}
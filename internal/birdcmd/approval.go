@@ -0,0 +1,47 @@
+package birdcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/audit"
+)
+
+// ErrDenied is returned for an agent's write command the user denied.
+var ErrDenied = errors.New("denied by the user")
+
+// ApproveAgentWrite asks the user to approve a write command issued by an
+// agent run, over the run's approval socket (approval.SocketEnv when socket
+// is empty). It returns the args to run, which the user may have edited,
+// and whether they were. Reads, commands outside agent runs and runs
+// without a socket are not gated.
+func ApproveAgentWrite(ctx context.Context, socket, runID string, args []string) ([]string, bool, error) {
+	if socket == "" {
+		socket = os.Getenv(approval.SocketEnv)
+	}
+	if runID == "" {
+		runID = audit.RunID()
+	}
+	if _, write := WriteCommands[commandLabel(args)]; !write || socket == "" || runID == "" {
+		return args, false, nil
+	}
+
+	d, err := approval.Ask(ctx, socket, approval.Request{RunID: runID, Args: args})
+	if err != nil {
+		return nil, false, fmt.Errorf("asking the user to approve %q: %w", commandLabel(args), err)
+	}
+	switch d.Action {
+	case approval.Approve:
+		return args, false, nil
+	case approval.Edit:
+		if _, write := WriteCommands[commandLabel(d.Args)]; write && ReadOnly() {
+			return nil, false, fmt.Errorf("%q is disabled in read-only mode (BIRDY_READ_ONLY)", commandLabel(d.Args))
+		}
+		return d.Args, true, nil
+	default:
+		return nil, false, fmt.Errorf("%w: birdy %s", ErrDenied, approval.Quote(args))
+	}
+}
@@ -0,0 +1,70 @@
+package birdcmd
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/guzus/birdy/internal/approval"
+)
+
+func TestApproveAgentWrite(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets")
+	}
+	t.Setenv(approval.SocketEnv, "")
+	srv, err := approval.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	var asked [][]string
+	go func() {
+		for p := range srv.Requests() {
+			asked = append(asked, p.Args)
+			switch p.Args[0] {
+			case "tweet":
+				p.Decide(approval.Decision{Action: approval.Edit, Args: []string{"tweet", "better text"}})
+			case "follow":
+				p.Decide(approval.Decision{Action: approval.Approve})
+			default:
+				p.Decide(approval.Decision{Action: approval.Deny})
+			}
+		}
+	}()
+
+	ctx := context.Background()
+	sock := srv.Path()
+
+	// Reads and commands outside an agent run or without a socket run as is.
+	for _, tc := range []struct {
+		socket, runID string
+		args          []string
+	}{
+		{sock, "run1", []string{"home", "--json"}},
+		{sock, "", []string{"tweet", "hi"}},
+		{"", "run1", []string{"tweet", "hi"}},
+	} {
+		got, edited, err := ApproveAgentWrite(ctx, tc.socket, tc.runID, tc.args)
+		if err != nil || edited || !slices.Equal(got, tc.args) {
+			t.Fatalf("%+v: got %q, %v, %v", tc, got, edited, err)
+		}
+	}
+
+	if got, edited, err := ApproveAgentWrite(ctx, sock, "run1", []string{"follow", "@a"}); err != nil || edited || !slices.Equal(got, []string{"follow", "@a"}) {
+		t.Fatalf("approve: %q, %v, %v", got, edited, err)
+	}
+	if got, edited, err := ApproveAgentWrite(ctx, sock, "run1", []string{"tweet", "hi"}); err != nil || !edited || !slices.Equal(got, []string{"tweet", "better text"}) {
+		t.Fatalf("edit: %q, %v, %v", got, edited, err)
+	}
+	_, _, err = ApproveAgentWrite(ctx, sock, "run1", []string{"unfollow", "@a"})
+	if !errors.Is(err, ErrDenied) || err.Error() != "denied by the user: birdy unfollow @a" {
+		t.Fatalf("deny: %v", err)
+	}
+	if len(asked) != 3 {
+		t.Fatalf("asked %d times, want 3", len(asked))
+	}
+}
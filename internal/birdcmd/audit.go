@@ -0,0 +1,57 @@
+package birdcmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/guzus/birdy/internal/audit"
+)
+
+// WriteCommands are the bird commands that change account state.
+var WriteCommands = func() map[string]struct{} {
+	m := map[string]struct{}{}
+	for _, c := range Commands {
+		if c.Write {
+			m[c.Name] = struct{}{}
+		}
+	}
+	return m
+}()
+
+// Class returns audit.ClassWrite for commands in WriteCommands and
+// audit.ClassRead otherwise.
+func Class(command string) string {
+	if _, ok := WriteCommands[command]; ok {
+		return audit.ClassWrite
+	}
+	return audit.ClassRead
+}
+
+// Audit appends an executed command to the audit log, filling in the
+// command, class, redacted args and, when unset, the caller and agent run
+// from the environment. A failure to write is reported on stderr and does
+// not fail the command.
+func Audit(e audit.Entry, args []string, elapsed time.Duration, runErr error) {
+	e.Command = commandLabel(args)
+	e.Class = Class(e.Command)
+	e.Args = audit.Redact(args)
+	e.DurationMS = elapsed.Milliseconds()
+	if runErr != nil {
+		e.Error = runErr.Error()
+	}
+	if e.Caller == "" {
+		e.Caller = audit.Caller()
+	}
+	if e.RunID == "" {
+		e.RunID = audit.RunID()
+	}
+
+	path, err := audit.DefaultPath()
+	if err == nil {
+		_, err = audit.Open(path).Append(e)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[birdy] audit log: %v\n", err)
+	}
+}
@@ -0,0 +1,24 @@
+package birdcmd
+
+import (
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/budget"
+)
+
+// ChargeAgentCall counts a bird invocation against the agent budget when it
+// comes from an agent run: runID, or the run id in the environment. Once a
+// budget is spent it returns an error matching budget.ErrExhausted and the
+// command must not run.
+func ChargeAgentCall(runID string) error {
+	if runID == "" {
+		runID = audit.RunID()
+	}
+	if runID == "" {
+		return nil
+	}
+	t, err := budget.Open()
+	if err != nil {
+		return err
+	}
+	return t.Call(runID)
+}
@@ -0,0 +1,228 @@
+package birdcmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Param is one typed input of a bird command.
+type Param struct {
+	Name        string
+	Type        string // "string", "integer", "boolean" or "array" (of strings)
+	Description string
+	Required    bool
+	Flag        string // bird flag; empty for positional args; a boolean is passed bare
+	Handle      bool   // a username, passed to bird with a leading @
+}
+
+// Command describes a bird command birdy forwards.
+type Command struct {
+	Name        string
+	Description string
+	Write       bool // changes account state
+	JSON        bool // accepts --json
+	Params      []Param
+}
+
+var (
+	paramCount    = Param{Name: "count", Type: "integer", Description: "Number of results to fetch", Flag: "-n"}
+	paramCursor   = Param{Name: "cursor", Type: "string", Description: "Pagination cursor from a previous page", Flag: "--cursor"}
+	paramTweet    = Param{Name: "tweet", Type: "string", Description: "Tweet ID or URL", Required: true}
+	paramUsername = Param{Name: "username", Type: "string", Description: "Username, with or without @", Required: true, Handle: true}
+	paramUserID   = Param{Name: "user", Type: "string", Description: "Numeric user ID; defaults to the current account", Flag: "--user"}
+)
+
+// Commands is every bird command birdy forwards, in alphabetical order.
+var Commands = []Command{
+	{Name: "about", Description: "Get account information for a user", JSON: true, Params: []Param{paramUsername}},
+	{Name: "bookmarks", Description: "Get your bookmarked tweets", JSON: true, Params: []Param{paramCount}},
+	{Name: "check", Description: "Check credential availability"},
+	{Name: "follow", Description: "Follow a user", Write: true, Params: []Param{paramUsername}},
+	{Name: "followers", Description: "Get users that follow you (or another user, by ID)", JSON: true, Params: []Param{paramUserID, paramCount, paramCursor}},
+	{Name: "following", Description: "Get users that you (or another user, by ID) follow", JSON: true, Params: []Param{paramUserID, paramCount, paramCursor}},
+	{Name: "home", Description: "Get your home timeline", JSON: true, Params: []Param{paramCount}},
+	{Name: "likes", Description: "Get your liked tweets", JSON: true, Params: []Param{paramCount, paramCursor}},
+	{Name: "list-timeline", Description: "Get tweets from a list", JSON: true, Params: []Param{
+		{Name: "list", Type: "string", Description: "List ID or URL", Required: true}, paramCount,
+	}},
+	{Name: "lists", Description: "Get your lists", JSON: true, Params: []Param{
+		{Name: "member_of", Type: "boolean", Description: "Lists you are a member of instead of the ones you own", Flag: "--member-of"}, paramCount,
+	}},
+	{Name: "mentions", Description: "Get your mentions", JSON: true, Params: []Param{paramCount}},
+	{Name: "news", Description: "Get trending news", JSON: true, Params: []Param{paramCount}},
+	{Name: "query-ids", Description: "Query tweets by IDs", JSON: true, Params: []Param{
+		{Name: "ids", Type: "array", Description: "Tweet IDs", Required: true},
+	}},
+	{Name: "read", Description: "Read a tweet by ID or URL", JSON: true, Params: []Param{paramTweet}},
+	{Name: "replies", Description: "Get replies to a tweet", JSON: true, Params: []Param{paramTweet}},
+	{Name: "reply", Description: "Reply to a tweet", Write: true, Params: []Param{
+		paramTweet, {Name: "text", Type: "string", Description: "Reply text", Required: true},
+	}},
+	{Name: "search", Description: "Search for tweets", JSON: true, Params: []Param{
+		{Name: "query", Type: "string", Description: "Search query, with X search operators", Required: true}, paramCount, paramCursor,
+	}},
+	{Name: "thread", Description: "Read a tweet thread", JSON: true, Params: []Param{paramTweet, paramCursor}},
+	{Name: "tweet", Description: "Post a new tweet", Write: true, Params: []Param{
+		{Name: "text", Type: "string", Description: "Tweet text", Required: true},
+	}},
+	{Name: "unbookmark", Description: "Remove a tweet from bookmarks", Write: true, Params: []Param{paramTweet}},
+	{Name: "unfollow", Description: "Unfollow a user", Write: true, Params: []Param{paramUsername}},
+	{Name: "user-tweets", Description: "Get tweets for a user", JSON: true, Params: []Param{paramUsername, paramCount, paramCursor}},
+	{Name: "whoami", Description: "Show current authenticated user"},
+}
+
+// ToolName is the command's name as an agent tool; some clients reject
+// hyphens in tool names.
+func (c Command) ToolName() string {
+	return strings.ReplaceAll(c.Name, "-", "_")
+}
+
+// ReadOnlyEnv disables write commands when set to 1, true, yes or on.
+const ReadOnlyEnv = "BIRDY_READ_ONLY"
+
+// ReadOnly reports whether BIRDY_READ_ONLY disables write commands.
+func ReadOnly() bool {
+	return ReadOnlyValue(os.Getenv(ReadOnlyEnv))
+}
+
+// ReadOnlyValue reports whether v, a value of ReadOnlyEnv, disables write
+// commands.
+func ReadOnlyValue(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// Lookup returns the command with the given name.
+func Lookup(name string) (Command, bool) {
+	for _, c := range Commands {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+// Args converts typed input to bird args: the command, positional params in
+// order, then flags and --json when the command supports it. Positional
+// values that look like flags go after "--". Unknown or mistyped params are
+// reported as an InputError.
+func (c Command) Args(input map[string]any) ([]string, error) {
+	known := make(map[string]bool, len(c.Params))
+	var positional, flags []string
+	dashed := false
+	for _, p := range c.Params {
+		known[p.Name] = true
+		v, ok := input[p.Name]
+		if !ok || v == nil {
+			if p.Required {
+				return nil, &InputError{Err: fmt.Errorf("missing %q", p.Name)}
+			}
+			continue
+		}
+		values, err := p.values(v)
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+		if p.Type == "boolean" {
+			if values[0] == "true" {
+				flags = append(flags, p.Flag)
+			}
+			continue
+		}
+		if p.Flag != "" {
+			flags = append(flags, p.Flag, values[0])
+			continue
+		}
+		for _, s := range values {
+			dashed = dashed || strings.HasPrefix(s, "-")
+		}
+		positional = append(positional, values...)
+	}
+	for name := range input {
+		if !known[name] {
+			return nil, &InputError{Err: fmt.Errorf("unknown parameter %q", name)}
+		}
+	}
+	if c.JSON {
+		flags = append(flags, "--json")
+	}
+	args := []string{c.Name}
+	if dashed {
+		args = append(append(append(args, flags...), "--"), positional...)
+	} else {
+		args = append(append(args, positional...), flags...)
+	}
+	return args, nil
+}
+
+func (p Param) values(v any) ([]string, error) {
+	switch p.Type {
+	case "integer":
+		n, ok := v.(float64)
+		if !ok || n != float64(int(n)) || n < 1 {
+			return nil, fmt.Errorf("%q must be a positive integer", p.Name)
+		}
+		return []string{strconv.Itoa(int(n))}, nil
+	case "boolean":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%q must be true or false", p.Name)
+		}
+		return []string{strconv.FormatBool(b)}, nil
+	case "array":
+		items, ok := v.([]any)
+		if !ok || len(items) == 0 {
+			return nil, fmt.Errorf("%q must be a non-empty array of strings", p.Name)
+		}
+		out := make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if s = strings.TrimSpace(s); !ok || s == "" {
+				return nil, fmt.Errorf("%q must be a non-empty array of strings", p.Name)
+			}
+			out = append(out, s)
+		}
+		return out, nil
+	default:
+		s, ok := v.(string)
+		if s = strings.TrimSpace(s); !ok || s == "" {
+			return nil, fmt.Errorf("%q must be a non-empty string", p.Name)
+		}
+		if p.Handle {
+			s = "@" + strings.TrimPrefix(s, "@")
+		}
+		return []string{s}, nil
+	}
+}
+
+// Schema returns the JSON Schema of the command's input.
+func (c Command) Schema() map[string]any {
+	props := map[string]any{}
+	required := []string{}
+	for _, p := range c.Params {
+		prop := map[string]any{"type": p.Type, "description": p.Description}
+		switch p.Type {
+		case "integer":
+			prop["minimum"] = 1
+		case "array":
+			prop["items"] = map[string]any{"type": "string"}
+			prop["minItems"] = 1
+		}
+		props[p.Name] = prop
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	return map[string]any{
+		"type":                 "object",
+		"properties":           props,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
@@ -0,0 +1,68 @@
+package birdcmd
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestCommandArgs(t *testing.T) {
+	search, _ := Lookup("search")
+	reply, _ := Lookup("reply")
+	about, _ := Lookup("about")
+	queryIDs, _ := Lookup("query-ids")
+	followers, _ := Lookup("followers")
+	following, _ := Lookup("following")
+	likes, _ := Lookup("likes")
+	lists, _ := Lookup("lists")
+
+	tests := []struct {
+		cmd   Command
+		input map[string]any
+		want  []string
+	}{
+		{search, map[string]any{"query": "golang", "count": float64(5)}, []string{"search", "golang", "-n", "5", "--json"}},
+		{search, map[string]any{"query": "-is:retweet go"}, []string{"search", "--json", "--", "-is:retweet go"}},
+		{about, map[string]any{"username": "steipete"}, []string{"about", "@steipete", "--json"}},
+		{reply, map[string]any{"tweet": "123", "text": "thanks!"}, []string{"reply", "123", "thanks!"}},
+		{queryIDs, map[string]any{"ids": []any{"1", "2"}}, []string{"query-ids", "1", "2", "--json"}},
+		// bird followers|following [--user <userId>] [-n] [--cursor]: no positional args.
+		{followers, map[string]any{}, []string{"followers", "--json"}},
+		{followers, map[string]any{"user": "42", "count": float64(50), "cursor": "c1"}, []string{"followers", "--user", "42", "-n", "50", "--cursor", "c1", "--json"}},
+		{following, map[string]any{"user": "42"}, []string{"following", "--user", "42", "--json"}},
+		// bird likes [-n] [--cursor] and bird lists [--member-of] [-n] cover the current account.
+		{likes, map[string]any{"count": float64(10), "cursor": "c1"}, []string{"likes", "-n", "10", "--cursor", "c1", "--json"}},
+		{lists, map[string]any{}, []string{"lists", "--json"}},
+		{lists, map[string]any{"member_of": true, "count": float64(5)}, []string{"lists", "--member-of", "-n", "5", "--json"}},
+		{lists, map[string]any{"member_of": false}, []string{"lists", "--json"}},
+	}
+	for _, tt := range tests {
+		got, err := tt.cmd.Args(tt.input)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%s %v = %q, %v; want %q", tt.cmd.Name, tt.input, got, err, tt.want)
+		}
+	}
+
+	var inputErr *InputError
+	for _, input := range []map[string]any{
+		{},
+		{"query": "go", "count": "5"},
+		{"query": "go", "count": float64(1.5)},
+		{"query": "go", "account": "main"},
+	} {
+		if _, err := search.Args(input); !errors.As(err, &inputErr) {
+			t.Errorf("search %v: expected InputError, got %v", input, err)
+		}
+	}
+	for _, input := range []map[string]any{
+		{"username": "alice"},
+		{"member_of": "yes"},
+	} {
+		if _, err := lists.Args(input); !errors.As(err, &inputErr) {
+			t.Errorf("lists %v: expected InputError, got %v", input, err)
+		}
+	}
+	if _, err := likes.Args(map[string]any{"username": "alice"}); !errors.As(err, &inputErr) {
+		t.Errorf("likes takes no username, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package birdcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/metrics"
+	"github.com/guzus/birdy/internal/rotation"
+	"github.com/guzus/birdy/internal/runner"
+	"github.com/guzus/birdy/internal/state"
+	"github.com/guzus/birdy/internal/store"
+)
+
+// The bird metrics count invocations made by this process only. Web TUI
+// sessions and claude CLI agent runs call bird from child processes (birdy
+// tui, birdy agent-exec), whose counts never reach the host's /metrics; the
+// audit log has every invocation.
+var (
+	executions = metrics.NewCounter("birdy_bird_executions_total",
+		"bird invocations made by the host process by command, account and result (ok, error for a non-zero exit, failed when bird could not run). Excludes web TUI sessions and claude CLI agent runs, which run bird in child processes.",
+		"command", "account", "result")
+	duration = metrics.NewHistogram("birdy_bird_duration_seconds",
+		"bird invocation latency by command, for invocations made by the host process.", nil, "command")
+	picks = metrics.NewCounter("birdy_rotation_picks_total",
+		"Accounts picked for bird invocations made by the host process; mode is rotation or pinned. Excludes web TUI sessions and claude CLI agent runs.", "account", "mode")
+)
+
+var commandLabelPattern = regexp.MustCompile(`^[a-z][a-z-]{0,31}$`)
+
+// commandLabel is the bird subcommand in args, bounded for use as a metric label.
+func commandLabel(args []string) string {
+	for _, a := range args {
+		if strings.HasPrefix(a, "-") {
+			continue
+		}
+		if commandLabelPattern.MatchString(a) {
+			return a
+		}
+		break
+	}
+	return "other"
+}
+
+// ErrNoAccounts is returned when the account store is empty.
+var ErrNoAccounts = errors.New("no accounts configured")
+
+// InputError marks a failure caused by the request itself, such as an
+// unknown account name or an invalid rotation strategy.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string { return e.Err.Error() }
+func (e *InputError) Unwrap() error { return e.Err }
+
+// Request describes a single captured bird invocation.
+type Request struct {
+	Args     []string
+	Account  string   // use this account and skip rotation
+	Strategy string   // rotation strategy; defaults to round-robin
+	Pool     []string // when set, only these accounts may be used
+	Caller   string   // recorded in the audit log; defaults to audit.Caller()
+	RunID    string   // agent run recorded in the audit log; defaults to audit.RunID()
+}
+
+// Result is the captured outcome of a bird invocation.
+type Result struct {
+	Account  string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Duration time.Duration
+}
+
+// PickAccount resolves the account for a request: the named account when
+// one is given, otherwise the next account in the rotation. A non-empty pool
+// restricts both to the listed accounts. Rotation state and usage counters
+// are persisted before returning.
+func PickAccount(st *store.Store, name, strategy string, pool []string) (*store.Account, error) {
+	if st.Len() == 0 {
+		return nil, ErrNoAccounts
+	}
+
+	var account *store.Account
+	if name = strings.TrimSpace(name); name != "" {
+		a, err := st.Get(name)
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+		if len(pool) > 0 && !slices.Contains(pool, a.Name) {
+			return nil, &InputError{Err: fmt.Errorf("account %q is not allowed", a.Name)}
+		}
+		account = a
+	} else {
+		if strings.TrimSpace(strategy) == "" {
+			strategy = string(rotation.RoundRobin)
+		}
+		strat, err := rotation.ParseStrategy(strings.TrimSpace(strategy))
+		if err != nil {
+			return nil, &InputError{Err: err}
+		}
+
+		rs, err := state.Load()
+		if err != nil {
+			return nil, fmt.Errorf("loading rotation state: %w", err)
+		}
+		candidates := st.List()
+		if len(pool) > 0 {
+			candidates = slices.DeleteFunc(candidates, func(a store.Account) bool {
+				return !slices.Contains(pool, a.Name)
+			})
+			if len(candidates) == 0 {
+				return nil, &InputError{Err: fmt.Errorf("none of the allowed accounts are configured")}
+			}
+		}
+		account, err = rotation.Pick(candidates, strat, rs.LastUsedName)
+		if err != nil {
+			return nil, err
+		}
+		rs.LastUsedName = account.Name
+		if err := rs.Save(); err != nil {
+			return nil, fmt.Errorf("saving rotation state: %w", err)
+		}
+	}
+
+	mode := "rotation"
+	if name != "" {
+		mode = "pinned"
+	}
+	picks.Inc(account.Name, mode)
+
+	// Copy before RecordUsage mutates the backing slice.
+	picked := *account
+	if err := st.RecordUsage(picked.Name); err != nil {
+		return nil, err
+	}
+	if err := st.Save(); err != nil {
+		return nil, fmt.Errorf("saving account store: %w", err)
+	}
+	return &picked, nil
+}
+
+// Run picks an account and executes bird with captured output.
+func Run(ctx context.Context, req Request) (*Result, error) {
+	if len(req.Args) == 0 {
+		return nil, fmt.Errorf("missing command")
+	}
+	if err := ChargeAgentCall(req.RunID); err != nil {
+		return nil, err
+	}
+
+	st, err := store.Open()
+	if err != nil {
+		return nil, fmt.Errorf("opening account store: %w", err)
+	}
+	account, err := PickAccount(st, req.Account, req.Strategy, req.Pool)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	exitCode, stdout, stderr, err := runner.RunCaptureContext(ctx, account, req.Args)
+	elapsed := time.Since(start)
+	command := commandLabel(req.Args)
+	duration.Observe(elapsed.Seconds(), command)
+
+	entry := audit.Entry{Caller: req.Caller, RunID: req.RunID, Account: account.Name, ExitCode: exitCode}
+	if strings.TrimSpace(req.Account) == "" {
+		entry.Strategy = req.Strategy
+		if entry.Strategy == "" {
+			entry.Strategy = string(rotation.RoundRobin)
+		}
+	}
+	Audit(entry, req.Args, elapsed, err)
+
+	switch {
+	case err != nil:
+		executions.Inc(command, account.Name, "failed")
+		return nil, err
+	case exitCode != 0:
+		executions.Inc(command, account.Name, "error")
+	default:
+		executions.Inc(command, account.Name, "ok")
+	}
+	return &Result{
+		Account:  account.Name,
+		ExitCode: exitCode,
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Duration: elapsed,
+	}, nil
+}
@@ -0,0 +1,30 @@
+package birdcmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/guzus/birdy/internal/profile"
+)
+
+// ErrNotInProfile is returned for a command the agent profile does not allow.
+var ErrNotInProfile = errors.New("not allowed by the agent profile")
+
+// CheckProfile returns an error matching ErrNotInProfile when the agent
+// profile p does not allow the command in args. A nil profile allows
+// everything.
+func CheckProfile(p *profile.Profile, args []string) error {
+	if p == nil {
+		return nil
+	}
+	name := commandLabel(args)
+	_, write := WriteCommands[name]
+	switch {
+	case p.Allows(name, write):
+		return nil
+	case p.Name == "":
+		return fmt.Errorf("%q is %w of this run", name, ErrNotInProfile)
+	default:
+		return fmt.Errorf("%q is %w %q", name, ErrNotInProfile, p.Name)
+	}
+}
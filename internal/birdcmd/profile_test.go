@@ -0,0 +1,29 @@
+package birdcmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/guzus/birdy/internal/profile"
+)
+
+func TestCheckProfile(t *testing.T) {
+	if err := CheckProfile(nil, []string{"tweet", "hi"}); err != nil {
+		t.Fatalf("nil profile: %v", err)
+	}
+	researcher := &profile.Profile{Name: "researcher", ReadOnly: true}
+	if err := CheckProfile(researcher, []string{"search", "golang"}); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	err := CheckProfile(researcher, []string{"tweet", "hi"})
+	if !errors.Is(err, ErrNotInProfile) || err.Error() != `"tweet" is not allowed by the agent profile "researcher"` {
+		t.Fatalf("write = %v", err)
+	}
+	drafter := &profile.Profile{Name: "drafter", Commands: []string{"read", "reply"}}
+	if err := CheckProfile(drafter, []string{"--json", "read", "123"}); err != nil {
+		t.Fatalf("allowed: %v", err)
+	}
+	if err := CheckProfile(drafter, []string{"home"}); !errors.Is(err, ErrNotInProfile) {
+		t.Fatalf("outside subset = %v", err)
+	}
+}
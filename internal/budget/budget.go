@@ -0,0 +1,340 @@
+// Package budget limits what agent runs may spend. Each agent run carries a
+// run id (audit.RunIDEnv); model turns, bird invocations and dollars are
+// counted per run and per day in ~/.config/birdy/budget.json, shared by
+// every birdy process.
+package budget
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/filelock"
+)
+
+// DefaultRunTurns is the per-run turn limit when none is configured.
+const DefaultRunTurns = 25
+
+// Environment variables holding the limits. Zero or unset means unlimited,
+// except for the run turn limit, which defaults to DefaultRunTurns.
+const (
+	RunTurnsEnv = "BIRDY_AGENT_RUN_TURNS"
+	RunCallsEnv = "BIRDY_AGENT_RUN_CALLS"
+	RunCostEnv  = "BIRDY_AGENT_RUN_COST"
+	DayTurnsEnv = "BIRDY_AGENT_DAY_TURNS"
+	DayCallsEnv = "BIRDY_AGENT_DAY_CALLS"
+	DayCostEnv  = "BIRDY_AGENT_DAY_COST"
+)
+
+// Limits caps agent spending per run and per calendar day (local time).
+type Limits struct {
+	RunTurns   int
+	RunCalls   int
+	RunCostUSD float64
+	DayTurns   int
+	DayCalls   int
+	DayCostUSD float64
+}
+
+// LoadLimits reads the limits from the environment. Invalid values are
+// treated as unset.
+func LoadLimits() Limits {
+	l := Limits{
+		RunTurns:   envInt(RunTurnsEnv),
+		RunCalls:   envInt(RunCallsEnv),
+		RunCostUSD: envFloat(RunCostEnv),
+		DayTurns:   envInt(DayTurnsEnv),
+		DayCalls:   envInt(DayCallsEnv),
+		DayCostUSD: envFloat(DayCostEnv),
+	}
+	if l.RunTurns <= 0 {
+		l.RunTurns = DefaultRunTurns
+	}
+	return l
+}
+
+func envInt(key string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(os.Getenv(key)))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func envFloat(key string) float64 {
+	f, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(key)), 64)
+	if err != nil || f < 0 {
+		return 0
+	}
+	return f
+}
+
+// Usage is what a run or a day has spent.
+type Usage struct {
+	Turns   int     `json:"turns"`
+	Calls   int     `json:"calls"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// ErrExhausted matches every *ExhaustedError.
+var ErrExhausted = errors.New("agent budget exhausted")
+
+// ExhaustedError reports which budget ran out.
+type ExhaustedError struct {
+	Scope    string // "run" or "day"
+	Resource string // "turns", "calls" or "cost"
+	Used     float64
+	Limit    float64
+	Env      string // variable that sets the limit
+}
+
+func (e *ExhaustedError) Error() string {
+	scope := "this agent run"
+	if e.Scope == "day" {
+		scope = "today"
+	}
+	var used string
+	switch e.Resource {
+	case "cost":
+		used = fmt.Sprintf("$%.2f of $%.2f spent %s", e.Used, e.Limit, scope)
+	case "calls":
+		used = fmt.Sprintf("%d of %d bird calls used %s", int(e.Used), int(e.Limit), scope)
+	default:
+		used = fmt.Sprintf("%d of %d agent turns used %s", int(e.Used), int(e.Limit), scope)
+	}
+	return fmt.Sprintf("agent budget exhausted: %s (raise %s to allow more)", used, e.Env)
+}
+
+func (e *ExhaustedError) Is(target error) bool { return target == ErrExhausted }
+
+// runRetention is how long per-run counters are kept.
+const runRetention = 24 * time.Hour
+
+type runState struct {
+	Usage
+	Refused string    `json:"refused,omitempty"` // why a call was refused
+	Updated time.Time `json:"updated"`
+}
+
+type fileState struct {
+	Day  string               `json:"day"`
+	Used Usage                `json:"used"`
+	Runs map[string]*runState `json:"runs,omitempty"`
+}
+
+// Tracker counts agent spending against limits.
+type Tracker struct {
+	path   string
+	limits Limits
+	now    func() time.Time
+}
+
+// DefaultPath returns ~/.config/birdy/budget.json.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "budget.json"), nil
+}
+
+// Open returns a tracker for the default path with limits from the
+// environment.
+func Open() (*Tracker, error) {
+	p, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return OpenPath(p, LoadLimits()), nil
+}
+
+// OpenPath returns a tracker for the state file at path.
+func OpenPath(path string, limits Limits) *Tracker {
+	return &Tracker{path: path, limits: limits, now: time.Now}
+}
+
+// Limits returns the tracker's limits.
+func (t *Tracker) Limits() Limits { return t.limits }
+
+// Turn checks the budgets before a model turn and counts it.
+func (t *Tracker) Turn(runID string) error {
+	return t.update(runID, func(day, run *Usage) error {
+		if err := t.check(day, run, "turns"); err != nil {
+			return err
+		}
+		day.Turns++
+		run.Turns++
+		return nil
+	})
+}
+
+// Call checks the budgets before a bird invocation and counts it. A refused
+// call is remembered on the run so Refused can report it.
+func (t *Tracker) Call(runID string) error {
+	return t.update(runID, func(day, run *Usage) error {
+		if err := t.check(day, run, "calls"); err != nil {
+			return err
+		}
+		day.Calls++
+		run.Calls++
+		return nil
+	})
+}
+
+// Spend adds cost and, for agents that count turns themselves, turns.
+func (t *Tracker) Spend(runID string, turns int, costUSD float64) error {
+	return t.update(runID, func(day, run *Usage) error {
+		day.Turns += turns
+		run.Turns += turns
+		day.CostUSD += costUSD
+		run.CostUSD += costUSD
+		return nil
+	})
+}
+
+// Check reports whether a new agent run may start: nil unless a daily
+// budget is exhausted.
+func (t *Tracker) Check() error {
+	st, err := t.load()
+	if err != nil {
+		return err
+	}
+	return t.check(&st.Used, &Usage{}, "")
+}
+
+// Refused returns the message of the error that refused a call in the run,
+// or "" when none was refused.
+func (t *Tracker) Refused(runID string) (string, error) {
+	st, err := t.load()
+	if err != nil {
+		return "", err
+	}
+	if r := st.Runs[runID]; r != nil {
+		return r.Refused, nil
+	}
+	return "", nil
+}
+
+// Status returns today's usage and, when runID is set, the run's.
+func (t *Tracker) Status(runID string) (day, run Usage, err error) {
+	st, err := t.load()
+	if err != nil {
+		return Usage{}, Usage{}, err
+	}
+	if r := st.Runs[runID]; r != nil {
+		run = r.Usage
+	}
+	return st.Used, run, nil
+}
+
+// Reset clears today's counters.
+func (t *Tracker) Reset() error {
+	return t.update("", func(day, run *Usage) error {
+		*day = Usage{}
+		return nil
+	})
+}
+
+// check returns the first exhausted budget. resource names what is about
+// to be used: turns and calls are refused once their limit is reached,
+// and every request is refused once the cost limit is reached.
+func (t *Tracker) check(day, run *Usage, resource string) error {
+	l := t.limits
+	switch {
+	case l.DayCostUSD > 0 && day.CostUSD >= l.DayCostUSD:
+		return &ExhaustedError{"day", "cost", day.CostUSD, l.DayCostUSD, DayCostEnv}
+	case l.RunCostUSD > 0 && run.CostUSD >= l.RunCostUSD:
+		return &ExhaustedError{"run", "cost", run.CostUSD, l.RunCostUSD, RunCostEnv}
+	case resource != "calls" && l.DayTurns > 0 && day.Turns >= l.DayTurns:
+		return &ExhaustedError{"day", "turns", float64(day.Turns), float64(l.DayTurns), DayTurnsEnv}
+	case resource == "turns" && run.Turns >= l.RunTurns:
+		return &ExhaustedError{"run", "turns", float64(run.Turns), float64(l.RunTurns), RunTurnsEnv}
+	case resource != "turns" && l.DayCalls > 0 && day.Calls >= l.DayCalls:
+		return &ExhaustedError{"day", "calls", float64(day.Calls), float64(l.DayCalls), DayCallsEnv}
+	case resource == "calls" && l.RunCalls > 0 && run.Calls >= l.RunCalls:
+		return &ExhaustedError{"run", "calls", float64(run.Calls), float64(l.RunCalls), RunCallsEnv}
+	}
+	return nil
+}
+
+// update applies fn to today's and the run's usage under the lock and
+// saves the result, including when fn refuses.
+func (t *Tracker) update(runID string, fn func(day, run *Usage) error) error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return fmt.Errorf("creating config dir: %w", err)
+	}
+	unlock, err := filelock.Acquire(t.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	st, err := t.load()
+	if err != nil {
+		return err
+	}
+	run := &runState{}
+	if runID != "" {
+		if r := st.Runs[runID]; r != nil {
+			run = r
+		}
+		st.Runs[runID] = run
+		run.Updated = t.now().UTC()
+	}
+	fnErr := fn(&st.Used, &run.Usage)
+	if fnErr != nil && runID != "" {
+		run.Refused = fnErr.Error()
+	}
+	if err := t.save(st); err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// load reads the state, starting a new day and dropping old runs as needed.
+func (t *Tracker) load() (*fileState, error) {
+	st := &fileState{}
+	data, err := os.ReadFile(t.path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading budget: %w", err)
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, st); err != nil {
+			return nil, fmt.Errorf("parsing budget: %w", err)
+		}
+	}
+	now := t.now()
+	if today := now.Format("2006-01-02"); st.Day != today {
+		st.Day = today
+		st.Used = Usage{}
+	}
+	if st.Runs == nil {
+		st.Runs = map[string]*runState{}
+	}
+	for id, r := range st.Runs {
+		if now.Sub(r.Updated) > runRetention {
+			delete(st.Runs, id)
+		}
+	}
+	return st, nil
+}
+
+func (t *Tracker) save(st *fileState) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling budget: %w", err)
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing budget: %w", err)
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		return fmt.Errorf("writing budget: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package budget
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTracker(t *testing.T, l Limits) (*Tracker, *time.Time) {
+	t.Helper()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	tr := OpenPath(filepath.Join(t.TempDir(), "budget.json"), l)
+	tr.now = func() time.Time { return now }
+	return tr, &now
+}
+
+func TestRunCalls(t *testing.T) {
+	tr, _ := newTracker(t, Limits{RunTurns: 25, RunCalls: 2})
+	for i := 0; i < 2; i++ {
+		if err := tr.Call("run1"); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	err := tr.Call("run1")
+	var ex *ExhaustedError
+	if !errors.As(err, &ex) || !errors.Is(err, ErrExhausted) || ex.Scope != "run" || ex.Resource != "calls" {
+		t.Fatalf("third call: %v", err)
+	}
+	if !strings.Contains(err.Error(), "2 of 2 bird calls used this agent run") || !strings.Contains(err.Error(), RunCallsEnv) {
+		t.Fatalf("message = %q", err)
+	}
+	if msg, _ := tr.Refused("run1"); msg != err.Error() {
+		t.Fatalf("Refused = %q", msg)
+	}
+	// Other runs have their own run budget.
+	if err := tr.Call("run2"); err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := tr.Refused("run2"); msg != "" {
+		t.Fatalf("run2 refused: %q", msg)
+	}
+	day, run, err := tr.Status("run1")
+	if err != nil || day.Calls != 3 || run.Calls != 2 {
+		t.Fatalf("status day=%+v run=%+v err=%v", day, run, err)
+	}
+}
+
+func TestDayBudgetsResetAtMidnight(t *testing.T) {
+	tr, now := newTracker(t, Limits{RunTurns: 25, DayTurns: 3, DayCostUSD: 1})
+	for i := 0; i < 3; i++ {
+		if err := tr.Turn("a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tr.Turn("b"); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("fourth turn today: %v", err)
+	}
+	if err := tr.Check(); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("Check = %v", err)
+	}
+	// Calls aren't limited by turns.
+	if err := tr.Call("b"); err != nil {
+		t.Fatalf("call: %v", err)
+	}
+
+	*now = now.Add(24 * time.Hour)
+	if err := tr.Check(); err != nil {
+		t.Fatalf("next day: %v", err)
+	}
+	if err := tr.Spend("c", 1, 1.5); err != nil {
+		t.Fatal(err)
+	}
+	err := tr.Call("c")
+	var ex *ExhaustedError
+	if !errors.As(err, &ex) || ex.Scope != "day" || ex.Resource != "cost" {
+		t.Fatalf("call after spending: %v", err)
+	}
+
+	if err := tr.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tr.Call("c"); err != nil {
+		t.Fatalf("after reset: %v", err)
+	}
+}
+
+func TestRunTurnsDefault(t *testing.T) {
+	t.Setenv(RunTurnsEnv, "")
+	t.Setenv(DayCostEnv, "2.50")
+	t.Setenv(RunCallsEnv, "nope")
+	l := LoadLimits()
+	if l.RunTurns != DefaultRunTurns || l.DayCostUSD != 2.5 || l.RunCalls != 0 {
+		t.Fatalf("limits = %+v", l)
+	}
+
+	tr, _ := newTracker(t, Limits{RunTurns: 2})
+	for i := 0; i < 2; i++ {
+		if err := tr.Turn("r"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tr.Turn("r"); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("third turn: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+// Package chatsession keeps multi-turn agent chats for the host API. Each
+// session's history is a markdown transcript in the TUI's chat directory, so
+// it shows up in the TUI history and in birdy find; the session record (owner,
+// model, in-flight turn) lives under ~/.config/birdy/sessions/.
+package chatsession
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guzus/birdy/internal/transcript"
+)
+
+var (
+	ErrNotFound = errors.New("session not found")
+	ErrBusy     = errors.New("a turn is already in progress")
+	ErrIdle     = errors.New("no turn in progress")
+)
+
+var idPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// Session is the record of a chat session.
+type Session struct {
+	ID         string    `json:"id"`
+	Owner      string    `json:"owner"`
+	Model      string    `json:"model"`
+	Title      string    `json:"title,omitempty"`
+	Turns      int       `json:"turns"`
+	Transcript string    `json:"transcript"`
+	Busy       bool      `json:"busy"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Manager creates sessions and serializes their turns.
+type Manager struct {
+	dir     string
+	chatDir string
+	mu      sync.Mutex
+	turns   map[string]context.CancelFunc
+}
+
+// DefaultDir returns ~/.config/birdy/sessions.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "sessions"), nil
+}
+
+// Open keeps session records in dir and transcripts in chatDir.
+func Open(dir, chatDir string) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating sessions dir: %w", err)
+	}
+	return &Manager{dir: dir, chatDir: chatDir, turns: map[string]context.CancelFunc{}}, nil
+}
+
+// Create starts an empty session.
+func (m *Manager) Create(owner, model string) (*Session, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generating session id: %w", err)
+	}
+	now := time.Now().UTC()
+	s := &Session{
+		ID:        hex.EncodeToString(b),
+		Owner:     owner,
+		Model:     model,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.Transcript = filepath.Join(m.chatDir, now.Local().Format("2006-01-02_150405")+"-api-"+s.ID+".md")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.save(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns a session by id.
+func (m *Manager) Get(id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.load(id)
+}
+
+// List returns the owner's sessions, or every session for an empty owner,
+// most recently updated first.
+func (m *Manager) List(owner string) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading sessions dir: %w", err)
+	}
+	out := []Session{}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok {
+			continue
+		}
+		s, err := m.load(id)
+		if err != nil || (owner != "" && s.Owner != owner) {
+			continue
+		}
+		out = append(out, *s)
+	}
+	slices.SortFunc(out, func(a, b Session) int { return b.UpdatedAt.Compare(a.UpdatedAt) })
+	return out, nil
+}
+
+// Messages returns the session's history.
+func (m *Manager) Messages(id string) ([]transcript.Message, error) {
+	s, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.messages(s)
+}
+
+func (m *Manager) messages(s *Session) ([]transcript.Message, error) {
+	raw, err := os.ReadFile(s.Transcript)
+	if os.IsNotExist(err) {
+		return []transcript.Message{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading transcript: %w", err)
+	}
+	return transcript.Parse(string(raw)), nil
+}
+
+// Turn is an in-flight exchange started by Begin.
+type Turn struct {
+	Session *Session
+	// History is the conversation including the new user message.
+	History []transcript.Message
+	ctx     context.Context
+	m       *Manager
+}
+
+// Context is canceled when the turn is canceled or the session deleted.
+func (t *Turn) Context() context.Context { return t.ctx }
+
+// Begin records the user's message and marks the session busy until End.
+// Only one turn runs per session at a time.
+func (m *Manager) Begin(ctx context.Context, id, prompt string) (*Turn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, err := m.load(id)
+	if err != nil {
+		return nil, err
+	}
+	if _, busy := m.turns[id]; busy {
+		return nil, ErrBusy
+	}
+	history, err := m.messages(s)
+	if err != nil {
+		return nil, err
+	}
+	history = append(history, transcript.Message{Role: transcript.RoleUser, Content: prompt})
+	if s.Title == "" {
+		s.Title = title(prompt)
+	}
+	if err := m.write(s, history); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	m.turns[id] = cancel
+	return &Turn{Session: s, History: history, ctx: ctx, m: m}, nil
+}
+
+// End appends the agent's reply and frees the session for the next turn.
+func (t *Turn) End(reply []transcript.Message) error {
+	m := t.m
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	id := t.Session.ID
+	if cancel, ok := m.turns[id]; ok {
+		cancel()
+		delete(m.turns, id)
+	}
+	s, err := m.load(id)
+	if err != nil {
+		return err // deleted mid-turn
+	}
+	history, err := m.messages(s)
+	if err != nil {
+		return err
+	}
+	s.Turns++
+	return m.write(s, append(history, reply...))
+}
+
+// Cancel stops the session's in-flight turn.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.load(id); err != nil {
+		return err
+	}
+	cancel, ok := m.turns[id]
+	if !ok {
+		return ErrIdle
+	}
+	cancel()
+	return nil
+}
+
+// Delete cancels any in-flight turn and removes the session and its
+// transcript.
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, err := m.load(id)
+	if err != nil {
+		return err
+	}
+	if cancel, ok := m.turns[id]; ok {
+		cancel()
+		delete(m.turns, id)
+	}
+	if err := os.Remove(s.Transcript); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing transcript: %w", err)
+	}
+	if err := os.Remove(m.path(id)); err != nil {
+		return fmt.Errorf("removing session: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) write(s *Session, history []transcript.Message) error {
+	if err := transcript.Write(s.Transcript, history, s.CreatedAt.Local(), ""); err != nil {
+		return err
+	}
+	s.UpdatedAt = time.Now().UTC()
+	return m.save(s)
+}
+
+func title(prompt string) string {
+	t := strings.Join(strings.Fields(prompt), " ")
+	if r := []rune(t); len(r) > 60 {
+		t = string(r[:57]) + "..."
+	}
+	return t
+}
+
+func (m *Manager) path(id string) string {
+	return filepath.Join(m.dir, id+".json")
+}
+
+// load reads a session record. The caller must hold m.mu.
+func (m *Manager) load(id string) (*Session, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(m.path(id))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading session: %w", err)
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parsing session %s: %w", id, err)
+	}
+	_, s.Busy = m.turns[id]
+	return &s, nil
+}
+
+// save writes a session record. The caller must hold m.mu.
+func (m *Manager) save(s *Session) error {
+	rec := *s
+	rec.Busy = false
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling session: %w", err)
+	}
+	tmp := m.path(s.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing session: %w", err)
+	}
+	if err := os.Rename(tmp, m.path(s.ID)); err != nil {
+		return fmt.Errorf("writing session: %w", err)
+	}
+	return nil
+}
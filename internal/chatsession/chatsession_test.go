@@ -0,0 +1,84 @@
+package chatsession
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/guzus/birdy/internal/transcript"
+)
+
+func TestSessionTurns(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Open(filepath.Join(dir, "sessions"), filepath.Join(dir, "chats"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := m.Create("key:a", "sonnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Create("key:b", "opus"); err != nil {
+		t.Fatal(err)
+	}
+
+	turn, err := m.Begin(context.Background(), s.ID, "first question")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Begin(context.Background(), s.ID, "again"); !errors.Is(err, ErrBusy) {
+		t.Fatalf("second Begin: %v", err)
+	}
+	if got, _ := m.Get(s.ID); !got.Busy {
+		t.Fatal("expected session to be busy during a turn")
+	}
+	if err := m.Cancel(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if turn.Context().Err() == nil {
+		t.Fatal("expected Cancel to cancel the turn context")
+	}
+	if err := turn.End([]transcript.Message{{Role: transcript.RoleAssistant, Content: "partial"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Cancel(s.ID); !errors.Is(err, ErrIdle) {
+		t.Fatalf("Cancel when idle: %v", err)
+	}
+
+	turn, err = m.Begin(context.Background(), s.ID, "second question")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(turn.History) != 3 || turn.History[2].Content != "second question" {
+		t.Fatalf("history: %+v", turn.History)
+	}
+	if err := turn.End(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := m.Get(s.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Turns != 2 || got.Title != "first question" || got.Busy {
+		t.Fatalf("session: %+v", got)
+	}
+	if list, _ := m.List("key:a"); len(list) != 1 || list[0].ID != s.ID {
+		t.Fatalf("List(key:a) = %+v", list)
+	}
+	if list, _ := m.List(""); len(list) != 2 {
+		t.Fatalf("List() = %d sessions", len(list))
+	}
+
+	if err := m.Delete(s.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(got.Transcript); !os.IsNotExist(err) {
+		t.Fatalf("transcript not removed: %v", err)
+	}
+	if _, err := m.Get(s.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package claude
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/guzus/birdy/internal/birdcmd"
+)
+
+// The claude CLI reaches birdy through one MCP server, birdy agent-exec,
+// whose tools take typed parameters instead of a shell string: there is no
+// way to chain, substitute or redirect commands.
+const (
+	mcpServerName = "birdy"
+	mcpToolPrefix = "mcp__" + mcpServerName + "__"
+)
+
+// MCPConfig returns the claude CLI --mcp-config JSON that starts
+// birdyCmd agent-exec. The server gets the BIRDY_ variables of env (run id,
+// profile, approval socket, read-only mode, ...), later ones winning, since
+// the CLI starts MCP servers with a minimal environment.
+func MCPConfig(birdyCmd string, env []string) string {
+	vars := map[string]string{}
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, "BIRDY_") {
+			vars[k] = v
+		}
+	}
+	cfg := map[string]any{
+		"mcpServers": map[string]any{
+			mcpServerName: map[string]any{
+				"type":    "stdio",
+				"command": birdyCmd,
+				"args":    []string{"agent-exec"},
+				"env":     vars,
+			},
+		},
+	}
+	b, _ := json.Marshal(cfg)
+	return string(b)
+}
+
+// MCPArgs returns the claude CLI arguments that give the agent birdy's
+// tools and nothing else. --tools "" turns off the built-in tools (Bash,
+// Read, Grep, WebFetch, ...), which would otherwise reach the config dir
+// and the network outside agent-exec's checks; --allowedTools only spares
+// birdy's tools the permission prompt.
+func MCPArgs(birdyCmd string, env []string) []string {
+	return []string{
+		"--mcp-config", MCPConfig(birdyCmd, env),
+		"--strict-mcp-config",
+		"--tools", "",
+		"--allowedTools", "mcp__" + mcpServerName,
+	}
+}
+
+// ToolUseCommand describes a claude CLI tool call as the birdy command it
+// runs, or returns "" for calls that are not birdy's.
+func ToolUseCommand(name string, input json.RawMessage) string {
+	tool, ok := strings.CutPrefix(name, mcpToolPrefix)
+	if !ok {
+		return ""
+	}
+	for _, c := range birdcmd.Commands {
+		if c.ToolName() != tool {
+			continue
+		}
+		var params map[string]any
+		_ = json.Unmarshal(input, &params)
+		args, err := c.Args(params)
+		if err != nil {
+			return "birdy " + c.Name
+		}
+		return "birdy " + strings.Join(args, " ")
+	}
+	return ""
+}
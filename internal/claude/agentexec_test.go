@@ -0,0 +1,48 @@
+package claude
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestMCPArgsOnlyBirdyTools(t *testing.T) {
+	args := MCPArgs("/bin/birdy", []string{"BIRDY_AGENT_RUN_ID=run1", "HOME=/root"})
+	flag := func(name string) (string, bool) {
+		i := slices.Index(args, name)
+		if i < 0 || i+1 >= len(args) {
+			return "", false
+		}
+		return args[i+1], true
+	}
+
+	// Built-in tools are off; only birdy's MCP server is allowed.
+	if v, ok := flag("--tools"); !ok || v != "" {
+		t.Fatalf(`want --tools "", got %q`, args)
+	}
+	if v, ok := flag("--allowedTools"); !ok || v != "mcp__birdy" {
+		t.Fatalf("want --allowedTools mcp__birdy, got %q", args)
+	}
+	if !slices.Contains(args, "--strict-mcp-config") {
+		t.Fatalf("want --strict-mcp-config, got %q", args)
+	}
+
+	raw, _ := flag("--mcp-config")
+	var cfg struct {
+		MCPServers map[string]struct {
+			Command string            `json:"command"`
+			Args    []string          `json:"args"`
+			Env     map[string]string `json:"env"`
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	srv, ok := cfg.MCPServers["birdy"]
+	if !ok || len(cfg.MCPServers) != 1 || srv.Command != "/bin/birdy" || !slices.Equal(srv.Args, []string{"agent-exec"}) {
+		t.Fatalf("unexpected mcp config %s", raw)
+	}
+	if srv.Env["BIRDY_AGENT_RUN_ID"] != "run1" || srv.Env["HOME"] != "" {
+		t.Fatalf("server env should carry only BIRDY_ vars: %v", srv.Env)
+	}
+}
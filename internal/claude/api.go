@@ -0,0 +1,368 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/untrusted"
+)
+
+const (
+	defaultAPIBaseURL = "https://api.anthropic.com"
+	anthropicVersion  = "2023-06-01"
+	apiMaxTokens      = 4096
+)
+
+// modelAliases maps the CLI's model aliases to Messages API model ids.
+var modelAliases = map[string]string{
+	"sonnet": "claude-sonnet-4-5",
+	"opus":   "claude-opus-4-1",
+	"haiku":  "claude-haiku-4-5",
+}
+
+// APIClient runs the agent against the Anthropic Messages API, executing
+// bird commands in-process with account rotation.
+type APIClient struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+	Strategy   string // rotation strategy for tool calls
+	Caller     string // recorded in the audit log
+	RunID      string
+	Approval   string           // approval socket for write commands; see package approval
+	Profile    *profile.Profile // agent profile; nil allows everything
+}
+
+// NewAPIClient reads ANTHROPIC_API_KEY and ANTHROPIC_BASE_URL.
+func NewAPIClient() *APIClient {
+	base := strings.TrimSpace(os.Getenv("ANTHROPIC_BASE_URL"))
+	if base == "" {
+		base = defaultAPIBaseURL
+	}
+	return &APIClient{
+		BaseURL:    strings.TrimRight(base, "/"),
+		APIKey:     strings.TrimSpace(os.Getenv("ANTHROPIC_API_KEY")),
+		HTTPClient: http.DefaultClient,
+		RunID:      audit.NewRunID(),
+	}
+}
+
+type apiTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type apiBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	IsError   bool            `json:"is_error,omitempty"`
+}
+
+type apiMessage struct {
+	Role    string     `json:"role"`
+	Content []apiBlock `json:"content"`
+}
+
+type apiRequest struct {
+	Model     string       `json:"model"`
+	MaxTokens int          `json:"max_tokens"`
+	System    string       `json:"system"`
+	Tools     []apiTool    `json:"tools"`
+	Messages  []apiMessage `json:"messages"`
+	Stream    bool         `json:"stream"`
+}
+
+// apiTools describes the bird tools in Messages API form.
+func apiTools(p *profile.Profile) []apiTool {
+	var tools []apiTool
+	for _, c := range birdTools(p) {
+		tools = append(tools, apiTool{Name: c.ToolName(), Description: c.Description, InputSchema: c.Schema()})
+	}
+	return tools
+}
+
+// ToolSystemPrompt describes the agent to backends that call bird commands
+// as tools rather than through a shell.
+func ToolSystemPrompt() string {
+	return `You are birdy, an AI assistant for managing X/Twitter accounts.
+Each tool runs one bird command through birdy's account pool and returns its output, usually JSON.
+
+Execution policy (aggressive tool use):
+- Default to calling tools first. Do not answer from memory when a tool can verify.
+- For factual questions, call at least one relevant read tool before answering.
+- For research/exploration tasks, call multiple tools in sequence without waiting for confirmation.
+- If output is ambiguous, call follow-up tools until you can provide a clear, evidence-based answer.
+- Ask for confirmation only before state-changing actions (tweet, reply, follow, unfollow, unbookmark).
+
+When showing tweets, format them nicely. Be concise and helpful.
+
+` + untrusted.PromptNote
+}
+
+// Stream runs the agent loop: it streams each model response, runs the
+// requested tools, and feeds their results back until the model stops
+// asking for tools or the agent budget
+// (internal/budget) runs out.
+func (c *APIClient) Stream(ctx context.Context, prompt, model string, emit func(Event)) {
+	if c.APIKey == "" {
+		emit(Event{Type: EventError, Error: "ANTHROPIC_API_KEY is not set (required with BIRDY_AGENT_BACKEND=api)"})
+		emit(Event{Type: EventDone})
+		return
+	}
+	if id, ok := modelAliases[strings.ToLower(strings.TrimSpace(model))]; ok {
+		model = id
+	}
+	tools := apiTools(c.Profile)
+	runner := &toolRunner{Strategy: c.Strategy, Caller: c.Caller, RunID: c.RunID, Approval: c.Approval, Profile: c.Profile}
+	messages := []apiMessage{{Role: "user", Content: []apiBlock{{Type: "text", Text: prompt}}}}
+
+	start := time.Now()
+	var usage Usage
+	done := func() {
+		if usage.Turns > 0 {
+			usage.CostUSD = estimateCost(model, usage)
+			usage.DurationMS = time.Since(start).Milliseconds()
+			emit(Event{Type: EventUsage, Usage: &usage})
+		}
+		emit(Event{Type: EventDone})
+	}
+
+	for turn := 1; ; turn++ {
+		if err := chargeTurn(c.RunID, c.Profile, turn); err != nil {
+			emit(Event{Type: EventError, Error: err.Error()})
+			done()
+			return
+		}
+		reply, err := c.streamMessage(ctx, apiRequest{
+			Model:     model,
+			MaxTokens: apiMaxTokens,
+			System:    ToolSystemPrompt() + ProfilePrompt(c.Profile),
+			Tools:     tools,
+			Messages:  messages,
+			Stream:    true,
+		}, emit)
+		if err != nil {
+			if ctx.Err() == nil {
+				emit(Event{Type: EventError, Error: err.Error()})
+			}
+			done()
+			return
+		}
+		u := reply.Usage.usage()
+		u.Turns = 1
+		chargeCost(c.RunID, estimateCost(model, u))
+		usage.Add(u)
+		messages = append(messages, apiMessage{Role: "assistant", Content: reply.Content})
+		if reply.StopReason != "tool_use" {
+			done()
+			return
+		}
+
+		var results []apiBlock
+		for _, block := range reply.Content {
+			if block.Type != "tool_use" {
+				continue
+			}
+			out, isError := runner.run(ctx, block.Name, block.Input, emit)
+			results = append(results, apiBlock{Type: "tool_result", ToolUseID: block.ID, Content: out, IsError: isError})
+		}
+		if err := runner.refusal(); err != nil {
+			emit(Event{Type: EventError, Error: err.Error()})
+			done()
+			return
+		}
+		messages = append(messages, apiMessage{Role: "user", Content: results})
+	}
+}
+
+// modelPrices are USD per million input and output tokens. Cache reads
+// cost a tenth of input and cache writes a quarter more.
+var modelPrices = []struct {
+	family        string
+	input, output float64
+}{
+	{"opus", 15, 75},
+	{"sonnet", 3, 15},
+	{"haiku", 1, 5},
+}
+
+// estimateCost prices usage by model family; unknown models cost zero.
+func estimateCost(model string, u Usage) float64 {
+	for _, p := range modelPrices {
+		if strings.Contains(model, p.family) {
+			in := float64(u.InputTokens) + 0.1*float64(u.CacheReadTokens) + 1.25*float64(u.CacheWriteTokens)
+			return (in*p.input + float64(u.OutputTokens)*p.output) / 1e6
+		}
+	}
+	return 0
+}
+
+// apiReply is one streamed assistant message.
+type apiReply struct {
+	Content    []apiBlock
+	StopReason string
+	Usage      apiUsage
+}
+
+// streamMessage sends one Messages API request and relays its text as
+// token and snapshot events.
+func (c *APIClient) streamMessage(ctx context.Context, req apiRequest, emit func(Event)) (apiReply, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return apiReply{}, fmt.Errorf("encoding request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return apiReply{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("X-Api-Key", c.APIKey)
+	httpReq.Header.Set("Anthropic-Version", anthropicVersion)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return apiReply{}, fmt.Errorf("calling messages api: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return apiReply{}, apiStatusError("messages api", resp)
+	}
+
+	var (
+		reply  apiReply
+		blocks []apiBlock
+		inputs []strings.Builder
+		text   strings.Builder
+		batch  tokenBatcher
+	)
+	flush := func() {
+		if t, ok := batch.flush(); ok {
+			emit(Event{Type: EventToken, Text: t})
+		}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		var ev struct {
+			Type         string   `json:"type"`
+			Index        int      `json:"index"`
+			ContentBlock apiBlock `json:"content_block"`
+			Message      struct {
+				Usage apiUsage `json:"usage"`
+			} `json:"message"`
+			Usage *apiUsage `json:"usage"`
+			Delta struct {
+				Type        string `json:"type"`
+				Text        string `json:"text"`
+				PartialJSON string `json:"partial_json"`
+				StopReason  string `json:"stop_reason"`
+			} `json:"delta"`
+			Error struct {
+				Type    string `json:"type"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &ev); err != nil {
+			continue
+		}
+
+		switch ev.Type {
+		case "message_start":
+			reply.Usage = ev.Message.Usage
+		case "content_block_start":
+			for len(blocks) <= ev.Index {
+				blocks = append(blocks, apiBlock{})
+				inputs = append(inputs, strings.Builder{})
+			}
+			block := ev.ContentBlock
+			block.Input = nil
+			blocks[ev.Index] = block
+		case "content_block_delta":
+			if ev.Index >= len(blocks) {
+				continue
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				blocks[ev.Index].Text += ev.Delta.Text
+				text.WriteString(ev.Delta.Text)
+				if t, ok := batch.add(ev.Delta.Text); ok {
+					emit(Event{Type: EventToken, Text: t})
+				}
+			case "input_json_delta":
+				inputs[ev.Index].WriteString(ev.Delta.PartialJSON)
+			}
+		case "content_block_stop":
+			if ev.Index < len(blocks) && blocks[ev.Index].Type == "tool_use" {
+				raw := strings.TrimSpace(inputs[ev.Index].String())
+				if raw == "" {
+					raw = "{}"
+				}
+				blocks[ev.Index].Input = json.RawMessage(raw)
+			}
+		case "message_delta":
+			if ev.Delta.StopReason != "" {
+				reply.StopReason = ev.Delta.StopReason
+			}
+			if ev.Usage != nil {
+				// Output tokens are cumulative for the message.
+				reply.Usage.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			flush()
+			if text.Len() > 0 {
+				emit(Event{Type: EventSnapshot, Text: text.String()})
+			}
+			reply.Content = blocks
+			return reply, nil
+		case "error":
+			flush()
+			return apiReply{}, fmt.Errorf("messages api: %s", ev.Error.Message)
+		}
+	}
+	flush()
+	if err := scanner.Err(); err != nil {
+		return apiReply{}, fmt.Errorf("reading messages stream: %w", err)
+	}
+	return apiReply{}, fmt.Errorf("messages stream ended early")
+}
+
+// apiStatusError describes a non-200 response, using the error message in
+// the body when there is one.
+func apiStatusError(label string, resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(raw, &body) == nil && body.Error.Message != "" {
+		return fmt.Errorf("%s: %s (HTTP %d)", label, body.Error.Message, resp.StatusCode)
+	}
+	return fmt.Errorf("%s: HTTP %d", label, resp.StatusCode)
+}
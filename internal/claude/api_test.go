@@ -0,0 +1,361 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/store"
+)
+
+// sseEvents renders Messages API stream events as an SSE body.
+func sseEvents(events ...string) string {
+	var b strings.Builder
+	for _, ev := range events {
+		var typ struct {
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal([]byte(ev), &typ)
+		fmt.Fprintf(&b, "event: %s\ndata: %s\n\n", typ.Type, ev)
+	}
+	return b.String()
+}
+
+var toolUseResponse = sseEvents(
+	`{"type":"message_start","message":{"id":"msg_1","role":"assistant","content":[],"usage":{"input_tokens":100,"cache_read_input_tokens":50,"output_tokens":1}}}`,
+	`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Searching."}}`,
+	`{"type":"content_block_stop","index":0}`,
+	`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"search","input":{}}}`,
+	`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"query\": \"gol"}}`,
+	`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"ang\"}"}}`,
+	`{"type":"content_block_stop","index":1}`,
+	`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}`,
+	`{"type":"message_stop"}`,
+)
+
+var textResponse = sseEvents(
+	`{"type":"message_start","message":{"id":"msg_2","role":"assistant","content":[],"usage":{"input_tokens":200,"output_tokens":1}}}`,
+	`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Found "}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"one tweet."}}`,
+	`{"type":"content_block_stop","index":0}`,
+	`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":10}}`,
+	`{"type":"message_stop"}`,
+)
+
+func setupFakeBird(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake bird is a shell script")
+	}
+	t.Setenv("HOME", t.TempDir())
+	st, err := store.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Add("main", "token", "ct0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Save(); err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(t.TempDir(), "bird")
+	script := "#!/bin/sh\necho '[{\"id\":\"1\",\"text\":\"hello golang\"}]'\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BIRDY_BIRD_PATH", bin)
+}
+
+func TestAPIClientToolLoop(t *testing.T) {
+	setupFakeBird(t)
+
+	var (
+		mu       sync.Mutex
+		requests []apiRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages" || r.Header.Get("X-Api-Key") != "test-key" || r.Header.Get("Anthropic-Version") == "" {
+			http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var req apiRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		n := len(requests)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		if n == 1 {
+			io.WriteString(w, toolUseResponse)
+		} else {
+			io.WriteString(w, textResponse)
+		}
+	}))
+	defer srv.Close()
+
+	c := &APIClient{BaseURL: srv.URL, APIKey: "test-key", Caller: "test", RunID: "run1"}
+	var events []Event
+	c.Stream(context.Background(), "find golang tweets", "sonnet", func(ev Event) {
+		events = append(events, ev)
+	})
+
+	var tools, snapshots []string
+	var text strings.Builder
+	var results []Event
+	var usage *Usage
+	for _, ev := range events {
+		switch ev.Type {
+		case EventToolUse:
+			tools = append(tools, ev.Command)
+		case EventToolResult:
+			results = append(results, ev)
+		case EventUsage:
+			usage = ev.Usage
+		case EventSnapshot:
+			snapshots = append(snapshots, ev.Text)
+		case EventToken:
+			text.WriteString(ev.Text)
+		case EventError:
+			t.Fatalf("unexpected error event: %s", ev.Error)
+		}
+	}
+	if last := events[len(events)-1]; last.Type != EventDone {
+		t.Fatalf("last event = %+v, want done", last)
+	}
+	if len(tools) != 1 || tools[0] != "birdy search golang --json" {
+		t.Fatalf("tool events = %q", tools)
+	}
+	if want := []string{"Searching.", "Found one tweet."}; strings.Join(snapshots, "|") != strings.Join(want, "|") {
+		t.Fatalf("snapshots = %q, want %q", snapshots, want)
+	}
+	if text.String() != "Searching.Found one tweet." {
+		t.Fatalf("tokens = %q", text.String())
+	}
+	if len(results) != 1 || results[0].IsError || !strings.Contains(results[0].Output, "hello golang") {
+		t.Fatalf("tool results = %+v", results)
+	}
+	if usage == nil || usage.InputTokens != 300 || usage.OutputTokens != 30 || usage.CacheReadTokens != 50 || usage.Turns != 2 || usage.CostUSD <= 0 {
+		t.Fatalf("usage = %+v", usage)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if requests[0].Model != "claude-sonnet-4-5" || !requests[0].Stream {
+		t.Fatalf("first request model=%q stream=%v", requests[0].Model, requests[0].Stream)
+	}
+	msgs := requests[1].Messages
+	if len(msgs) != 3 || msgs[1].Role != "assistant" || msgs[2].Role != "user" {
+		t.Fatalf("second request messages = %+v", msgs)
+	}
+	result := msgs[2].Content[0]
+	if result.Type != "tool_result" || result.ToolUseID != "toolu_1" || result.IsError || !strings.Contains(result.Content, "hello golang") {
+		t.Fatalf("tool result = %+v", result)
+	}
+	if string(msgs[1].Content[1].Input) != `{"query":"golang"}` {
+		t.Fatalf("replayed tool input = %s", msgs[1].Content[1].Input)
+	}
+}
+
+func TestAPIClientErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+	}))
+	defer srv.Close()
+	t.Setenv("HOME", t.TempDir())
+
+	for name, c := range map[string]*APIClient{
+		"no key":   {BaseURL: srv.URL},
+		"rejected": {BaseURL: srv.URL, APIKey: "bad"},
+	} {
+		var events []Event
+		c.Stream(context.Background(), "hi", "sonnet", func(ev Event) { events = append(events, ev) })
+		if len(events) != 2 || events[0].Type != EventError || events[1].Type != EventDone {
+			t.Fatalf("%s: events = %+v", name, events)
+		}
+		if name == "rejected" && !strings.Contains(events[0].Error, "invalid x-api-key") {
+			t.Fatalf("%s: error = %q", name, events[0].Error)
+		}
+	}
+}
+
+func TestMetaEvents(t *testing.T) {
+	lines := []string{
+		`{"type":"system","subtype":"init","session_id":"sess-1","tools":["Bash"]}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"[{\"id\":\"1\"}]"}]}}`,
+		`{"type":"user","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","is_error":true,"content":[{"type":"text","text":"exit 1"}]}]}}`,
+		`{"type":"result","subtype":"success","is_error":false,"duration_ms":4200,"num_turns":3,"result":"done","session_id":"sess-1","total_cost_usd":0.0123,"usage":{"input_tokens":10,"cache_read_input_tokens":900,"output_tokens":55}}`,
+		`{"type":"assistant","message":{"content":[{"type":"text","text":"hi"}]}}`,
+	}
+	var got []Event
+	for _, l := range lines {
+		got = append(got, MetaEvents([]byte(l))...)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d events: %+v", len(got), got)
+	}
+	if got[0].Type != EventSession || got[0].SessionID != "sess-1" {
+		t.Fatalf("session event = %+v", got[0])
+	}
+	if got[1].Type != EventToolResult || got[1].Output != `[{"id":"1"}]` || got[1].IsError {
+		t.Fatalf("tool result = %+v", got[1])
+	}
+	if got[2].Output != "exit 1" || !got[2].IsError {
+		t.Fatalf("failed tool result = %+v", got[2])
+	}
+	u := got[3].Usage
+	if got[3].Type != EventUsage || u == nil || u.InputTokens != 10 || u.CacheReadTokens != 900 || u.OutputTokens != 55 ||
+		u.CostUSD != 0.0123 || u.DurationMS != 4200 || u.Turns != 3 || got[3].SessionID != "sess-1" {
+		t.Fatalf("usage event = %+v (%+v)", got[3], u)
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	long := strings.Repeat("é", maxEventOutput)
+	out := truncateOutput(long)
+	if len(out) > maxEventOutput+64 || !strings.Contains(out, "[truncated") {
+		t.Fatalf("truncated to %d bytes", len(out))
+	}
+	if !utf8.ValidString(out) {
+		t.Fatal("truncation split a rune")
+	}
+}
+
+func TestToolRunnerApproval(t *testing.T) {
+	setupFakeBird(t)
+	srv, err := approval.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	go func() {
+		for p := range srv.Requests() {
+			if p.Args[0] == "tweet" {
+				p.Decide(approval.Decision{Action: approval.Edit, Args: []string{"tweet", "edited text"}})
+			} else {
+				p.Decide(approval.Decision{Action: approval.Deny})
+			}
+		}
+	}()
+
+	r := &toolRunner{Caller: "test", RunID: "run1", Approval: srv.Path()}
+	emit := func(Event) {}
+	out, isError := r.run(context.Background(), "tweet", json.RawMessage(`{"text":"draft"}`), emit)
+	if isError || !strings.HasPrefix(out, "The user edited the command to: birdy tweet 'edited text'\n") {
+		t.Fatalf("edited tweet: %q (error %v)", out, isError)
+	}
+	out, isError = r.run(context.Background(), "follow", json.RawMessage(`{"username":"golang"}`), emit)
+	if !isError || out != "denied by the user: birdy follow @golang" {
+		t.Fatalf("denied follow: %q (error %v)", out, isError)
+	}
+}
+
+func TestAPIClientProfile(t *testing.T) {
+	setupFakeBird(t)
+
+	var (
+		mu       sync.Mutex
+		requests []apiRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req apiRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, toolUseResponse)
+	}))
+	defer srv.Close()
+
+	p := &profile.Profile{Name: "drafter", Prompt: "Write in our brand voice.", Commands: []string{"read"}, MaxTurns: 2}
+	c := &APIClient{BaseURL: srv.URL, APIKey: "test-key", Caller: "test", RunID: "run1", Profile: p}
+	var results []Event
+	var errs []string
+	c.Stream(context.Background(), "find golang tweets", "sonnet", func(ev Event) {
+		switch ev.Type {
+		case EventToolResult:
+			results = append(results, ev)
+		case EventError:
+			errs = append(errs, ev.Error)
+		}
+	})
+
+	if len(requests) != 2 {
+		t.Fatalf("requests = %d, want 2 (max_turns)", len(requests))
+	}
+	if tools := requests[0].Tools; len(tools) != 1 || tools[0].Name != "read" {
+		t.Fatalf("tools = %+v", tools)
+	}
+	if !strings.Contains(requests[0].System, "Active profile: drafter.\nWrite in our brand voice.") ||
+		!strings.Contains(requests[0].System, "Only these birdy commands are allowed in this profile: read.") {
+		t.Fatalf("system prompt lacks the profile:\n%s", requests[0].System)
+	}
+	if len(results) != 0 {
+		t.Fatalf("search ran outside the profile: %+v", results)
+	}
+	if len(errs) != 1 || errs[0] != `agent profile "drafter" allows 2 turns per run` {
+		t.Fatalf("errors = %q", errs)
+	}
+
+	// The profile's account pool limits rotation.
+	r := &toolRunner{Caller: "test", RunID: "run2", Profile: &profile.Profile{Name: "brand", Accounts: []string{"brand"}}}
+	out, isError := r.run(context.Background(), "search", json.RawMessage(`{"query":"golang"}`), func(Event) {})
+	if !isError || out != "none of the allowed accounts are configured" {
+		t.Fatalf("pool: %q (error %v)", out, isError)
+	}
+}
+
+func TestMCPTools(t *testing.T) {
+	setupFakeBird(t)
+	t.Setenv("BIRDY_READ_ONLY", "1")
+
+	p := &profile.Profile{Name: "drafter", Commands: []string{"search", "tweet"}}
+	tools := MCPTools("", "test", "run1", "", p)
+	if len(tools) != 1 || tools[0].Name != "search" {
+		t.Fatalf("tools = %+v, want search only (profile and read-only)", tools)
+	}
+	if _, err := tools[0].Call(context.Background(), map[string]any{"query": "golang; birdy tweet pwned", "count": "5 && rm"}); err == nil || !strings.Contains(err.Error(), "count") {
+		t.Fatalf("mistyped input: %v", err)
+	}
+	out, err := tools[0].Call(context.Background(), map[string]any{"query": "golang"})
+	if err != nil || !strings.Contains(out, "<<<UNTRUSTED ") || !strings.Contains(out, "hello golang") {
+		t.Fatalf("search = %q, %v", out, err)
+	}
+}
+
+func TestToolUseCommand(t *testing.T) {
+	for _, tc := range []struct {
+		name, input, want string
+	}{
+		{"mcp__birdy__user_tweets", `{"username":"golang","count":5}`, "birdy user-tweets @golang -n 5 --json"},
+		{"mcp__birdy__search", `{"query":"-from:spam"}`, "birdy search --json -- -from:spam"},
+		{"mcp__birdy__search", `{"partial`, "birdy search"},
+		{"mcp__birdy__rm", `{}`, ""},
+		{"Bash", `{"command":"birdy home"}`, ""},
+	} {
+		if got := ToolUseCommand(tc.name, json.RawMessage(tc.input)); got != tc.want {
+			t.Errorf("ToolUseCommand(%s, %s) = %q, want %q", tc.name, tc.input, got, tc.want)
+		}
+	}
+}
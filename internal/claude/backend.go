@@ -0,0 +1,106 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/profile"
+)
+
+// BackendEnv selects the agent backend: "cli" (default) runs the claude
+// CLI, "api" calls the Anthropic Messages API and "openai" calls an
+// OpenAI-compatible chat completions endpoint.
+const BackendEnv = "BIRDY_AGENT_BACKEND"
+
+// Backend names.
+const (
+	BackendCLI    = "cli"
+	BackendAPI    = "api"
+	BackendOpenAI = "openai"
+)
+
+// Backend runs one agent prompt and reports progress as events. Stream
+// always finishes with an EventDone.
+type Backend interface {
+	Stream(ctx context.Context, prompt, model string, emit func(Event))
+}
+
+// BackendName returns the configured backend, falling back to the CLI for
+// unknown values.
+func BackendName() string {
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv(BackendEnv))); v {
+	case BackendAPI, BackendOpenAI:
+		return v
+	default:
+		return BackendCLI
+	}
+}
+
+// NewBackend returns the configured backend. birdyCmd is the command the
+// CLI agent uses to call birdy; env is added to its environment, and the
+// in-process backends read the audit caller and approval socket from it.
+// Every backend applies the agent profile, command limit and account pool
+// in env (profile.Env, profile.CommandsEnv, profile.AccountsEnv) and its
+// read-only mode (birdcmd.ReadOnlyEnv); an unknown profile fails each
+// prompt.
+func NewBackend(birdyCmd string, env []string) Backend {
+	p, err := profile.Resolve(envValue(env, profile.Env), envValue(env, profile.CommandsEnv))
+	if err == nil {
+		p, err = p.LimitAccounts(envValue(env, profile.AccountsEnv))
+	}
+	if err != nil {
+		return errorBackend{err: fmt.Errorf("loading agent profile: %w", err)}
+	}
+	if birdcmd.ReadOnlyValue(envValue(env, birdcmd.ReadOnlyEnv)) {
+		// The CLI's birdy agent-exec reads it from its own environment.
+		if p == nil {
+			p = &profile.Profile{}
+		}
+		p.ReadOnly = true
+	}
+	switch BackendName() {
+	case BackendAPI:
+		c := NewAPIClient()
+		c.Caller = envValue(env, audit.CallerEnv)
+		c.Approval = envValue(env, approval.SocketEnv)
+		c.Profile = p
+		return c
+	case BackendOpenAI:
+		c := NewOpenAIClient()
+		c.Caller = envValue(env, audit.CallerEnv)
+		c.Approval = envValue(env, approval.SocketEnv)
+		c.Profile = p
+		return c
+	default:
+		return &CLIBackend{BirdyCmd: birdyCmd, Env: env, Profile: p}
+	}
+}
+
+// Models lists the models the configured backend offers, default first.
+func Models() []string {
+	if BackendName() == BackendOpenAI {
+		return OpenAIModels()
+	}
+	return []string{"sonnet", "opus", "haiku"}
+}
+
+// DefaultModel is the first of Models.
+func DefaultModel() string {
+	return Models()[0]
+}
+
+// envValue returns the last value of key in a KEY=value list.
+func envValue(env []string, key string) string {
+	v := ""
+	for _, kv := range env {
+		if k, val, ok := strings.Cut(kv, "="); ok && k == key {
+			v = val
+		}
+	}
+	return v
+}
@@ -0,0 +1,67 @@
+package claude
+
+import (
+	"fmt"
+
+	"github.com/guzus/birdy/internal/budget"
+	"github.com/guzus/birdy/internal/profile"
+)
+
+// CheckBudget returns an error when a daily agent budget is exhausted and
+// no new run may start.
+func CheckBudget() error {
+	t, err := budget.Open()
+	if err != nil {
+		return err
+	}
+	return t.Check()
+}
+
+// MaxTurns is the per-run turn limit passed to the claude CLI: the budget's
+// limit, lowered by agent profile p's max_turns.
+func MaxTurns(p *profile.Profile) int {
+	n := budget.LoadLimits().RunTurns
+	if p != nil && p.MaxTurns > 0 && (n <= 0 || p.MaxTurns < n) {
+		n = p.MaxTurns
+	}
+	return n
+}
+
+// SettleRun charges a finished CLI run's turns and cost, which the CLI
+// reports in its result, and returns why the run's bird calls were refused,
+// if they were.
+func SettleRun(runID string, u *Usage) string {
+	t, err := budget.Open()
+	if err != nil {
+		return ""
+	}
+	if u != nil {
+		_ = t.Spend(runID, u.Turns, u.CostUSD)
+	}
+	msg, _ := t.Refused(runID)
+	return msg
+}
+
+// chargeTurn counts turn, a model turn of an in-process backend, against
+// the budget before it is requested. Turns beyond agent profile p's
+// max_turns are refused.
+func chargeTurn(runID string, p *profile.Profile, turn int) error {
+	if p != nil && p.MaxTurns > 0 && turn > p.MaxTurns {
+		return fmt.Errorf("agent profile %q allows %d turns per run", p.Name, p.MaxTurns)
+	}
+	t, err := budget.Open()
+	if err != nil {
+		return err
+	}
+	return t.Turn(runID)
+}
+
+// chargeCost adds the cost of a model reply to the budget.
+func chargeCost(runID string, costUSD float64) {
+	if costUSD <= 0 {
+		return
+	}
+	if t, err := budget.Open(); err == nil {
+		_ = t.Spend(runID, 0, costUSD)
+	}
+}
@@ -0,0 +1,322 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/profile"
+)
+
+const (
+	defaultOpenAIBaseURL = "http://localhost:11434/v1" // Ollama
+	defaultOpenAIModel   = "llama3.1"
+)
+
+// OpenAIModels returns the models listed in BIRDY_OPENAI_MODELS
+// (comma-separated), or the default local model.
+func OpenAIModels() []string {
+	var models []string
+	for _, m := range strings.Split(os.Getenv("BIRDY_OPENAI_MODELS"), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			models = append(models, m)
+		}
+	}
+	if len(models) == 0 {
+		return []string{defaultOpenAIModel}
+	}
+	return models
+}
+
+// OpenAIClient runs the agent against an OpenAI-compatible chat completions
+// endpoint with function calling, such as llama.cpp, Ollama or vLLM. Bird
+// commands run in-process with account rotation.
+type OpenAIClient struct {
+	BaseURL    string
+	APIKey     string // optional; local servers usually need none
+	HTTPClient *http.Client
+	Strategy   string // rotation strategy for tool calls
+	Caller     string // recorded in the audit log
+	RunID      string
+	Approval   string           // approval socket for write commands; see package approval
+	Profile    *profile.Profile // agent profile; nil allows everything
+}
+
+// NewOpenAIClient reads BIRDY_OPENAI_BASE_URL and BIRDY_OPENAI_API_KEY
+// (falling back to OPENAI_API_KEY).
+func NewOpenAIClient() *OpenAIClient {
+	base := strings.TrimSpace(os.Getenv("BIRDY_OPENAI_BASE_URL"))
+	if base == "" {
+		base = defaultOpenAIBaseURL
+	}
+	key := strings.TrimSpace(os.Getenv("BIRDY_OPENAI_API_KEY"))
+	if key == "" {
+		key = strings.TrimSpace(os.Getenv("OPENAI_API_KEY"))
+	}
+	return &OpenAIClient{
+		BaseURL:    strings.TrimRight(base, "/"),
+		APIKey:     key,
+		HTTPClient: http.DefaultClient,
+		RunID:      audit.NewRunID(),
+	}
+}
+
+type openAIFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+	Arguments   string         `json:"arguments,omitempty"`
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIToolCall struct {
+	ID       string         `json:"id"`
+	Type     string         `json:"type"`
+	Function openAIFunction `json:"function"`
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIRequest struct {
+	Model         string          `json:"model"`
+	Messages      []openAIMessage `json:"messages"`
+	Tools         []openAITool    `json:"tools"`
+	Stream        bool            `json:"stream"`
+	StreamOptions struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+func openAIText(s string) *string { return &s }
+
+// openAITools describes the bird tools as chat completions functions.
+func openAITools(p *profile.Profile) []openAITool {
+	var tools []openAITool
+	for _, c := range birdTools(p) {
+		tools = append(tools, openAITool{Type: "function", Function: openAIFunction{
+			Name:        c.ToolName(),
+			Description: c.Description,
+			Parameters:  c.Schema(),
+		}})
+	}
+	return tools
+}
+
+// Stream runs the agent loop: it streams each completion, runs the
+// requested tool calls, and feeds their results back until the model stops
+// calling tools or the agent budget
+// (internal/budget) runs out.
+func (c *OpenAIClient) Stream(ctx context.Context, prompt, model string, emit func(Event)) {
+	if strings.TrimSpace(model) == "" {
+		model = OpenAIModels()[0]
+	}
+	tools := openAITools(c.Profile)
+	runner := &toolRunner{Strategy: c.Strategy, Caller: c.Caller, RunID: c.RunID, Approval: c.Approval, Profile: c.Profile}
+	messages := []openAIMessage{
+		{Role: "system", Content: openAIText(ToolSystemPrompt() + ProfilePrompt(c.Profile))},
+		{Role: "user", Content: openAIText(prompt)},
+	}
+
+	start := time.Now()
+	var usage Usage
+	done := func() {
+		if usage.Turns > 0 {
+			usage.DurationMS = time.Since(start).Milliseconds()
+			emit(Event{Type: EventUsage, Usage: &usage})
+		}
+		emit(Event{Type: EventDone})
+	}
+
+	for turn := 1; ; turn++ {
+		if err := chargeTurn(c.RunID, c.Profile, turn); err != nil {
+			emit(Event{Type: EventError, Error: err.Error()})
+			done()
+			return
+		}
+		req := openAIRequest{Model: model, Messages: messages, Tools: tools, Stream: true}
+		req.StreamOptions.IncludeUsage = true
+		reply, u, err := c.streamCompletion(ctx, req, emit)
+		if err != nil {
+			if ctx.Err() == nil {
+				emit(Event{Type: EventError, Error: err.Error()})
+			}
+			done()
+			return
+		}
+		u.Turns = 1
+		usage.Add(u)
+		messages = append(messages, reply)
+		// Some local servers finish with "stop" even when they call tools,
+		// so the tool calls themselves decide whether to continue.
+		if len(reply.ToolCalls) == 0 {
+			done()
+			return
+		}
+		for _, call := range reply.ToolCalls {
+			out, _ := runner.run(ctx, call.Function.Name, json.RawMessage(call.Function.Arguments), emit)
+			messages = append(messages, openAIMessage{Role: "tool", ToolCallID: call.ID, Content: openAIText(out)})
+		}
+		if err := runner.refusal(); err != nil {
+			emit(Event{Type: EventError, Error: err.Error()})
+			done()
+			return
+		}
+	}
+}
+
+// streamCompletion sends one chat completions request and relays its text
+// as token and snapshot events. It returns the assistant message and the
+// token usage, when the server reports it.
+func (c *OpenAIClient) streamCompletion(ctx context.Context, req openAIRequest, emit func(Event)) (openAIMessage, Usage, error) {
+	reply := openAIMessage{Role: "assistant"}
+	var usage Usage
+	body, err := json.Marshal(req)
+	if err != nil {
+		return reply, usage, fmt.Errorf("encoding request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return reply, usage, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if c.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return reply, usage, fmt.Errorf("calling %s: %w", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return reply, usage, apiStatusError("chat completions", resp)
+	}
+
+	var (
+		text  strings.Builder
+		batch tokenBatcher
+		calls = map[int]*openAIToolCall{}
+	)
+	flush := func() {
+		if t, ok := batch.flush(); ok {
+			emit(Event{Type: EventToken, Text: t})
+		}
+	}
+	finish := func() openAIMessage {
+		flush()
+		if text.Len() > 0 {
+			emit(Event{Type: EventSnapshot, Text: text.String()})
+			reply.Content = openAIText(text.String())
+		}
+		indexes := make([]int, 0, len(calls))
+		for i := range calls {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+		for _, i := range indexes {
+			call := calls[i]
+			if call.ID == "" {
+				call.ID = fmt.Sprintf("call_%d", i)
+			}
+			if strings.TrimSpace(call.Function.Arguments) == "" {
+				call.Function.Arguments = "{}"
+			}
+			reply.ToolCalls = append(reply.ToolCalls, *call)
+		}
+		return reply
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return finish(), usage, nil
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content   string `json:"content"`
+					ToolCalls []struct {
+						Index    int            `json:"index"`
+						ID       string         `json:"id"`
+						Function openAIFunction `json:"function"`
+					} `json:"tool_calls"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct {
+				PromptTokens     int `json:"prompt_tokens"`
+				CompletionTokens int `json:"completion_tokens"`
+			} `json:"usage"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			continue
+		}
+		if chunk.Error != nil {
+			flush()
+			return reply, usage, fmt.Errorf("chat completions: %s", chunk.Error.Message)
+		}
+		if chunk.Usage != nil {
+			usage.InputTokens, usage.OutputTokens = chunk.Usage.PromptTokens, chunk.Usage.CompletionTokens
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		delta := chunk.Choices[0].Delta
+		if delta.Content != "" {
+			text.WriteString(delta.Content)
+			if t, ok := batch.add(delta.Content); ok {
+				emit(Event{Type: EventToken, Text: t})
+			}
+		}
+		for _, tc := range delta.ToolCalls {
+			call, ok := calls[tc.Index]
+			if !ok {
+				call = &openAIToolCall{Type: "function"}
+				calls[tc.Index] = call
+			}
+			if tc.ID != "" {
+				call.ID = tc.ID
+			}
+			if tc.Function.Name != "" {
+				call.Function.Name = tc.Function.Name
+			}
+			call.Function.Arguments += tc.Function.Arguments
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		flush()
+		return reply, usage, fmt.Errorf("reading completions stream: %w", err)
+	}
+	// Tolerate servers that close the stream without [DONE].
+	return finish(), usage, nil
+}
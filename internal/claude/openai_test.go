@@ -0,0 +1,122 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func completionChunks(chunks ...string) string {
+	var b strings.Builder
+	for _, c := range chunks {
+		b.WriteString("data: " + c + "\n\n")
+	}
+	b.WriteString("data: [DONE]\n\n")
+	return b.String()
+}
+
+var toolCallCompletion = completionChunks(
+	`{"choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_a","type":"function","function":{"name":"user_tweets","arguments":""}}]}}]}`,
+	`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"username\":"}}]}}]}`,
+	`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"golang\"}"}}]}}]}`,
+	`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+)
+
+var textCompletion = completionChunks(
+	`{"choices":[{"index":0,"delta":{"role":"assistant","content":"They tweeted "}}]}`,
+	`{"choices":[{"index":0,"delta":{"content":"about Go."}}]}`,
+	`{"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+)
+
+func TestOpenAIClientToolLoop(t *testing.T) {
+	setupFakeBird(t)
+
+	var requests []openAIRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer local" {
+			http.Error(w, `{"error":{"message":"bad request"}}`, http.StatusBadRequest)
+			return
+		}
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, req)
+		w.Header().Set("Content-Type", "text/event-stream")
+		if len(requests) == 1 {
+			io.WriteString(w, toolCallCompletion)
+		} else {
+			io.WriteString(w, textCompletion)
+		}
+	}))
+	defer srv.Close()
+
+	c := &OpenAIClient{BaseURL: srv.URL + "/v1", APIKey: "local", Caller: "test", RunID: "run1"}
+	var events []Event
+	c.Stream(context.Background(), "what does golang tweet about?", "qwen2.5", func(ev Event) {
+		events = append(events, ev)
+	})
+
+	var tools, snapshots []string
+	for _, ev := range events {
+		switch ev.Type {
+		case EventToolUse:
+			tools = append(tools, ev.Command)
+		case EventSnapshot:
+			snapshots = append(snapshots, ev.Text)
+		case EventError:
+			t.Fatalf("unexpected error event: %s", ev.Error)
+		}
+	}
+	if last := events[len(events)-1]; last.Type != EventDone {
+		t.Fatalf("last event = %+v, want done", last)
+	}
+	if len(tools) != 1 || tools[0] != "birdy user-tweets @golang --json" {
+		t.Fatalf("tool events = %q", tools)
+	}
+	if len(snapshots) != 1 || snapshots[0] != "They tweeted about Go." {
+		t.Fatalf("snapshots = %q", snapshots)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	if requests[0].Model != "qwen2.5" || len(requests[0].Tools) == 0 || requests[0].Messages[0].Role != "system" {
+		t.Fatalf("first request = %+v", requests[0])
+	}
+	msgs := requests[1].Messages
+	if len(msgs) != 4 {
+		t.Fatalf("second request has %d messages, want 4", len(msgs))
+	}
+	call := msgs[2].ToolCalls
+	if msgs[2].Role != "assistant" || len(call) != 1 || call[0].ID != "call_a" || call[0].Function.Arguments != `{"username":"golang"}` {
+		t.Fatalf("assistant message = %+v", msgs[2])
+	}
+	result := msgs[3]
+	if result.Role != "tool" || result.ToolCallID != "call_a" || result.Content == nil || !strings.Contains(*result.Content, "hello golang") {
+		t.Fatalf("tool message = %+v", result)
+	}
+}
+
+func TestModelsFollowBackend(t *testing.T) {
+	t.Setenv(BackendEnv, "")
+	if got := strings.Join(Models(), ","); got != "sonnet,opus,haiku" {
+		t.Fatalf("cli models = %q", got)
+	}
+	t.Setenv(BackendEnv, "openai")
+	t.Setenv("BIRDY_OPENAI_MODELS", " qwen2.5, llama3.1 ,")
+	if got := strings.Join(Models(), ","); got != "qwen2.5,llama3.1" {
+		t.Fatalf("openai models = %q", got)
+	}
+	if DefaultModel() != "qwen2.5" {
+		t.Fatalf("default model = %q", DefaultModel())
+	}
+	if _, ok := NewBackend("birdy", nil).(*OpenAIClient); !ok {
+		t.Fatal("openai backend not selected")
+	}
+}
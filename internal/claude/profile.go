@@ -0,0 +1,54 @@
+package claude
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/guzus/birdy/internal/profile"
+)
+
+// ProfilePrompt is the system prompt section describing agent profile p,
+// or "" without one: the profile's own text and the commands it allows.
+// A profile without a name only limits the commands.
+// birdy refuses the other commands either way; telling the agent saves it
+// the failed calls.
+func ProfilePrompt(p *profile.Profile) string {
+	if p == nil {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("\n")
+	if p.Name != "" {
+		fmt.Fprintf(&b, "\nActive profile: %s.", p.Name)
+	}
+	if p.Prompt != "" {
+		b.WriteString("\n" + p.Prompt)
+	}
+	if len(p.Commands) > 0 {
+		fmt.Fprintf(&b, "\nOnly these birdy commands are allowed in this profile: %s. Others are refused.", strings.Join(p.Commands, ", "))
+	}
+	if p.ReadOnly {
+		b.WriteString("\nThis profile is read-only: state-changing actions (tweet, reply, follow, unfollow, unbookmark) are refused.")
+	}
+	return b.String()
+}
+
+// errorBackend reports an error for every prompt, for a backend that could
+// not be set up.
+type errorBackend struct {
+	err error
+}
+
+func (b errorBackend) Stream(_ context.Context, _, _ string, emit func(Event)) {
+	emit(Event{Type: EventError, Error: b.err.Error()})
+	emit(Event{Type: EventDone})
+}
+
+// profilePool is the account pool of profile p.
+func profilePool(p *profile.Profile) []string {
+	if p == nil {
+		return nil
+	}
+	return p.Accounts
+}
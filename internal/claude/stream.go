@@ -6,26 +6,177 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/untrusted"
 )
 
 type EventType string
 
 const (
-	EventSnapshot EventType = "snapshot"
-	EventToken    EventType = "token"
-	EventToolUse  EventType = "tool_use"
-	EventError    EventType = "error"
-	EventDone     EventType = "done"
+	EventSnapshot   EventType = "snapshot"
+	EventToken      EventType = "token"
+	EventToolUse    EventType = "tool_use"
+	EventToolResult EventType = "tool_result"
+	EventUsage      EventType = "usage"
+	EventSession    EventType = "session"
+	EventError      EventType = "error"
+	EventDone       EventType = "done"
 )
 
 type Event struct {
-	Type    EventType `json:"type"`
-	Text    string    `json:"text,omitempty"`
-	Command string    `json:"command,omitempty"`
-	Error   string    `json:"error,omitempty"`
+	Type      EventType `json:"type"`
+	Text      string    `json:"text,omitempty"`
+	Command   string    `json:"command,omitempty"`
+	Output    string    `json:"output,omitempty"`   // tool_result, truncated
+	IsError   bool      `json:"is_error,omitempty"` // tool_result: the command failed
+	Usage     *Usage    `json:"usage,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CachedAt  time.Time `json:"cached_at,omitzero"` // snapshot: the answer came from the answer cache
+}
+
+// Usage is the token usage, cost and duration of one prompt. CostUSD is
+// reported by the CLI, estimated for the Messages API and zero for other
+// backends.
+type Usage struct {
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int     `json:"cache_write_tokens,omitempty"`
+	CostUSD          float64 `json:"cost_usd"`
+	DurationMS       int64   `json:"duration_ms"`
+	Turns            int     `json:"turns,omitempty"`
+}
+
+// Tokens is the total number of tokens processed.
+func (u Usage) Tokens() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
+
+// Add accumulates o into u.
+func (u *Usage) Add(o Usage) {
+	u.InputTokens += o.InputTokens
+	u.OutputTokens += o.OutputTokens
+	u.CacheReadTokens += o.CacheReadTokens
+	u.CacheWriteTokens += o.CacheWriteTokens
+	u.CostUSD += o.CostUSD
+	u.DurationMS += o.DurationMS
+	u.Turns += o.Turns
+}
+
+// maxEventOutput caps tool output carried in tool_result events.
+const maxEventOutput = 4 * 1024
+
+// truncateOutput shortens tool output for events, keeping valid UTF-8.
+func truncateOutput(s string) string {
+	if len(s) <= maxEventOutput {
+		return s
+	}
+	cut := maxEventOutput
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("\n...[truncated %d bytes]", len(s)-cut)
+}
+
+// apiUsage is the usage object of the Messages API and the CLI.
+type apiUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+func (u apiUsage) usage() Usage {
+	return Usage{
+		InputTokens:      u.InputTokens,
+		OutputTokens:     u.OutputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// MetaEvents returns the tool_result, usage and session events carried by
+// one line of the CLI's stream-json output, which the text handling in
+// Stream ignores.
+func MetaEvents(line []byte) []Event {
+	var ev struct {
+		Type      string `json:"type"`
+		Subtype   string `json:"subtype"`
+		SessionID string `json:"session_id"`
+		Message   *struct {
+			Content []struct {
+				Type    string          `json:"type"`
+				Content json.RawMessage `json:"content"`
+				IsError bool            `json:"is_error"`
+			} `json:"content"`
+		} `json:"message"`
+		Usage        *apiUsage `json:"usage"`
+		TotalCostUSD float64   `json:"total_cost_usd"`
+		DurationMS   int64     `json:"duration_ms"`
+		NumTurns     int       `json:"num_turns"`
+	}
+	if err := json.Unmarshal(line, &ev); err != nil {
+		return nil
+	}
+
+	var out []Event
+	switch ev.Type {
+	case "system":
+		if ev.Subtype == "init" && ev.SessionID != "" {
+			out = append(out, Event{Type: EventSession, SessionID: ev.SessionID})
+		}
+	case "user":
+		if ev.Message == nil {
+			return nil
+		}
+		for _, block := range ev.Message.Content {
+			if block.Type == "tool_result" {
+				out = append(out, Event{Type: EventToolResult, Output: truncateOutput(toolResultText(block.Content)), IsError: block.IsError})
+			}
+		}
+	case "result":
+		u := Usage{CostUSD: ev.TotalCostUSD, DurationMS: ev.DurationMS, Turns: ev.NumTurns}
+		if ev.Usage != nil {
+			cost := u.CostUSD
+			u = ev.Usage.usage()
+			u.CostUSD, u.DurationMS, u.Turns = cost, ev.DurationMS, ev.NumTurns
+		}
+		out = append(out, Event{Type: EventUsage, Usage: &u, SessionID: ev.SessionID})
+	}
+	return out
+}
+
+// toolResultText flattens tool_result content, which is either a string or
+// a list of text blocks.
+func toolResultText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if json.Unmarshal(raw, &blocks) != nil {
+		return ""
+	}
+	var parts []string
+	for _, b := range blocks {
+		if b.Type == "text" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
 }
 
 type cliEvent struct {
@@ -81,46 +232,54 @@ func (b *tokenBatcher) flush() (string, bool) {
 	return out, true
 }
 
-func BuildSystemPrompt(cmd string) string {
-	return fmt.Sprintf(`You are birdy, an AI assistant for managing X/Twitter accounts.
-You have access to the birdy CLI tool. Available commands:
+// BuildSystemPrompt returns the claude CLI agent's system prompt. With
+// approval, write commands wait for the user's approval over the run's
+// approval socket, so the agent issues them directly rather than asking in
+// chat.
+func BuildSystemPrompt(approval bool) string {
+	writes := "- Ask for confirmation only before state-changing actions (tweet, reply, follow, unfollow, unbookmark)."
+	if approval {
+		writes = "- State-changing actions (tweet, reply, follow, unfollow, unbookmark) are shown to the user for approval before they run, so issue them directly instead of asking in chat. If one is denied, do not retry it; if the user edited it, report what was actually run."
+	}
+	return `You are birdy, an AI assistant for managing X/Twitter accounts.
+You have access to birdy's tools, named mcp__birdy__<command> with - written as _
+(e.g. mcp__birdy__user_tweets). Each runs one bird command through birdy's
+account pool and takes the command's parameters as JSON. Available commands:
 
 Reading & Browsing:
-  %[1]s read <tweet-id>         Read a tweet by ID or URL
-  %[1]s thread <tweet-id>       Read a tweet thread
-  %[1]s search "<query>"        Search for tweets
-  %[1]s home                    Get your home timeline
-  %[1]s mentions                Get your mentions
-  %[1]s bookmarks               Get your bookmarked tweets
-  %[1]s news                    Get trending news
-  %[1]s replies <tweet-id>      Get replies to a tweet
+  read <tweet-id>         Read a tweet by ID or URL
+  thread <tweet-id>       Read a tweet thread
+  search "<query>"        Search for tweets
+  home                    Get your home timeline
+  mentions                Get your mentions
+  bookmarks               Get your bookmarked tweets
+  news                    Get trending news
+  replies <tweet-id>      Get replies to a tweet
 
 User Info:
-  %[1]s about <username>        Get account information for a user
-  %[1]s whoami                  Show current authenticated user
-  %[1]s followers <username>    Get followers for a user
-  %[1]s following <username>    Get following for a user
-  %[1]s user-tweets <username>  Get tweets for a user
-  %[1]s likes <username>        Get likes for a user
+  about <username>        Get account information for a user
+  whoami                  Show current authenticated user
+  followers [user-id]     Get your followers, or a user's by numeric ID
+  following [user-id]     Get who you follow, or who a user follows by ID
+  user-tweets <username>  Get tweets for a user
+  likes                   Get your liked tweets
 
 Actions:
-  %[1]s tweet "<text>"          Post a new tweet
-  %[1]s reply <id> "<text>"     Reply to a tweet
-  %[1]s follow <username>       Follow a user
-  %[1]s unfollow <username>     Unfollow a user
-  %[1]s unbookmark <tweet-id>   Remove a tweet from bookmarks
+  tweet "<text>"          Post a new tweet
+  reply <id> "<text>"     Reply to a tweet
+  follow <username>       Follow a user
+  unfollow <username>     Unfollow a user
+  unbookmark <tweet-id>   Remove a tweet from bookmarks
 
 Lists:
-  %[1]s lists <username>        Get lists for a user
-  %[1]s list-timeline <list-id> Get tweets from a list
+  lists [--member-of]     Get your lists, or the ones you are a member of
+  list-timeline <list-id> Get tweets from a list
 
 Other:
-  %[1]s query-ids <id1> <id2>   Query tweets by IDs
-  %[1]s check                   Check credential availability
-  %[1]s account list            List configured accounts
-  %[1]s status                  Show rotation status
+  query-ids <id1> <id2>   Query tweets by IDs
+  check                   Check credential availability
 
-IMPORTANT: Always use the exact command "%[1]s" — never use "go run .", "birdy", or any other alternative.
+IMPORTANT: Run commands only through these tools. There is no shell, and nothing else can reach the accounts.
 
 Execution policy (aggressive tool use):
 - Default to running birdy commands first. Do not answer from memory when a command can verify.
@@ -128,37 +287,85 @@ Execution policy (aggressive tool use):
 - For research/exploration tasks, run multiple commands in sequence without waiting for confirmation.
 - If output is ambiguous, run follow-up commands until you can provide a clear, evidence-based answer.
 - Include concise evidence by referencing which commands were run.
-- Ask for confirmation only before state-changing actions (tweet, reply, follow, unfollow, unbookmark).
+` + writes + `
 
 Use these commands to help the user. Run commands and explain the results clearly.
 When showing tweets, format them nicely. Be concise and helpful.
 
 When the user asks you to "dive deeper", "explore", or "browse" their timeline:
-- Start with %[1]s home to get the timeline
-- Proactively read interesting tweet threads using %[1]s thread <id>
-- Check replies on popular tweets with %[1]s replies <id>
-- Look up users who posted interesting content with %[1]s about <username>
-- Browse their recent tweets with %[1]s user-tweets <username>
+- Start with home to get the timeline
+- Proactively read interesting tweet threads using thread <id>
+- Check replies on popular tweets with replies <id>
+- Look up users who posted interesting content with about <username>
+- Browse their recent tweets with user-tweets <username>
 - Follow conversation chains and summarize the most interesting findings
-- You can chain multiple commands without asking — explore autonomously and report back`, cmd)
+- You can chain multiple commands without asking — explore autonomously and report back
+
+` + untrusted.PromptNote
 }
 
-func BuildArgs(prompt, model, birdyCmd string) []string {
-	return []string{
+// BuildArgs returns the claude CLI arguments for prompt under agent profile
+// p, which may be nil. The agent's only tools are birdy agent-exec's,
+// started with the BIRDY_ variables of env; the prompt tells the agent
+// about approvals when env carries an approval socket.
+func BuildArgs(prompt, model, birdyCmd string, p *profile.Profile, env []string) []string {
+	args := []string{
 		"-p", prompt,
 		"--model", model,
 		"--output-format", "stream-json",
 		"--verbose",
-		"--max-turns", "25",
-		"--allowedTools", fmt.Sprintf("Bash(%s *),Skill(birdy)", birdyCmd),
-		"--append-system-prompt", BuildSystemPrompt(birdyCmd),
+		"--max-turns", strconv.Itoa(MaxTurns(p)),
+		"--append-system-prompt", BuildSystemPrompt(envValue(env, approval.SocketEnv) != "") + ProfilePrompt(p),
 	}
+	return append(args, MCPArgs(birdyCmd, env)...)
+}
+
+// Stream runs prompt on the configured backend and emits events as they
+// arrive. env is added to the agent's environment.
+func Stream(ctx context.Context, prompt, model, birdyCmd string, env []string, emit func(Event)) {
+	NewBackend(birdyCmd, env).Stream(ctx, prompt, model, emit)
 }
 
-// Stream runs the claude CLI and emits events as they arrive.
-func Stream(ctx context.Context, prompt, model, birdyCmd string, emit func(Event)) {
-	args := BuildArgs(prompt, model, birdyCmd)
-	cmd := exec.CommandContext(ctx, "claude", args...)
+// CLIBackend runs the claude CLI with birdy agent-exec as its only MCP
+// server, so every bird call goes through the agent policy checks there.
+type CLIBackend struct {
+	BirdyCmd string
+	Env      []string         // added to the CLI's environment
+	Profile  *profile.Profile // agent profile; nil allows everything
+}
+
+// Stream runs the claude CLI with a fresh agent run id, which the audit log
+// and the agent budget use to attribute its birdy calls.
+func (b *CLIBackend) Stream(ctx context.Context, prompt, model string, emit func(Event)) {
+	if err := CheckBudget(); err != nil {
+		emit(Event{Type: EventError, Error: err.Error()})
+		emit(Event{Type: EventDone})
+		return
+	}
+	runID := audit.NewRunID()
+	var usage *Usage
+	settled := false
+	parentEmit := emit
+	emit = func(ev Event) {
+		switch ev.Type {
+		case EventUsage:
+			usage = ev.Usage
+		case EventDone:
+			if !settled {
+				settled = true
+				if msg := SettleRun(runID, usage); msg != "" {
+					parentEmit(Event{Type: EventError, Error: msg})
+				}
+			}
+		}
+		parentEmit(ev)
+	}
+
+	// The birdy calls apply the profile set here, so it must match the one
+	// in the prompt.
+	env := append(append(append(os.Environ(), b.Env...), audit.RunIDEnv+"="+runID), b.Profile.Environ()...)
+	cmd := exec.CommandContext(ctx, "claude", BuildArgs(prompt, model, b.BirdyCmd, b.Profile, env)...)
+	cmd.Env = env
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -217,6 +424,7 @@ func Stream(ctx context.Context, prompt, model, birdyCmd string, emit func(Event
 	}
 
 	var toolInput strings.Builder
+	var toolName string
 	inToolBlock := false
 
 	for scanner.Scan() {
@@ -232,6 +440,14 @@ func Stream(ctx context.Context, prompt, model, birdyCmd string, emit func(Event
 		if err := json.Unmarshal([]byte(line), &event); err != nil {
 			continue
 		}
+		meta := MetaEvents([]byte(line))
+		if len(meta) > 0 && event.Type != "result" {
+			flushPendingSnapshot()
+			flushPendingToken()
+			for _, ev := range meta {
+				emit(ev)
+			}
+		}
 
 		switch event.Type {
 		case "assistant":
@@ -250,13 +466,8 @@ func Stream(ctx context.Context, prompt, model, birdyCmd string, emit func(Event
 						seenToolIDs[block.ID] = true
 						flushPendingSnapshot()
 						flushPendingToken()
-						var input struct {
-							Command string `json:"command"`
-						}
-						if len(block.Input) > 0 {
-							if err := json.Unmarshal(block.Input, &input); err == nil && input.Command != "" {
-								emit(Event{Type: EventToolUse, Command: input.Command})
-							}
+						if command := ToolUseCommand(block.Name, block.Input); command != "" {
+							emit(Event{Type: EventToolUse, Command: command})
 						}
 					}
 				}
@@ -294,10 +505,12 @@ func Stream(ctx context.Context, prompt, model, birdyCmd string, emit func(Event
 			var raw struct {
 				ContentBlock struct {
 					Type string `json:"type"`
+					Name string `json:"name"`
 				} `json:"content_block"`
 			}
 			if err := json.Unmarshal([]byte(line), &raw); err == nil && raw.ContentBlock.Type == "tool_use" {
 				inToolBlock = true
+				toolName = raw.ContentBlock.Name
 				toolInput.Reset()
 			}
 
@@ -305,11 +518,8 @@ func Stream(ctx context.Context, prompt, model, birdyCmd string, emit func(Event
 			if inToolBlock {
 				flushPendingSnapshot()
 				flushPendingToken()
-				var input struct {
-					Command string `json:"command"`
-				}
-				if err := json.Unmarshal([]byte(toolInput.String()), &input); err == nil && input.Command != "" {
-					emit(Event{Type: EventToolUse, Command: input.Command})
+				if command := ToolUseCommand(toolName, json.RawMessage(toolInput.String())); command != "" {
+					emit(Event{Type: EventToolUse, Command: command})
 				}
 				inToolBlock = false
 			}
@@ -325,6 +535,9 @@ func Stream(ctx context.Context, prompt, model, birdyCmd string, emit func(Event
 			}
 			flushPendingSnapshot()
 			flushPendingToken()
+			for _, ev := range meta {
+				emit(ev)
+			}
 			_ = cmd.Wait()
 			emit(Event{Type: EventDone})
 			return
@@ -0,0 +1,164 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/budget"
+	"github.com/guzus/birdy/internal/mcp"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/untrusted"
+)
+
+// maxToolOutput caps the bird output handed back to the model.
+const maxToolOutput = 64 * 1024
+
+// birdTools returns the bird commands offered as tools. Write commands are
+// left out in read-only mode, and commands agent profile p does not allow
+// are left out too.
+func birdTools(p *profile.Profile) []birdcmd.Command {
+	var out []birdcmd.Command
+	for _, c := range birdcmd.Commands {
+		if c.Write && birdcmd.ReadOnly() || !p.Allows(c.Name, c.Write) {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// toolRunner executes tool calls as bird commands in-process, with account
+// rotation, audit logging, budget checks, the agent profile's limits and,
+// when Approval is set, the user's approval of write commands. Tweet texts,
+// user names and bios in the output are marked as untrusted content.
+type toolRunner struct {
+	Strategy string
+	Caller   string
+	RunID    string
+	Approval string           // approval socket path
+	Profile  *profile.Profile // agent profile; nil allows everything
+
+	// MCP servers run calls concurrently, so refused is guarded by mu.
+	mu      sync.Mutex
+	refused error // set once the agent budget refuses a call
+}
+
+// refusal returns why the agent budget refused a call, if it did.
+func (r *toolRunner) refusal() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.refused
+}
+
+// run executes the named tool with its JSON input and returns the text to
+// send back to the model and whether it is an error.
+func (r *toolRunner) run(ctx context.Context, name string, input json.RawMessage, emit func(Event)) (string, bool) {
+	var cmd *birdcmd.Command
+	for _, c := range birdTools(r.Profile) {
+		if c.ToolName() == name {
+			cmd = &c
+			break
+		}
+	}
+	if cmd == nil {
+		return fmt.Sprintf("unknown tool %q", name), true
+	}
+	var params map[string]any
+	if raw := strings.TrimSpace(string(input)); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &params); err != nil {
+			return "tool input must be a JSON object", true
+		}
+	}
+	args, err := cmd.Args(params)
+	if err != nil {
+		return err.Error(), true
+	}
+	emit(Event{Type: EventToolUse, Command: "birdy " + strings.Join(args, " ")})
+
+	out, isError := r.exec(ctx, args)
+	emit(Event{Type: EventToolResult, Output: truncateOutput(out), IsError: isError})
+	return out, isError
+}
+
+func (r *toolRunner) exec(ctx context.Context, args []string) (string, bool) {
+	note := ""
+	if r.Approval != "" {
+		approved, edited, err := birdcmd.ApproveAgentWrite(ctx, r.Approval, r.RunID, args)
+		if err != nil {
+			return err.Error(), true
+		}
+		if edited {
+			if err := birdcmd.CheckProfile(r.Profile, approved); err != nil {
+				return err.Error(), true
+			}
+			args = approved
+			note = "The user edited the command to: birdy " + approval.Quote(args) + "\n"
+		}
+	}
+	res, err := birdcmd.Run(ctx, birdcmd.Request{
+		Args:     args,
+		Strategy: r.Strategy,
+		Pool:     profilePool(r.Profile),
+		Caller:   r.Caller,
+		RunID:    r.RunID,
+	})
+	if errors.Is(err, budget.ErrExhausted) {
+		r.mu.Lock()
+		r.refused = err
+		r.mu.Unlock()
+	}
+	if err != nil {
+		return err.Error(), true
+	}
+	out, isError := string(untrusted.Mark([]byte(res.Stdout), untrusted.NewNonce())), false
+	if res.ExitCode != 0 {
+		out = untrusted.Sanitize(strings.TrimSpace(res.Stdout + "\n" + res.Stderr))
+		if out == "" {
+			out = fmt.Sprintf("bird exited with code %d", res.ExitCode)
+		}
+		isError = true
+	}
+	if len(out) > maxToolOutput {
+		out = out[:maxToolOutput] + fmt.Sprintf("\n...[truncated %d bytes]", len(out)-maxToolOutput)
+	}
+	return note + out, isError
+}
+
+// MCPTools returns the bird tools agent profile p allows as MCP tools, for
+// the claude CLI's birdy agent-exec server. Calls get the same checks as the
+// API backend's: typed input against the command registry, read-only mode,
+// the profile, the budget of run runID and, when socket is set, the user's
+// approval of writes.
+func MCPTools(strategy, caller, runID, socket string, p *profile.Profile) []mcp.Tool {
+	r := &toolRunner{Strategy: strategy, Caller: caller, RunID: runID, Approval: socket, Profile: p}
+	var tools []mcp.Tool
+	for _, c := range birdTools(p) {
+		tools = append(tools, mcp.Tool{
+			Name:        c.ToolName(),
+			Description: c.Description,
+			InputSchema: c.Schema(),
+			Annotations: map[string]any{
+				"readOnlyHint":  !c.Write,
+				"openWorldHint": true,
+			},
+			Call: func(ctx context.Context, input map[string]any) (string, error) {
+				args, err := c.Args(input)
+				if err != nil {
+					return "", err
+				}
+				out, isError := r.exec(ctx, args)
+				if isError {
+					return "", errors.New(out)
+				}
+				return out, nil
+			},
+		})
+	}
+	return tools
+}
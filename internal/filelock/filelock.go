@@ -0,0 +1,40 @@
+// Package filelock serializes access to files under ~/.config/birdy across
+// birdy processes with a lock file created next to them.
+package filelock
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// Wait is how long Acquire waits for a held lock.
+	Wait = 5 * time.Second
+	// Stale is the age after which a lock is treated as abandoned.
+	Stale = 30 * time.Second
+)
+
+// Acquire takes an exclusive lock by creating path and returns the function
+// that releases it.
+func Acquire(path string) (func(), error) {
+	deadline := time.Now().Add(Wait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("locking %s: %w", path, err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > Stale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is held (remove it if no birdy process is running)", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
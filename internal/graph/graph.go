@@ -0,0 +1,442 @@
+package graph
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+// Lists are the two edge lists captured in a snapshot.
+var Lists = []string{"followers", "following"}
+
+// A pending snapshot is kept as pending.json, holding only the paging
+// progress, plus one pending-<list>.jsonl per list that each page's users
+// are appended to, so a page costs a write of its own size.
+const (
+	pendingFile    = "pending.json"
+	snapshotLayout = "2006-01-02T150405Z"
+)
+
+// pendingListFile returns the name of the file collecting list's users.
+func pendingListFile(list string) string {
+	return "pending-" + list + ".jsonl"
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// Snapshot is the follower/following graph of one account at a point in time.
+type Snapshot struct {
+	Handle    string      `json:"handle"`
+	UserID    string      `json:"user_id"`
+	StartedAt time.Time   `json:"started_at"`
+	TakenAt   time.Time   `json:"taken_at,omitempty"`
+	Followers []bird.User `json:"followers"`
+	Following []bird.User `json:"following"`
+
+	// Paging progress; only meaningful while the snapshot is pending.
+	// It is all pending.json holds: the users are in the list files.
+	Cursors map[string]string `json:"cursors,omitempty"`
+	Done    map[string]bool   `json:"done,omitempty"`
+	Pages   int               `json:"pages,omitempty"`
+
+	path string
+}
+
+// Store keeps snapshots for one handle under ~/.config/birdy/graph/<handle>/.
+type Store struct {
+	Handle string
+	dir    string
+}
+
+// NormalizeHandle strips @ and lowercases a handle.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+// Open returns the snapshot store for handle in the default config dir.
+func Open(handle string) (*Store, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return OpenDir(filepath.Join(home, ".config", "birdy", "graph"), handle)
+}
+
+// OpenDir returns the snapshot store for handle under a custom root.
+func OpenDir(root, handle string) (*Store, error) {
+	h := NormalizeHandle(handle)
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(h, "_"), "_")
+	if name == "" {
+		return nil, fmt.Errorf("invalid handle %q", handle)
+	}
+	return &Store{Handle: h, dir: filepath.Join(root, name)}, nil
+}
+
+// Dir returns the directory holding this handle's snapshots.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Pending returns the in-progress snapshot, or nil if none is pending.
+func (s *Store) Pending() (*Snapshot, error) {
+	snap, err := readSnapshot(filepath.Join(s.dir, pendingFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range Lists {
+		users, err := readUsers(filepath.Join(s.dir, pendingListFile(list)))
+		if err != nil {
+			return nil, err
+		}
+		// A page whose cursor was not saved is fetched again, so its users
+		// may be in the file twice.
+		*snap.list(list) = appendUnique(*snap.list(list), users)
+	}
+	return snap, nil
+}
+
+// Begin starts a new pending snapshot, replacing any earlier one.
+func (s *Store) Begin(userID string, now time.Time) (*Snapshot, error) {
+	snap := &Snapshot{
+		Handle:    s.Handle,
+		UserID:    userID,
+		StartedAt: now.UTC(),
+		Followers: []bird.User{},
+		Following: []bird.User{},
+		Cursors:   map[string]string{},
+		Done:      map[string]bool{},
+		path:      filepath.Join(s.dir, pendingFile),
+	}
+	if err := s.Discard(); err != nil {
+		return nil, err
+	}
+	return snap, snap.savePending()
+}
+
+// Discard removes the pending snapshot, if any.
+func (s *Store) Discard() error {
+	names := []string{pendingFile}
+	for _, list := range Lists {
+		names = append(names, pendingListFile(list))
+	}
+	for _, name := range names {
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPage appends one page of users to a list and records where to resume.
+// An empty next cursor marks the list as complete. The users are written
+// before the cursor, so a crash in between only fetches the page again.
+func (snap *Snapshot) AddPage(list string, users []bird.User, next string) error {
+	dst := snap.list(list)
+	if dst == nil {
+		return fmt.Errorf("unknown list %q", list)
+	}
+	n := len(*dst)
+	*dst = appendUnique(*dst, users)
+	if err := appendUsers(filepath.Join(filepath.Dir(snap.path), pendingListFile(list)), (*dst)[n:]); err != nil {
+		return err
+	}
+	snap.Pages++
+	snap.Cursors[list] = next
+	if next == "" || len(users) == 0 {
+		snap.Done[list] = true
+		delete(snap.Cursors, list)
+	}
+	return snap.savePending()
+}
+
+// list returns the users of the named list, or nil for an unknown one.
+func (snap *Snapshot) list(name string) *[]bird.User {
+	switch name {
+	case "followers":
+		return &snap.Followers
+	case "following":
+		return &snap.Following
+	}
+	return nil
+}
+
+// Complete reports whether both lists have been fully paged.
+func (snap *Snapshot) Complete() bool {
+	for _, l := range Lists {
+		if !snap.Done[l] {
+			return false
+		}
+	}
+	return true
+}
+
+// Finish writes the completed snapshot under a dated file name and clears
+// the pending state.
+func (s *Store) Finish(snap *Snapshot, now time.Time) (string, error) {
+	if !snap.Complete() {
+		return "", fmt.Errorf("snapshot is not complete")
+	}
+	snap.TakenAt = now.UTC()
+	snap.Cursors = nil
+	snap.Done = nil
+	snap.path = filepath.Join(s.dir, snap.TakenAt.Format(snapshotLayout)+".json")
+	if err := snap.save(); err != nil {
+		return "", err
+	}
+	if err := s.Discard(); err != nil {
+		return "", err
+	}
+	return snap.path, nil
+}
+
+// List returns completed snapshots, oldest first, without loading them.
+func (s *Store) List() ([]SnapshotInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading graph dir: %w", err)
+	}
+	var out []SnapshotInfo
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || name == pendingFile || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		taken, err := time.Parse(snapshotLayout, strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		out = append(out, SnapshotInfo{Path: filepath.Join(s.dir, name), TakenAt: taken})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TakenAt.Before(out[j].TakenAt) })
+	return out, nil
+}
+
+// SnapshotInfo identifies a stored snapshot.
+type SnapshotInfo struct {
+	Path    string
+	TakenAt time.Time
+}
+
+// Load reads a stored snapshot.
+func (info SnapshotInfo) Load() (*Snapshot, error) {
+	return readSnapshot(info.Path)
+}
+
+// Latest returns the newest completed snapshot, or nil if there is none.
+func (s *Store) Latest() (*Snapshot, error) {
+	infos, err := s.List()
+	if err != nil || len(infos) == 0 {
+		return nil, err
+	}
+	return infos[len(infos)-1].Load()
+}
+
+// Baseline picks the snapshot to diff the latest one against: the newest
+// snapshot taken at or before since, or the one before the latest when
+// since is zero.
+func (s *Store) Baseline(since time.Time) (*Snapshot, error) {
+	infos, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) < 2 {
+		return nil, fmt.Errorf("need at least two snapshots of @%s to diff (have %d)", s.Handle, len(infos))
+	}
+	candidates := infos[:len(infos)-1]
+	if since.IsZero() {
+		return candidates[len(candidates)-1].Load()
+	}
+	for i := len(candidates) - 1; i >= 0; i-- {
+		if !candidates[i].TakenAt.After(since) {
+			return candidates[i].Load()
+		}
+	}
+	// Nothing that old: fall back to the oldest snapshot we have.
+	return candidates[0].Load()
+}
+
+func readSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snap := &Snapshot{path: path}
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", filepath.Base(path), err)
+	}
+	if snap.Followers == nil {
+		snap.Followers = []bird.User{}
+	}
+	if snap.Following == nil {
+		snap.Following = []bird.User{}
+	}
+	if snap.Cursors == nil {
+		snap.Cursors = map[string]string{}
+	}
+	if snap.Done == nil {
+		snap.Done = map[string]bool{}
+	}
+	return snap, nil
+}
+
+// save writes the whole snapshot to its path.
+func (snap *Snapshot) save() error {
+	return writeJSON(snap.path, snap)
+}
+
+// savePending writes the paging progress to pending.json; the users are
+// already in the list files.
+func (snap *Snapshot) savePending() error {
+	progress := *snap
+	progress.Followers, progress.Following = nil, nil
+	return writeJSON(snap.path, &progress)
+}
+
+func writeJSON(path string, snap *Snapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating graph dir: %w", err)
+	}
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling snapshot: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// appendUsers appends users to a list file, one JSON object per line.
+func appendUsers(path string, users []bird.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, u := range users {
+		if err := enc.Encode(u); err != nil {
+			return fmt.Errorf("marshaling user: %w", err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating graph dir: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	_, err = f.Write(buf.Bytes())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	return nil
+}
+
+// readUsers reads a list file. A line cut short by a crash is dropped from
+// the file, so later pages append after the last whole user.
+func readUsers(path string) ([]bird.User, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading snapshot: %w", err)
+	}
+	if whole := bytes.LastIndexByte(data, '\n') + 1; whole < len(data) {
+		if err := os.Truncate(path, int64(whole)); err != nil {
+			return nil, fmt.Errorf("repairing snapshot: %w", err)
+		}
+		data = data[:whole]
+	}
+	var users []bird.User
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		var u bird.User
+		if err := json.Unmarshal(sc.Bytes(), &u); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", filepath.Base(path), err)
+		}
+		users = append(users, u)
+	}
+	return users, sc.Err()
+}
+
+func appendUnique(dst, users []bird.User) []bird.User {
+	seen := make(map[string]struct{}, len(dst))
+	for _, u := range dst {
+		seen[u.ID] = struct{}{}
+	}
+	for _, u := range users {
+		if u.ID == "" {
+			continue
+		}
+		if _, ok := seen[u.ID]; ok {
+			continue
+		}
+		seen[u.ID] = struct{}{}
+		dst = append(dst, u)
+	}
+	return dst
+}
+
+// Diff is the change between two snapshots.
+type Diff struct {
+	Handle        string      `json:"handle"`
+	From          time.Time   `json:"from"`
+	To            time.Time   `json:"to"`
+	NewFollowers  []bird.User `json:"new_followers"`
+	LostFollowers []bird.User `json:"lost_followers"`
+	NewFollows    []bird.User `json:"new_follows"`
+	Unfollowed    []bird.User `json:"unfollowed"`
+	Followers     int         `json:"followers"`
+	Following     int         `json:"following"`
+}
+
+// Compare diffs two snapshots of the same account by user id.
+func Compare(from, to *Snapshot) Diff {
+	return Diff{
+		Handle:        to.Handle,
+		From:          from.TakenAt,
+		To:            to.TakenAt,
+		NewFollowers:  subtract(to.Followers, from.Followers),
+		LostFollowers: subtract(from.Followers, to.Followers),
+		NewFollows:    subtract(to.Following, from.Following),
+		Unfollowed:    subtract(from.Following, to.Following),
+		Followers:     len(to.Followers),
+		Following:     len(to.Following),
+	}
+}
+
+// subtract returns users in a that are not in b, keeping a's order.
+func subtract(a, b []bird.User) []bird.User {
+	in := make(map[string]struct{}, len(b))
+	for _, u := range b {
+		in[u.ID] = struct{}{}
+	}
+	out := []bird.User{}
+	for _, u := range a {
+		if _, ok := in[u.ID]; !ok {
+			out = append(out, u)
+		}
+	}
+	return out
+}
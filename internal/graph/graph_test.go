@@ -0,0 +1,223 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+func users(ids ...string) []bird.User {
+	out := make([]bird.User, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, bird.User{ID: id, Username: "u" + id})
+	}
+	return out
+}
+
+// fakeLists serves followers/following two users per page and can be told
+// to fail after a number of calls.
+type fakeLists struct {
+	lists     map[string][]string
+	calls     int
+	failAfter int
+}
+
+func (f *fakeLists) fetch(_ context.Context, args []string) (string, string, error) {
+	f.calls++
+	if f.failAfter > 0 && f.calls > f.failAfter {
+		return "", "", errors.New("rate limited")
+	}
+	start := 0
+	for i, a := range args {
+		if a == "--cursor" {
+			fmt.Sscanf(args[i+1], "p%d", &start)
+		}
+	}
+	ids := f.lists[args[0]]
+	end := start + 2
+	if end > len(ids) {
+		end = len(ids)
+	}
+	next := ""
+	if end < len(ids) {
+		next = fmt.Sprintf("p%d", end)
+	}
+	out, _ := json.Marshal(map[string]any{"users": users(ids[start:end]...), "nextCursor": next})
+	return string(out), fmt.Sprintf("acct%d", f.calls), nil
+}
+
+func TestPageResumesAfterFailure(t *testing.T) {
+	store, err := OpenDir(t.TempDir(), "@Example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed := &fakeLists{
+		lists: map[string][]string{
+			"followers": {"1", "2", "3", "4", "5"},
+			"following": {"9"},
+		},
+		failAfter: 2,
+	}
+	snap, err := store.Begin("42", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Page(context.Background(), snap, feed.fetch, PageOptions{}); err == nil {
+		t.Fatal("expected the third page to fail")
+	}
+
+	// A new run picks up the pending snapshot at the saved cursor.
+	pending, err := store.Pending()
+	if err != nil || pending == nil {
+		t.Fatalf("expected pending snapshot, got %v %v", pending, err)
+	}
+	if pending.Cursors["followers"] != "p4" || len(pending.Followers) != 4 {
+		t.Fatalf("unexpected pending progress: %+v", pending)
+	}
+	feed.failAfter = 0
+	if _, err := Page(context.Background(), pending, feed.fetch, PageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !pending.Complete() || len(pending.Followers) != 5 || len(pending.Following) != 1 {
+		t.Fatalf("expected complete snapshot, got %+v", pending)
+	}
+	if _, err := store.Finish(pending, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := store.Pending(); p != nil {
+		t.Fatal("expected pending snapshot to be cleared")
+	}
+	latest, err := store.Latest()
+	if err != nil || latest == nil || latest.UserID != "42" {
+		t.Fatalf("unexpected latest: %+v %v", latest, err)
+	}
+}
+
+func TestPendingAppendsPagesToListFiles(t *testing.T) {
+	store, _ := OpenDir(t.TempDir(), "example")
+	snap, _ := store.Begin("42", time.Now())
+	if err := snap.AddPage("followers", users("1", "2"), "p2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.AddPage("followers", users("2", "3"), "p4"); err != nil {
+		t.Fatal(err)
+	}
+
+	// pending.json holds the progress only; each page appends its new users.
+	data, err := os.ReadFile(filepath.Join(store.Dir(), pendingFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"u1"`) || !strings.Contains(string(data), `"p4"`) {
+		t.Fatalf("pending.json = %s", data)
+	}
+	list := filepath.Join(store.Dir(), pendingListFile("followers"))
+	data, _ = os.ReadFile(list)
+	if n := strings.Count(string(data), "\n"); n != 3 {
+		t.Fatalf("followers file has %d users, want 3:\n%s", n, data)
+	}
+
+	// A line cut short by a crash is dropped, and later pages still load.
+	f, _ := os.OpenFile(list, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"id":"4","user`)
+	f.Close()
+	pending, err := store.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := pending.AddPage("followers", users("4"), ""); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = store.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending.Followers) != 4 || !pending.Done["followers"] || pending.Pages != 3 {
+		t.Fatalf("unexpected pending snapshot %+v", pending)
+	}
+
+	if _, err := store.Begin("42", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(list); !os.IsNotExist(err) {
+		t.Fatalf("Begin should clear the old list files, got %v", err)
+	}
+}
+
+func TestPageRespectsBudget(t *testing.T) {
+	store, _ := OpenDir(t.TempDir(), "example")
+	feed := &fakeLists{lists: map[string][]string{"followers": {"1", "2", "3"}, "following": {}}}
+	snap, _ := store.Begin("42", time.Now())
+	n, err := Page(context.Background(), snap, feed.fetch, PageOptions{MaxPages: 1})
+	if err != nil || n != 1 || snap.Complete() {
+		t.Fatalf("expected one page and an incomplete snapshot, got n=%d err=%v", n, err)
+	}
+}
+
+func TestPageArgs(t *testing.T) {
+	got := strings.Join(PageArgs("followers", "42", "abc", 50), " ")
+	want := "followers --user 42 -n 50 --all --max-pages 1 --json --cursor abc"
+	if got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestCompareAndBaseline(t *testing.T) {
+	store, _ := OpenDir(t.TempDir(), "example")
+	save := func(at time.Time, followers, following []string) {
+		snap, err := store.Begin("42", at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := snap.AddPage("followers", users(followers...), ""); err != nil {
+			t.Fatal(err)
+		}
+		if err := snap.AddPage("following", users(following...), ""); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Finish(snap, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+	week1 := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	save(week1, []string{"1", "2"}, []string{"7"})
+	save(week1.AddDate(0, 0, 7), []string{"2", "3"}, []string{"7", "8"})
+	save(week1.AddDate(0, 0, 14), []string{"2", "3", "4"}, []string{"8"})
+
+	latest, err := store.Latest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev, err := store.Baseline(time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Compare(prev, latest)
+	if len(d.NewFollowers) != 1 || d.NewFollowers[0].ID != "4" || len(d.LostFollowers) != 0 ||
+		len(d.NewFollows) != 0 || len(d.Unfollowed) != 1 || d.Unfollowed[0].ID != "7" {
+		t.Fatalf("unexpected diff vs previous: %+v", d)
+	}
+
+	base, err := store.Baseline(week1.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = Compare(base, latest)
+	if !d.From.Equal(week1) || len(d.NewFollowers) != 2 || len(d.LostFollowers) != 1 || d.LostFollowers[0].ID != "1" ||
+		len(d.NewFollows) != 1 || d.NewFollows[0].ID != "8" {
+		t.Fatalf("unexpected diff since week 1: %+v", d)
+	}
+
+	one, _ := OpenDir(t.TempDir(), "lonely")
+	if _, err := one.Baseline(time.Time{}); err == nil {
+		t.Fatal("expected error with fewer than two snapshots")
+	}
+}
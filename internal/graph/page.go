@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+// Fetcher runs bird once (on the next account in the rotation) and returns
+// its stdout and the account that served it.
+type Fetcher func(ctx context.Context, args []string) (stdout, account string, err error)
+
+// PageOptions tunes how a pending snapshot is paged.
+type PageOptions struct {
+	Count    int           // users requested per page
+	MaxPages int           // page budget for this run; 0 means no limit
+	Delay    time.Duration // pause between pages
+	Progress func(list string, page int, account string, total int)
+}
+
+// PageArgs builds the bird argv for one page of a list. bird only prints
+// the next cursor in --all mode, so each call is capped at one page.
+func PageArgs(list, userID, cursor string, count int) []string {
+	args := []string{list, "--user", userID, "-n", strconv.Itoa(count), "--all", "--max-pages", "1", "--json"}
+	if cursor != "" {
+		args = append(args, "--cursor", cursor)
+	}
+	return args
+}
+
+// Page fetches pages for every unfinished list of snap, saving progress
+// after each page so an interrupted run resumes where it stopped. It
+// returns the number of pages fetched.
+func Page(ctx context.Context, snap *Snapshot, fetch Fetcher, opts PageOptions) (int, error) {
+	if opts.Count <= 0 {
+		opts.Count = 100
+	}
+	pages := 0
+	for _, list := range Lists {
+		for !snap.Done[list] {
+			if opts.MaxPages > 0 && pages >= opts.MaxPages {
+				return pages, nil
+			}
+			if pages > 0 && opts.Delay > 0 {
+				select {
+				case <-ctx.Done():
+					return pages, ctx.Err()
+				case <-time.After(opts.Delay):
+				}
+			}
+			stdout, account, err := fetch(ctx, PageArgs(list, snap.UserID, snap.Cursors[list], opts.Count))
+			if err != nil {
+				return pages, fmt.Errorf("fetching %s: %w", list, err)
+			}
+			users, next, err := bird.ParseUsers([]byte(stdout))
+			if err != nil {
+				return pages, fmt.Errorf("fetching %s: %w", list, err)
+			}
+			if next == snap.Cursors[list] {
+				next = ""
+			}
+			if err := snap.AddPage(list, users, next); err != nil {
+				return pages, err
+			}
+			pages++
+			if opts.Progress != nil {
+				total := len(snap.Followers)
+				if list == "following" {
+					total = len(snap.Following)
+				}
+				opts.Progress(list, pages, account, total)
+			}
+		}
+	}
+	return pages, nil
+}
+
+// ResolveUserID looks up the numeric id of handle from its recent tweets,
+// since followers/following only accept ids.
+func ResolveUserID(ctx context.Context, handle string, fetch Fetcher) (string, error) {
+	h := NormalizeHandle(handle)
+	stdout, _, err := fetch(ctx, []string{"user-tweets", "@" + h, "-n", "20", "--json"})
+	if err != nil {
+		return "", fmt.Errorf("looking up @%s: %w", h, err)
+	}
+	tweets, _, err := bird.ParseTweets([]byte(stdout))
+	if err != nil {
+		return "", fmt.Errorf("looking up @%s: %w", h, err)
+	}
+	for _, t := range tweets {
+		if t.AuthorID != "" && strings.EqualFold(t.Author.Username, h) {
+			return t.AuthorID, nil
+		}
+	}
+	return "", fmt.Errorf("could not resolve a user id for @%s from its tweets; pass --user-id", h)
+}
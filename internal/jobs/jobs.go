@@ -0,0 +1,327 @@
+// Package jobs runs long bird commands and chats in the background for the
+// host API. Each job's record and event log are persisted under
+// ~/.config/birdy/jobs/ so results survive client disconnects and restarts.
+package jobs
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Done reports whether the job has finished.
+func (s Status) Done() bool { return s != StatusRunning }
+
+// Retention is how long finished jobs are kept on disk.
+const Retention = 7 * 24 * time.Hour
+
+var (
+	ErrNotFound = errors.New("job not found")
+	ErrFinished = errors.New("job already finished")
+)
+
+var idPattern = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// Job is the persisted record of a background job.
+type Job struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	Owner      string          `json:"owner"`
+	Status     Status          `json:"status"`
+	Request    json.RawMessage `json:"request"`
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Events     int             `json:"events"`
+	CreatedAt  time.Time       `json:"created_at"`
+	FinishedAt time.Time       `json:"finished_at,omitempty"`
+}
+
+// Event is one entry in a job's event log. Seq starts at 0 and is the
+// offset clients resume from.
+type Event struct {
+	Seq  int             `json:"seq"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+	At   time.Time       `json:"at"`
+}
+
+// RunFunc does a job's work. It reports progress through emit and returns
+// the job's result.
+type RunFunc func(ctx context.Context, emit func(typ string, data any)) (result any, err error)
+
+type liveJob struct {
+	job     Job
+	cancel  context.CancelFunc
+	events  []Event
+	log     *os.File
+	changed chan struct{} // closed and replaced whenever the job changes
+}
+
+// Manager starts jobs and serves their state.
+type Manager struct {
+	dir  string
+	mu   sync.Mutex
+	live map[string]*liveJob
+}
+
+// DefaultDir returns ~/.config/birdy/jobs.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "jobs"), nil
+}
+
+// Open prepares dir, marks jobs left running by a previous process as
+// failed and removes finished jobs older than Retention.
+func Open(dir string) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating jobs dir: %w", err)
+	}
+	m := &Manager{dir: dir, live: map[string]*liveJob{}}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading jobs dir: %w", err)
+	}
+	now := time.Now()
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || !idPattern.MatchString(id) {
+			continue
+		}
+		job, err := m.load(id)
+		if err != nil {
+			continue
+		}
+		switch {
+		case job.Status == StatusRunning:
+			job.Status = StatusFailed
+			job.Error = "interrupted by host restart"
+			job.FinishedAt = now
+			_ = m.save(job)
+		case now.Sub(job.FinishedAt) > Retention:
+			_ = os.Remove(m.path(id, ".json"))
+			_ = os.Remove(m.path(id, ".events.jsonl"))
+		}
+	}
+	return m, nil
+}
+
+func (m *Manager) path(id, ext string) string {
+	return filepath.Join(m.dir, id+ext)
+}
+
+// Start records a new job and runs it in the background.
+func (m *Manager) Start(kind, owner string, request any, run RunFunc) (*Job, error) {
+	req, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("encoding job request: %w", err)
+	}
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generating job id: %w", err)
+	}
+	job := Job{
+		ID:        hex.EncodeToString(b),
+		Kind:      kind,
+		Owner:     owner,
+		Status:    StatusRunning,
+		Request:   req,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := m.save(&job); err != nil {
+		return nil, err
+	}
+	log, err := os.OpenFile(m.path(job.ID, ".events.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("creating job log: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lj := &liveJob{job: job, cancel: cancel, log: log, changed: make(chan struct{})}
+	m.mu.Lock()
+	m.live[job.ID] = lj
+	m.mu.Unlock()
+
+	go m.run(ctx, lj, run)
+	return &job, nil
+}
+
+func (m *Manager) run(ctx context.Context, lj *liveJob, run RunFunc) {
+	emit := func(typ string, data any) {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			raw = nil
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		ev := Event{Seq: len(lj.events), Type: typ, Data: raw, At: time.Now().UTC()}
+		lj.events = append(lj.events, ev)
+		lj.job.Events = len(lj.events)
+		if line, err := json.Marshal(ev); err == nil {
+			_, _ = lj.log.Write(append(line, '\n'))
+		}
+		lj.notify()
+	}
+
+	result, err := run(ctx, emit)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job := &lj.job
+	switch {
+	case ctx.Err() != nil:
+		job.Status = StatusCanceled
+	case err != nil:
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	default:
+		job.Status = StatusSucceeded
+	}
+	if result != nil {
+		if raw, mErr := json.Marshal(result); mErr == nil {
+			job.Result = raw
+		}
+	}
+	job.FinishedAt = time.Now().UTC()
+	_ = m.save(job)
+	_ = lj.log.Close()
+	lj.cancel()
+	delete(m.live, job.ID)
+	lj.notify()
+}
+
+func (lj *liveJob) notify() {
+	close(lj.changed)
+	lj.changed = make(chan struct{})
+}
+
+// Get returns a job by id.
+func (m *Manager) Get(id string) (*Job, error) {
+	m.mu.Lock()
+	if lj, ok := m.live[id]; ok {
+		job := lj.job
+		m.mu.Unlock()
+		return &job, nil
+	}
+	m.mu.Unlock()
+	return m.load(id)
+}
+
+// Cancel stops a running job. The job finishes as canceled shortly after.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if lj, ok := m.live[id]; ok {
+		lj.cancel()
+		return nil
+	}
+	if _, err := m.load(id); err != nil {
+		return err
+	}
+	return ErrFinished
+}
+
+// Events returns the job's events from offset on. While the job is still
+// running, wait is closed when more events arrive or the job finishes.
+func (m *Manager) Events(id string, offset int) (events []Event, done bool, wait <-chan struct{}, err error) {
+	if offset < 0 {
+		offset = 0
+	}
+	m.mu.Lock()
+	if lj, ok := m.live[id]; ok {
+		if offset < len(lj.events) {
+			events = append(events, lj.events[offset:]...)
+		}
+		wait = lj.changed
+		m.mu.Unlock()
+		return events, false, wait, nil
+	}
+	m.mu.Unlock()
+
+	if _, err := m.load(id); err != nil {
+		return nil, false, nil, err
+	}
+	events, err = m.readLog(id, offset)
+	return events, true, nil, err
+}
+
+func (m *Manager) readLog(id string, offset int) ([]Event, error) {
+	f, err := os.Open(m.path(id, ".events.jsonl"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading job log: %w", err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			continue
+		}
+		if ev.Seq >= offset {
+			events = append(events, ev)
+		}
+	}
+	return events, scanner.Err()
+}
+
+func (m *Manager) load(id string) (*Job, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrNotFound
+	}
+	data, err := os.ReadFile(m.path(id, ".json"))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading job: %w", err)
+	}
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("parsing job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+func (m *Manager) save(job *Job) error {
+	data, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling job: %w", err)
+	}
+	tmp := m.path(job.ID, ".json.tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing job: %w", err)
+	}
+	if err := os.Rename(tmp, m.path(job.ID, ".json")); err != nil {
+		return fmt.Errorf("writing job: %w", err)
+	}
+	return nil
+}
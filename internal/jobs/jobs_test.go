@@ -0,0 +1,134 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitDone follows a job's events until it finishes and returns them all.
+func waitDone(t *testing.T, m *Manager, id string) []Event {
+	t.Helper()
+	var all []Event
+	deadline := time.After(5 * time.Second)
+	for {
+		evs, done, wait, err := m.Events(id, len(all))
+		if err != nil {
+			t.Fatal(err)
+		}
+		all = append(all, evs...)
+		if done {
+			return all
+		}
+		select {
+		case <-wait:
+		case <-deadline:
+			t.Fatal("timed out waiting for job")
+		}
+	}
+}
+
+func TestJobRunsAndPersists(t *testing.T) {
+	dir := t.TempDir()
+	m, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := m.Start("command", "key:a", map[string]any{"args": []string{"home"}}, func(ctx context.Context, emit func(string, any)) (any, error) {
+		emit("started", nil)
+		emit("output", map[string]string{"text": "hello"})
+		return map[string]int{"exit_code": 0}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := waitDone(t, m, job.ID)
+	if len(events) != 2 || events[1].Seq != 1 || events[1].Type != "output" {
+		t.Fatalf("unexpected events %+v", events)
+	}
+
+	// A fresh manager (host restart) still serves the finished job.
+	m, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]int
+	if err := json.Unmarshal(got.Result, &result); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusSucceeded || got.Events != 2 || result["exit_code"] != 0 || len(result) != 1 {
+		t.Fatalf("unexpected job %+v", got)
+	}
+	tail, done, _, err := m.Events(job.ID, 1)
+	if err != nil || !done || len(tail) != 1 || tail[0].Type != "output" {
+		t.Fatalf("resume from offset: %+v %v %v", tail, done, err)
+	}
+	if err := m.Cancel(job.ID); !errors.Is(err, ErrFinished) {
+		t.Fatalf("expected ErrFinished, got %v", err)
+	}
+	if _, err := m.Get("0123456789abcdef"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := m.Get("../escape"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for bad id, got %v", err)
+	}
+}
+
+func TestCancelAndFailure(t *testing.T) {
+	m, _ := Open(t.TempDir())
+	started := make(chan struct{})
+	job, _ := m.Start("chat", "", nil, func(ctx context.Context, emit func(string, any)) (any, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+	if err := m.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitDone(t, m, job.ID)
+	if got, _ := m.Get(job.ID); got.Status != StatusCanceled {
+		t.Fatalf("expected canceled, got %+v", got)
+	}
+
+	failed, _ := m.Start("command", "", nil, func(ctx context.Context, emit func(string, any)) (any, error) {
+		return nil, errors.New("boom")
+	})
+	waitDone(t, m, failed.ID)
+	if got, _ := m.Get(failed.ID); got.Status != StatusFailed || got.Error != "boom" {
+		t.Fatalf("expected failed, got %+v", got)
+	}
+}
+
+func TestOpenRecoversInterruptedJobs(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-2 * Retention)
+	write := func(job Job) {
+		data, _ := json.Marshal(job)
+		if err := os.WriteFile(filepath.Join(dir, job.ID+".json"), data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(Job{ID: "00000000000000aa", Status: StatusRunning, CreatedAt: time.Now()})
+	write(Job{ID: "00000000000000bb", Status: StatusSucceeded, CreatedAt: old, FinishedAt: old})
+
+	m, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := m.Get("00000000000000aa"); err != nil || got.Status != StatusFailed {
+		t.Fatalf("expected interrupted job to be failed, got %+v %v", got, err)
+	}
+	if _, err := m.Get("00000000000000bb"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected expired job to be pruned, got %v", err)
+	}
+}
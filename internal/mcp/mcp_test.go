@@ -0,0 +1,86 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testServer() *Server {
+	return &Server{Name: "birdy", Version: "test", Tools: []Tool{{
+		Name:        "echo",
+		Description: "Echo text",
+		InputSchema: map[string]any{"type": "object"},
+		Call: func(ctx context.Context, args map[string]any) (string, error) {
+			text, _ := args["text"].(string)
+			if text == "" {
+				return "", errors.New("missing text")
+			}
+			return text, nil
+		},
+	}}}
+}
+
+func TestServeStdio(t *testing.T) {
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"text":"hi"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"nope"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"resources/list"}`,
+		`not json`,
+	}, "\n")
+	var out bytes.Buffer
+	if err := testServer().ServeStdio(context.Background(), strings.NewReader(in), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	byID := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var msg map[string]any
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		id, _ := json.Marshal(msg["id"])
+		byID[string(id)] = msg
+	}
+	if len(byID) != 7 {
+		t.Fatalf("got %d responses, want 7 (no reply to the notification):\n%s", len(byID), out.String())
+	}
+
+	result := func(id string) map[string]any {
+		r, _ := byID[id]["result"].(map[string]any)
+		return r
+	}
+	errorCode := func(id string) float64 {
+		e, _ := byID[id]["error"].(map[string]any)
+		code, _ := e["code"].(float64)
+		return code
+	}
+	if v := result("1")["protocolVersion"]; v != "2025-03-26" {
+		t.Errorf("negotiated version %v", v)
+	}
+	if tools := result("2")["tools"].([]any); len(tools) != 1 || tools[0].(map[string]any)["name"] != "echo" {
+		t.Errorf("tools/list: %v", tools)
+	}
+	if r := result("3"); r["isError"] != nil || r["content"].([]any)[0].(map[string]any)["text"] != "hi" {
+		t.Errorf("tools/call: %v", r)
+	}
+	if r := result("4"); r["isError"] != true {
+		t.Errorf("failed tool call should set isError: %v", r)
+	}
+	if code := errorCode("5"); code != codeInvalidParams {
+		t.Errorf("unknown tool: code %v", code)
+	}
+	if code := errorCode("6"); code != codeMethodNotFound {
+		t.Errorf("unknown method: code %v", code)
+	}
+	if code := errorCode("null"); code != codeParseError {
+		t.Errorf("bad json: code %v", code)
+	}
+}
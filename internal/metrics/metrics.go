@@ -0,0 +1,337 @@
+// Package metrics is a small Prometheus-compatible registry: counters,
+// gauges and histograms with labels, written in the text exposition format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds, from 50ms to 10 minutes.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300, 600}
+
+type collector interface {
+	write(w io.Writer) error
+}
+
+// Registry holds metrics in registration order.
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// Default is the registry the package-level constructors register with.
+var Default = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText writes every metric in the Prometheus text format.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := slices.Clone(r.collectors)
+	r.mu.Unlock()
+	for _, c := range collectors {
+		if err := c.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// vec stores one value per label combination.
+type vec[T any] struct {
+	name, help, kind string
+	labels           []string
+	mu               sync.Mutex
+	series           map[string]*T
+	values           map[string][]string
+	newSeries        func() *T
+}
+
+func newVec[T any](name, help, kind string, labels []string, newSeries func() *T) *vec[T] {
+	return &vec[T]{
+		name: name, help: help, kind: kind, labels: labels,
+		series: map[string]*T{}, values: map[string][]string{}, newSeries: newSeries,
+	}
+}
+
+// with returns the series for the label values, creating it if needed.
+// The caller must hold v.mu.
+func (v *vec[T]) with(lvs []string) *T {
+	if len(lvs) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", v.name, len(v.labels), len(lvs)))
+	}
+	key := strings.Join(lvs, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = v.newSeries()
+		v.series[key] = s
+		v.values[key] = slices.Clone(lvs)
+	}
+	return s
+}
+
+// get returns the series for the label values without creating it.
+// The caller must hold v.mu.
+func (v *vec[T]) get(lvs []string) (*T, bool) {
+	s, ok := v.series[strings.Join(lvs, "\xff")]
+	return s, ok
+}
+
+// sorted returns series keys in a stable order.
+func (v *vec[T]) sorted() []string {
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (v *vec[T]) header(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, v.kind)
+	return err
+}
+
+// labelString renders {a="x",b="y"} plus any extra pairs.
+func labelString(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	n := 0
+	add := func(k, v string) {
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(v))
+		b.WriteByte('"')
+		n++
+	}
+	for i, name := range names {
+		add(name, values[i])
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		add(extra[i], extra[i+1])
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// Counter is a monotonically increasing value per label combination.
+type Counter struct {
+	v *vec[float64]
+}
+
+// NewCounter registers a counter with Default.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewCounter registers a counter with r.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{v: newVec(name, help, "counter", labels, func() *float64 { return new(float64) })}
+	r.register(c)
+	return c
+}
+
+// Inc adds one to the series for the label values.
+func (c *Counter) Inc(lvs ...string) { c.Add(1, lvs...) }
+
+// Add adds delta, which must not be negative.
+func (c *Counter) Add(delta float64, lvs ...string) {
+	if delta < 0 {
+		return
+	}
+	c.v.mu.Lock()
+	defer c.v.mu.Unlock()
+	*c.v.with(lvs) += delta
+}
+
+// Value returns the current value for the label values.
+func (c *Counter) Value(lvs ...string) float64 {
+	c.v.mu.Lock()
+	defer c.v.mu.Unlock()
+	if v, ok := c.v.get(lvs); ok {
+		return *v
+	}
+	return 0
+}
+
+func (c *Counter) write(w io.Writer) error {
+	c.v.mu.Lock()
+	defer c.v.mu.Unlock()
+	if err := c.v.header(w); err != nil {
+		return err
+	}
+	for _, k := range c.v.sorted() {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", c.v.name, labelString(c.v.labels, c.v.values[k]), formatFloat(*c.v.series[k])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Gauge is a value that goes up and down, per label combination.
+type Gauge struct {
+	v *vec[float64]
+}
+
+// NewGauge registers a gauge with Default. A gauge without labels is
+// reported as 0 until it is first set.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+// NewGauge registers a gauge with r.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{v: newVec(name, help, "gauge", labels, func() *float64 { return new(float64) })}
+	if len(labels) == 0 {
+		g.v.with(nil)
+	}
+	r.register(g)
+	return g
+}
+
+// Inc adds one.
+func (g *Gauge) Inc(lvs ...string) { g.Add(1, lvs...) }
+
+// Dec subtracts one.
+func (g *Gauge) Dec(lvs ...string) { g.Add(-1, lvs...) }
+
+// Add adds delta.
+func (g *Gauge) Add(delta float64, lvs ...string) {
+	g.v.mu.Lock()
+	defer g.v.mu.Unlock()
+	*g.v.with(lvs) += delta
+}
+
+// Set replaces the value.
+func (g *Gauge) Set(value float64, lvs ...string) {
+	g.v.mu.Lock()
+	defer g.v.mu.Unlock()
+	*g.v.with(lvs) = value
+}
+
+// Value returns the current value for the label values.
+func (g *Gauge) Value(lvs ...string) float64 {
+	g.v.mu.Lock()
+	defer g.v.mu.Unlock()
+	if v, ok := g.v.get(lvs); ok {
+		return *v
+	}
+	return 0
+}
+
+func (g *Gauge) write(w io.Writer) error {
+	g.v.mu.Lock()
+	defer g.v.mu.Unlock()
+	if err := g.v.header(w); err != nil {
+		return err
+	}
+	for _, k := range g.v.sorted() {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", g.v.name, labelString(g.v.labels, g.v.values[k]), formatFloat(*g.v.series[k])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type histogramSeries struct {
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Histogram counts observations into buckets, per label combination.
+type Histogram struct {
+	v       *vec[histogramSeries]
+	buckets []float64
+}
+
+// NewHistogram registers a histogram with Default. Nil buckets means
+// DefaultBuckets.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// NewHistogram registers a histogram with r.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	h := &Histogram{buckets: buckets}
+	h.v = newVec(name, help, "histogram", labels, func() *histogramSeries {
+		return &histogramSeries{counts: make([]uint64, len(buckets))}
+	})
+	r.register(h)
+	return h
+}
+
+// Observe records one value.
+func (h *Histogram) Observe(value float64, lvs ...string) {
+	h.v.mu.Lock()
+	defer h.v.mu.Unlock()
+	s := h.v.with(lvs)
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.v.mu.Lock()
+	defer h.v.mu.Unlock()
+	if err := h.v.header(w); err != nil {
+		return err
+	}
+	for _, k := range h.v.sorted() {
+		s, lvs := h.v.series[k], h.v.values[k]
+		var cum uint64
+		for i, le := range h.buckets {
+			cum += s.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.v.name, labelString(h.v.labels, lvs, "le", formatFloat(le)), cum); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.v.name, labelString(h.v.labels, lvs, "le", "+Inf"), s.count,
+			h.v.name, labelString(h.v.labels, lvs), formatFloat(s.sum),
+			h.v.name, labelString(h.v.labels, lvs), s.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := &Registry{}
+	runs := r.NewCounter("birdy_runs_total", "Runs.", "command", "result")
+	active := r.NewGauge("birdy_active", "Active sessions.")
+	latency := r.NewHistogram("birdy_latency_seconds", "Latency.", []float64{1, 0.5}, "command")
+
+	runs.Inc("home", "ok")
+	runs.Inc("home", "ok")
+	runs.Inc(`se"arch`, "error")
+	active.Inc()
+	active.Inc()
+	active.Dec()
+	latency.Observe(0.5, "home")
+	latency.Observe(0.7, "home")
+	latency.Observe(3, "home")
+
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP birdy_runs_total Runs.
+# TYPE birdy_runs_total counter
+birdy_runs_total{command="home",result="ok"} 2
+birdy_runs_total{command="se\"arch",result="error"} 1
+# HELP birdy_active Active sessions.
+# TYPE birdy_active gauge
+birdy_active 1
+# HELP birdy_latency_seconds Latency.
+# TYPE birdy_latency_seconds histogram
+birdy_latency_seconds_bucket{command="home",le="0.5"} 1
+birdy_latency_seconds_bucket{command="home",le="1"} 2
+birdy_latency_seconds_bucket{command="home",le="+Inf"} 3
+birdy_latency_seconds_sum{command="home"} 4.2
+birdy_latency_seconds_count{command="home"} 3
+`
+	if got := b.String(); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if runs.Value("home", "ok") != 2 || runs.Value("tweet", "ok") != 0 {
+		t.Fatal("unexpected counter values")
+	}
+}
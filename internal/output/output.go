@@ -0,0 +1,354 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Format is an output format for structured results.
+type Format string
+
+const (
+	Raw      Format = "" // print bird's output untouched
+	Table    Format = "table"
+	JSON     Format = "json"
+	CSV      Format = "csv"
+	Markdown Format = "markdown"
+)
+
+// Names lists the accepted --format values.
+var Names = []string{"table", "json", "csv", "markdown"}
+
+// Parse validates a --format value. "md" is accepted for markdown.
+func Parse(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "":
+		return Raw, nil
+	case "table":
+		return Table, nil
+	case "json":
+		return JSON, nil
+	case "csv":
+		return CSV, nil
+	case "markdown", "md":
+		return Markdown, nil
+	default:
+		return Raw, fmt.Errorf("unknown format %q (valid: %s)", s, strings.Join(Names, ", "))
+	}
+}
+
+// ParseFields splits a --fields value into field paths.
+func ParseFields(s string) []string {
+	var out []string
+	for _, f := range strings.Split(s, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Kinds of result sets, used to pick default fields and markdown layout.
+const (
+	KindTweets  = "tweets"
+	KindUsers   = "users"
+	KindObjects = "objects"
+)
+
+var defaultFields = map[string][]string{
+	KindTweets: {"id", "author.username", "createdAt", "text", "likeCount", "retweetCount", "replyCount"},
+	KindUsers:  {"id", "username", "name", "followersCount", "followingCount"},
+}
+
+// Rows is a structured result set: a list of JSON-like objects.
+type Rows struct {
+	Kind   string
+	Fields []string // default columns, in order; derived from the data if empty
+	Items  []map[string]any
+}
+
+// Decode parses bird --json output: an array, a {tweets|users, nextCursor}
+// envelope, or a single object.
+func Decode(data []byte) (Rows, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return Rows{Kind: KindObjects}, nil
+	}
+
+	var items []map[string]any
+	switch data[0] {
+	case '[':
+		if err := json.Unmarshal(data, &items); err != nil {
+			return Rows{}, fmt.Errorf("parsing bird output: %w", err)
+		}
+	case '{':
+		var obj map[string]any
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return Rows{}, fmt.Errorf("parsing bird output: %w", err)
+		}
+		items = []map[string]any{obj}
+		for _, key := range []string{"tweets", "users"} {
+			list, ok := obj[key].([]any)
+			if !ok {
+				continue
+			}
+			items = items[:0]
+			for _, v := range list {
+				if m, ok := v.(map[string]any); ok {
+					items = append(items, m)
+				}
+			}
+			break
+		}
+	default:
+		return Rows{}, fmt.Errorf("bird output is not JSON")
+	}
+
+	rows := Rows{Kind: KindObjects, Items: items}
+	if len(items) > 0 {
+		switch first := items[0]; {
+		case first["text"] != nil && first["id"] != nil:
+			rows.Kind = KindTweets
+		case first["username"] != nil && first["id"] != nil:
+			rows.Kind = KindUsers
+		}
+	}
+	return rows, nil
+}
+
+// Render writes rows in format f. fields overrides the default columns;
+// dotted paths select nested values (author.username).
+//
+// JSON without explicit fields prints the objects compactly as they are.
+func Render(w io.Writer, f Format, rows Rows, fields []string) error {
+	if f == JSON && len(fields) == 0 {
+		return writeCompactJSON(w, rows.Items)
+	}
+	if len(fields) == 0 {
+		fields = rows.columns()
+	}
+	switch f {
+	case JSON:
+		return renderJSON(w, rows, fields)
+	case CSV:
+		return renderCSV(w, rows, fields)
+	case Markdown:
+		if rows.Kind == KindTweets {
+			return renderTweetCards(w, rows, fields)
+		}
+		return renderMarkdownTable(w, rows, fields)
+	case Table, Raw:
+		return renderTable(w, rows, fields)
+	default:
+		return fmt.Errorf("unknown format %q", f)
+	}
+}
+
+func (r Rows) columns() []string {
+	if len(r.Fields) > 0 {
+		return r.Fields
+	}
+	if d, ok := defaultFields[r.Kind]; ok {
+		return d
+	}
+	seen := map[string]struct{}{}
+	var cols []string
+	for _, item := range r.Items {
+		for k := range flatten("", item, map[string]any{}) {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				cols = append(cols, k)
+			}
+		}
+	}
+	sort.Strings(cols)
+	return cols
+}
+
+func flatten(prefix string, v map[string]any, out map[string]any) map[string]any {
+	for k, val := range v {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if m, ok := val.(map[string]any); ok {
+			flatten(key, m, out)
+			continue
+		}
+		out[key] = val
+	}
+	return out
+}
+
+// Lookup resolves a dotted field path in an object.
+func Lookup(item map[string]any, path string) any {
+	var cur any = item
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = m[part]
+	}
+	return cur
+}
+
+// Cell renders a value as a single table cell.
+func Cell(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		if x == float64(int64(x)) {
+			return strconv.FormatInt(int64(x), 10)
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(x)
+	case fmt.Stringer:
+		return x.String()
+	default:
+		if b, err := json.Marshal(x); err == nil {
+			return string(b)
+		}
+		return fmt.Sprint(x)
+	}
+}
+
+func renderJSON(w io.Writer, rows Rows, fields []string) error {
+	out := make([]map[string]any, 0, len(rows.Items))
+	for _, item := range rows.Items {
+		sel := make(map[string]any, len(fields))
+		for _, f := range fields {
+			sel[f] = Lookup(item, f)
+		}
+		out = append(out, sel)
+	}
+	return writeCompactJSON(w, out)
+}
+
+func writeCompactJSON(w io.Writer, items []map[string]any) error {
+	if items == nil {
+		items = []map[string]any{}
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+func renderCSV(w io.Writer, rows Rows, fields []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fields); err != nil {
+		return err
+	}
+	for _, item := range rows.Items {
+		rec := make([]string, len(fields))
+		for i, f := range fields {
+			rec[i] = Cell(Lookup(item, f))
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func renderTable(w io.Writer, rows Rows, fields []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(fields))
+	for i, f := range fields {
+		header[i] = strings.ToUpper(f)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, item := range rows.Items {
+		cells := make([]string, len(fields))
+		for i, f := range fields {
+			cells[i] = oneLine(Cell(Lookup(item, f)), 80)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func renderMarkdownTable(w io.Writer, rows Rows, fields []string) error {
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(fields, " | ") + " |\n")
+	b.WriteString("|" + strings.Repeat(" --- |", len(fields)) + "\n")
+	for _, item := range rows.Items {
+		cells := make([]string, len(fields))
+		for i, f := range fields {
+			cells[i] = strings.ReplaceAll(oneLine(Cell(Lookup(item, f)), 0), "|", `\|`)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// renderTweetCards prints each tweet as a quoted card. Fields beyond the
+// card's own (author, date, text, counts) are listed under it.
+func renderTweetCards(w io.Writer, rows Rows, fields []string) error {
+	card := map[string]bool{
+		"id": true, "author.username": true, "createdAt": true, "text": true,
+		"likeCount": true, "retweetCount": true, "replyCount": true,
+	}
+	var b strings.Builder
+	for i, item := range rows.Items {
+		if i > 0 {
+			b.WriteString("---\n\n")
+		}
+		author := Cell(Lookup(item, "author.username"))
+		id := Cell(Lookup(item, "id"))
+		fmt.Fprintf(&b, "**@%s**", author)
+		if created := Cell(Lookup(item, "createdAt")); created != "" {
+			fmt.Fprintf(&b, " · %s", created)
+		}
+		b.WriteString("\n\n")
+		for _, line := range strings.Split(strings.TrimSpace(Cell(Lookup(item, "text"))), "\n") {
+			b.WriteString("> " + line + "\n")
+		}
+		if author == "" {
+			author = "i"
+		}
+		fmt.Fprintf(&b, "\n%s replies · %s reposts · %s likes · [link](https://x.com/%s/status/%s)\n",
+			orZero(Cell(Lookup(item, "replyCount"))), orZero(Cell(Lookup(item, "retweetCount"))),
+			orZero(Cell(Lookup(item, "likeCount"))), author, id)
+		for _, f := range fields {
+			if card[f] {
+				continue
+			}
+			fmt.Fprintf(&b, "- **%s:** %s\n", f, oneLine(Cell(Lookup(item, f)), 0))
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func orZero(s string) string {
+	if s == "" {
+		return "0"
+	}
+	return s
+}
+
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if max > 3 && len([]rune(s)) > max {
+		return string([]rune(s)[:max-3]) + "..."
+	}
+	return s
+}
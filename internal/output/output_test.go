@@ -0,0 +1,96 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const tweetsPage = `{
+  "tweets": [
+    {"id": "2", "text": "second | tweet\nwith newline", "author": {"username": "bob", "name": "Bob"}, "createdAt": "Tue Feb 03 10:00:00 +0000 2026", "likeCount": 5},
+    {"id": "1", "text": "first", "author": {"username": "alice", "name": "Alice"}, "replyCount": 1}
+  ],
+  "nextCursor": "abc"
+}`
+
+func TestDecodeDetectsKinds(t *testing.T) {
+	rows, err := Decode([]byte(tweetsPage))
+	if err != nil || rows.Kind != KindTweets || len(rows.Items) != 2 {
+		t.Fatalf("tweets: %+v %v", rows, err)
+	}
+	rows, err = Decode([]byte(`[{"id":"9","username":"carol","name":"Carol","followersCount":10}]`))
+	if err != nil || rows.Kind != KindUsers || len(rows.Items) != 1 {
+		t.Fatalf("users: %+v %v", rows, err)
+	}
+	rows, err = Decode([]byte(`{"id":"5","text":"single tweet from read"}`))
+	if err != nil || rows.Kind != KindTweets || len(rows.Items) != 1 {
+		t.Fatalf("single object: %+v %v", rows, err)
+	}
+	if _, err := Decode([]byte("Posted tweet 123")); err == nil {
+		t.Fatal("expected error for non-JSON output")
+	}
+}
+
+func TestRenderFormats(t *testing.T) {
+	rows, err := Decode([]byte(tweetsPage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	render := func(f Format, fields []string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := Render(&buf, f, rows, fields); err != nil {
+			t.Fatalf("Render(%s): %v", f, err)
+		}
+		return buf.String()
+	}
+
+	table := render(Table, []string{"author.username", "likeCount", "text"})
+	lines := strings.Split(strings.TrimSpace(table), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "AUTHOR.USERNAME") || !strings.Contains(lines[1], "second | tweet with newline") {
+		t.Fatalf("unexpected table:\n%s", table)
+	}
+
+	csv := render(CSV, []string{"id", "author.username", "likeCount"})
+	if csv != "id,author.username,likeCount\n2,bob,5\n1,alice,\n" {
+		t.Fatalf("unexpected csv:\n%q", csv)
+	}
+
+	js := render(JSON, []string{"id", "author.username"})
+	if strings.TrimSpace(js) != `[{"author.username":"bob","id":"2"},{"author.username":"alice","id":"1"}]` {
+		t.Fatalf("unexpected json: %s", js)
+	}
+	if full := render(JSON, nil); strings.Count(full, "\n") != 1 || !strings.Contains(full, `"author":{"name":"Bob","username":"bob"}`) {
+		t.Fatalf("expected compact original objects, got %s", full)
+	}
+
+	md := render(Markdown, []string{"id", "author.name"})
+	if !strings.Contains(md, "**@bob** · Tue Feb 03") || !strings.Contains(md, "> with newline") ||
+		!strings.Contains(md, "[link](https://x.com/bob/status/2)") || !strings.Contains(md, "- **author.name:** Bob") {
+		t.Fatalf("unexpected markdown cards:\n%s", md)
+	}
+}
+
+func TestRenderMarkdownTableForNonTweets(t *testing.T) {
+	rows := Rows{Fields: []string{"name", "uses"}, Items: []map[string]any{{"name": "a|b", "uses": int64(3)}}}
+	var buf bytes.Buffer
+	if err := Render(&buf, Markdown, rows, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "| name | uses |\n| --- | --- |\n| a\\|b | 3 |\n" {
+		t.Fatalf("unexpected markdown table:\n%s", buf.String())
+	}
+}
+
+func TestParse(t *testing.T) {
+	if f, err := Parse("MD"); err != nil || f != Markdown {
+		t.Fatalf("got %q %v", f, err)
+	}
+	if _, err := Parse("yaml"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+	if got := ParseFields(" id, ,text "); len(got) != 2 || got[1] != "text" {
+		t.Fatalf("unexpected fields: %v", got)
+	}
+}
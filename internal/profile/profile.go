@@ -0,0 +1,214 @@
+// Package profile loads agent personas from ~/.config/birdy/profiles.json.
+// A profile adds to the agent's system prompt and limits what its runs may
+// do: which bird commands they can call, the model, the number of turns and
+// the accounts they rotate through.
+//
+//	{
+//	  "researcher": {
+//	    "prompt": "You are a research analyst. Cite tweet URLs for every claim.",
+//	    "read_only": true,
+//	    "model": "opus"
+//	  },
+//	  "drafter": {
+//	    "prompt": "Write replies in our brand voice: warm, short, no hashtags.",
+//	    "commands": ["read", "thread", "replies", "mentions", "reply"],
+//	    "accounts": ["brand"]
+//	  }
+//	}
+package profile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Env names the profile of an agent run, so the birdy processes the agent
+// starts apply it.
+const Env = "BIRDY_AGENT_PROFILE"
+
+// CommandsEnv further limits an agent run to a comma-separated list of
+// bird commands, as recipes do.
+const CommandsEnv = "BIRDY_AGENT_COMMANDS"
+
+// AccountsEnv further limits an agent run to a comma-separated account
+// pool, as API keys limited to a pool do.
+const AccountsEnv = "BIRDY_AGENT_ACCOUNTS"
+
+// ErrNotFound is returned for an unknown profile name.
+var ErrNotFound = errors.New("profile not found")
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// Profile is a named agent persona.
+type Profile struct {
+	Name     string   `json:"-"`
+	Prompt   string   `json:"prompt,omitempty"`    // added to the system prompt
+	Commands []string `json:"commands,omitempty"`  // allowed bird commands; empty allows all
+	ReadOnly bool     `json:"read_only,omitempty"` // refuse write commands
+	Model    string   `json:"model,omitempty"`     // default model
+	MaxTurns int      `json:"max_turns,omitempty"` // per-run turn limit
+	Accounts []string `json:"accounts,omitempty"`  // account pool to rotate through
+}
+
+// Allows reports whether the profile lets the agent run command; write says
+// whether the command changes account state. A nil profile allows
+// everything.
+func (p *Profile) Allows(command string, write bool) bool {
+	if p == nil {
+		return true
+	}
+	if write && p.ReadOnly {
+		return false
+	}
+	return len(p.Commands) == 0 || slices.Contains(p.Commands, command)
+}
+
+// DefaultPath returns ~/.config/birdy/profiles.json.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "profiles.json"), nil
+}
+
+// Load returns the profiles at the default path, sorted by name. A missing
+// file has no profiles.
+func Load() ([]Profile, error) {
+	p, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return LoadPath(p)
+}
+
+// LoadPath returns the profiles in the file at path, sorted by name.
+func LoadPath(path string) ([]Profile, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading profiles: %w", err)
+	}
+	var byName map[string]Profile
+	if err := json.Unmarshal(data, &byName); err != nil {
+		return nil, fmt.Errorf("parsing profiles: %w", err)
+	}
+	out := make([]Profile, 0, len(byName))
+	for name, p := range byName {
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid profile name %q: use lowercase letters, digits, - and _", name)
+		}
+		if p.MaxTurns < 0 {
+			return nil, fmt.Errorf("profile %q: max_turns must not be negative", name)
+		}
+		p.Name = name
+		p.Prompt = strings.TrimSpace(p.Prompt)
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Get returns the named profile, or nil for an empty name.
+func Get(name string) (*Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+	profiles, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return &p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+}
+
+// Resolve returns the profile of an agent run from the values of Env and
+// CommandsEnv: the named profile, limited to commands when that is set. It
+// returns nil when both are empty. The result of a command list without a
+// profile has no name.
+func Resolve(name, commands string) (*Profile, error) {
+	p, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+	only := splitList(commands)
+	if len(only) == 0 {
+		return p, nil
+	}
+	if p == nil {
+		return &Profile{Commands: only}, nil
+	}
+	if len(p.Commands) > 0 {
+		only = slices.DeleteFunc(only, func(c string) bool { return !slices.Contains(p.Commands, c) })
+		if len(only) == 0 {
+			return nil, fmt.Errorf("profile %q allows none of the commands %s", p.Name, commands)
+		}
+	}
+	p.Commands = only
+	return p, nil
+}
+
+// LimitAccounts narrows p's account pool to the comma-separated accounts,
+// the value of AccountsEnv. An empty list leaves p as it is.
+func (p *Profile) LimitAccounts(accounts string) (*Profile, error) {
+	only := splitList(accounts)
+	if len(only) == 0 {
+		return p, nil
+	}
+	if p == nil {
+		return &Profile{Accounts: only}, nil
+	}
+	if len(p.Accounts) > 0 {
+		only = slices.DeleteFunc(only, func(a string) bool { return !slices.Contains(p.Accounts, a) })
+		if len(only) == 0 {
+			return nil, fmt.Errorf("profile %q allows none of the accounts %s", p.Name, accounts)
+		}
+	}
+	limited := *p
+	limited.Accounts = only
+	return &limited, nil
+}
+
+// FromEnv returns the profile of this agent run from Env, CommandsEnv and
+// AccountsEnv, or nil when none is set.
+func FromEnv() (*Profile, error) {
+	p, err := Resolve(os.Getenv(Env), os.Getenv(CommandsEnv))
+	if err != nil {
+		return nil, err
+	}
+	return p.LimitAccounts(os.Getenv(AccountsEnv))
+}
+
+// Environ returns Env, CommandsEnv and AccountsEnv describing p, for the
+// environment of an agent run. They are set even for a nil profile, so the
+// run does not inherit another's.
+func (p *Profile) Environ() []string {
+	if p == nil {
+		return []string{Env + "=", CommandsEnv + "=", AccountsEnv + "="}
+	}
+	return []string{Env + "=" + p.Name, CommandsEnv + "=" + strings.Join(p.Commands, ","), AccountsEnv + "=" + strings.Join(p.Accounts, ",")}
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
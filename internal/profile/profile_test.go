@@ -0,0 +1,140 @@
+package profile
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeProfiles(t *testing.T, data string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".config", "birdy")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "profiles.json"), []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadAndGet(t *testing.T) {
+	writeProfiles(t, `{
+		"researcher": {"prompt": "  Cite sources.  ", "read_only": true, "model": "opus", "max_turns": 8},
+		"drafter": {"commands": ["read", "reply"], "accounts": ["brand"]}
+	}`)
+
+	profiles, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != "drafter" || profiles[1].Name != "researcher" {
+		t.Fatalf("profiles = %+v", profiles)
+	}
+
+	p, err := Get("researcher")
+	if err != nil || p.Prompt != "Cite sources." || p.Model != "opus" || p.MaxTurns != 8 {
+		t.Fatalf("Get = %+v, %v", p, err)
+	}
+	if p, err := Get(""); p != nil || err != nil {
+		t.Fatalf("empty name = %+v, %v", p, err)
+	}
+	if _, err := Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing = %v", err)
+	}
+
+	t.Setenv(Env, "drafter")
+	p, err = FromEnv()
+	if err != nil || p.Name != "drafter" || len(p.Accounts) != 1 {
+		t.Fatalf("FromEnv = %+v, %v", p, err)
+	}
+}
+
+func TestAllows(t *testing.T) {
+	var none *Profile
+	if !none.Allows("tweet", true) {
+		t.Error("nil profile should allow everything")
+	}
+	researcher := &Profile{ReadOnly: true}
+	if researcher.Allows("tweet", true) || !researcher.Allows("search", false) {
+		t.Error("read-only profile")
+	}
+	drafter := &Profile{Commands: []string{"read", "reply"}}
+	if !drafter.Allows("reply", true) || drafter.Allows("tweet", true) || drafter.Allows("search", false) {
+		t.Error("command subset")
+	}
+}
+
+func TestLoadRejectsBadNames(t *testing.T) {
+	writeProfiles(t, `{"Bad Name": {}}`)
+	if _, err := Load(); err == nil {
+		t.Fatal("expected an error for an invalid name")
+	}
+	t.Setenv("HOME", t.TempDir())
+	if profiles, err := Load(); err != nil || len(profiles) != 0 {
+		t.Fatalf("missing file = %+v, %v", profiles, err)
+	}
+}
+
+func TestResolveLimitsCommands(t *testing.T) {
+	writeProfiles(t, `{"drafter": {"commands": ["read", "reply"]}, "researcher": {"read_only": true}}`)
+
+	if p, err := Resolve("", ""); p != nil || err != nil {
+		t.Fatalf("nothing set = %+v, %v", p, err)
+	}
+	p, err := Resolve("", "search, read")
+	if err != nil || p.Name != "" || !slices.Equal(p.Commands, []string{"search", "read"}) {
+		t.Fatalf("commands only = %+v, %v", p, err)
+	}
+	p, err = Resolve("drafter", "search,read")
+	if err != nil || p.Name != "drafter" || !slices.Equal(p.Commands, []string{"read"}) {
+		t.Fatalf("intersection = %+v, %v", p, err)
+	}
+	if _, err := Resolve("drafter", "search"); err == nil {
+		t.Fatal("expected an error for no common commands")
+	}
+	p, err = Resolve("researcher", "tweet,search")
+	if err != nil || !p.ReadOnly || p.Allows("tweet", true) || !p.Allows("search", false) {
+		t.Fatalf("read-only with commands = %+v, %v", p, err)
+	}
+
+	env := p.Environ()
+	if !slices.Equal(env, []string{Env + "=researcher", CommandsEnv + "=tweet,search", AccountsEnv + "="}) {
+		t.Fatalf("Environ = %q", env)
+	}
+	var none *Profile
+	if env := none.Environ(); !slices.Equal(env, []string{Env + "=", CommandsEnv + "=", AccountsEnv + "="}) {
+		t.Fatalf("nil Environ = %q", env)
+	}
+}
+
+func TestLimitAccounts(t *testing.T) {
+	var none *Profile
+	if p, err := none.LimitAccounts(""); p != nil || err != nil {
+		t.Fatalf("nothing set = %+v, %v", p, err)
+	}
+	p, err := none.LimitAccounts("brand, alt")
+	if err != nil || !slices.Equal(p.Accounts, []string{"brand", "alt"}) {
+		t.Fatalf("accounts only = %+v, %v", p, err)
+	}
+
+	drafter := &Profile{Name: "drafter", Accounts: []string{"brand", "main"}}
+	p, err = drafter.LimitAccounts("alt,brand")
+	if err != nil || p.Name != "drafter" || !slices.Equal(p.Accounts, []string{"brand"}) {
+		t.Fatalf("intersection = %+v, %v", p, err)
+	}
+	if !slices.Equal(drafter.Accounts, []string{"brand", "main"}) {
+		t.Fatalf("the profile itself changed: %+v", drafter)
+	}
+	if _, err := drafter.LimitAccounts("alt"); err == nil {
+		t.Fatal("expected an error for no common accounts")
+	}
+
+	t.Setenv(AccountsEnv, "alt")
+	if p, err := FromEnv(); err != nil || !slices.Equal(p.Accounts, []string{"alt"}) {
+		t.Fatalf("FromEnv = %+v, %v", p, err)
+	}
+}
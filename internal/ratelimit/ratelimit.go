@@ -0,0 +1,208 @@
+// Package ratelimit throttles host API clients with a per-minute request
+// window and a daily quota. Daily counts are persisted so a restart does not
+// reset them.
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const window = time.Minute
+
+// Limit caps one client. Zero disables that part of the limit.
+type Limit struct {
+	PerMinute int
+	PerDay    int
+}
+
+// Subject is one client identity a request is charged to, such as an API
+// key or an IP address.
+type Subject struct {
+	ID    string
+	Limit Limit
+}
+
+// Decision is the outcome of Allow and carries what the X-RateLimit-*
+// headers report.
+type Decision struct {
+	Allowed    bool
+	Subject    string // the subject that was denied, or the most constrained one
+	Limit      int    // 0 when no limit applies
+	Remaining  int
+	Reset      time.Time     // when Remaining is replenished
+	RetryAfter time.Duration // set when denied
+	Daily      bool          // whether Limit is the daily quota
+}
+
+type counter struct {
+	start time.Time
+	count int
+}
+
+// Limiter tracks per-minute windows in memory and daily counts in a
+// persisted quota file.
+type Limiter struct {
+	mu      sync.Mutex
+	windows map[string]*counter
+	path    string
+	day     string
+	counts  map[string]int
+}
+
+type quotaFile struct {
+	Day    string         `json:"day"`
+	Counts map[string]int `json:"counts"`
+}
+
+// DefaultPath returns ~/.config/birdy/quota.json.
+func DefaultPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "quota.json"), nil
+}
+
+// Open loads the daily counts at path. An empty path keeps them in memory.
+func Open(path string) (*Limiter, error) {
+	l := &Limiter{windows: map[string]*counter{}, path: path, counts: map[string]int{}}
+	if path == "" {
+		return l, nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading quota: %w", err)
+	}
+	var f quotaFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parsing quota: %w", err)
+	}
+	l.day = f.Day
+	if f.Counts != nil {
+		l.counts = f.Counts
+	}
+	return l, nil
+}
+
+// Allow charges one request to every subject, or to none of them if any
+// subject is over its limit.
+func (l *Limiter) Allow(now time.Time, subjects ...Subject) (Decision, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := now.UTC().Format("2006-01-02")
+	if day != l.day {
+		l.day = day
+		l.counts = map[string]int{}
+	}
+	l.prune(now)
+
+	best := Decision{Allowed: true}
+	for _, s := range subjects {
+		if s.Limit.PerMinute > 0 {
+			c := l.windows[s.ID]
+			if c == nil || now.Sub(c.start) >= window {
+				c = &counter{start: now}
+				l.windows[s.ID] = c
+			}
+			d := Decision{
+				Allowed:   c.count < s.Limit.PerMinute,
+				Subject:   s.ID,
+				Limit:     s.Limit.PerMinute,
+				Remaining: s.Limit.PerMinute - c.count,
+				Reset:     c.start.Add(window),
+			}
+			if !d.Allowed {
+				d.RetryAfter = d.Reset.Sub(now)
+				return d, nil
+			}
+			best = tighter(best, d)
+		}
+		if s.Limit.PerDay > 0 {
+			used := l.counts[s.ID]
+			reset := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day()+1, 0, 0, 0, 0, time.UTC)
+			d := Decision{
+				Allowed:   used < s.Limit.PerDay,
+				Subject:   s.ID,
+				Limit:     s.Limit.PerDay,
+				Remaining: s.Limit.PerDay - used,
+				Reset:     reset,
+				Daily:     true,
+			}
+			if !d.Allowed {
+				d.RetryAfter = reset.Sub(now)
+				return d, nil
+			}
+			best = tighter(best, d)
+		}
+	}
+
+	daily := false
+	for _, s := range subjects {
+		if c := l.windows[s.ID]; c != nil && s.Limit.PerMinute > 0 {
+			c.count++
+		}
+		if s.Limit.PerDay > 0 {
+			l.counts[s.ID]++
+			daily = true
+		}
+	}
+	if best.Limit > 0 {
+		best.Remaining--
+	}
+	if daily {
+		if err := l.save(); err != nil {
+			return best, err
+		}
+	}
+	return best, nil
+}
+
+// tighter returns whichever decision has fewer requests remaining.
+func tighter(a, b Decision) Decision {
+	if a.Limit == 0 || b.Remaining < a.Remaining {
+		return b
+	}
+	return a
+}
+
+// prune drops expired windows so idle clients don't accumulate.
+func (l *Limiter) prune(now time.Time) {
+	if len(l.windows) < 1024 {
+		return
+	}
+	for id, c := range l.windows {
+		if now.Sub(c.start) >= window {
+			delete(l.windows, id)
+		}
+	}
+}
+
+func (l *Limiter) save() error {
+	if l.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("creating config dir: %w", err)
+	}
+	data, err := json.Marshal(quotaFile{Day: l.day, Counts: l.counts})
+	if err != nil {
+		return fmt.Errorf("marshaling quota: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("writing quota: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("writing quota: %w", err)
+	}
+	return nil
+}
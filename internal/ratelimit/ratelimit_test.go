@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPerMinuteWindow(t *testing.T) {
+	l, _ := Open("")
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	key := Subject{ID: "key:a", Limit: Limit{PerMinute: 2}}
+
+	for i := 0; i < 2; i++ {
+		d, err := l.Allow(now, key)
+		if err != nil || !d.Allowed {
+			t.Fatalf("request %d: %+v %v", i, d, err)
+		}
+		if d.Remaining != 1-i {
+			t.Fatalf("request %d: remaining %d", i, d.Remaining)
+		}
+	}
+	d, _ := l.Allow(now.Add(20*time.Second), key)
+	if d.Allowed || d.RetryAfter != 40*time.Second || d.Subject != "key:a" {
+		t.Fatalf("expected denial with 40s retry, got %+v", d)
+	}
+	if d, _ := l.Allow(now.Add(time.Minute), key); !d.Allowed {
+		t.Fatalf("expected a new window, got %+v", d)
+	}
+}
+
+func TestDeniedSubjectChargesNobody(t *testing.T) {
+	l, _ := Open("")
+	now := time.Now()
+	key := Subject{ID: "key:a", Limit: Limit{PerMinute: 10}}
+	ip := Subject{ID: "ip:1.2.3.4", Limit: Limit{PerMinute: 1}}
+
+	if d, _ := l.Allow(now, key, ip); !d.Allowed || d.Subject != "ip:1.2.3.4" || d.Remaining != 0 {
+		t.Fatalf("expected the ip to be the tighter limit, got %+v", d)
+	}
+	if d, _ := l.Allow(now, key, ip); d.Allowed {
+		t.Fatal("expected ip limit to deny")
+	}
+	if d, _ := l.Allow(now, key); !d.Allowed || d.Remaining != 8 {
+		t.Fatalf("denied request should not count against the key, got %+v", d)
+	}
+}
+
+func TestDailyQuotaPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quota.json")
+	now := time.Date(2026, 5, 1, 22, 0, 0, 0, time.UTC)
+	key := Subject{ID: "key:a", Limit: Limit{PerDay: 2}}
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Allow(now, key); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart keeps the count.
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, _ := l.Allow(now, key); !d.Allowed || !d.Daily || d.Remaining != 0 {
+		t.Fatalf("expected last request of the day, got %+v", d)
+	}
+	d, _ := l.Allow(now, key)
+	if d.Allowed || d.RetryAfter != 2*time.Hour {
+		t.Fatalf("expected quota denial until midnight UTC, got %+v", d)
+	}
+	if d, _ := l.Allow(now.Add(2*time.Hour), key); !d.Allowed {
+		t.Fatalf("expected quota to reset the next day, got %+v", d)
+	}
+}
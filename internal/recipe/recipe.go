@@ -0,0 +1,327 @@
+// Package recipe loads saved agent workflows from ~/.config/birdy/recipes.
+// A recipe is a markdown file whose body is a prompt template; a short
+// front matter header names its parameters and limits the run:
+//
+//	---
+//	description: What a competitor posted lately
+//	model: opus
+//	profile: researcher
+//	commands: user-tweets, thread, replies, about
+//	params: handle, days=7
+//	output: competitor-{handle}-{date}.md
+//	cache: 6h
+//	---
+//	Write a digest of what @{handle} posted in the last {days} days ...
+//
+// Parameters without a default are required. {date} is today's date. The
+// reply of each run is saved as a chat transcript next to the chat history,
+// under output or a timestamped name. With cache set, an answer to the same
+// filled-in prompt is reused for that long.
+package recipe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/transcript"
+)
+
+// ErrNotFound is returned for an unknown recipe name.
+var ErrNotFound = errors.New("recipe not found")
+
+var (
+	namePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+	paramPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+	placeholder  = regexp.MustCompile(`\{([a-z][a-z0-9_]*)\}`)
+	unsafeChars  = regexp.MustCompile(`[^A-Za-z0-9._@-]+`)
+)
+
+// Param is a recipe parameter. A parameter without a default is required.
+type Param struct {
+	Name       string `json:"name"`
+	Default    string `json:"default,omitempty"`
+	HasDefault bool   `json:"-"`
+}
+
+// Recipe is a named prompt template.
+type Recipe struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Model       string        `json:"model,omitempty"`
+	Profile     string        `json:"profile,omitempty"`
+	Commands    []string      `json:"commands,omitempty"` // allowed bird commands; empty allows all
+	Params      []Param       `json:"params,omitempty"`
+	Output      string        `json:"output,omitempty"` // output file name template
+	Cache       time.Duration `json:"cache,omitempty"`  // how long answers are reused; 0 for never
+	Prompt      string        `json:"prompt"`
+}
+
+// DefaultDir returns ~/.config/birdy/recipes.
+func DefaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "recipes"), nil
+}
+
+// List returns the recipes in the default directory, sorted by name. Files
+// that fail to parse are skipped and reported in the error.
+func List() ([]Recipe, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading recipes dir: %w", err)
+	}
+	var (
+		out  []Recipe
+		errs []error
+	)
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".md")
+		if !ok || e.IsDir() {
+			continue
+		}
+		r, err := loadFile(filepath.Join(dir, e.Name()), name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, errors.Join(errs...)
+}
+
+// Load returns the named recipe.
+func Load(name string) (*Recipe, error) {
+	name = strings.TrimSpace(name)
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	dir, err := DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return loadFile(filepath.Join(dir, name+".md"), name)
+}
+
+func loadFile(path, name string) (*Recipe, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid recipe name %q: use lowercase letters, digits, - and _", name)
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading recipe: %w", err)
+	}
+	r, err := Parse(name, string(data))
+	if err != nil {
+		return nil, fmt.Errorf("recipe %q: %w", name, err)
+	}
+	return r, nil
+}
+
+// Parse reads a recipe from its markdown source.
+func Parse(name, src string) (*Recipe, error) {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	r := &Recipe{Name: name}
+	if rest, ok := strings.CutPrefix(src, "---\n"); ok {
+		header, body, ok := strings.Cut(rest, "\n---\n")
+		if !ok {
+			header, ok = strings.CutSuffix(rest, "\n---")
+			if !ok {
+				return nil, fmt.Errorf("front matter is not closed with ---")
+			}
+		}
+		src = body
+		if err := r.parseHeader(header); err != nil {
+			return nil, err
+		}
+	}
+	r.Prompt = strings.TrimSpace(src)
+	if r.Prompt == "" {
+		return nil, fmt.Errorf("empty prompt")
+	}
+	return r, nil
+}
+
+func (r *Recipe) parseHeader(header string) error {
+	for i, line := range strings.Split(header, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: expected key: value", i+2)
+		}
+		key, value = strings.TrimSpace(key), unquote(strings.TrimSpace(value))
+		switch key {
+		case "description":
+			r.Description = value
+		case "model":
+			r.Model = value
+		case "profile":
+			r.Profile = value
+		case "output":
+			r.Output = value
+		case "cache":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return fmt.Errorf("cache: %q is not a duration such as 30m or 6h", value)
+			}
+			r.Cache = d
+		case "commands":
+			for _, c := range splitList(value) {
+				if _, ok := birdcmd.Lookup(c); !ok {
+					return fmt.Errorf("unknown command %q", c)
+				}
+				r.Commands = append(r.Commands, c)
+			}
+		case "params":
+			for _, item := range splitList(value) {
+				name, def, hasDefault := strings.Cut(item, "=")
+				name = strings.TrimSpace(name)
+				if !paramPattern.MatchString(name) || name == "date" {
+					return fmt.Errorf("invalid parameter name %q", name)
+				}
+				r.Params = append(r.Params, Param{Name: name, Default: unquote(strings.TrimSpace(def)), HasDefault: hasDefault})
+			}
+		default:
+			return fmt.Errorf("unknown field %q", key)
+		}
+	}
+	return nil
+}
+
+// splitList reads a comma-separated list, optionally in [brackets].
+func splitList(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = unquote(strings.TrimSpace(item)); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// Run is a recipe with its parameters filled in.
+type Run struct {
+	Recipe  *Recipe
+	Prompt  string // rendered prompt
+	Output  string // path the reply is saved to
+	Started time.Time
+}
+
+// Start fills in the recipe's parameters. Every required parameter must be
+// given, and no unknown ones.
+func (r *Recipe) Start(params map[string]string, now time.Time) (*Run, error) {
+	values := map[string]string{"date": now.Format("2006-01-02")}
+	for _, p := range r.Params {
+		if p.HasDefault {
+			values[p.Name] = p.Default
+		}
+	}
+	for name, v := range params {
+		if !r.hasParam(name) {
+			return nil, fmt.Errorf("recipe %q has no parameter %q", r.Name, name)
+		}
+		values[name] = v
+	}
+	for _, p := range r.Params {
+		if _, ok := values[p.Name]; !ok {
+			return nil, fmt.Errorf("recipe %q needs parameter %q", r.Name, p.Name)
+		}
+	}
+
+	dir, err := transcript.Dir()
+	if err != nil {
+		return nil, err
+	}
+	output := now.Format("2006-01-02_150405") + "-recipe-" + r.Name + ".md"
+	if r.Output != "" {
+		// Parameters may come from API callers, so they must not pick the
+		// directory.
+		output = placeholder.ReplaceAllStringFunc(r.Output, func(m string) string {
+			if v, ok := values[m[1:len(m)-1]]; ok {
+				return strings.Trim(unsafeChars.ReplaceAllString(v, "-"), ".-")
+			}
+			return m
+		})
+		output = filepath.Base(output)
+		if !strings.HasSuffix(output, ".md") {
+			output += ".md"
+		}
+	}
+
+	return &Run{
+		Recipe:  r,
+		Prompt:  fill(r.Prompt, values),
+		Output:  filepath.Join(dir, output),
+		Started: now,
+	}, nil
+}
+
+func (r *Recipe) hasParam(name string) bool {
+	for _, p := range r.Params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// fill replaces the {name} placeholders of known values; other braces are
+// left alone.
+func fill(tmpl string, values map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(tmpl, func(m string) string {
+		if v, ok := values[m[1:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// Save writes the run's prompt and the agent's reply as a chat transcript
+// at run.Output.
+func (run *Run) Save(reply []transcript.Message) error {
+	messages := append([]transcript.Message{{Role: transcript.RoleUser, Content: run.Prompt}}, reply...)
+	return transcript.Write(run.Output, messages, run.Started, "")
+}
+
+// ParseParams reads key=value pairs, as given to birdy recipe run --param.
+func ParseParams(pairs []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, kv := range pairs {
+		k, v, ok := strings.Cut(kv, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			return nil, fmt.Errorf("parameter %q must be key=value", kv)
+		}
+		out[k] = v
+	}
+	return out, nil
+}
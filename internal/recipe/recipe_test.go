@@ -0,0 +1,134 @@
+package recipe
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/birdy/internal/transcript"
+)
+
+const digest = `---
+description: What a competitor posted lately
+model: opus
+commands: [user-tweets, thread, about]
+params: handle, days=7
+output: "competitor-{handle}-{date}"
+cache: 6h
+---
+Write a digest of what @{handle} posted in the last {days} days.
+Keep JSON like {"id": 1} as is; {unknown} stays too.
+`
+
+func writeRecipe(t *testing.T, name, src string) {
+	t.Helper()
+	home, _ := os.UserHomeDir()
+	dir := filepath.Join(home, ".config", "birdy", "recipes")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name+".md"), []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse("digest", digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Description != "What a competitor posted lately" || r.Model != "opus" ||
+		!slices.Equal(r.Commands, []string{"user-tweets", "thread", "about"}) ||
+		r.Output != "competitor-{handle}-{date}" || r.Cache != 6*time.Hour {
+		t.Fatalf("recipe = %+v", r)
+	}
+	want := []Param{{Name: "handle"}, {Name: "days", Default: "7", HasDefault: true}}
+	if !slices.Equal(r.Params, want) {
+		t.Fatalf("params = %+v", r.Params)
+	}
+
+	if r, err := Parse("plain", "Triage my mentions."); err != nil || r.Prompt != "Triage my mentions." {
+		t.Fatalf("no front matter = %+v, %v", r, err)
+	}
+	for _, bad := range []string{
+		"---\nmodel: opus\nno body",
+		"---\ncolour: red\n---\nhi",
+		"---\ncommands: tweet, launch\n---\nhi",
+		"---\nparams: date\n---\nhi",
+		"---\nmodel: opus\n---\n",
+		"---\ncache: daily\n---\nhi",
+	} {
+		if _, err := Parse("bad", bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestStartFillsParamsAndSaves(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	writeRecipe(t, "digest", digest)
+	writeRecipe(t, "Broken", "hi")
+
+	recipes, err := List()
+	if len(recipes) != 1 || recipes[0].Name != "digest" || err == nil {
+		t.Fatalf("List = %+v, %v", recipes, err)
+	}
+	r, err := Load("digest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing = %v", err)
+	}
+
+	now := time.Date(2026, 3, 4, 5, 6, 7, 0, time.Local)
+	if _, err := r.Start(nil, now); err == nil || !strings.Contains(err.Error(), `needs parameter "handle"`) {
+		t.Fatalf("missing param = %v", err)
+	}
+	if _, err := r.Start(map[string]string{"handle": "a", "colour": "red"}, now); err == nil {
+		t.Fatal("expected an error for an unknown param")
+	}
+
+	run, err := r.Start(map[string]string{"handle": "../../etc/acme"}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(run.Prompt, "Write a digest of what @../../etc/acme posted in the last 7 days.\n") ||
+		!strings.Contains(run.Prompt, `{"id": 1}`) || !strings.Contains(run.Prompt, "{unknown}") {
+		t.Fatalf("prompt = %q", run.Prompt)
+	}
+	chats := filepath.Join(home, ".config", "birdy", "chats")
+	if run.Output != filepath.Join(chats, "competitor-etc-acme-2026-03-04.md") {
+		t.Fatalf("output = %q", run.Output)
+	}
+
+	reply := []transcript.Message{{Role: transcript.RoleTool, Content: "birdy user-tweets acme"}, {Role: transcript.RoleAssistant, Content: "Quiet week."}}
+	if err := run.Save(reply); err != nil {
+		t.Fatal(err)
+	}
+	msgs, _, err := transcript.Load(run.Output)
+	if err != nil || len(msgs) != 3 || msgs[0].Content != run.Prompt || msgs[2].Content != "Quiet week." {
+		t.Fatalf("saved = %+v, %v", msgs, err)
+	}
+
+	plain, _ := Parse("triage", "Triage my mentions.")
+	run, _ = plain.Start(nil, now)
+	if filepath.Base(run.Output) != "2026-03-04_050607-recipe-triage.md" {
+		t.Fatalf("default output = %q", run.Output)
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	got, err := ParseParams([]string{"handle=acme", "query=a=b c"})
+	if err != nil || got["handle"] != "acme" || got["query"] != "a=b c" {
+		t.Fatalf("ParseParams = %v, %v", got, err)
+	}
+	if _, err := ParseParams([]string{"handle"}); err == nil {
+		t.Fatal("expected an error without =")
+	}
+}
@@ -2,6 +2,7 @@ package runner
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -14,25 +15,30 @@ import (
 // Run executes the bird CLI with the given account's credentials and args.
 // It passes auth_token and ct0 as environment variables.
 func Run(account *store.Account, args []string) (int, error) {
-	exitCode, _, _, err := runWithIO(account, args, os.Stdin, os.Stdout, os.Stderr)
+	exitCode, _, _, err := runWithIO(context.Background(), account, args, os.Stdin, os.Stdout, os.Stderr)
 	return exitCode, err
 }
 
 // RunCapture executes the bird CLI and captures stdout/stderr.
 func RunCapture(account *store.Account, args []string) (exitCode int, stdout, stderr string, err error) {
+	return RunCaptureContext(context.Background(), account, args)
+}
+
+// RunCaptureContext is like RunCapture but kills bird when ctx is cancelled.
+func RunCaptureContext(ctx context.Context, account *store.Account, args []string) (exitCode int, stdout, stderr string, err error) {
 	var outBuf bytes.Buffer
 	var errBuf bytes.Buffer
-	exitCode, _, _, err = runWithIO(account, args, nil, &outBuf, &errBuf)
+	exitCode, _, _, err = runWithIO(ctx, account, args, nil, &outBuf, &errBuf)
 	return exitCode, outBuf.String(), errBuf.String(), err
 }
 
-func runWithIO(account *store.Account, args []string, stdin any, stdout any, stderr any) (exitCode int, out string, errOut string, err error) {
+func runWithIO(ctx context.Context, account *store.Account, args []string, stdin any, stdout any, stderr any) (exitCode int, out string, errOut string, err error) {
 	birdBin, err := findBird()
 	if err != nil {
 		return 1, "", "", err
 	}
 
-	cmd := exec.Command(birdBin, args...)
+	cmd := exec.CommandContext(ctx, birdBin, args...)
 	if stdin != nil {
 		if r, ok := stdin.(*os.File); ok {
 			cmd.Stdin = r
@@ -12,6 +12,7 @@ type State struct {
 	path         string
 	LastUsedName string `json:"last_used_name"`
 	Model        string `json:"model,omitempty"`
+	Profile      string `json:"profile,omitempty"` // TUI agent profile
 }
 
 func defaultPath() (string, error) {
@@ -0,0 +1,224 @@
+// Package transcript is the markdown chat format shared by the TUI and the
+// host's chat sessions, and the prompt that replays a conversation to the
+// agent for the next turn when its claude CLI session can't be resumed.
+package transcript
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message roles.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+	RoleError     = "error"
+)
+
+// Message is one entry in a conversation. Tool messages hold the command the
+// agent ran.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Dir returns ~/.config/birdy/chats.
+func Dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "birdy", "chats"), nil
+}
+
+// sessionPrefix starts the comment that records the claude CLI session a
+// transcript continues.
+const sessionPrefix = "<!-- birdy-session: "
+
+// Markdown renders messages as a transcript titled with created. A non-empty
+// sessionID is kept in an HTML comment under the title.
+func Markdown(messages []Message, created time.Time, sessionID string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("# birdy chat — %s\n\n", created.Format("2006-01-02 15:04:05")))
+	if sessionID != "" {
+		b.WriteString(sessionPrefix + sessionID + " -->\n\n")
+	}
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case RoleUser:
+			b.WriteString("## You\n\n")
+			b.WriteString(msg.Content)
+			b.WriteString("\n\n")
+		case RoleAssistant:
+			if msg.Content != "" {
+				b.WriteString("## birdy\n\n")
+				b.WriteString(msg.Content)
+				b.WriteString("\n\n")
+			}
+		case RoleTool:
+			b.WriteString(fmt.Sprintf("> `%s`\n\n", msg.Content))
+		case RoleError:
+			b.WriteString(fmt.Sprintf("**Error:** %s\n\n", msg.Content))
+		}
+	}
+	return b.String()
+}
+
+// Write saves messages as a markdown transcript at path.
+func Write(path string, messages []Message, created time.Time, sessionID string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating chat history dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(Markdown(messages, created, sessionID)), 0600); err != nil {
+		return fmt.Errorf("writing chat history: %w", err)
+	}
+	return nil
+}
+
+// Parse reads messages back from a markdown transcript.
+func Parse(raw string) []Message {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+
+	var (
+		messages []Message
+		role     string
+		buf      []string
+	)
+	flush := func() {
+		if role == "" {
+			buf = nil
+			return
+		}
+		content := strings.TrimSpace(strings.Join(buf, "\n"))
+		if content != "" {
+			messages = append(messages, Message{Role: role, Content: content})
+		}
+		buf = nil
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "## You":
+			flush()
+			role = RoleUser
+		case trimmed == "## birdy":
+			flush()
+			role = RoleAssistant
+		case strings.HasPrefix(trimmed, "> `") && strings.HasSuffix(trimmed, "`"):
+			flush()
+			cmd := strings.TrimSuffix(strings.TrimPrefix(trimmed, "> `"), "`")
+			if cmd != "" {
+				messages = append(messages, Message{Role: RoleTool, Content: cmd})
+			}
+			role = ""
+		case strings.HasPrefix(trimmed, "**Error:**"):
+			flush()
+			errMsg := strings.TrimSpace(strings.TrimPrefix(trimmed, "**Error:**"))
+			if errMsg != "" {
+				messages = append(messages, Message{Role: RoleError, Content: errMsg})
+			}
+			role = ""
+		case strings.HasPrefix(trimmed, "# "), strings.HasPrefix(trimmed, sessionPrefix):
+			// Skip markdown title and session rows.
+			continue
+		default:
+			if role != "" {
+				buf = append(buf, line)
+			}
+		}
+	}
+	flush()
+	return messages
+}
+
+// SessionID returns the claude CLI session recorded in a transcript, or ""
+// when there is none.
+func SessionID(raw string) string {
+	for _, line := range strings.Split(raw, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if rest, ok := strings.CutPrefix(trimmed, sessionPrefix); ok {
+			return strings.TrimSpace(strings.TrimSuffix(rest, "-->"))
+		}
+		if trimmed != "" && !strings.HasPrefix(trimmed, "# ") {
+			return ""
+		}
+	}
+	return ""
+}
+
+// Load parses the transcript at path and returns its messages and claude
+// CLI session id.
+func Load(path string) ([]Message, string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	messages := Parse(string(raw))
+	if len(messages) == 0 {
+		return nil, "", fmt.Errorf("no chat messages found in %s", filepath.Base(path))
+	}
+	return messages, SessionID(string(raw)), nil
+}
+
+// TurnPrompt replays the most recent messages so the agent can answer the
+// latest user message in context. Errors are left out.
+func TurnPrompt(messages []Message) string {
+	const (
+		maxMessages = 20
+		maxChars    = 1600
+	)
+
+	if len(messages) == 0 {
+		return ""
+	}
+
+	start := len(messages) - maxMessages
+	if start < 0 {
+		start = 0
+	}
+
+	var b strings.Builder
+	b.WriteString("Continue this ongoing birdy TUI chat session.\n")
+	b.WriteString("Do not restart with a generic greeting. Respond directly to the latest user message.\n\n")
+	b.WriteString("Conversation history (oldest to newest):\n")
+
+	for _, m := range messages[start:] {
+		text := strings.TrimSpace(m.Content)
+		if text == "" {
+			continue
+		}
+		text = truncate(text, maxChars)
+
+		switch m.Role {
+		case RoleUser:
+			b.WriteString("User: ")
+			b.WriteString(text)
+			b.WriteString("\n")
+		case RoleAssistant:
+			b.WriteString("Assistant: ")
+			b.WriteString(text)
+			b.WriteString("\n")
+		case RoleTool:
+			b.WriteString("Tool: ")
+			b.WriteString(text)
+			b.WriteString("\n")
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+func truncate(s string, maxChars int) string {
+	if maxChars <= 0 || len(s) <= maxChars {
+		return s
+	}
+	trimmed := strings.TrimSpace(s[:maxChars])
+	return trimmed + " ...[truncated " + strconv.Itoa(len(s)-maxChars) + " chars]"
+}
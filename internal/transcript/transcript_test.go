@@ -0,0 +1,60 @@
+package transcript
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteLoadRoundTrip(t *testing.T) {
+	messages := []Message{
+		{Role: RoleUser, Content: "what's new?"},
+		{Role: RoleTool, Content: "birdy home -n 5"},
+		{Role: RoleAssistant, Content: "Line one.\n\nLine two."},
+		{Role: RoleError, Content: "cancelled"},
+	}
+	path := filepath.Join(t.TempDir(), "chats", "2026-01-02_030405.md")
+	if err := Write(path, messages, time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local), "3f2c9a1e-session"); err != nil {
+		t.Fatal(err)
+	}
+	got, sessionID, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, messages) {
+		t.Fatalf("round trip:\ngot  %+v\nwant %+v", got, messages)
+	}
+	if sessionID != "3f2c9a1e-session" {
+		t.Fatalf("session id = %q", sessionID)
+	}
+}
+
+func TestSessionIDOnlyFromHeader(t *testing.T) {
+	raw := Markdown([]Message{{Role: RoleUser, Content: "hi"}}, time.Now(), "")
+	if got := SessionID(raw); got != "" {
+		t.Fatalf("no session: got %q", got)
+	}
+	// A message quoting the marker doesn't count.
+	raw = Markdown([]Message{{Role: RoleUser, Content: sessionPrefix + "abc -->"}}, time.Now(), "")
+	if got := SessionID(raw); got != "" {
+		t.Fatalf("quoted marker: got %q", got)
+	}
+}
+
+func TestTurnPromptKeepsRecentHistory(t *testing.T) {
+	var messages []Message
+	for i := 0; i < 30; i++ {
+		messages = append(messages, Message{Role: RoleUser, Content: strings.Repeat("x", i) + "q"})
+	}
+	messages = append(messages, Message{Role: RoleUser, Content: strings.Repeat("y", 2000)})
+
+	prompt := TurnPrompt(messages)
+	if strings.Contains(prompt, "User: q\n") {
+		t.Error("expected the oldest messages to be dropped")
+	}
+	if !strings.Contains(prompt, "...[truncated 400 chars]") {
+		t.Error("expected long messages to be truncated")
+	}
+}
@@ -0,0 +1,176 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+// Sink delivers a batch of new tweets somewhere.
+type Sink interface {
+	Deliver(ctx context.Context, name string, tweets []bird.Tweet) error
+}
+
+// Target is a sink together with the spec it was parsed from. The spec keys
+// the sink's pending deliveries in the watch state.
+type Target struct {
+	Spec string
+	Sink Sink
+}
+
+// ParseSink builds a sink from a spec:
+//
+//	stdout               JSONL on stdout (default)
+//	file:<path>          append JSONL to a file
+//	exec:<cmd> [args]    run a command with the batch as JSONL on stdin
+//	webhook:<url>        POST the batch as JSON (http(s):// URLs also work)
+func ParseSink(spec string, stdout io.Writer) (Sink, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case spec == "" || spec == "stdout" || spec == "-":
+		return &writerSink{w: stdout}, nil
+	case strings.HasPrefix(spec, "file:"):
+		path := strings.TrimSpace(strings.TrimPrefix(spec, "file:"))
+		if path == "" {
+			return nil, fmt.Errorf("file sink requires a path")
+		}
+		return &fileSink{path: path}, nil
+	case strings.HasPrefix(spec, "exec:"):
+		argv := strings.Fields(strings.TrimPrefix(spec, "exec:"))
+		if len(argv) == 0 {
+			return nil, fmt.Errorf("exec sink requires a command")
+		}
+		return &execSink{argv: argv}, nil
+	case strings.HasPrefix(spec, "webhook:"):
+		return newWebhookSink(strings.TrimSpace(strings.TrimPrefix(spec, "webhook:")))
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		return newWebhookSink(spec)
+	default:
+		return nil, fmt.Errorf("unknown sink %q (valid: stdout, file:<path>, exec:<cmd>, webhook:<url>)", spec)
+	}
+}
+
+func encodeJSONL(tweets []bird.Tweet) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, t := range tweets {
+		if err := enc.Encode(t); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+type writerSink struct {
+	w io.Writer
+}
+
+func (s *writerSink) Deliver(_ context.Context, _ string, tweets []bird.Tweet) error {
+	data, err := encodeJSONL(tweets)
+	if err != nil {
+		return err
+	}
+	_, err = s.w.Write(data)
+	return err
+}
+
+type fileSink struct {
+	path string
+}
+
+func (s *fileSink) Deliver(_ context.Context, _ string, tweets []bird.Tweet) error {
+	data, err := encodeJSONL(tweets)
+	if err != nil {
+		return err
+	}
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("creating sink dir: %w", err)
+		}
+	}
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("opening sink file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("writing sink file: %w", err)
+	}
+	return f.Close()
+}
+
+type execSink struct {
+	argv []string
+}
+
+func (s *execSink) Deliver(ctx context.Context, name string, tweets []bird.Tweet) error {
+	data, err := encodeJSONL(tweets)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, s.argv[0], s.argv[1:]...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"BIRDY_WATCH="+name,
+		"BIRDY_WATCH_COUNT="+strconv.Itoa(len(tweets)),
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("exec sink %s: %w", s.argv[0], err)
+	}
+	return nil
+}
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+type webhookPayload struct {
+	Watch  string       `json:"watch"`
+	Count  int          `json:"count"`
+	Tweets []bird.Tweet `json:"tweets"`
+}
+
+func newWebhookSink(url string) (*webhookSink, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("webhook sink requires an http(s) URL")
+	}
+	return &webhookSink{url: url, client: &http.Client{Timeout: 15 * time.Second}}, nil
+}
+
+func (s *webhookSink) Deliver(ctx context.Context, name string, tweets []bird.Tweet) error {
+	body, err := json.Marshal(webhookPayload{Watch: name, Count: len(tweets), Tweets: tweets})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "birdy-watch")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook sink: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook sink: %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
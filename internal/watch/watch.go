@@ -0,0 +1,185 @@
+package watch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+// maxSeenIDs bounds the dedupe window kept alongside the high-water mark.
+const maxSeenIDs = 1000
+
+// maxPendingTweets bounds the batch kept for a sink that keeps failing.
+const maxPendingTweets = 1000
+
+var unsafeNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// State is the persisted progress of a single watch.
+type State struct {
+	path      string
+	HighWater string    `json:"high_water,omitempty"`
+	Seen      []string  `json:"seen,omitempty"`
+	Polls     int64     `json:"polls"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+	// Pending holds tweets a sink has not accepted yet, keyed by sink spec.
+	Pending map[string][]bird.Tweet `json:"pending,omitempty"`
+}
+
+func defaultDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "birdy", "watch"), nil
+}
+
+// Key derives a stable state file name from the watched bird args.
+// An explicit name wins over the derived key.
+func Key(name string, args []string) string {
+	if name = strings.TrimSpace(name); name != "" {
+		return strings.Trim(unsafeNameChars.ReplaceAllString(name, "-"), "-")
+	}
+	sum := sha256.Sum256([]byte(strings.Join(args, "\x00")))
+	prefix := "watch"
+	if len(args) > 0 {
+		prefix = unsafeNameChars.ReplaceAllString(args[0], "-")
+	}
+	return prefix + "-" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Load reads the state for key from the default watch dir.
+func Load(key string) (*State, error) {
+	dir, err := defaultDir()
+	if err != nil {
+		return nil, err
+	}
+	return LoadPath(filepath.Join(dir, key+".json"))
+}
+
+// LoadPath reads the state file from a custom path.
+func LoadPath(path string) (*State, error) {
+	s := &State{path: path}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading watch state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parsing watch state: %w", err)
+	}
+	return s, nil
+}
+
+// Save persists state to disk.
+func (s *State) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("creating watch dir: %w", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling watch state: %w", err)
+	}
+	return os.WriteFile(s.path, data, 0600)
+}
+
+// Fresh reports whether the watch has never completed a poll.
+func (s *State) Fresh() bool {
+	return s.Polls == 0 && s.HighWater == ""
+}
+
+// Filter returns tweets newer than the high-water mark that have not been
+// seen before, oldest first, and advances the state past them.
+func (s *State) Filter(tweets []bird.Tweet, now time.Time) []bird.Tweet {
+	seen := make(map[string]struct{}, len(s.Seen))
+	for _, id := range s.Seen {
+		seen[id] = struct{}{}
+	}
+
+	var fresh []bird.Tweet
+	for _, t := range tweets {
+		if t.ID == "" {
+			continue
+		}
+		if _, ok := seen[t.ID]; ok {
+			continue
+		}
+		if s.HighWater != "" && bird.CompareIDs(t.ID, s.HighWater) <= 0 {
+			continue
+		}
+		seen[t.ID] = struct{}{}
+		fresh = append(fresh, t)
+	}
+
+	sort.SliceStable(fresh, func(i, j int) bool {
+		return bird.CompareIDs(fresh[i].ID, fresh[j].ID) < 0
+	})
+
+	for _, t := range fresh {
+		s.Seen = append(s.Seen, t.ID)
+		if s.HighWater == "" || bird.CompareIDs(t.ID, s.HighWater) > 0 {
+			s.HighWater = t.ID
+		}
+	}
+	if len(s.Seen) > maxSeenIDs {
+		s.Seen = append([]string(nil), s.Seen[len(s.Seen)-maxSeenIDs:]...)
+	}
+	s.Polls++
+	s.UpdatedAt = now
+	return fresh
+}
+
+// Deliver sends fresh to every target along with whatever that target failed
+// to accept on earlier polls. Each target is tracked on its own: one that
+// fails keeps its batch in Pending for the next call, while the others are
+// not sent it again. Delivery is at-least-once; a target may see a batch
+// twice if the state is not saved after a successful delivery. Pending
+// batches for sinks no longer in targets are dropped.
+func (s *State) Deliver(ctx context.Context, name string, targets []Target, fresh []bird.Tweet) error {
+	pending := make(map[string][]bird.Tweet, len(targets))
+	var errs []error
+	for _, t := range targets {
+		if _, done := pending[t.Spec]; done {
+			continue
+		}
+		batch := append(append([]bird.Tweet(nil), s.Pending[t.Spec]...), fresh...)
+		if len(batch) == 0 {
+			pending[t.Spec] = nil
+			continue
+		}
+		if err := t.Sink.Deliver(ctx, name, batch); err != nil {
+			if len(batch) > maxPendingTweets {
+				batch = batch[len(batch)-maxPendingTweets:]
+			}
+			pending[t.Spec] = batch
+			errs = append(errs, fmt.Errorf("sink %s: %w", t.Spec, err))
+			continue
+		}
+		pending[t.Spec] = nil
+	}
+
+	s.Pending = nil
+	for spec, batch := range pending {
+		if len(batch) == 0 {
+			continue
+		}
+		if s.Pending == nil {
+			s.Pending = make(map[string][]bird.Tweet)
+		}
+		s.Pending[spec] = batch
+	}
+	return errors.Join(errs...)
+}
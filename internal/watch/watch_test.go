@@ -0,0 +1,179 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guzus/birdy/internal/bird"
+)
+
+func TestFilterEmitsOnlyNewTweetsOldestFirst(t *testing.T) {
+	s := &State{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	first := s.Filter([]bird.Tweet{{ID: "102"}, {ID: "101"}}, now)
+	if len(first) != 2 || first[0].ID != "101" || first[1].ID != "102" {
+		t.Fatalf("unexpected first batch: %#v", first)
+	}
+	if s.HighWater != "102" {
+		t.Fatalf("expected high-water 102, got %q", s.HighWater)
+	}
+
+	second := s.Filter([]bird.Tweet{{ID: "103"}, {ID: "102"}, {ID: "99"}}, now)
+	if len(second) != 1 || second[0].ID != "103" {
+		t.Fatalf("expected only 103, got %#v", second)
+	}
+	if s.Polls != 2 {
+		t.Fatalf("expected 2 polls, got %d", s.Polls)
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "w.json")
+	s, err := LoadPath(path)
+	if err != nil {
+		t.Fatalf("LoadPath: %v", err)
+	}
+	if !s.Fresh() {
+		t.Fatal("expected fresh state")
+	}
+	s.Filter([]bird.Tweet{{ID: "5"}}, time.Now())
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadPath(path)
+	if err != nil {
+		t.Fatalf("LoadPath: %v", err)
+	}
+	if loaded.HighWater != "5" || loaded.Fresh() {
+		t.Fatalf("unexpected loaded state: %#v", loaded)
+	}
+}
+
+func TestKeyIsStableAndSafe(t *testing.T) {
+	a := Key("", []string{"search", "golang"})
+	b := Key("", []string{"search", "golang"})
+	if a != b || !strings.HasPrefix(a, "search-") {
+		t.Fatalf("expected stable derived key, got %q and %q", a, b)
+	}
+	if got := Key("my watch/../x", nil); got != "my-watch-..-x" {
+		t.Fatalf("unexpected sanitized key %q", got)
+	}
+}
+
+func TestParseSinkStdoutWritesJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	sink, err := ParseSink("", &buf)
+	if err != nil {
+		t.Fatalf("ParseSink: %v", err)
+	}
+	if err := sink.Deliver(context.Background(), "w", []bird.Tweet{{ID: "1"}, {ID: "2"}}); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 JSONL lines, got %q", buf.String())
+	}
+}
+
+func TestFileSinkAppends(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out", "tweets.jsonl")
+	sink, err := ParseSink("file:"+path, nil)
+	if err != nil {
+		t.Fatalf("ParseSink: %v", err)
+	}
+	for _, id := range []string{"1", "2"} {
+		if err := sink.Deliver(context.Background(), "w", []bird.Tweet{{ID: id}}); err != nil {
+			t.Fatalf("Deliver: %v", err)
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if strings.Count(string(data), "\n") != 2 {
+		t.Fatalf("expected two appended lines, got %q", data)
+	}
+}
+
+func TestWebhookSinkPostsBatch(t *testing.T) {
+	var got webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer srv.Close()
+
+	sink, err := ParseSink("webhook:"+srv.URL, nil)
+	if err != nil {
+		t.Fatalf("ParseSink: %v", err)
+	}
+	if err := sink.Deliver(context.Background(), "mentions", []bird.Tweet{{ID: "7"}}); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if got.Watch != "mentions" || got.Count != 1 || got.Tweets[0].ID != "7" {
+		t.Fatalf("unexpected payload: %#v", got)
+	}
+}
+
+func TestParseSinkRejectsUnknown(t *testing.T) {
+	if _, err := ParseSink("smtp:me@example.com", nil); err == nil {
+		t.Fatal("expected unknown sink to be rejected")
+	}
+}
+
+type recordSink struct {
+	fail    bool
+	batches [][]string
+}
+
+func (s *recordSink) Deliver(_ context.Context, _ string, tweets []bird.Tweet) error {
+	if s.fail {
+		return errors.New("down")
+	}
+	var ids []string
+	for _, t := range tweets {
+		ids = append(ids, t.ID)
+	}
+	s.batches = append(s.batches, ids)
+	return nil
+}
+
+func TestDeliverTracksEachSink(t *testing.T) {
+	ok := &recordSink{}
+	flaky := &recordSink{fail: true}
+	targets := []Target{{Spec: "ok", Sink: ok}, {Spec: "flaky", Sink: flaky}}
+	s := &State{}
+
+	err := s.Deliver(context.Background(), "w", targets, []bird.Tweet{{ID: "1"}})
+	if err == nil || !strings.Contains(err.Error(), "sink flaky") {
+		t.Fatalf("expected flaky sink error, got %v", err)
+	}
+	if len(s.Pending["flaky"]) != 1 || len(s.Pending["ok"]) != 0 {
+		t.Fatalf("unexpected pending: %#v", s.Pending)
+	}
+
+	flaky.fail = false
+	if err := s.Deliver(context.Background(), "w", targets, []bird.Tweet{{ID: "2"}}); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if got := fmt.Sprint(ok.batches); got != "[[1] [2]]" {
+		t.Fatalf("ok sink should not see tweet 1 twice, got %s", got)
+	}
+	if got := fmt.Sprint(flaky.batches); got != "[[1 2]]" {
+		t.Fatalf("flaky sink should get its backlog, got %s", got)
+	}
+	if s.Pending != nil {
+		t.Fatalf("expected no pending batches, got %#v", s.Pending)
+	}
+}
@@ -0,0 +1,243 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guzus/birdy/internal/answercache"
+	"github.com/guzus/birdy/internal/birdcmd"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/profile"
+)
+
+const homeSummaryPrompt = "Give me a brief summary of what's on my home timeline."
+
+// answerTarget is a prompt whose answer goes to the answer cache.
+type answerTarget struct {
+	key     answercache.Key
+	ttl     time.Duration
+	label   string           // what the answer is, for notes: "home summary"
+	profile *profile.Profile // profile the prompt runs under
+}
+
+// cachedAnswer is the cached answer the chat last showed, which ctrl+l and
+// the background refresh replace.
+type cachedAnswer struct {
+	answerTarget
+	entry  *answercache.Entry
+	index  int // assistant message showing the answer
+	noteAt int // tool message noting the cache, or -1
+}
+
+// answerRefreshDueMsg fires when the cached answer stored at cachedAt should
+// be refreshed in the background.
+type answerRefreshDueMsg struct {
+	cachedAt time.Time
+}
+
+// answerRefreshedMsg carries the result of a background refresh.
+type answerRefreshedMsg struct {
+	key   answercache.Key
+	entry *answercache.Entry
+	err   error
+}
+
+// newAnswerTarget returns the cache target of prompt answered by model
+// under p, kept for ttl.
+func newAnswerTarget(prompt, model string, p *profile.Profile, ttl time.Duration, label string) *answerTarget {
+	key := answercache.Key{Prompt: prompt, Model: model, ReadOnly: birdcmd.ReadOnly()}
+	var pool []string
+	if p != nil {
+		pool = p.Accounts
+		key.Profile = p.Name
+		key.ReadOnly = key.ReadOnly || p.ReadOnly
+	}
+	key.Accounts = answercache.Pool(pool)
+	return &answerTarget{
+		key:     key,
+		ttl:     ttl,
+		label:   label,
+		profile: p,
+	}
+}
+
+// lookupAnswer returns the fresh cached answer for t, if any.
+func lookupAnswer(t *answerTarget, now time.Time) (*answercache.Entry, bool) {
+	if t.ttl <= 0 {
+		return nil, false
+	}
+	c, err := answercache.Open()
+	if err != nil {
+		return nil, false
+	}
+	e, ok, err := c.Get(t.key, now)
+	return e, ok && err == nil
+}
+
+// showCachedAnswer adds a cached answer to the chat in place of a run.
+func (m *ChatModel) showCachedAnswer(t *answerTarget, e *answercache.Entry, now time.Time) tea.Cmd {
+	m.messages = append(m.messages,
+		chatMessage{role: "user", content: t.key.Prompt},
+		chatMessage{role: "tool", content: fmt.Sprintf("using cached %s (%s old, ttl %s)", t.label, formatCacheAge(e.Age(now)), formatCacheAge(e.TTL()))},
+		chatMessage{role: "assistant", content: e.Answer},
+	)
+	n := len(m.messages)
+	m.cached = &cachedAnswer{answerTarget: *t, entry: e, index: n - 1, noteAt: n - 2}
+	m.refreshViewport()
+	return m.scheduleAnswerRefresh(now)
+}
+
+// storeAnswer caches the answer of the turn that just finished, when the
+// turn asked for it.
+func (m *ChatModel) storeAnswer(now time.Time) tea.Cmd {
+	t := m.cacheOnDone
+	m.cacheOnDone = nil
+	if t == nil {
+		return nil
+	}
+	answer := strings.TrimSpace(m.lastAssistantContent())
+	c, err := answercache.Open()
+	if answer == "" || err != nil {
+		return nil
+	}
+	e, err := c.Put(t.key, answer, t.ttl, now)
+	if err != nil || e == nil {
+		return nil
+	}
+	m.cached = &cachedAnswer{answerTarget: *t, entry: e, index: len(m.messages) - 1, noteAt: -1}
+	return m.scheduleAnswerRefresh(now)
+}
+
+// scheduleAnswerRefresh wakes up when the cached answer is due for a
+// background refresh.
+func (m *ChatModel) scheduleAnswerRefresh(now time.Time) tea.Cmd {
+	cachedAt := m.cached.entry.CachedAt
+	wait := m.cached.entry.RefreshAt().Sub(now)
+	if wait <= 0 {
+		return func() tea.Msg { return answerRefreshDueMsg{cachedAt: cachedAt} }
+	}
+	return tea.Tick(wait, func(time.Time) tea.Msg { return answerRefreshDueMsg{cachedAt: cachedAt} })
+}
+
+// refreshAnswerInBackground answers the cached prompt again without
+// showing the run, limited to read commands since nobody is there to
+// approve a write. The new answer replaces the cached one in the chat.
+func (m *ChatModel) refreshAnswerInBackground() tea.Cmd {
+	t := m.cached.answerTarget
+	m.refreshing = true
+	run := m.answerFn
+	if run == nil {
+		run = runAnswer
+	}
+	nowFn := m.nowFn
+	return func() tea.Msg {
+		p := &profile.Profile{}
+		if t.profile != nil {
+			p.Name, p.Accounts = t.profile.Name, t.profile.Accounts
+		}
+		for _, c := range birdcmd.Commands {
+			if !c.Write && t.profile.Allows(c.Name, false) {
+				p.Commands = append(p.Commands, c.Name)
+			}
+		}
+		if len(p.Commands) == 0 {
+			return answerRefreshedMsg{key: t.key, err: errors.New("the profile allows no read commands")}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 6*time.Minute)
+		defer cancel()
+		answer, err := run(ctx, t.key.Prompt, t.key.Model, p.Environ())
+		if err != nil {
+			return answerRefreshedMsg{key: t.key, err: err}
+		}
+		c, err := answercache.Open()
+		if err != nil {
+			return answerRefreshedMsg{key: t.key, err: err}
+		}
+		now := time.Now()
+		if nowFn != nil {
+			now = nowFn()
+		}
+		e, err := c.Put(t.key, answer, t.ttl, now)
+		if err == nil && e == nil {
+			err = errors.New("the agent gave no answer")
+		}
+		return answerRefreshedMsg{key: t.key, entry: e, err: err}
+	}
+}
+
+// applyAnswerRefresh shows a refreshed answer in place of the cached one.
+func (m *ChatModel) applyAnswerRefresh(msg answerRefreshedMsg) tea.Cmd {
+	m.refreshing = false
+	if m.cached == nil || m.cached.key != msg.key {
+		return nil
+	}
+	if msg.err != nil {
+		return m.showNotice("refresh failed: " + msg.err.Error())
+	}
+	m.cached.entry = msg.entry
+	if i := m.cached.index; i < len(m.messages) && m.messages[i].role == "assistant" {
+		m.messages[i].content = msg.entry.Answer
+		m.messages[i].usage = ""
+	}
+	if i := m.cached.noteAt; i >= 0 && i < len(m.messages) && m.messages[i].role == "tool" {
+		m.messages[i].content = fmt.Sprintf("refreshed cached %s in the background (ttl %s)", m.cached.label, formatCacheAge(msg.entry.TTL()))
+	}
+	m.refreshViewport()
+	return tea.Batch(m.showNotice("refreshed: "+m.cached.label), m.scheduleAnswerRefresh(msg.entry.CachedAt))
+}
+
+// refreshCachedAnswer answers the cached prompt again as a new chat turn,
+// for ctrl+l.
+func (m *ChatModel) refreshCachedAnswer() tea.Cmd {
+	t := m.cached.answerTarget
+	cmd := m.beginTurn(t.key.Prompt, t.key.Model, t.profile)
+	m.cacheOnDone = &t
+	return cmd
+}
+
+// runAnswer runs prompt on the agent backend and returns its final answer.
+func runAnswer(ctx context.Context, prompt, model string, env []string) (string, error) {
+	var text, tokens strings.Builder
+	var errs []string
+	claude.Stream(ctx, prompt, model, birdyCmd(), env, func(ev claude.Event) {
+		switch ev.Type {
+		case claude.EventSnapshot:
+			text.Reset()
+			text.WriteString(ev.Text)
+		case claude.EventToken:
+			tokens.WriteString(ev.Text)
+		case claude.EventError:
+			errs = append(errs, ev.Error)
+		}
+	})
+	if len(errs) > 0 {
+		return "", errors.New(strings.Join(slices.Compact(errs), "; "))
+	}
+	if text.Len() > 0 {
+		return text.String(), nil
+	}
+	return tokens.String(), nil
+}
+
+func formatCacheAge(age time.Duration) string {
+	if age < 0 {
+		age = 0
+	}
+	if age < time.Minute {
+		return "<1m"
+	}
+	if age < time.Hour {
+		return fmt.Sprintf("%dm", int(age/time.Minute))
+	}
+	h := int(age / time.Hour)
+	m := int((age % time.Hour) / time.Minute)
+	if m == 0 {
+		return fmt.Sprintf("%dh", h)
+	}
+	return fmt.Sprintf("%dh%02dm", h, m)
+}
@@ -0,0 +1,193 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guzus/birdy/internal/answercache"
+	"github.com/guzus/birdy/internal/profile"
+)
+
+// cacheHomeSummary stores summary as the home summary of m's model, cached
+// at cachedAt for the default TTL.
+func cacheHomeSummary(t *testing.T, m ChatModel, summary string, cachedAt time.Time) {
+	t.Helper()
+	target := newAnswerTarget(homeSummaryPrompt, m.model, m.profile, answercache.TTL(), "home summary")
+	c, err := answercache.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Put(target.key, summary, target.ttl, cachedAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAutoQueryUsesCachedHomeSummary(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(answercache.TTLEnv, "")
+	now := time.Date(2026, 2, 12, 12, 0, 0, 0, time.UTC)
+
+	m := NewChatModel()
+	cacheHomeSummary(t, m, "cached timeline summary", now.Add(-15*time.Minute))
+	m.nowFn = func() time.Time { return now }
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m.accountCount = 1
+
+	m, cmd := m.Update(autoQueryMsg{})
+	if cmd == nil {
+		t.Fatal("expected a scheduled background refresh")
+	}
+	if m.streaming {
+		t.Fatal("expected streaming=false when using cached summary")
+	}
+	if len(m.messages) != 3 {
+		t.Fatalf("expected 3 messages (user/tool/assistant), got %d", len(m.messages))
+	}
+	if m.messages[0].role != "user" || m.messages[0].content != homeSummaryPrompt {
+		t.Fatalf("unexpected first message: %#v", m.messages[0])
+	}
+	if m.messages[1].role != "tool" || !strings.Contains(m.messages[1].content, "15m old, ttl 1h") {
+		t.Fatalf("unexpected cache note message: %#v", m.messages[1])
+	}
+	if m.messages[2].role != "assistant" || m.messages[2].content != "cached timeline summary" {
+		t.Fatalf("unexpected assistant cache message: %#v", m.messages[2])
+	}
+	if header := m.headerRightInfo("1 account", 200); !strings.Contains(header, "CACHED 15m") {
+		t.Fatalf("header = %q", header)
+	}
+	if hint := m.footerHintText(200); !strings.Contains(hint, "ctrl+l: refresh") {
+		t.Fatalf("footer = %q", hint)
+	}
+}
+
+func TestAutoQueryCacheIsPerModel(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Date(2026, 2, 12, 12, 0, 0, 0, time.UTC)
+
+	m := NewChatModel()
+	cacheHomeSummary(t, m, "summary from another model", now.Add(-15*time.Minute))
+	m.model = "some-other-model"
+	m.nowFn = func() time.Time { return now }
+	m.accountCount = 1
+
+	m, _ = m.Update(autoQueryMsg{})
+	if !m.streaming {
+		t.Fatal("expected a live run for a model without a cached answer")
+	}
+}
+
+func TestAutoQueryStaleCacheStartsLivePrompt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Date(2026, 2, 12, 12, 0, 0, 0, time.UTC)
+
+	m := NewChatModel()
+	cacheHomeSummary(t, m, "stale summary", now.Add(-2*time.Hour))
+	m.nowFn = func() time.Time { return now }
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m.accountCount = 1
+
+	m, cmd := m.Update(autoQueryMsg{})
+	if cmd == nil {
+		t.Fatal("expected stale cache to trigger live Claude request")
+	}
+	if !m.streaming {
+		t.Fatal("expected streaming=true when cache is stale")
+	}
+	if m.cacheOnDone == nil || m.cacheOnDone.key.Prompt != homeSummaryPrompt {
+		t.Fatalf("expected the home summary to be cached on done, got %+v", m.cacheOnDone)
+	}
+	if len(m.messages) != 1 || m.messages[0].role != "user" || m.messages[0].content != homeSummaryPrompt {
+		t.Fatalf("unexpected live auto-query messages: %#v", m.messages)
+	}
+}
+
+func TestClaudeDoneSavesHomeSummaryCache(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	now := time.Date(2026, 2, 12, 12, 0, 0, 0, time.UTC)
+
+	m := NewChatModel()
+	m.nowFn = func() time.Time { return now }
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	_ = m.beginPrompt(homeSummaryPrompt)
+	m.messages = append(m.messages, chatMessage{role: "assistant", content: "fresh summary from live run"})
+
+	m, _ = m.Update(claudeDoneMsg{})
+	if m.cacheOnDone != nil {
+		t.Fatal("expected cache-save target to be cleared after done")
+	}
+	if m.cached == nil || m.cached.entry.Answer != "fresh summary from live run" {
+		t.Fatalf("cached = %+v", m.cached)
+	}
+
+	target := newAnswerTarget(homeSummaryPrompt, m.model, nil, answercache.TTL(), "home summary")
+	if e, ok := lookupAnswer(target, now); !ok || e.Answer != "fresh summary from live run" {
+		t.Fatalf("expected cached summary to be saved on done, got %+v", e)
+	}
+}
+
+func TestCachedAnswerRefreshesInBackground(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(answercache.TTLEnv, "1h")
+	now := time.Date(2026, 2, 12, 12, 0, 0, 0, time.UTC)
+
+	m := NewChatModel()
+	m.profile = &profile.Profile{Name: "drafter", Commands: []string{"home", "tweet"}, Accounts: []string{"brand"}}
+	cacheHomeSummary(t, m, "old summary", now.Add(-50*time.Minute))
+	m.nowFn = func() time.Time { return now }
+	var gotEnv []string
+	m.answerFn = func(ctx context.Context, prompt, model string, env []string) (string, error) {
+		gotEnv = env
+		return "new summary", nil
+	}
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m.accountCount = 1
+
+	// Past three quarters of the TTL, the refresh is due right away.
+	m, cmd := m.Update(autoQueryMsg{})
+	due, ok := cmd().(answerRefreshDueMsg)
+	if !ok {
+		t.Fatalf("expected a due refresh, got %T", cmd())
+	}
+	m, cmd = m.Update(due)
+	if !m.refreshing || cmd == nil {
+		t.Fatal("expected a background refresh")
+	}
+	if header := m.headerRightInfo("1 account", 200); !strings.Contains(header, "REFRESHING") {
+		t.Fatalf("header = %q", header)
+	}
+	msg := cmd()
+	if strings.Join(gotEnv, " ") != profile.Env+"=drafter "+profile.CommandsEnv+"=home "+profile.AccountsEnv+"=brand" {
+		t.Fatalf("refresh env = %q, want read commands only", gotEnv)
+	}
+
+	m, _ = m.Update(msg)
+	if m.refreshing || m.messages[2].content != "new summary" || !strings.Contains(m.messages[1].content, "refreshed") {
+		t.Fatalf("after refresh: %#v", m.messages)
+	}
+	if header := m.headerRightInfo("1 account", 200); !strings.Contains(header, "CACHED <1m") {
+		t.Fatalf("header = %q", header)
+	}
+
+	// ctrl+l asks again as a new turn.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlL})
+	if !m.streaming || m.cacheOnDone == nil || m.cached != nil {
+		t.Fatalf("ctrl+l: streaming = %v, cacheOnDone = %+v", m.streaming, m.cacheOnDone)
+	}
+}
+
+func TestFormatCacheAge(t *testing.T) {
+	for age, want := range map[time.Duration]string{
+		-time.Minute:     "<1m",
+		30 * time.Second: "<1m",
+		15 * time.Minute: "15m",
+		time.Hour:        "1h",
+		90 * time.Minute: "1h30m",
+	} {
+		if got := formatCacheAge(age); got != want {
+			t.Errorf("formatCacheAge(%v) = %q, want %q", age, got, want)
+		}
+	}
+}
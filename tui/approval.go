@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/birdcmd"
+)
+
+// approvalRequestMsg asks the user to approve a write command the agent
+// wants to run.
+type approvalRequestMsg struct {
+	pending *approval.Pending
+}
+
+// forwardApprovals sends the server's requests to the stream channel until
+// stop is called. Requests that arrive after stop are denied.
+func forwardApprovals(srv *approval.Server, ch chan<- tea.Msg) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case p := <-srv.Requests():
+				select {
+				case ch <- approvalRequestMsg{pending: p}:
+				case <-done:
+					p.Decide(approval.Decision{Action: approval.Deny})
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// approvalCommand renders a request as the command line shown to the user.
+func approvalCommand(args []string) string {
+	return "birdy " + approval.Quote(args)
+}
+
+// approvalText returns the tweet or reply text of a write command, shown in
+// full under the command, or "" for commands without text.
+func approvalText(args []string) string {
+	for _, c := range birdcmd.Commands {
+		if len(args) == 0 || c.Name != args[0] {
+			continue
+		}
+		pos := 0
+		for _, p := range c.Params {
+			if p.Flag != "" {
+				continue
+			}
+			pos++
+			if p.Name == "text" && pos < len(args) {
+				return args[pos]
+			}
+		}
+	}
+	return ""
+}
+
+// updateApproval handles keys while an approval is pending: y or enter
+// approves, e edits the command in the input, n or esc denies. While
+// editing, enter submits the edited command and esc goes back.
+func (m *ChatModel) updateApproval(msg tea.KeyMsg) tea.Cmd {
+	p := m.approvals[0]
+	if m.approvalEditing {
+		switch msg.String() {
+		case "enter":
+			line := strings.TrimSpace(m.input.Value())
+			line = strings.TrimSpace(strings.TrimPrefix(line, "birdy "))
+			args, err := approval.Split(line)
+			if err != nil || len(args) == 0 {
+				return m.showNotice("edit: enter a bird command")
+			}
+			return m.decideApproval(approval.Decision{Action: approval.Edit, Args: args}, "edited")
+		case "esc":
+			m.endApprovalEdit()
+			m.refreshViewport()
+			return nil
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return cmd
+	}
+
+	switch msg.String() {
+	case "y", "enter":
+		return m.decideApproval(approval.Decision{Action: approval.Approve}, "approved")
+	case "n", "esc":
+		return m.decideApproval(approval.Decision{Action: approval.Deny}, "denied")
+	case "e":
+		m.approvalEditing = true
+		m.approvalDraft = m.input.Value()
+		m.input.SetValue(approvalCommand(p.Args))
+		m.input.CursorEnd()
+	}
+	return nil
+}
+
+// decideApproval answers the oldest pending request and shows the outcome.
+func (m *ChatModel) decideApproval(d approval.Decision, verb string) tea.Cmd {
+	p := m.approvals[0]
+	p.Decide(d)
+	m.approvals = m.approvals[1:]
+	command := approvalCommand(p.Args)
+	if d.Action == approval.Edit {
+		command = approvalCommand(d.Args)
+	}
+	m.endApprovalEdit()
+	m.refreshViewport()
+	return m.showNotice(verb + ": " + summarizeQueueNotice(command, 40))
+}
+
+func (m *ChatModel) endApprovalEdit() {
+	if m.approvalEditing {
+		m.approvalEditing = false
+		m.input.SetValue(m.approvalDraft)
+		m.input.CursorEnd()
+		m.approvalDraft = ""
+	}
+}
+
+// denyApprovals denies every pending request, for when the stream ends.
+func (m *ChatModel) denyApprovals() {
+	for _, p := range m.approvals {
+		p.Decide(approval.Decision{Action: approval.Deny})
+	}
+	m.approvals = nil
+	m.endApprovalEdit()
+}
+
+func (m *ChatModel) showNotice(text string) tea.Cmd {
+	m.queueNoticeID++
+	id := m.queueNoticeID
+	m.queueNotice = text
+	return tea.Tick(1600*time.Millisecond, func(time.Time) tea.Msg {
+		return clearQueueNoticeMsg{ID: id}
+	})
+}
+
+// renderApproval draws the pending request in place of the feed.
+func (m ChatModel) renderApproval(width, height int) string {
+	p := m.approvals[0]
+	inner := width - 6
+	if inner < 10 {
+		inner = 10
+	}
+
+	lines := []string{
+		errorMsgStyle.Render("The agent wants to run a write command"),
+		"",
+		userMsgStyle.Width(inner).Render(approvalCommand(p.Args)),
+	}
+	if text := approvalText(p.Args); text != "" {
+		lines = append(lines, "", toolMsgStyle.Render("Text:"), assistantMsgStyle.Width(inner).Render(text))
+	}
+	hint := "y/enter: approve | e: edit | n/esc: deny"
+	if m.approvalEditing {
+		hint = "edit the command below | enter: run edited | esc: back"
+	}
+	lines = append(lines, "", toolMsgStyle.Render(hint))
+	if n := len(m.approvals) - 1; n > 0 {
+		lines = append(lines, toolMsgStyle.Render(fmt.Sprintf("%d more waiting", n)))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorRed).
+		Background(colorDarkBg).
+		Padding(0, 1).
+		Width(width - 2).
+		Render(strings.Join(lines, "\n"))
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceBackground(colorDarkBg))
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -17,6 +18,11 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guzus/birdy/internal/answercache"
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/recipe"
 	"github.com/guzus/birdy/internal/state"
 	"github.com/guzus/birdy/internal/store"
 )
@@ -24,47 +30,74 @@ import (
 type chatMessage struct {
 	role    string // "user", "assistant", "tool", "error"
 	content string
+	// Tool messages: the command's output once it has finished.
+	output string
+	failed bool
+	ran    bool
+	// usage summarizes the reply's tokens and cost on its last message.
+	usage string
 }
 
 // ChatModel is the main chat screen with viewport, input, and streaming state.
 type ChatModel struct {
-	viewport               viewport.Model
-	input                  textinput.Model
-	spinner                spinner.Model
-	messages               []chatMessage
-	queuedPrompts          []string
-	queueNotice            string
-	queueNoticeID          int
-	streaming              bool
-	streamCh               <-chan tea.Msg
-	cancelStream           context.CancelFunc
-	width                  int
-	height                 int
-	ready                  bool
-	accountCount           int
-	autoQueried            bool
-	cacheHomeSummaryOnDone bool
-	copied                 bool
-	model                  string
-	mouseSeqMode           bool
-	followOutput           bool
-	historyMode            bool
-	historyFiles           []string
-	historyIndex           int
-	historyPreview         string
-	historyError           string
-	hideHistory            bool
-	lastWheelAt            time.Time
-	streamTailContent      string
-	streamTailRendered     string
-	streamTailWidth        int
-	streamTailRenderedAt   time.Time
-	markdownCache          map[string]string
-	mdRenderers            map[int]*glamour.TermRenderer
-	lastStreamRender       time.Time
-	nowFn                  func() time.Time
-	readClipboardFn        func() (string, error)
-	writeClipboardFn       func(string) error
+	viewport             viewport.Model
+	input                textinput.Model
+	spinner              spinner.Model
+	messages             []chatMessage
+	queuedPrompts        []string
+	queueNotice          string
+	queueNoticeID        int
+	streaming            bool
+	streamCh             <-chan tea.Msg
+	cancelStream         context.CancelFunc
+	width                int
+	height               int
+	ready                bool
+	accountCount         int
+	autoQueried          bool
+	cacheOnDone          *answerTarget // cache the answer of the current turn
+	cached               *cachedAnswer // cached answer shown last
+	refreshing           bool          // a background refresh of cached is running
+	copied               bool
+	model                string
+	profile              *profile.Profile // agent profile of new turns; nil for none
+	mouseSeqMode         bool
+	followOutput         bool
+	historyMode          bool
+	historyFiles         []string
+	historyIndex         int
+	historyPreview       string
+	historyError         string
+	historySearching     bool
+	historyQuery         string
+	historySnippets      map[string]string
+	historySeq           int  // bumped on every query edit; stale results are dropped
+	historyIndexing      bool // the chat index is being brought up to date
+	hideHistory          bool
+	lastWheelAt          time.Time
+	streamTailContent    string
+	streamTailRendered   string
+	streamTailWidth      int
+	streamTailRenderedAt time.Time
+	markdownCache        map[string]string
+	mdRenderers          map[int]*glamour.TermRenderer
+	lastStreamRender     time.Time
+	nowFn                func() time.Time
+	answerFn             func(ctx context.Context, prompt, model string, env []string) (string, error)
+	readClipboardFn      func() (string, error)
+	writeClipboardFn     func(string) error
+	totalUsage           claude.Usage
+	sessionID            string // claude CLI session the next turn resumes
+	approvals            []*approval.Pending
+	approvalEditing      bool
+	approvalDraft        string // prompt input saved while editing a command
+	recipes              []recipe.Recipe
+	recipeIndex          int
+	recipePicking        bool
+	recipeEditing        bool
+	recipeDraft          string      // prompt input saved while editing parameters
+	recipeRun            *recipe.Run // recipe run of the current turn
+	recipeFrom           int         // index of the first reply message of recipeRun
 }
 
 type clearCopiedMsg struct{}
@@ -129,15 +162,21 @@ func NewChatModel() ChatModel {
 	sp.Spinner = spinner.Dot
 	sp.Style = lipgloss.NewStyle().Foreground(colorBlue)
 
-	model := "sonnet"
-	if s, err := state.Load(); err == nil && s.Model != "" {
-		model = s.Model
+	model := claude.DefaultModel()
+	var prof *profile.Profile
+	if s, err := state.Load(); err == nil {
+		// A profile removed from profiles.json since is dropped.
+		prof, _ = profile.Get(s.Profile)
+		if slices.Contains(claude.Models(), s.Model) || prof != nil && s.Model == prof.Model {
+			model = s.Model
+		}
 	}
 
 	m := ChatModel{
 		input:            ti,
 		spinner:          sp,
 		model:            model,
+		profile:          prof,
 		followOutput:     true,
 		hideHistory:      hideHistoryEnabled(),
 		markdownCache:    make(map[string]string, 128),
@@ -233,8 +272,28 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 			return m, nil
 		}
 
+		if len(m.approvals) > 0 {
+			return m, m.updateApproval(msg)
+		}
+
+		if m.recipePicking {
+			return m, m.updateRecipePicker(msg)
+		}
+
 		if m.historyMode {
+			if m.historySearching {
+				if handled, cmd := m.updateHistorySearch(msg); handled {
+					m.refreshViewport()
+					m.viewport.GotoTop()
+					return m, cmd
+				}
+			}
 			switch msg.String() {
+			case "ctrl+f":
+				m.historySearching = true
+				m.historyIndexing = true
+				m.refreshViewport()
+				return m, indexChatHistoryCmd()
 			case "esc", "/":
 				m.historyMode = false
 				m.historyError = ""
@@ -314,7 +373,8 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 				m.streaming = false
 				m.lastStreamRender = time.Time{}
 				m.streamCh = nil
-				m.cacheHomeSummaryOnDone = false
+				m.cacheOnDone = nil
+				m.recipeRun = nil
 				m.messages = append(m.messages, chatMessage{role: "error", content: "cancelled"})
 				m.refreshViewport()
 				if cmd := m.startNextQueuedPrompt(); cmd != nil {
@@ -325,14 +385,7 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 
 		case "ctrl+t":
 			if !m.streaming {
-				switch m.model {
-				case "sonnet":
-					m.model = "opus"
-				case "opus":
-					m.model = "haiku"
-				default:
-					m.model = "sonnet"
-				}
+				m.model = nextModel(claude.Models(), m.model)
 				if s, err := state.Load(); err == nil {
 					s.Model = m.model
 					_ = s.Save()
@@ -340,6 +393,21 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 				return m, nil
 			}
 
+		case "ctrl+p":
+			if !m.streaming {
+				return m, m.cycleProfile()
+			}
+
+		case "ctrl+r":
+			if !m.streaming {
+				return m, m.openRecipePicker()
+			}
+
+		case "ctrl+l":
+			if !m.streaming && m.cached != nil {
+				return m, m.refreshCachedAnswer()
+			}
+
 		case "ctrl+y":
 			if text := m.lastAssistantContent(); text != "" {
 				return m, m.writeClipboardCmd(text)
@@ -406,21 +474,51 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 		if m.nowFn != nil {
 			now = m.nowFn()
 		}
-		if summary, cachedAt, ok, err := loadHomeSummaryCache(now); err == nil && ok {
-			age := now.Sub(cachedAt)
-			if age < 0 {
-				age = 0
-			}
-			m.messages = append(m.messages,
-				chatMessage{role: "user", content: homeSummaryPrompt},
-				chatMessage{role: "tool", content: fmt.Sprintf("using cached home summary (%s old, ttl 1h)", formatHomeSummaryAge(age))},
-				chatMessage{role: "assistant", content: summary},
-			)
-			m.cacheHomeSummaryOnDone = false
+		t := newAnswerTarget(homeSummaryPrompt, m.model, m.profile, answercache.TTL(), "home summary")
+		if e, ok := lookupAnswer(t, now); ok {
+			return m, m.showCachedAnswer(t, e, now)
+		}
+		return m, m.beginPrompt(homeSummaryPrompt)
+
+	case answerRefreshDueMsg:
+		if m.cached != nil && !m.refreshing && m.cached.entry.CachedAt.Equal(msg.cachedAt) {
+			return m, m.refreshAnswerInBackground()
+		}
+		return m, nil
+
+	case answerRefreshedMsg:
+		return m, m.applyAnswerRefresh(msg)
+
+	case historyIndexedMsg:
+		m.historyIndexing = false
+		if !m.historyMode || !m.historySearching {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.historyError = fmt.Sprintf("indexing chats failed: %v", msg.err)
 			m.refreshViewport()
 			return m, nil
 		}
-		return m, m.beginPrompt(homeSummaryPrompt)
+		// A search that came due while indexing runs now.
+		if strings.TrimSpace(m.historyQuery) != "" {
+			return m, searchChatHistoryCmd(m.historySeq, m.historyQuery)
+		}
+		return m, nil
+
+	case historySearchDueMsg:
+		if !m.historyMode || !m.historySearching || msg.seq != m.historySeq || m.historyIndexing {
+			return m, nil
+		}
+		return m, searchChatHistoryCmd(msg.seq, m.historyQuery)
+
+	case historySearchResultMsg:
+		if !m.historyMode || !m.historySearching || msg.seq != m.historySeq {
+			return m, nil
+		}
+		m.applyHistorySearch(msg)
+		m.refreshViewport()
+		m.viewport.GotoTop()
+		return m, nil
 
 	case claudeNextMsg:
 		m.streamCh = msg.ch
@@ -477,37 +575,81 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 			return m, waitForNext(m.streamCh)
 		}
 
+	case claudeToolResultMsg:
+		// Results arrive in the order the commands were issued, so fill the
+		// oldest pending tool message of the current turn.
+		start := len(m.messages)
+		for start > 0 && m.messages[start-1].role != "user" {
+			start--
+		}
+		for i := start; i < len(m.messages); i++ {
+			if m.messages[i].role == "tool" && !m.messages[i].ran {
+				m.messages[i].output = sanitizeStreamOutput(msg.Output)
+				m.messages[i].failed = msg.IsError
+				m.messages[i].ran = true
+				break
+			}
+		}
+		if m.followOutput {
+			m.refreshViewport()
+		}
+		if m.streamCh != nil {
+			return m, waitForNext(m.streamCh)
+		}
+
+	case approvalRequestMsg:
+		m.approvals = append(m.approvals, msg.pending)
+		m.refreshViewport()
+		if m.streamCh != nil {
+			return m, waitForNext(m.streamCh)
+		}
+
+	case claudeSessionMsg:
+		m.sessionID = msg.ID
+		if m.streamCh != nil {
+			return m, waitForNext(m.streamCh)
+		}
+
+	case claudeUsageMsg:
+		m.totalUsage.Add(msg.Usage)
+		if len(m.messages) > 0 {
+			m.messages[len(m.messages)-1].usage = formatUsage(msg.Usage)
+			m.refreshViewport()
+		}
+		if m.streamCh != nil {
+			return m, waitForNext(m.streamCh)
+		}
+
 	case claudeDoneMsg:
+		m.denyApprovals()
 		m.streaming = false
 		m.lastStreamRender = time.Time{}
 		m.streamCh = nil
 		m.cancelStream = nil
-		if m.cacheHomeSummaryOnDone {
-			m.cacheHomeSummaryOnDone = false
-			if summary := strings.TrimSpace(m.lastAssistantContent()); summary != "" {
-				now := time.Now()
-				if m.nowFn != nil {
-					now = m.nowFn()
-				}
-				_ = saveHomeSummaryCache(summary, now)
-			}
+		now := time.Now()
+		if m.nowFn != nil {
+			now = m.nowFn()
 		}
+		stored := m.storeAnswer(now)
 		if !m.hideHistory {
-			saveChatHistory(m.messages)
+			saveChatHistory(m.messages, m.sessionID)
 		}
+		saved := m.finishRecipe()
 		m.refreshViewport()
 		if cmd := m.startNextQueuedPrompt(); cmd != nil {
-			return m, cmd
+			return m, tea.Batch(saved, cmd)
 		}
-		return m, nil
+		return m, tea.Batch(stored, saved)
 
 	case claudeErrorMsg:
+		m.denyApprovals()
 		m.streaming = false
 		m.lastStreamRender = time.Time{}
 		m.streamCh = nil
 		m.cancelStream = nil
-		m.cacheHomeSummaryOnDone = false
+		m.cacheOnDone = nil
 		if m.historyMode {
+			m.recipeRun = nil
 			m.queueNoticeID++
 			id := m.queueNoticeID
 			m.queueNotice = "stream failed in background"
@@ -521,13 +663,14 @@ func (m ChatModel) Update(msg tea.Msg) (ChatModel, tea.Cmd) {
 		}
 		m.messages = append(m.messages, chatMessage{role: "error", content: msg.Err.Error()})
 		if !m.hideHistory {
-			saveChatHistory(m.messages)
+			saveChatHistory(m.messages, m.sessionID)
 		}
+		saved := m.finishRecipe()
 		m.refreshViewport()
 		if cmd := m.startNextQueuedPrompt(); cmd != nil {
-			return m, cmd
+			return m, tea.Batch(saved, cmd)
 		}
-		return m, nil
+		return m, saved
 
 	case spinner.TickMsg:
 		// Keep the inline "thinking..." spinner inside viewport content animated.
@@ -622,15 +765,27 @@ func (m *ChatModel) startNextQueuedPrompt() tea.Cmd {
 }
 
 func (m *ChatModel) beginPrompt(prompt string) tea.Cmd {
+	return m.beginTurn(prompt, m.model, m.profile)
+}
+
+// beginTurn sends prompt as the next turn of the chat, answered by model
+// under the agent profile p.
+func (m *ChatModel) beginTurn(prompt, model string, p *profile.Profile) tea.Cmd {
 	m.messages = append(m.messages, chatMessage{role: "user", content: prompt})
 	m.streaming = true
 	m.followOutput = true
 	m.lastStreamRender = time.Time{}
-	m.cacheHomeSummaryOnDone = prompt == homeSummaryPrompt
+	m.cached = nil
+	m.cacheOnDone = nil
+	if prompt == homeSummaryPrompt {
+		m.cacheOnDone = newAnswerTarget(prompt, model, p, answercache.TTL(), "home summary")
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	m.cancelStream = cancel
 	m.refreshViewport()
-	return tea.Batch(startClaude(ctx, buildTurnPrompt(m.messages), m.model), m.spinner.Tick)
+	turn := newClaudeTurn(m.messages, m.sessionID)
+	turn.profile = p
+	return tea.Batch(startClaude(ctx, turn, model), m.spinner.Tick)
 }
 
 func (m *ChatModel) shouldRefreshStream(delta string) bool {
@@ -704,11 +859,23 @@ func (m *ChatModel) renderMessages() string {
 			}
 		case "tool":
 			b.WriteString(toolMsgStyle.Width(w).Render("  > " + linkifyURLs(msg.content)))
+			if msg.ran {
+				b.WriteString("\n")
+				style, mark := toolMsgStyle, "✓"
+				if msg.failed {
+					style, mark = errorMsgStyle, "✗"
+				}
+				b.WriteString(style.Width(w).Render("    " + mark + " " + summarizeToolOutput(msg.output, w-6)))
+			}
 			b.WriteString("\n\n")
 		case "error":
 			b.WriteString(errorMsgStyle.Width(w).Render("Error: " + linkifyURLs(msg.content)))
 			b.WriteString("\n\n")
 		}
+		if msg.usage != "" {
+			b.WriteString(toolMsgStyle.Width(w).Render("  " + msg.usage))
+			b.WriteString("\n\n")
+		}
 	}
 
 	// Show thinking indicator when streaming and last message has no assistant content yet.
@@ -729,6 +896,10 @@ func (m *ChatModel) openHistoryMode() {
 	m.historyFiles = nil
 	m.historyIndex = 0
 	m.historyPreview = ""
+	m.historySearching = false
+	m.historyQuery = ""
+	m.historySnippets = nil
+	m.historySeq++
 
 	files, err := listChatHistoryFiles(128)
 	if err != nil {
@@ -743,9 +914,69 @@ func (m *ChatModel) openHistoryMode() {
 	m.refreshHistoryPreview()
 }
 
+// updateHistorySearch edits the history search query. It reports whether
+// the key was consumed; navigation and enter fall through to the list.
+func (m *ChatModel) updateHistorySearch(msg tea.KeyMsg) (bool, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyEsc:
+		m.openHistoryMode()
+		return true, nil
+	case tea.KeyBackspace:
+		if r := []rune(m.historyQuery); len(r) > 0 {
+			m.historyQuery = string(r[:len(r)-1])
+		}
+	case tea.KeyCtrlU:
+		m.historyQuery = ""
+	case tea.KeySpace:
+		m.historyQuery += " "
+	case tea.KeyRunes:
+		m.historyQuery += string(msg.Runes)
+	default:
+		return false, nil
+	}
+	return true, m.runHistorySearch()
+}
+
+// runHistorySearch schedules a search for the current query, debounced by
+// historySearchDelay. An empty query lists every chat again right away.
+func (m *ChatModel) runHistorySearch() tea.Cmd {
+	m.historySeq++
+	if strings.TrimSpace(m.historyQuery) != "" {
+		seq := m.historySeq
+		return tea.Tick(historySearchDelay, func(time.Time) tea.Msg { return historySearchDueMsg{seq: seq} })
+	}
+	m.historyIndex = 0
+	m.historyError = ""
+	m.historySnippets = nil
+	files, err := listChatHistoryFiles(128)
+	if err != nil {
+		m.historyError = fmt.Sprintf("failed to load history: %v", err)
+		return nil
+	}
+	m.historyFiles = files
+	m.refreshHistoryPreview()
+	return nil
+}
+
+// applyHistorySearch filters the history list to a search's matches, best
+// match first.
+func (m *ChatModel) applyHistorySearch(msg historySearchResultMsg) {
+	m.historyIndex = 0
+	m.historyError = ""
+	m.historySnippets = nil
+	if msg.err != nil {
+		m.historyFiles = nil
+		m.historyError = fmt.Sprintf("search failed: %v", msg.err)
+		return
+	}
+	m.historyFiles = msg.files
+	m.historySnippets = msg.snippets
+	m.refreshHistoryPreview()
+}
+
 func (m *ChatModel) refreshHistoryPreview() {
 	if len(m.historyFiles) == 0 {
-		m.historyPreview = "No saved chats yet."
+		m.historyPreview = m.historyEmptyText()
 		return
 	}
 	if m.historyIndex < 0 {
@@ -774,7 +1005,13 @@ func (m *ChatModel) renderHistoryMessages() string {
 	var b strings.Builder
 	b.WriteString(toolMsgStyle.Width(w).Render("saved at: " + chatHistoryDisplayDir()))
 	b.WriteString("\n")
-	b.WriteString(toolMsgStyle.Width(w).Render("up/down: select | enter: open full chat | esc or /: close"))
+	if m.historySearching {
+		b.WriteString(toolMsgStyle.Width(w).Render("type to search chat contents | up/down: select | enter: open | esc: clear search"))
+		b.WriteString("\n")
+		b.WriteString(accountSelectedStyle.Width(w).Render("search: " + m.historyQuery + "_"))
+	} else {
+		b.WriteString(toolMsgStyle.Width(w).Render("up/down: select | enter: open full chat | ctrl+f: search | esc or /: close"))
+	}
 	b.WriteString("\n\n")
 
 	if m.historyError != "" {
@@ -784,7 +1021,7 @@ func (m *ChatModel) renderHistoryMessages() string {
 	}
 
 	if len(m.historyFiles) == 0 {
-		b.WriteString(toolMsgStyle.Width(w).Render("No saved chats yet."))
+		b.WriteString(toolMsgStyle.Width(w).Render(m.historyEmptyText()))
 		b.WriteString("\n")
 		return b.String()
 	}
@@ -805,6 +1042,9 @@ func (m *ChatModel) renderHistoryMessages() string {
 			line = fmt.Sprintf("> %2d. %s", i+1, chatHistoryFileLabel(m.historyFiles[i]))
 			style = accountSelectedStyle
 		}
+		if snippet := m.historySnippets[m.historyFiles[i]]; snippet != "" {
+			line = summarizeQueueNotice(line+"  "+snippet, w)
+		}
 		b.WriteString(style.Width(w).Render(line))
 		b.WriteString("\n")
 	}
@@ -824,6 +1064,13 @@ func (m *ChatModel) renderHistoryMessages() string {
 	return b.String()
 }
 
+func (m ChatModel) historyEmptyText() string {
+	if strings.TrimSpace(m.historyQuery) != "" {
+		return fmt.Sprintf("No chats match %q.", strings.TrimSpace(m.historyQuery))
+	}
+	return "No saved chats yet."
+}
+
 func (m ChatModel) View() string {
 	if !m.ready {
 		return ""
@@ -899,13 +1146,21 @@ func (m ChatModel) View() string {
 		feedLabel = "HISTORY"
 	}
 	feedBodyWidth := m.feedBodyWidth()
+	feedBody := m.renderFeedBodyWithScrollbar()
+	if len(m.approvals) > 0 {
+		feedLabel = "APPROVAL"
+		feedBody = m.renderApproval(feedBodyWidth, m.viewport.Height)
+	} else if m.recipePicking {
+		feedLabel = "RECIPES"
+		feedBody = m.renderRecipePicker(feedBodyWidth, m.viewport.Height)
+	}
 	feedTitle := sectionTitleStyle.Width(feedBodyWidth).Render(feedLabel)
 	body := lipgloss.NewStyle().
 		Background(colorDarkBg).
 		Foreground(colorLightFg).
 		Width(feedBodyWidth).
 		Height(m.viewport.Height).
-		Render(m.renderFeedBodyWithScrollbar())
+		Render(feedBody)
 	feedContent := lipgloss.JoinVertical(lipgloss.Left, feedTitle, body)
 	feed := feedPanelStyle.Width(panelWidth).Render(feedContent)
 
@@ -964,6 +1219,15 @@ func (m ChatModel) headerRightInfo(accountInfo string, available int) string {
 	}
 
 	statusLabel := "READY"
+	if m.refreshing {
+		statusLabel = "REFRESHING"
+	} else if m.cached != nil {
+		now := time.Now()
+		if m.nowFn != nil {
+			now = m.nowFn()
+		}
+		statusLabel = "CACHED " + formatCacheAge(m.cached.entry.Age(now))
+	}
 	if m.historyMode {
 		statusLabel = "HISTORY"
 	} else if m.streaming {
@@ -974,13 +1238,27 @@ func (m ChatModel) headerRightInfo(accountInfo string, available int) string {
 	}
 
 	model := strings.ToUpper(m.model)
+	if m.profile != nil {
+		model = strings.ToUpper(m.profile.Name) + "/" + model
+	}
 	thinkingLabel := ""
 	if m.streaming && !m.historyMode {
 		thinkingLabel = " thinking..."
 	}
 
+	usage := ""
+	if m.totalUsage.Tokens() > 0 {
+		usage = fmt.Sprintf("%s tok", formatTokenCount(m.totalUsage.Tokens()))
+		if m.totalUsage.CostUSD > 0 {
+			usage += fmt.Sprintf(" $%.2f", m.totalUsage.CostUSD)
+		}
+		usage += " | "
+	}
+
 	candidates := []string{
+		fmt.Sprintf("ACCTS %s | MODEL %s | %s%s%s", accountInfo, model, usage, statusLabel, thinkingLabel),
 		fmt.Sprintf("ACCTS %s | MODEL %s | %s%s", accountInfo, model, statusLabel, thinkingLabel),
+		fmt.Sprintf("%s | %s%s%s", model, usage, statusLabel, thinkingLabel),
 		fmt.Sprintf("%s | %s%s", model, statusLabel, thinkingLabel),
 		statusLabel + thinkingLabel,
 		thinkingLabel,
@@ -1001,7 +1279,10 @@ func (m ChatModel) renderCommandBarContent() string {
 		innerWidth = 1
 	}
 	if m.historyMode {
-		help := summarizeQueueNotice("enter: open full chat | up/down: navigate | esc or /: close", innerWidth)
+		help := summarizeQueueNotice("enter: open full chat | up/down: navigate | ctrl+f: search | esc or /: close", innerWidth)
+		if m.historySearching {
+			help = summarizeQueueNotice("search: "+m.historyQuery+"_", innerWidth)
+		}
 		selected := "no history selected"
 		if len(m.historyFiles) > 0 && m.historyIndex >= 0 && m.historyIndex < len(m.historyFiles) {
 			selected = strings.Replace(m.historyFiles[m.historyIndex], os.Getenv("HOME"), "~", 1)
@@ -1196,8 +1477,36 @@ func (m ChatModel) footerHintText(available int) string {
 	}
 
 	var candidates []string
-	if m.historyMode {
+	if len(m.approvals) > 0 && m.approvalEditing {
+		candidates = []string{
+			"enter: run edited command | esc: back | ctrl+c: quit",
+			"enter: run edited | esc: back",
+		}
+	} else if len(m.approvals) > 0 {
+		candidates = []string{
+			"y/enter: approve | e: edit | n/esc: deny | ctrl+c: quit",
+			"y: approve | e: edit | n: deny",
+		}
+	} else if m.recipePicking && m.recipeEditing {
 		candidates = []string{
+			"enter: run recipe | esc: back | ctrl+c: quit",
+			"enter: run | esc: back",
+		}
+	} else if m.recipePicking {
+		candidates = []string{
+			"^/v: select | enter: run | esc: close | ctrl+c: quit",
+			"enter: run | esc: close",
+		}
+	} else if m.historyMode && m.historySearching {
+		candidates = []string{
+			"type: search | ^/v: select | enter: open | esc: clear search | ctrl+c: quit",
+			"type: search | enter: open | esc: clear search | ctrl+c: quit",
+			"enter: open | esc: clear search",
+			"esc: clear",
+		}
+	} else if m.historyMode {
+		candidates = []string{
+			"^/v: select | enter: open | ctrl+f: search | esc: close | /: close | ctrl+c: quit",
 			"^/v: select | enter: open | esc: close | /: close | ctrl+c: quit",
 			"^/v: select | enter: open | esc: close | ctrl+c: quit",
 			"enter: open | esc: close | ctrl+c: quit",
@@ -1226,6 +1535,7 @@ func (m ChatModel) footerHintText(available int) string {
 	} else {
 		if m.hideHistory {
 			candidates = []string{
+				"^/v: scroll | enter: send | ctrl+t: model | ctrl+p: profile | ctrl+r: recipes | ctrl+y: copy | ctrl+v: paste | tab: accounts | ctrl+c: quit",
 				"^/v: scroll | enter: send | ctrl+t: model | ctrl+y: copy | ctrl+v: paste | tab: accounts | ctrl+c: quit",
 				"^/v: scroll | enter: send | ctrl+t: model | ctrl+y: copy | tab: accounts | ctrl+c: quit",
 				"^/v: scroll | enter: send | ctrl+v: paste | tab: accounts | ctrl+c: quit",
@@ -1235,6 +1545,7 @@ func (m ChatModel) footerHintText(available int) string {
 			}
 		} else {
 			candidates = []string{
+				"^/v: scroll | enter: send | ctrl+t: model | ctrl+p: profile | ctrl+r: recipes | ctrl+y: copy | ctrl+v: paste | tab: accounts | ctrl+c: quit | hist: /",
 				"^/v: scroll | enter: send | ctrl+t: model | ctrl+y: copy | ctrl+v: paste | tab: accounts | ctrl+c: quit | hist: /",
 				"^/v: scroll | enter: send | ctrl+t: model | ctrl+y: copy | tab: accounts | ctrl+c: quit | hist: /",
 				"^/v: scroll | enter: send | ctrl+v: paste | tab: accounts | ctrl+c: quit | hist: /",
@@ -1246,6 +1557,14 @@ func (m ChatModel) footerHintText(available int) string {
 		}
 	}
 
+	if m.cached != nil && !m.streaming && !m.historyMode && !m.recipePicking && len(m.approvals) == 0 {
+		// Offer the refresh key first while a cached answer is shown.
+		withRefresh := make([]string, 0, 2*len(candidates))
+		for _, c := range candidates {
+			withRefresh = append(withRefresh, strings.Replace(c, "enter: send", "enter: send | ctrl+l: refresh", 1), c)
+		}
+		candidates = withRefresh
+	}
 	for _, c := range candidates {
 		if lipgloss.Width(c) <= available {
 			return c
@@ -1274,7 +1593,7 @@ func (m *ChatModel) loadSelectedHistory() tea.Cmd {
 		m.historyIndex = 0
 	}
 	path := m.historyFiles[m.historyIndex]
-	messages, err := loadChatHistoryMessages(path)
+	messages, sessionID, err := loadChatHistoryMessages(path)
 	if err != nil {
 		m.historyError = fmt.Sprintf("failed to load %s: %v", filepath.Base(path), err)
 		m.refreshViewport()
@@ -1282,6 +1601,7 @@ func (m *ChatModel) loadSelectedHistory() tea.Cmd {
 	}
 
 	m.messages = messages
+	m.sessionID = sessionID
 	m.streaming = false
 	m.streamCh = nil
 	m.cancelStream = nil
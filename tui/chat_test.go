@@ -1,16 +1,21 @@
 package tui
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/claude"
 	"github.com/muesli/termenv"
 )
 
@@ -661,6 +666,74 @@ func TestChatToolUseMsg(t *testing.T) {
 	}
 }
 
+func TestChatToolResultAndUsageMsgs(t *testing.T) {
+	m := NewChatModel()
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 120, Height: 24})
+	m.streaming = true
+	ch := make(chan tea.Msg, 10)
+	m.streamCh = ch
+
+	m, _ = m.Update(claudeToolUseMsg{Command: "birdy home"})
+	m, _ = m.Update(claudeToolUseMsg{Command: "birdy mentions"})
+	m, _ = m.Update(claudeToolResultMsg{Output: "[{\"id\":\"1\"}]\n"})
+	m, _ = m.Update(claudeToolResultMsg{Output: "rate limited", IsError: true})
+
+	if !m.messages[0].ran || m.messages[0].failed || m.messages[0].output != "[{\"id\":\"1\"}]\n" {
+		t.Fatalf("first tool = %+v", m.messages[0])
+	}
+	if !m.messages[1].ran || !m.messages[1].failed {
+		t.Fatalf("second tool = %+v", m.messages[1])
+	}
+
+	m, _ = m.Update(claudeTokenMsg{Text: "Done."})
+	m, _ = m.Update(claudeUsageMsg{Usage: claude.Usage{InputTokens: 1200, OutputTokens: 300, CostUSD: 0.25, DurationMS: 4000}})
+	m, _ = m.Update(claudeUsageMsg{Usage: claude.Usage{InputTokens: 500, OutputTokens: 100, CostUSD: 0.5}})
+
+	if got := m.messages[len(m.messages)-1].usage; got != "500 in · 100 out · $0.5000" {
+		t.Errorf("turn usage = %q", got)
+	}
+	if m.totalUsage.Tokens() != 2100 {
+		t.Errorf("total tokens = %d", m.totalUsage.Tokens())
+	}
+	if header := m.headerRightInfo("1/1", 200); !strings.Contains(header, "2.1k tok $0.75") {
+		t.Errorf("header = %q", header)
+	}
+	if view := m.renderMessages(); !strings.Contains(view, "✗ rate limited") {
+		t.Errorf("tool failure not rendered:\n%s", view)
+	}
+}
+
+func TestChatSessionMsgSavedWithHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewChatModel()
+	m.hideHistory = false
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+	m.messages = []chatMessage{{role: "user", content: "hello"}}
+	m.streaming = true
+	m.streamCh = make(chan tea.Msg, 1)
+
+	m, _ = m.Update(claudeSessionMsg{ID: "sess-1"})
+	m, _ = m.Update(claudeTokenMsg{Text: "hi"})
+	m, _ = m.Update(claudeDoneMsg{})
+	if m.sessionID != "sess-1" {
+		t.Fatalf("session id = %q", m.sessionID)
+	}
+
+	files, err := listChatHistoryFiles(1)
+	if err != nil || len(files) != 1 {
+		t.Fatalf("history files = %v (%v)", files, err)
+	}
+	m.sessionID = ""
+	m.historyFiles = files
+	m.loadSelectedHistory()
+	if m.sessionID != "sess-1" {
+		t.Fatalf("reloaded session id = %q", m.sessionID)
+	}
+	if turn := newClaudeTurn(append(m.messages, chatMessage{role: "user", content: "more"}), m.sessionID); turn.resume != "sess-1" || turn.prompt != "more" {
+		t.Fatalf("next turn = %+v", turn)
+	}
+}
+
 func TestChatDoneMsg(t *testing.T) {
 	m := NewChatModel()
 	m, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
@@ -1527,3 +1600,259 @@ func TestLinkifyURLsHandlesWWWAndTrailingPunctuation(t *testing.T) {
 func contains(s, substr string) bool {
 	return len(s) > 0 && len(substr) > 0 && strings.Contains(s, substr)
 }
+
+// runHistoryCmds feeds the history search's index, debounce and result
+// messages back into m until cmd yields none.
+func runHistoryCmds(m ChatModel, cmd tea.Cmd) ChatModel {
+	for cmd != nil {
+		msg := cmd()
+		switch msg.(type) {
+		case historyIndexedMsg, historySearchDueMsg, historySearchResultMsg:
+		default:
+			return m
+		}
+		m, cmd = m.Update(msg)
+	}
+	return m
+}
+
+func TestChatHistorySearchFiltersByContent(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	chatsDir := filepath.Join(home, ".config", "birdy", "chats")
+	if err := os.MkdirAll(chatsDir, 0700); err != nil {
+		t.Fatalf("mkdir chats: %v", err)
+	}
+	for name, body := range map[string]string{
+		"2026-02-11_123000.md": "# birdy chat\n\n## You\n\nsummarize sqlite bookmarks\n",
+		"2026-02-12_090000.md": "# birdy chat\n\n## You\n\nwhat is trending\n",
+	} {
+		if err := os.WriteFile(filepath.Join(chatsDir, name), []byte(body), 0600); err != nil {
+			t.Fatalf("write history: %v", err)
+		}
+	}
+
+	m := NewChatModel()
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	if len(m.historyFiles) != 2 {
+		t.Fatalf("expected 2 history files, got %d", len(m.historyFiles))
+	}
+
+	m, cmd := m.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	if !m.historySearching {
+		t.Fatal("expected ctrl+f to start history search")
+	}
+	m = runHistoryCmds(m, cmd)
+
+	// Keystrokes are debounced: a tick that is no longer the latest edit
+	// does not search.
+	m, stale := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("sq")})
+	// "sqli" matches as a prefix while typing; j/k/g are text, not navigation.
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("li")})
+	if len(m.historyFiles) != 2 {
+		t.Fatalf("expected the list to wait for the search, got %v", m.historyFiles)
+	}
+	if _, next := m.Update(stale()); next != nil {
+		t.Fatal("expected a stale keystroke not to search")
+	}
+	m = runHistoryCmds(m, cmd)
+	if len(m.historyFiles) != 1 || filepath.Base(m.historyFiles[0]) != "2026-02-11_123000.md" {
+		t.Fatalf("expected only the sqlite chat, got %v", m.historyFiles)
+	}
+	if !contains(m.renderMessages(), "[sqlite]") {
+		t.Fatal("expected highlighted snippet in history list")
+	}
+
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	if !m.historyMode {
+		t.Fatal("expected slash to be typed into the search, not close history")
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if !m.historyMode || m.historySearching || len(m.historyFiles) != 2 {
+		t.Fatalf("expected esc to clear search and restore the list, got searching=%v files=%d", m.historySearching, len(m.historyFiles))
+	}
+
+	m, cmd = m.Update(tea.KeyMsg{Type: tea.KeyCtrlF})
+	// Typing before the index is ready searches once it is.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("trending")})
+	m = runHistoryCmds(m, cmd)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.historyMode {
+		t.Fatal("expected enter to open the matching chat")
+	}
+	if len(m.messages) == 0 || m.messages[0].content != "what is trending" {
+		t.Fatalf("unexpected loaded messages: %#v", m.messages)
+	}
+}
+
+func TestChatApprovalModal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix sockets")
+	}
+	srv, err := approval.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	ask := func(args ...string) <-chan approval.Decision {
+		out := make(chan approval.Decision, 1)
+		go func() {
+			d, _ := approval.Ask(context.Background(), srv.Path(), approval.Request{RunID: "run1", Args: args})
+			out <- d
+		}()
+		return out
+	}
+
+	m := NewChatModel()
+	m, _ = m.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	m.streaming = true
+	m.streamCh = make(chan tea.Msg, 1)
+	m.input.SetValue("draft prompt")
+
+	first := ask("reply", "123", "thanks for sharing")
+	m, _ = m.Update(approvalRequestMsg{pending: <-srv.Requests()})
+	view := m.View()
+	for _, want := range []string{"APPROVAL", "birdy reply 123 'thanks for sharing'", "y/enter: approve"} {
+		if !strings.Contains(view, want) {
+			t.Fatalf("view missing %q:\n%s", want, view)
+		}
+	}
+
+	// Edit the command in the input, then go back and edit again.
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	if !m.approvalEditing || m.input.Value() != "birdy reply 123 'thanks for sharing'" {
+		t.Fatalf("editing %v, input %q", m.approvalEditing, m.input.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if m.approvalEditing || m.input.Value() != "draft prompt" || len(m.approvals) != 1 {
+		t.Fatalf("esc while editing: editing %v, input %q", m.approvalEditing, m.input.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	m.input.SetValue(`birdy reply 123 "thanks!"`)
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if d := <-first; d.Action != approval.Edit || !slices.Equal(d.Args, []string{"reply", "123", "thanks!"}) {
+		t.Fatalf("edit decision = %+v", d)
+	}
+	if len(m.approvals) != 0 || m.input.Value() != "draft prompt" {
+		t.Fatalf("after edit: %d pending, input %q", len(m.approvals), m.input.Value())
+	}
+
+	second := ask("follow", "@someone")
+	m, _ = m.Update(approvalRequestMsg{pending: <-srv.Requests()})
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	if d := <-second; d.Action != approval.Deny {
+		t.Fatalf("deny decision = %+v", d)
+	}
+
+	// Requests still open when the reply ends are denied.
+	third := ask("tweet", "hi")
+	m, _ = m.Update(approvalRequestMsg{pending: <-srv.Requests()})
+	m, _ = m.Update(claudeDoneMsg{})
+	if d := <-third; d.Action != approval.Deny || len(m.approvals) != 0 {
+		t.Fatalf("decision on done = %+v, %d pending", d, len(m.approvals))
+	}
+}
+
+func TestChatCtrlPCyclesProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(claude.BackendEnv, "")
+	dir := filepath.Join(home, ".config", "birdy")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	profiles := `{"researcher": {"read_only": true, "model": "opus", "max_turns": 3}, "drafter": {}}`
+	if err := os.WriteFile(filepath.Join(dir, "profiles.json"), []byte(profiles), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewChatModel()
+	if m.profile != nil {
+		t.Fatalf("profile = %+v, want none", m.profile)
+	}
+	ctrlP := tea.KeyMsg{Type: tea.KeyCtrlP}
+	var names []string
+	for range 3 {
+		m, _ = m.Update(ctrlP)
+		if m.profile == nil {
+			names = append(names, "")
+		} else {
+			names = append(names, m.profile.Name)
+		}
+		if m.profile != nil && m.profile.Name == "researcher" {
+			if m.model != "opus" {
+				t.Fatalf("model = %q, want the profile's opus", m.model)
+			}
+			if header := m.headerRightInfo("1/1", 200); !strings.Contains(header, "RESEARCHER/OPUS") {
+				t.Fatalf("header = %q", header)
+			}
+			args := strings.Join(buildClaudeArgs("hi", m.model, "birdy", "", m.profile, nil), "\n")
+			if !strings.Contains(args, "--max-turns\n3\n") || !strings.Contains(args, "Active profile: researcher.") {
+				t.Fatalf("args lack the profile:\n%s", args)
+			}
+		}
+	}
+	if !slices.Equal(names, []string{"drafter", "researcher", ""}) {
+		t.Fatalf("cycle = %q", names)
+	}
+
+	// The choice is remembered.
+	m, _ = m.Update(ctrlP)
+	m, _ = m.Update(ctrlP)
+	if m = NewChatModel(); m.profile == nil || m.profile.Name != "researcher" || m.model != "opus" {
+		t.Fatalf("restored profile = %+v, model %q", m.profile, m.model)
+	}
+}
+
+func TestChatRecipePickerRunsAndSaves(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".config", "birdy", "recipes")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	digest := "---\nmodel: opus\ncommands: user-tweets\nparams: handle, days=7\n---\nDigest @{handle} over {days} days."
+	if err := os.WriteFile(filepath.Join(dir, "digest.md"), []byte(digest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "triage.md"), []byte("Triage my mentions."), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewChatModel()
+	m.nowFn = func() time.Time { return time.Date(2026, 3, 4, 5, 6, 7, 0, time.Local) }
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyCtrlR})
+	if !m.recipePicking || len(m.recipes) != 2 || m.recipes[0].Name != "digest" {
+		t.Fatalf("picker = %v, %+v", m.recipePicking, m.recipes)
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.recipeEditing || m.input.Value() != "handle= days=7" {
+		t.Fatalf("editing = %v, input %q", m.recipeEditing, m.input.Value())
+	}
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m.streaming || !m.recipeEditing {
+		t.Fatal("a missing parameter should keep the parameters open")
+	}
+
+	m.input.SetValue("handle=acme days=3")
+	m, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if !m.streaming || m.recipePicking || m.recipeRun == nil {
+		t.Fatalf("streaming = %v, picking = %v", m.streaming, m.recipePicking)
+	}
+	if got := m.messages[len(m.messages)-1].content; got != "Digest @acme over 3 days." {
+		t.Fatalf("prompt = %q", got)
+	}
+
+	m, _ = m.Update(claudeToolUseMsg{Command: "birdy user-tweets acme"})
+	m, _ = m.Update(claudeSnapshotMsg{Text: "Quiet week."})
+	m, _ = m.Update(claudeDoneMsg{})
+	if m.recipeRun != nil || !strings.HasPrefix(m.queueNotice, "saved: ") {
+		t.Fatalf("notice = %q", m.queueNotice)
+	}
+	path := filepath.Join(home, ".config", "birdy", "chats", "2026-03-04_050607-recipe-digest.md")
+	msgs, _, err := loadChatHistoryMessages(path)
+	if err != nil || len(msgs) != 3 || msgs[0].content != "Digest @acme over 3 days." || msgs[2].content != "Quiet week." {
+		t.Fatalf("saved = %+v, %v", msgs, err)
+	}
+}
@@ -5,14 +5,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/transcript"
 )
 
 // birdyCmd returns the command to invoke birdy. If the current executable
@@ -25,132 +31,37 @@ func birdyCmd() string {
 	return "birdy"
 }
 
-func buildSystemPrompt(cmd string) string {
-	return fmt.Sprintf(`You are birdy, an AI assistant for managing X/Twitter accounts.
-You have access to the birdy CLI tool. Available commands:
-
-Reading & Browsing:
-  %[1]s read <tweet-id>         Read a tweet by ID or URL
-  %[1]s thread <tweet-id>       Read a tweet thread
-  %[1]s search "<query>"        Search for tweets
-  %[1]s home                    Get your home timeline
-  %[1]s mentions                Get your mentions
-  %[1]s bookmarks               Get your bookmarked tweets
-  %[1]s news                    Get trending news
-  %[1]s replies <tweet-id>      Get replies to a tweet
-
-User Info:
-  %[1]s about <username>        Get account information for a user
-  %[1]s whoami                  Show current authenticated user
-  %[1]s followers <username>    Get followers for a user
-  %[1]s following <username>    Get following for a user
-  %[1]s user-tweets <username>  Get tweets for a user
-  %[1]s likes <username>        Get likes for a user
-
-Actions:
-  %[1]s tweet "<text>"          Post a new tweet
-  %[1]s reply <id> "<text>"     Reply to a tweet
-  %[1]s follow <username>       Follow a user
-  %[1]s unfollow <username>     Unfollow a user
-  %[1]s unbookmark <tweet-id>   Remove a tweet from bookmarks
-
-Lists:
-  %[1]s lists <username>        Get lists for a user
-  %[1]s list-timeline <list-id> Get tweets from a list
-
-Other:
-  %[1]s query-ids <id1> <id2>   Query tweets by IDs
-  %[1]s check                   Check credential availability
-  %[1]s account list            List configured accounts
-  %[1]s status                  Show rotation status
-
-IMPORTANT: Always use the exact command "%[1]s" — never use "go run .", "birdy", or any other alternative.
-
-Execution policy (aggressive tool use):
-- Default to running birdy commands first. Do not answer from memory when a command can verify.
-- For factual questions, run at least one relevant read command before answering.
-- For research/exploration tasks, run multiple commands in sequence without waiting for confirmation.
-- If output is ambiguous, run follow-up commands until you can provide a clear, evidence-based answer.
-- Include concise evidence by referencing which commands were run.
-- Ask for confirmation only before state-changing actions (tweet, reply, follow, unfollow, unbookmark).
-
-Use these commands to help the user. Run commands and explain the results clearly.
-When showing tweets, format them nicely. Be concise and helpful.
-
-When the user asks you to "dive deeper", "explore", or "browse" their timeline:
-- Start with %[1]s home to get the timeline
-- Proactively read interesting tweet threads using %[1]s thread <id>
-- Check replies on popular tweets with %[1]s replies <id>
-- Look up users who posted interesting content with %[1]s about <username>
-- Browse their recent tweets with %[1]s user-tweets <username>
-- Follow conversation chains and summarize the most interesting findings
-- You can chain multiple commands without asking — explore autonomously and report back`, cmd)
-}
-
-func buildClaudeArgs(prompt, model, cmd string) []string {
-	return []string{
-		"-p", prompt,
-		"--model", model,
-		"--output-format", "stream-json",
-		"--verbose",
-		"--max-turns", "25",
-		"--allowedTools", fmt.Sprintf("Bash(%s *),Skill(birdy)", cmd),
-		"--append-system-prompt", buildSystemPrompt(cmd),
+// buildClaudeArgs returns claude.BuildArgs for prompt, continuing the CLI
+// session resume when it is set.
+func buildClaudeArgs(prompt, model, cmd, resume string, p *profile.Profile, env []string) []string {
+	args := claude.BuildArgs(prompt, model, cmd, p, env)
+	if resume != "" {
+		args = append(args, "--resume", resume)
 	}
+	return args
 }
 
 func buildTurnPrompt(messages []chatMessage) string {
-	const (
-		maxMessages = 20
-		maxChars    = 1600
-	)
-
-	if len(messages) == 0 {
-		return ""
-	}
-
-	start := len(messages) - maxMessages
-	if start < 0 {
-		start = 0
-	}
-
-	var b strings.Builder
-	b.WriteString("Continue this ongoing birdy TUI chat session.\n")
-	b.WriteString("Do not restart with a generic greeting. Respond directly to the latest user message.\n\n")
-	b.WriteString("Conversation history (oldest to newest):\n")
-
-	for _, m := range messages[start:] {
-		text := strings.TrimSpace(m.content)
-		if text == "" {
-			continue
-		}
-		text = truncatePromptText(text, maxChars)
-
-		switch m.role {
-		case "user":
-			b.WriteString("User: ")
-			b.WriteString(text)
-			b.WriteString("\n")
-		case "assistant":
-			b.WriteString("Assistant: ")
-			b.WriteString(text)
-			b.WriteString("\n")
-		case "tool":
-			b.WriteString("Tool: ")
-			b.WriteString(text)
-			b.WriteString("\n")
-		}
-	}
+	return transcript.TurnPrompt(toTranscript(messages))
+}
 
-	return strings.TrimSpace(b.String())
+// claudeTurn is what a chat turn sends to the agent. When the claude CLI
+// session can be resumed it only needs the new message; otherwise the
+// replayed conversation starts a new session.
+type claudeTurn struct {
+	prompt  string           // latest user message
+	resume  string           // CLI session to continue
+	replay  string           // buildTurnPrompt of the conversation
+	profile *profile.Profile // agent profile; nil for none
 }
 
-func truncatePromptText(s string, maxChars int) string {
-	if maxChars <= 0 || len(s) <= maxChars {
-		return s
+// newClaudeTurn prepares the turn answering the last message in messages.
+func newClaudeTurn(messages []chatMessage, sessionID string) claudeTurn {
+	t := claudeTurn{resume: sessionID, replay: buildTurnPrompt(messages)}
+	if n := len(messages); n > 0 {
+		t.prompt = messages[n-1].content
 	}
-	trimmed := strings.TrimSpace(s[:maxChars])
-	return trimmed + " ...[truncated " + strconv.Itoa(len(s)-maxChars) + " chars]"
+	return t
 }
 
 // Message types for Bubble Tea streaming
@@ -166,6 +77,23 @@ type claudeToolUseMsg struct {
 	Command string
 }
 
+// claudeToolResultMsg carries the (truncated) output of the last command.
+type claudeToolResultMsg struct {
+	Output  string
+	IsError bool
+}
+
+// claudeSessionMsg carries the claude CLI session id of the reply, which
+// the next turn resumes.
+type claudeSessionMsg struct {
+	ID string
+}
+
+// claudeUsageMsg reports the tokens, cost and duration of a reply.
+type claudeUsageMsg struct {
+	Usage claude.Usage
+}
+
 type claudeDoneMsg struct{}
 
 type claudeErrorMsg struct {
@@ -241,18 +169,25 @@ func (b *tokenBatcher) flush() (string, bool) {
 // startClaude spawns a claude process and returns a channel-based message
 // for the Bubble Tea streaming pattern. The context allows cancelling the
 // subprocess when the user presses escape or quits the TUI.
-func startClaude(ctx context.Context, prompt, model string) tea.Cmd {
+func startClaude(ctx context.Context, turn claudeTurn, model string) tea.Cmd {
 	return func() tea.Msg {
-		if _, err := exec.LookPath("claude"); err != nil {
+		if _, err := exec.LookPath("claude"); err != nil && claude.BackendName() == claude.BackendCLI {
 			return claudeErrorMsg{Err: fmt.Errorf("claude CLI not found — install it from https://claude.ai/claude-code")}
 		}
 
 		ch := make(chan tea.Msg, 256)
-		go runClaudeProcess(ctx, prompt, model, ch)
+		go runClaudeProcess(ctx, turn, model, ch)
 		return claudeNextMsg{ch: ch}
 	}
 }
 
+// nextModel returns the model after current in models, wrapping around.
+// An unknown current model selects the first.
+func nextModel(models []string, current string) string {
+	i := slices.Index(models, current)
+	return models[(i+1)%len(models)]
+}
+
 // waitForNext blocks on the channel and returns the next message.
 // Standard Bubble Tea pattern for channel-based streaming.
 func waitForNext(ch <-chan tea.Msg) tea.Cmd {
@@ -265,27 +200,112 @@ func waitForNext(ch <-chan tea.Msg) tea.Cmd {
 	}
 }
 
-// runClaudeProcess executes the claude CLI, scans stdout line-by-line,
-// parses stream-json, and sends messages to the channel.
-func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.Msg) {
+// runBackend streams a reply from an in-process agent backend (the
+// Messages API or an OpenAI-compatible server), which runs bird commands as
+// tools instead of through the claude CLI.
+func runBackend(ctx context.Context, b claude.Backend, prompt, model string, ch chan<- tea.Msg) {
+	b.Stream(ctx, prompt, model, func(ev claude.Event) {
+		switch ev.Type {
+		case claude.EventToken:
+			ch <- claudeTokenMsg{Text: ev.Text}
+		case claude.EventSnapshot:
+			ch <- claudeSnapshotMsg{Text: ev.Text}
+		case claude.EventError:
+			ch <- claudeErrorMsg{Err: fmt.Errorf("%s", ev.Error)}
+		default:
+			if msg := metaMsg(ev); msg != nil {
+				ch <- msg
+			}
+		}
+	})
+}
+
+// metaMsg converts tool and usage events to their Bubble Tea messages.
+func metaMsg(ev claude.Event) tea.Msg {
+	switch ev.Type {
+	case claude.EventToolUse:
+		return claudeToolUseMsg{Command: ev.Command}
+	case claude.EventToolResult:
+		return claudeToolResultMsg{Output: ev.Output, IsError: ev.IsError}
+	case claude.EventSession:
+		return claudeSessionMsg{ID: ev.SessionID}
+	case claude.EventUsage:
+		if ev.Usage != nil {
+			return claudeUsageMsg{Usage: *ev.Usage}
+		}
+	}
+	return nil
+}
+
+// runClaudeProcess answers a chat turn with the claude CLI, resuming the
+// turn's session when it has one, and sends messages to the channel. The
+// in-process backends keep no sessions and always get the replay prompt.
+func runClaudeProcess(ctx context.Context, turn claudeTurn, model string, ch chan<- tea.Msg) {
 	defer close(ch)
 
-	args := buildClaudeArgs(prompt, model, birdyCmd())
+	// Write commands the agent issues wait for the user's approval, asked
+	// over a socket only this run knows.
+	var env []string
+	if srv, err := approval.Listen(); err == nil {
+		defer srv.Close()
+		defer forwardApprovals(srv, ch)()
+		env = append(env, approval.SocketEnv+"="+srv.Path())
+	}
+	// The birdy calls apply the turn's profile, overriding any inherited one.
+	env = append(env, turn.profile.Environ()...)
+
+	if claude.BackendName() != claude.BackendCLI {
+		runBackend(ctx, claude.NewBackend(birdyCmd(), env), turn.replay, model, ch)
+		return
+	}
+
+	if err := claude.CheckBudget(); err != nil {
+		ch <- claudeErrorMsg{Err: err}
+		return
+	}
+	// The run id attributes the agent's birdy calls in the audit log and
+	// the agent budget.
+	runID := audit.NewRunID()
+	var usage *claude.Usage
+	defer func() {
+		if msg := claude.SettleRun(runID, usage); msg != "" {
+			ch <- claudeErrorMsg{Err: errors.New(msg)}
+		}
+	}()
+
+	env = append(append(os.Environ(), env...), audit.RunIDEnv+"="+runID)
+	var err error
+	if turn.resume != "" {
+		usage, err = runClaudeCLI(ctx, buildClaudeArgs(turn.prompt, model, birdyCmd(), turn.resume, turn.profile, env), env, ch)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		// The session may have expired or been made on another machine;
+		// start a new one from the replayed conversation.
+	}
+	usage, err = runClaudeCLI(ctx, buildClaudeArgs(turn.replay, model, birdyCmd(), "", turn.profile, env), env, ch)
+	if err != nil && ctx.Err() == nil {
+		ch <- claudeErrorMsg{Err: err}
+	}
+}
 
+// runClaudeCLI runs the claude CLI once, scans stdout line-by-line, parses
+// stream-json, and sends messages to the channel. It returns the run's
+// usage, and an error when the CLI could not start or sent no response.
+func runClaudeCLI(ctx context.Context, args, env []string, ch chan<- tea.Msg) (usage *claude.Usage, err error) {
 	cmd := exec.CommandContext(ctx, "claude", args...)
+	cmd.Env = env
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		ch <- claudeErrorMsg{Err: fmt.Errorf("failed to create pipe: %w", err)}
-		return
+		return nil, fmt.Errorf("failed to create pipe: %w", err)
 	}
 
 	var stderrBuf bytes.Buffer
 	cmd.Stderr = &stderrBuf
 
 	if err := cmd.Start(); err != nil {
-		ch <- claudeErrorMsg{Err: fmt.Errorf("failed to start claude: %w", err)}
-		return
+		return nil, fmt.Errorf("failed to start claude: %w", err)
 	}
 
 	scanner := bufio.NewScanner(stdout)
@@ -300,6 +320,7 @@ func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.M
 
 	// Also support raw API streaming format (fallback)
 	var toolInput strings.Builder
+	var toolName string
 	inToolBlock := false
 	flushPendingToken := func() {
 		if text, ok := batch.flush(); ok {
@@ -346,6 +367,22 @@ func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.M
 		if err := json.Unmarshal([]byte(line), &event); err != nil {
 			continue // silently skip malformed lines
 		}
+		var meta []tea.Msg
+		for _, ev := range claude.MetaEvents([]byte(line)) {
+			if ev.Type == claude.EventUsage {
+				usage = ev.Usage
+			}
+			if msg := metaMsg(ev); msg != nil {
+				meta = append(meta, msg)
+			}
+		}
+		if len(meta) > 0 && event.Type != "result" {
+			flushPendingSnapshot()
+			flushPendingToken()
+			for _, msg := range meta {
+				ch <- msg
+			}
+		}
 
 		switch event.Type {
 		case "assistant":
@@ -366,13 +403,8 @@ func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.M
 						seenToolIDs[block.ID] = true
 						flushPendingSnapshot()
 						flushPendingToken()
-						var input struct {
-							Command string `json:"command"`
-						}
-						if len(block.Input) > 0 {
-							if err := json.Unmarshal(block.Input, &input); err == nil && input.Command != "" {
-								ch <- claudeToolUseMsg{Command: input.Command}
-							}
+						if command := claude.ToolUseCommand(block.Name, block.Input); command != "" {
+							ch <- claudeToolUseMsg{Command: command}
 						}
 					}
 				}
@@ -414,10 +446,12 @@ func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.M
 			var raw struct {
 				ContentBlock struct {
 					Type string `json:"type"`
+					Name string `json:"name"`
 				} `json:"content_block"`
 			}
 			if err := json.Unmarshal([]byte(line), &raw); err == nil && raw.ContentBlock.Type == "tool_use" {
 				inToolBlock = true
+				toolName = raw.ContentBlock.Name
 				toolInput.Reset()
 			}
 
@@ -426,11 +460,8 @@ func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.M
 			if inToolBlock {
 				flushPendingSnapshot()
 				flushPendingToken()
-				var input struct {
-					Command string `json:"command"`
-				}
-				if err := json.Unmarshal([]byte(toolInput.String()), &input); err == nil && input.Command != "" {
-					ch <- claudeToolUseMsg{Command: input.Command}
+				if command := claude.ToolUseCommand(toolName, json.RawMessage(toolInput.String())); command != "" {
+					ch <- claudeToolUseMsg{Command: command}
 				}
 				inToolBlock = false
 			}
@@ -447,15 +478,18 @@ func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.M
 			}
 			flushPendingSnapshot()
 			flushPendingToken()
+			for _, msg := range meta {
+				ch <- msg
+			}
 			_ = cmd.Wait()
-			return
+			return usage, nil
 
 		case "message_stop":
 			// Raw API format: conversation turn complete
 			flushPendingSnapshot()
 			flushPendingToken()
 			_ = cmd.Wait()
-			return
+			return usage, nil
 		}
 	}
 	flushPendingSnapshot()
@@ -465,7 +499,7 @@ func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.M
 
 	// If context was cancelled, don't report an error
 	if ctx.Err() != nil {
-		return
+		return usage, nil
 	}
 
 	// If we never got any messages, report an error
@@ -474,6 +508,7 @@ func runClaudeProcess(ctx context.Context, prompt, model string, ch chan<- tea.M
 		if stderrBuf.Len() > 0 {
 			errMsg = strings.TrimSpace(stderrBuf.String())
 		}
-		ch <- claudeErrorMsg{Err: fmt.Errorf("%s", errMsg)}
+		return usage, errors.New(errMsg)
 	}
+	return usage, nil
 }
@@ -3,10 +3,18 @@ package tui
 import (
 	"context"
 	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/audit"
+	"github.com/guzus/birdy/internal/claude"
+	"github.com/guzus/birdy/internal/profile"
 )
 
 func TestCliEventParsing(t *testing.T) {
@@ -55,7 +63,7 @@ func TestCliEventParsing(t *testing.T) {
 }
 
 func TestCliContentBlockToolUse(t *testing.T) {
-	input := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tool1","name":"bash","input":{"command":"birdy home"}}]}}`
+	input := `{"type":"assistant","message":{"content":[{"type":"tool_use","id":"tool1","name":"mcp__birdy__home","input":{"count":5}}]}}`
 	var event cliEvent
 	if err := json.Unmarshal([]byte(input), &event); err != nil {
 		t.Fatalf("failed to unmarshal: %v", err)
@@ -76,14 +84,8 @@ func TestCliContentBlockToolUse(t *testing.T) {
 		t.Errorf("expected id=tool1, got %q", block.ID)
 	}
 
-	var input2 struct {
-		Command string `json:"command"`
-	}
-	if err := json.Unmarshal(block.Input, &input2); err != nil {
-		t.Fatalf("failed to unmarshal input: %v", err)
-	}
-	if input2.Command != "birdy home" {
-		t.Errorf("expected 'birdy home', got %q", input2.Command)
+	if got := claude.ToolUseCommand(block.Name, block.Input); got != "birdy home -n 5 --json" {
+		t.Errorf("expected 'birdy home -n 5 --json', got %q", got)
 	}
 }
 
@@ -119,7 +121,7 @@ func TestRunClaudeProcessCancelledContext(t *testing.T) {
 	cancel()
 
 	ch := make(chan tea.Msg, 64)
-	runClaudeProcess(ctx, "test", "sonnet", ch)
+	runClaudeProcess(ctx, claudeTurn{prompt: "test", replay: "test"}, "sonnet", ch)
 
 	// Channel should be closed without sending an error
 	// (either the process fails to start or we detect context cancellation)
@@ -136,7 +138,7 @@ func TestRunClaudeProcessCancelledContext(t *testing.T) {
 func TestStartClaudeWithoutClaudeBinary(t *testing.T) {
 	// Temporarily modify PATH to exclude claude
 	ctx := context.Background()
-	cmd := startClaude(ctx, "test", "sonnet")
+	cmd := startClaude(ctx, claudeTurn{prompt: "test", replay: "test"}, "sonnet")
 	msg := cmd()
 
 	// This should either be a claudeErrorMsg (claude not found)
@@ -152,24 +154,35 @@ func TestStartClaudeWithoutClaudeBinary(t *testing.T) {
 }
 
 func TestSystemPromptContainsKeyCommands(t *testing.T) {
-	prompt := buildSystemPrompt("birdy")
+	prompt := claude.BuildSystemPrompt(true)
 	commands := []string{
-		"birdy read",
-		"birdy search",
-		"birdy home",
-		"birdy tweet",
-		"birdy account list",
+		"mcp__birdy__<command>",
+		"  read <tweet-id>",
+		"  search \"<query>\"",
+		"  home ",
+		"  tweet \"<text>\"",
+		"There is no shell",
 		"dive deeper",
 		"explore autonomously",
 		"Execution policy (aggressive tool use)",
 		"Default to running birdy commands first.",
-		"Ask for confirmation only before state-changing actions",
+		"shown to the user for approval before they run",
+		"<<<UNTRUSTED id>>>",
 	}
 	for _, cmd := range commands {
 		if !containsStr(prompt, cmd) {
 			t.Errorf("system prompt missing: %s", cmd)
 		}
 	}
+
+	// Without an approval socket the agent asks in chat instead.
+	if prompt := claude.BuildSystemPrompt(false); containsStr(prompt, "for approval") || !containsStr(prompt, "Ask for confirmation only before state-changing actions") {
+		t.Errorf("prompt without approval:\n%s", prompt)
+	}
+	withSocket := strings.Join(buildClaudeArgs("hi", "sonnet", "birdy", "", nil, []string{approval.SocketEnv + "=/tmp/a.sock"}), "\n")
+	if !containsStr(withSocket, "shown to the user for approval before they run") {
+		t.Error("args with an approval socket should tell the agent about approvals")
+	}
 }
 
 func TestBirdyCmd(t *testing.T) {
@@ -179,32 +192,99 @@ func TestBirdyCmd(t *testing.T) {
 	}
 }
 
-func TestBuildSystemPromptUsesCommand(t *testing.T) {
-	prompt := buildSystemPrompt("/usr/local/bin/birdy")
-	if !containsStr(prompt, "/usr/local/bin/birdy home") {
-		t.Error("expected system prompt to use the provided command path")
+func TestBuildClaudeArgsUsesAgentExec(t *testing.T) {
+	env := []string{"HOME=/home/me", audit.RunIDEnv + "=run1", profile.Env + "=drafter", profile.Env + "=researcher"}
+	args := buildClaudeArgs("test prompt", "sonnet", "custom-birdy-cmd", "", nil, env)
+
+	i := slices.Index(args, "--mcp-config")
+	if i < 0 || i+1 >= len(args) {
+		t.Fatalf("expected --mcp-config in %q", args)
 	}
-	if !containsStr(prompt, "/usr/local/bin/birdy read") {
-		t.Error("expected system prompt to use the provided command for read")
+	var cfg struct {
+		MCPServers map[string]struct {
+			Command string
+			Args    []string
+			Env     map[string]string
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal([]byte(args[i+1]), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	srv := cfg.MCPServers["birdy"]
+	if srv.Command != "custom-birdy-cmd" || !slices.Equal(srv.Args, []string{"agent-exec"}) {
+		t.Fatalf("server = %+v", srv)
+	}
+	if len(srv.Env) != 2 || srv.Env[audit.RunIDEnv] != "run1" || srv.Env[profile.Env] != "researcher" {
+		t.Fatalf("server env = %v, want the BIRDY_ variables, last wins", srv.Env)
 	}
-}
 
-func TestBuildClaudeArgsUsesProvidedCommand(t *testing.T) {
-	args := buildClaudeArgs("test prompt", "sonnet", "custom-birdy-cmd")
+	joined := strings.Join(args, "\n")
+	if !strings.HasSuffix(joined, "--strict-mcp-config\n--tools\n\n--allowedTools\nmcp__birdy") {
+		t.Errorf("expected built-in tools off and only birdy's MCP tools allowed:\n%s", joined)
+	}
+	if containsStr(joined, "Bash") || containsStr(joined, "custom-birdy-cmd home") {
+		t.Error("expected no shell access to birdy")
+	}
+}
 
-	if len(args) == 0 {
-		t.Fatal("expected non-empty args")
+func TestNewClaudeTurnResumesSession(t *testing.T) {
+	msgs := []chatMessage{
+		{role: "user", content: "first question"},
+		{role: "assistant", content: "first answer"},
+		{role: "user", content: "follow-up question"},
+	}
+	turn := newClaudeTurn(msgs, "sess-1")
+	if turn.prompt != "follow-up question" || turn.resume != "sess-1" || !containsStr(turn.replay, "User: first question") {
+		t.Fatalf("unexpected turn: %+v", turn)
 	}
 
-	joined := strings.Join(args, "\n")
-	if !containsStr(joined, "Bash(custom-birdy-cmd *),Skill(birdy)") {
-		t.Error("expected allowed tools to use provided command")
+	args := strings.Join(buildClaudeArgs(turn.prompt, "sonnet", "birdy", turn.resume, nil, nil), "\n")
+	if !containsStr(args, "--resume\nsess-1") {
+		t.Error("expected --resume with the session id")
+	}
+	if containsStr(strings.Join(buildClaudeArgs(turn.replay, "sonnet", "birdy", "", nil, nil), "\n"), "--resume") {
+		t.Error("expected no --resume without a session")
 	}
-	if !containsStr(joined, "custom-birdy-cmd home") {
-		t.Error("expected system prompt to use provided command")
+}
+
+func TestRunClaudeProcessFallsBackToReplay(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake claude is a shell script")
+	}
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("BIRDY_AGENT_BACKEND", "")
+	bin := t.TempDir()
+	script := `#!/bin/sh
+for a in "$@"; do
+  if [ "$a" = "--resume" ]; then
+    echo "No conversation found with session ID: gone" >&2
+    exit 1
+  fi
+done
+echo '{"type":"system","subtype":"init","session_id":"fresh"}'
+echo '{"type":"result","subtype":"success","result":"replayed","session_id":"fresh","num_turns":1}'
+`
+	if err := os.WriteFile(filepath.Join(bin, "claude"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ch := make(chan tea.Msg, 64)
+	runClaudeProcess(context.Background(), claudeTurn{prompt: "again?", resume: "gone", replay: "User: again?"}, "sonnet", ch)
+
+	var session, text string
+	for msg := range ch {
+		switch msg := msg.(type) {
+		case claudeSessionMsg:
+			session = msg.ID
+		case claudeSnapshotMsg:
+			text = msg.Text
+		case claudeErrorMsg:
+			t.Fatalf("unexpected error: %v", msg.Err)
+		}
 	}
-	if containsStr(joined, "/birdy home") {
-		t.Error("expected args to avoid hardcoded /birdy command")
+	if session != "fresh" || text != "replayed" {
+		t.Fatalf("session %q, text %q", session, text)
 	}
 }
 
@@ -282,6 +362,18 @@ func TestTokenBatcherManualFlush(t *testing.T) {
 	}
 }
 
+func TestNextModel(t *testing.T) {
+	models := []string{"sonnet", "opus", "haiku"}
+	for current, want := range map[string]string{"sonnet": "opus", "opus": "haiku", "haiku": "sonnet", "llama3.1": "sonnet"} {
+		if got := nextModel(models, current); got != want {
+			t.Errorf("nextModel(%q) = %q, want %q", current, got, want)
+		}
+	}
+	if got := nextModel([]string{"qwen2.5"}, "qwen2.5"); got != "qwen2.5" {
+		t.Errorf("single model cycled to %q", got)
+	}
+}
+
 func containsStr(s, substr string) bool {
 	return len(s) > 0 && len(substr) > 0 && len(s) >= len(substr) &&
 		func() bool {
@@ -1,21 +1,20 @@
 package tui
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guzus/birdy/internal/archive"
+	"github.com/guzus/birdy/internal/transcript"
 )
 
 // chatHistoryDir returns the directory for storing chat history markdown files.
 func chatHistoryDir() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(home, ".config", "birdy", "chats"), nil
+	return transcript.Dir()
 }
 
 // chatHistoryDisplayDir returns a compact user-facing chat history path.
@@ -100,69 +99,27 @@ func loadChatHistoryPreview(path string, maxBytes int) (string, error) {
 	return string(raw), nil
 }
 
-// loadChatHistoryMessages parses a saved markdown transcript back to chat messages.
-func loadChatHistoryMessages(path string) ([]chatMessage, error) {
-	raw, err := os.ReadFile(path)
+// loadChatHistoryMessages parses a saved markdown transcript back to chat
+// messages and the claude CLI session they continue.
+func loadChatHistoryMessages(path string) ([]chatMessage, string, error) {
+	messages, sessionID, err := transcript.Load(path)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	lines := strings.Split(strings.ReplaceAll(string(raw), "\r\n", "\n"), "\n")
-
-	var (
-		messages []chatMessage
-		role     string
-		buf      []string
-	)
-	flush := func() {
-		if role == "" {
-			buf = nil
-			return
-		}
-		content := strings.TrimSpace(strings.Join(buf, "\n"))
-		if content != "" {
-			messages = append(messages, chatMessage{role: role, content: content})
-		}
-		buf = nil
-	}
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
-		switch {
-		case trimmed == "## You":
-			flush()
-			role = "user"
-		case trimmed == "## birdy":
-			flush()
-			role = "assistant"
-		case strings.HasPrefix(trimmed, "> `") && strings.HasSuffix(trimmed, "`"):
-			flush()
-			cmd := strings.TrimSuffix(strings.TrimPrefix(trimmed, "> `"), "`")
-			if cmd != "" {
-				messages = append(messages, chatMessage{role: "tool", content: cmd})
-			}
-			role = ""
-		case strings.HasPrefix(trimmed, "**Error:**"):
-			flush()
-			errMsg := strings.TrimSpace(strings.TrimPrefix(trimmed, "**Error:**"))
-			if errMsg != "" {
-				messages = append(messages, chatMessage{role: "error", content: errMsg})
-			}
-			role = ""
-		case strings.HasPrefix(trimmed, "# "):
-			// Skip markdown title row.
-			continue
-		default:
-			if role != "" {
-				buf = append(buf, line)
-			}
-		}
+	out := make([]chatMessage, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, chatMessage{role: m.Role, content: m.Content})
 	}
-	flush()
+	return out, sessionID, nil
+}
 
-	if len(messages) == 0 {
-		return nil, fmt.Errorf("no chat messages found in %s", filepath.Base(path))
+// toTranscript converts chat messages to the shared transcript format.
+func toTranscript(messages []chatMessage) []transcript.Message {
+	out := make([]transcript.Message, 0, len(messages))
+	for _, m := range messages {
+		out = append(out, transcript.Message{Role: m.role, Content: m.content})
 	}
-	return messages, nil
+	return out
 }
 
 func chatHistoryFileLabel(path string) string {
@@ -170,12 +127,24 @@ func chatHistoryFileLabel(path string) string {
 	if ts, err := time.Parse("2006-01-02_150405", base); err == nil {
 		return ts.Format("2006-01-02 15:04:05")
 	}
+	// API chat sessions are saved as <timestamp>-api-<session id>.
+	if stamp, _, ok := strings.Cut(base, "-api"); ok {
+		if ts, err := time.Parse("2006-01-02_150405", stamp); err == nil {
+			return ts.Format("2006-01-02 15:04:05") + " (api)"
+		}
+	}
+	// Recipe runs are saved as <timestamp>-recipe-<name>.
+	if stamp, name, ok := strings.Cut(base, "-recipe-"); ok {
+		if ts, err := time.Parse("2006-01-02_150405", stamp); err == nil {
+			return ts.Format("2006-01-02 15:04:05") + " (recipe " + name + ")"
+		}
+	}
 	return filepath.Base(path)
 }
 
-// saveChatHistory writes the current chat messages to a markdown file.
-// Returns the file path or an error.
-func saveChatHistory(messages []chatMessage) (string, error) {
+// saveChatHistory writes the current chat messages and claude CLI session
+// to a markdown file. Returns the file path or an error.
+func saveChatHistory(messages []chatMessage, sessionID string) (string, error) {
 	if len(messages) == 0 {
 		return "", nil
 	}
@@ -184,38 +153,82 @@ func saveChatHistory(messages []chatMessage) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return "", fmt.Errorf("creating chat history dir: %w", err)
+	now := time.Now()
+	path := filepath.Join(dir, now.Format("2006-01-02_150405")+".md")
+	if err := transcript.Write(path, toTranscript(messages), now, sessionID); err != nil {
+		return "", err
 	}
+	return path, nil
+}
 
-	now := time.Now()
-	filename := now.Format("2006-01-02_150405") + ".md"
-	path := filepath.Join(dir, filename)
-
-	var b strings.Builder
-	b.WriteString(fmt.Sprintf("# birdy chat — %s\n\n", now.Format("2006-01-02 15:04:05")))
-
-	for _, msg := range messages {
-		switch msg.role {
-		case "user":
-			b.WriteString("## You\n\n")
-			b.WriteString(msg.content)
-			b.WriteString("\n\n")
-		case "assistant":
-			if msg.content != "" {
-				b.WriteString("## birdy\n\n")
-				b.WriteString(msg.content)
-				b.WriteString("\n\n")
-			}
-		case "tool":
-			b.WriteString(fmt.Sprintf("> `%s`\n\n", msg.content))
-		case "error":
-			b.WriteString(fmt.Sprintf("**Error:** %s\n\n", msg.content))
+// historySearchDelay is how long the history search waits after a
+// keystroke before querying, so typing a word runs one search.
+const historySearchDelay = 150 * time.Millisecond
+
+// historyIndexedMsg reports that the chat index has caught up with the
+// saved transcripts.
+type historyIndexedMsg struct {
+	err error
+}
+
+// historySearchDueMsg fires historySearchDelay after the query edit seq.
+type historySearchDueMsg struct {
+	seq int
+}
+
+// historySearchResultMsg carries the results of the search for query edit
+// seq.
+type historySearchResultMsg struct {
+	seq      int
+	files    []string
+	snippets map[string]string
+	err      error
+}
+
+// indexChatHistoryCmd brings the full-text index of saved transcripts up to
+// date; history search runs it once when search opens.
+func indexChatHistoryCmd() tea.Cmd {
+	return func() tea.Msg {
+		dir, err := chatHistoryDir()
+		if err != nil {
+			return historyIndexedMsg{err: err}
+		}
+		db, err := archive.Open()
+		if err != nil {
+			return historyIndexedMsg{err: err}
 		}
+		defer db.Close()
+		return historyIndexedMsg{err: db.IndexChats(dir)}
 	}
+}
 
-	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
-		return "", fmt.Errorf("writing chat history: %w", err)
+// searchChatHistoryCmd searches the indexed transcripts for query.
+func searchChatHistoryCmd(seq int, query string) tea.Cmd {
+	return func() tea.Msg {
+		files, snippets, err := searchChatHistory(query, 128)
+		return historySearchResultMsg{seq: seq, files: files, snippets: snippets, err: err}
 	}
-	return path, nil
+}
+
+// searchChatHistory runs a ranked full-text search over indexed transcripts.
+// It returns matching paths best-first and a plain-text snippet per path
+// with matched terms wrapped in [brackets].
+func searchChatHistory(query string, limit int) ([]string, map[string]string, error) {
+	db, err := archive.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close()
+
+	hits, err := db.Search(archive.SearchOptions{Query: query, Chats: true, Limit: limit, Prefix: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	paths := make([]string, 0, len(hits))
+	snippets := make(map[string]string, len(hits))
+	for _, h := range hits {
+		paths = append(paths, h.Path)
+		snippets[h.Path] = strings.Join(strings.Fields(h.Highlight("[", "]")), " ")
+	}
+	return paths, snippets, nil
 }
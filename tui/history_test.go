@@ -9,7 +9,7 @@ import (
 )
 
 func TestSaveChatHistoryEmpty(t *testing.T) {
-	path, err := saveChatHistory(nil)
+	path, err := saveChatHistory(nil, "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -30,13 +30,16 @@ func TestSaveChatHistory(t *testing.T) {
 		{role: "error", content: "something failed"},
 	}
 
-	path, err := saveChatHistory(messages)
+	path, err := saveChatHistory(messages, "0b6c2f4e-session")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 	if path == "" {
 		t.Fatal("expected non-empty path")
 	}
+	if _, sessionID, err := loadChatHistoryMessages(path); err != nil || sessionID != "0b6c2f4e-session" {
+		t.Fatalf("expected saved session id, got %q (%v)", sessionID, err)
+	}
 
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -73,7 +76,7 @@ func TestSaveChatHistoryFilePermissions(t *testing.T) {
 	t.Setenv("HOME", dir)
 
 	messages := []chatMessage{{role: "user", content: "test"}}
-	path, err := saveChatHistory(messages)
+	path, err := saveChatHistory(messages, "")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -180,10 +183,13 @@ func TestLoadChatHistoryMessages(t *testing.T) {
 		t.Fatalf("write: %v", err)
 	}
 
-	msgs, err := loadChatHistoryMessages(path)
+	msgs, sessionID, err := loadChatHistoryMessages(path)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
+	if sessionID != "" {
+		t.Fatalf("expected no session id, got %q", sessionID)
+	}
 	if len(msgs) != 4 {
 		t.Fatalf("expected 4 messages, got %d", len(msgs))
 	}
@@ -207,3 +213,10 @@ func TestChatHistoryFileLabelFromTimestamp(t *testing.T) {
 		t.Fatalf("unexpected label: %q", got)
 	}
 }
+
+func TestChatHistoryFileLabelRecipe(t *testing.T) {
+	got := chatHistoryFileLabel("/tmp/2026-02-11_123000-recipe-mentions-triage.md")
+	if got != "2026-02-11 12:30:00 (recipe mentions-triage)" {
+		t.Fatalf("unexpected label: %q", got)
+	}
+}
@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/state"
+)
+
+// cycleProfile switches new turns to the next agent profile in
+// profiles.json, and back to no profile after the last one. A profile with
+// a model switches to it. The choice is remembered across sessions.
+func (m *ChatModel) cycleProfile() tea.Cmd {
+	profiles, err := profile.Load()
+	if err != nil {
+		return m.showNotice("profiles: " + err.Error())
+	}
+	if len(profiles) == 0 && m.profile == nil {
+		return m.showNotice("no profiles in ~/.config/birdy/profiles.json")
+	}
+	m.profile = nextProfile(profiles, m.profile)
+
+	name := ""
+	if m.profile != nil {
+		name = m.profile.Name
+		if m.profile.Model != "" {
+			m.model = m.profile.Model
+		}
+	}
+	if s, err := state.Load(); err == nil {
+		s.Profile = name
+		s.Model = m.model
+		_ = s.Save()
+	}
+	if name == "" {
+		return m.showNotice("profile: none")
+	}
+	return m.showNotice("profile: " + name)
+}
+
+// nextProfile returns the profile after current, or nil after the last one.
+// No profile comes before the first.
+func nextProfile(profiles []profile.Profile, current *profile.Profile) *profile.Profile {
+	i := -1
+	if current != nil {
+		i = slices.IndexFunc(profiles, func(p profile.Profile) bool { return p.Name == current.Name })
+	}
+	if i+1 >= len(profiles) {
+		return nil
+	}
+	p := profiles[i+1]
+	return &p
+}
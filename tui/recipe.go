@@ -0,0 +1,224 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/guzus/birdy/internal/approval"
+	"github.com/guzus/birdy/internal/profile"
+	"github.com/guzus/birdy/internal/recipe"
+)
+
+// openRecipePicker lists the saved recipes in place of the feed.
+func (m *ChatModel) openRecipePicker() tea.Cmd {
+	recipes, err := recipe.List()
+	if len(recipes) == 0 {
+		if err != nil {
+			return m.showNotice("recipes: " + err.Error())
+		}
+		return m.showNotice("no recipes in ~/.config/birdy/recipes")
+	}
+	m.recipes = recipes
+	m.recipeIndex = 0
+	m.recipePicking = true
+	if err != nil {
+		return m.showNotice("some recipes failed to load")
+	}
+	return nil
+}
+
+// updateRecipePicker handles keys while the picker is open: up/down select,
+// enter picks and esc closes. A recipe with parameters is not started
+// right away; its parameters are filled in the input first, where enter
+// runs it and esc goes back to the list.
+func (m *ChatModel) updateRecipePicker(msg tea.KeyMsg) tea.Cmd {
+	r := m.recipes[m.recipeIndex]
+	if m.recipeEditing {
+		switch msg.String() {
+		case "enter":
+			words, err := approval.Split(m.input.Value())
+			if err != nil {
+				return m.showNotice("params: " + err.Error())
+			}
+			params, err := recipe.ParseParams(words)
+			if err != nil {
+				return m.showNotice(err.Error())
+			}
+			// A parameter left empty counts as not given.
+			for k, v := range params {
+				if v == "" {
+					delete(params, k)
+				}
+			}
+			return m.startRecipe(&r, params)
+		case "esc":
+			m.endRecipeEdit()
+			return nil
+		}
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return cmd
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		if m.recipeIndex > 0 {
+			m.recipeIndex--
+		}
+	case "down", "j":
+		if m.recipeIndex < len(m.recipes)-1 {
+			m.recipeIndex++
+		}
+	case "esc":
+		m.recipePicking = false
+		m.recipes = nil
+	case "enter":
+		if len(r.Params) == 0 {
+			return m.startRecipe(&r, nil)
+		}
+		pairs := make([]string, 0, len(r.Params))
+		for _, p := range r.Params {
+			pairs = append(pairs, p.Name+"="+p.Default)
+		}
+		m.recipeEditing = true
+		m.recipeDraft = m.input.Value()
+		m.input.SetValue(approval.Quote(pairs))
+		m.input.CursorEnd()
+	}
+	return nil
+}
+
+func (m *ChatModel) endRecipeEdit() {
+	if m.recipeEditing {
+		m.recipeEditing = false
+		m.input.SetValue(m.recipeDraft)
+		m.input.CursorEnd()
+		m.recipeDraft = ""
+	}
+}
+
+// startRecipe runs r as the next turn of the chat, with the recipe's model
+// and profile (else the chat's), limited to its commands. The reply is
+// saved to the run's output when the turn ends. A recipe with cache set
+// shows a fresh cached answer instead, and caches the answer of its run.
+func (m *ChatModel) startRecipe(r *recipe.Recipe, params map[string]string) tea.Cmd {
+	now := time.Now()
+	if m.nowFn != nil {
+		now = m.nowFn()
+	}
+	run, err := r.Start(params, now)
+	if err != nil {
+		return m.showNotice(err.Error())
+	}
+	name := r.Profile
+	if name == "" && m.profile != nil {
+		name = m.profile.Name
+	}
+	p, err := profile.Resolve(name, strings.Join(r.Commands, ","))
+	if err != nil {
+		return m.showNotice("recipe: " + err.Error())
+	}
+	model := r.Model
+	if model == "" && p != nil {
+		model = p.Model
+	}
+	if model == "" {
+		model = m.model
+	}
+
+	m.endRecipeEdit()
+	m.recipePicking = false
+	m.recipes = nil
+	t := newAnswerTarget(run.Prompt, model, p, r.Cache, "recipe "+r.Name)
+	if e, ok := lookupAnswer(t, now); ok {
+		return m.showCachedAnswer(t, e, now)
+	}
+	m.recipeRun = run
+	m.recipeFrom = len(m.messages) + 1
+	cmd := m.beginTurn(run.Prompt, model, p)
+	if r.Cache > 0 {
+		m.cacheOnDone = t
+	}
+	return cmd
+}
+
+// finishRecipe saves the reply of the recipe run that just ended, if any.
+func (m *ChatModel) finishRecipe() tea.Cmd {
+	run := m.recipeRun
+	if run == nil {
+		return nil
+	}
+	m.recipeRun = nil
+	var reply []chatMessage
+	if m.recipeFrom <= len(m.messages) {
+		reply = m.messages[m.recipeFrom:]
+	}
+	if err := run.Save(toTranscript(reply)); err != nil {
+		return m.showNotice("recipe: " + err.Error())
+	}
+	return m.showNotice("saved: " + filepath.Base(run.Output))
+}
+
+// recipeParamList renders a recipe's parameters, with their defaults.
+func recipeParamList(r recipe.Recipe) string {
+	parts := make([]string, 0, len(r.Params))
+	for _, p := range r.Params {
+		if p.HasDefault {
+			parts = append(parts, p.Name+"="+p.Default)
+		} else {
+			parts = append(parts, p.Name)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// renderRecipePicker draws the recipe list in place of the feed.
+func (m ChatModel) renderRecipePicker(width, height int) string {
+	inner := width - 6
+	if inner < 10 {
+		inner = 10
+	}
+
+	lines := []string{toolMsgStyle.Render("Run a recipe"), ""}
+	if m.recipeEditing {
+		r := m.recipes[m.recipeIndex]
+		lines = append(lines,
+			userMsgStyle.Width(inner).Render(r.Name),
+			assistantMsgStyle.Width(inner).Render(r.Description),
+			"",
+			toolMsgStyle.Render("fill in the parameters below | enter: run | esc: back"),
+		)
+	} else {
+		for i, r := range m.recipes {
+			line := fmt.Sprintf("  %s", r.Name)
+			if params := recipeParamList(r); params != "" {
+				line += " (" + params + ")"
+			}
+			if r.Description != "" {
+				line += " - " + r.Description
+			}
+			line = summarizeQueueNotice(line, inner)
+			if i == m.recipeIndex {
+				line = userMsgStyle.Render(">" + line[1:])
+			} else {
+				line = assistantMsgStyle.Render(line)
+			}
+			lines = append(lines, line)
+		}
+		lines = append(lines, "", toolMsgStyle.Render("^/v: select | enter: run | esc: close"))
+	}
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorBlue).
+		Background(colorDarkBg).
+		Padding(0, 1).
+		Width(width - 2).
+		Render(strings.Join(lines, "\n"))
+	return lipgloss.Place(width, height, lipgloss.Center, lipgloss.Center, box,
+		lipgloss.WithWhitespaceBackground(colorDarkBg))
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/guzus/birdy/internal/claude"
 )
 
 func TestNewMainModel(t *testing.T) {
@@ -91,3 +92,26 @@ func TestMainModelRoutesToSplash(t *testing.T) {
 		t.Error("expected splash to return a command on keypress")
 	}
 }
+
+func TestMainModelRoutesStreamMetaToChat(t *testing.T) {
+	m := NewMainModel()
+	m.currentScreen = screenAccount
+	ch := make(chan tea.Msg)
+	m.chat.streaming = true
+	m.chat.streamCh = ch
+
+	for _, msg := range []tea.Msg{
+		claudeSessionMsg{ID: "sess-1"},
+		claudeUsageMsg{Usage: claude.Usage{InputTokens: 10}},
+		claudeToolResultMsg{Output: "ok"},
+	} {
+		updated, cmd := m.Update(msg)
+		m = updated.(MainModel)
+		if cmd == nil {
+			t.Fatalf("%T: expected the chat to keep reading the stream", msg)
+		}
+	}
+	if m.chat.sessionID != "sess-1" || m.chat.totalUsage.InputTokens != 10 {
+		t.Fatalf("chat not updated: session %q, usage %+v", m.chat.sessionID, m.chat.totalUsage)
+	}
+}
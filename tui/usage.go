@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/guzus/birdy/internal/claude"
+)
+
+// formatTokenCount renders a token count compactly: 950, 12.3k, 1.2M.
+func formatTokenCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1_000:
+		return fmt.Sprintf("%.1fk", float64(n)/1_000)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
+
+// formatUsage summarizes one reply, e.g. "12.3k in · 450 out · $0.0421 · 8.2s".
+func formatUsage(u claude.Usage) string {
+	parts := []string{
+		formatTokenCount(u.InputTokens+u.CacheReadTokens+u.CacheWriteTokens) + " in",
+		formatTokenCount(u.OutputTokens) + " out",
+	}
+	if u.CostUSD > 0 {
+		parts = append(parts, fmt.Sprintf("$%.4f", u.CostUSD))
+	}
+	if u.DurationMS > 0 {
+		parts = append(parts, fmt.Sprintf("%.1fs", float64(u.DurationMS)/1000))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// summarizeToolOutput shows the first line of a command's output, cut to
+// width, with a count of the remaining lines.
+func summarizeToolOutput(output string, width int) string {
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return "no output"
+	}
+	lines := strings.Split(output, "\n")
+	suffix := ""
+	if len(lines) > 1 {
+		suffix = fmt.Sprintf(" (+%d lines)", len(lines)-1)
+	}
+	first := strings.TrimSpace(lines[0])
+	if limit := width - len([]rune(suffix)); limit > 0 {
+		if r := []rune(first); len(r) > limit {
+			first = string(r[:max(limit-1, 0)]) + "…"
+		}
+	}
+	return first + suffix
+}